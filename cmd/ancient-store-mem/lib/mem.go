@@ -43,6 +43,7 @@ var (
 type MemFreezerRemoteServerAPI struct {
 	store map[string][]byte
 	count uint64
+	tail  uint64
 	mu    sync.Mutex
 }
 
@@ -57,8 +58,9 @@ func (r *MemFreezerRemoteServerAPI) storeKey(kind string, number uint64) string
 }
 
 func (f *MemFreezerRemoteServerAPI) Reset() {
-	f.count = 0
 	f.mu.Lock()
+	f.count = 0
+	f.tail = 0
 	f.store = make(map[string][]byte)
 	f.mu.Unlock()
 }
@@ -85,17 +87,33 @@ func (f *MemFreezerRemoteServerAPI) Ancient(kind string, number uint64) ([]byte,
 
 func (f *MemFreezerRemoteServerAPI) Ancients() (uint64, error) {
 	// fmt.Println("mock server called", "method=Ancients")
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	return f.count, nil
 }
 
+func (f *MemFreezerRemoteServerAPI) Tail() (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tail, nil
+}
+
+// AncientRange returns at most count items starting from start. If maxBytes is
+// specified, it returns at least one item, and as many as fit into maxBytes.
 func (f *MemFreezerRemoteServerAPI) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if start >= f.count || (isPrunable(kind) && start < f.tail) {
+		return nil, errOutOfBounds
+	}
 	res := make([][]byte, 0)
-	// fmt.Println("mock server called", "method=Ancients")
-	for i := uint64(0); i < count; i++ {
-		item := f.store[f.storeKey(kind, start+i)]
-		if len(item) > int(maxBytes) {
-			item = item[:int(maxBytes)]
+	size := uint64(0)
+	for i := start; i < start+count && i < f.count; i++ {
+		item := f.store[f.storeKey(kind, i)]
+		if maxBytes != 0 && len(res) > 0 && size+uint64(len(item)) > maxBytes {
+			break
 		}
+		size += uint64(len(item))
 		res = append(res, item)
 	}
 	return res, nil
@@ -103,6 +121,8 @@ func (f *MemFreezerRemoteServerAPI) AncientRange(kind string, start, count, maxB
 
 func (f *MemFreezerRemoteServerAPI) AncientSize(kind string) (uint64, error) {
 	// fmt.Println("mock server called", "method=AncientSize")
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	sum := uint64(0)
 	for k, v := range f.store {
 		if strings.HasPrefix(k, kind) {
@@ -123,12 +143,12 @@ var fieldNames = []string{
 func (f *MemFreezerRemoteServerAPI) AppendAncient(number uint64, hash, header, body, receipt, td []byte) error {
 	// fmt.Println("mock server called", "method=AppendAncient", "number=", number, "header", fmt.Sprintf("%x", header))
 	fields := [][]byte{hash, header, body, receipt, td}
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if number != f.count {
 		return errOutOfOrder
 	}
 	f.count = number + 1
-	f.mu.Lock()
-	defer f.mu.Unlock()
 	for i, fv := range fields {
 		kind := fieldNames[i]
 		f.store[f.storeKey(kind, number)] = fv
@@ -137,6 +157,8 @@ func (f *MemFreezerRemoteServerAPI) AppendAncient(number uint64, hash, header, b
 }
 
 func (f *MemFreezerRemoteServerAPI) Append(kind string, num uint64, item interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if f.count != num {
 		return fmt.Errorf("%w: num=%d, count=%d", errOutOfOrder, num, f.count)
 	}
@@ -155,55 +177,64 @@ func (f *MemFreezerRemoteServerAPI) Append(kind string, num uint64, item interfa
 	}
 
 	str := item.(string)
-
-	f.mu.Lock()
 	f.store[f.storeKey(kind, num)] = common.Hex2Bytes(str)
-	f.mu.Unlock()
 
 	return nil
 }
 
 func (f *MemFreezerRemoteServerAPI) AppendRaw(kind string, num uint64, item []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if f.count != num {
 		return fmt.Errorf("%w: num=%d, count=%d", errOutOfOrder, num, f.count)
 	}
-
-	f.mu.Lock()
+	// As for Append, the 'diffs' are the last field written for a block.
+	if kind == freezerRemoteDifficultyTable {
+		f.count = num + 1
+	}
 	f.store[f.storeKey(kind, num)] = item
-	f.mu.Unlock()
 
 	return nil
 }
 
 func (f *MemFreezerRemoteServerAPI) TruncateTail(n uint64) error {
 	// fmt.Println("mock server called", "method=TruncateAncients")
-	if f.count <= n {
-		return nil
-	}
-	f.count = n
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	if f.tail >= n {
+		return nil
+	}
+	f.tail = n
 	for k := range f.store {
 		spl := strings.Split(k, "-")
+		if !isPrunable(spl[0]) {
+			continue
+		}
 		num, err := strconv.ParseUint(spl[1], 10, 64)
 		if err != nil {
 			return err
 		}
-		if num <= n {
+		if num < n {
 			delete(f.store, k)
 		}
 	}
 	return nil
 }
 
+// isPrunable reports whether the tail applies to the table. As for the local
+// chain freezer, only bodies and receipts are pruned.
+func isPrunable(kind string) bool {
+	return kind == freezerRemoteBodiesTable || kind == freezerRemoteReceiptTable
+}
+
 func (f *MemFreezerRemoteServerAPI) TruncateHead(n uint64) error {
 	// fmt.Println("mock server called", "method=TruncateAncients")
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if f.count <= n {
 		return nil
 	}
 	f.count = n
-	f.mu.Lock()
-	defer f.mu.Unlock()
 	for k := range f.store {
 		spl := strings.Split(k, "-")
 		num, err := strconv.ParseUint(spl[1], 10, 64)
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/forkid"
+	"github.com/yuriy0803/core-geth1/params/confp"
+	"github.com/yuriy0803/core-geth1/params/types/coregeth"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+	"github.com/yuriy0803/core-geth1/params/types/genesisT"
+)
+
+// TestConvertRoundTrip tests that the forks, fork ids and genesis blocks of the
+// default chain configurations survive a round trip through the other client formats.
+func TestConvertRoundTrip(t *testing.T) {
+	for _, name := range []string{"classic", "mordor", "mintme"} {
+		for _, format := range []string{"parity", "besu"} {
+			t.Run(name+"/"+format, func(t *testing.T) {
+				original := defaultChainspecValues[name].(*genesisT.Genesis)
+
+				converted, err := newChainspecValue(format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := confp.Crush(converted, original, true); err != nil {
+					t.Fatal(err)
+				}
+				b, err := jsonMarshalPretty(converted)
+				if err != nil {
+					t.Fatal(err)
+				}
+				read, err := unmarshalChainSpec(format, b)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := &genesisT.Genesis{Config: &coregeth.CoreGethChainConfig{}}
+				if err := confp.Crush(got, read, true); err != nil {
+					t.Fatal(err)
+				}
+
+				if want, have := confp.BlockForks(original.Config), confp.BlockForks(got.Config); !reflect.DeepEqual(want, have) {
+					t.Errorf("block forks mismatch, want: %v, got: %v", want, have)
+				}
+				if want, have := confp.TimeForks(original.Config), confp.TimeForks(got.Config); !reflect.DeepEqual(want, have) {
+					t.Errorf("time forks mismatch, want: %v, got: %v", want, have)
+				}
+
+				wantBlock, gotBlock := core.GenesisToBlock(original, nil), core.GenesisToBlock(got, nil)
+				if wantBlock.Hash() != gotBlock.Hash() {
+					t.Errorf("genesis hash mismatch, want: %x, got: %x", wantBlock.Hash(), gotBlock.Hash())
+				}
+
+				heads := []uint64{0}
+				for _, f := range confp.BlockForks(original.Config) {
+					heads = append(heads, f-1, f)
+				}
+				for _, head := range heads {
+					want := forkid.NewID(original.Config, wantBlock.Hash(), head, 0)
+					have := forkid.NewID(got.Config, wantBlock.Hash(), head, 0)
+					if want != have {
+						t.Errorf("fork id mismatch at block %d, want: %v, got: %v", head, want, have)
+					}
+				}
+
+				if format == "parity" {
+					if diffs := confp.Equal(reflect.TypeOf((*ctypes.ChainConfigurator)(nil)), original.Config, got.Config); len(diffs) != 0 {
+						for _, diff := range diffs {
+							t.Errorf("not equal: %s, want: %v, got: %v", diff.Field, diff.A, diff.B)
+						}
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/common/math"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/forkid"
+	"github.com/yuriy0803/core-geth1/params/confp"
+	"github.com/yuriy0803/core-geth1/params/types/coregeth"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+	"github.com/yuriy0803/core-geth1/params/types/genesisT"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	diffFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: fmt.Sprintf("Format type of the compared configuration file, defaults to --inputf [%s]", strings.Join(chainspecFormats, "|")),
+	}
+	diffAtFlag = cli.StringFlag{
+		Name:  "at",
+		Usage: "Comma separated heads (<block>[@<timestamp>]) at which to compare fork ids, defaults to genesis and every fork",
+	}
+	diffJSONFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the comparison as JSON",
+	}
+)
+
+var diffCommand = cli.Command{
+	Name:        "diff",
+	Description: "Exits 0 if the configurations are equal, 1 if not.",
+	Usage:       "Compare the configuration with another, fork-by-fork",
+	ArgsUsage:   "<default name|file>",
+	Flags: []cli.Flag{
+		diffFormatFlag,
+		diffAtFlag,
+		diffJSONFlag,
+	},
+	Action: diff,
+}
+
+var errDiffConfigsDiffer = errors.New("configurations differ")
+
+// chainspecFieldDiff is a value which differs between two configurations.
+type chainspecFieldDiff struct {
+	Kind  string      `json:"kind"`
+	Field string      `json:"field"`
+	A     interface{} `json:"a"`
+	B     interface{} `json:"b"`
+}
+
+// forkHead is a chain head at which fork ids are compared.
+type forkHead struct {
+	Block uint64 `json:"block"`
+	Time  uint64 `json:"time"`
+}
+
+type forkIDJSON struct {
+	Hash hexutil.Bytes `json:"hash"`
+	Next uint64        `json:"next"`
+}
+
+type forkIDDiff struct {
+	forkHead
+	A     forkIDJSON `json:"a"`
+	B     forkIDJSON `json:"b"`
+	Equal bool       `json:"equal"`
+}
+
+// chainspecDiff is the result of comparing two configurations.
+type chainspecDiff struct {
+	A       string               `json:"a"`
+	B       string               `json:"b"`
+	Equal   bool                 `json:"equal"`
+	Diffs   []chainspecFieldDiff `json:"diffs"`
+	ForkIDs []forkIDDiff         `json:"forkIds"`
+}
+
+func diff(ctx *cli.Context) error {
+	if !ctx.Args().Present() {
+		return errors.New("missing configuration to compare with")
+	}
+	nameB := ctx.Args().First()
+	b, err := readDiffChainspec(ctx, nameB)
+	if err != nil {
+		return err
+	}
+	var heads []forkHead
+	if ctx.IsSet(diffAtFlag.Name) {
+		heads, err = parseForkHeads(ctx.String(diffAtFlag.Name))
+		if err != nil {
+			return err
+		}
+	}
+	nameA := ctx.GlobalString(defaultValueFlag.Name)
+	if nameA == "" {
+		nameA = ctx.GlobalString(fileInFlag.Name)
+	}
+	d, err := diffChainspecs(globalChainspecValue, b, heads)
+	if err != nil {
+		return err
+	}
+	d.A, d.B = nameA, nameB
+
+	if ctx.Bool(diffJSONFlag.Name) {
+		out, err := jsonMarshalPretty(d)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		printChainspecDiff(d)
+	}
+	if !d.Equal {
+		return cli.NewExitError(errDiffConfigsDiffer, 1)
+	}
+	return nil
+}
+
+// readDiffChainspec reads the configuration to compare with, either a default or a file.
+func readDiffChainspec(ctx *cli.Context, name string) (ctypes.Configurator, error) {
+	if v, ok := defaultChainspecValues[name]; ok {
+		return v, nil
+	}
+	format := ctx.String(diffFormatFlag.Name)
+	if format == "" {
+		format = ctx.GlobalString(formatInFlag.Name)
+	}
+	if format == "" {
+		return nil, fmt.Errorf("%w: unknown default %q and no format given", errInvalidChainspecValue, name)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalChainSpec(format, data)
+}
+
+// parseForkHeads parses a comma separated list of <block>[@<timestamp>] values.
+func parseForkHeads(s string) ([]forkHead, error) {
+	var heads []forkHead
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		var head forkHead
+		block, time, hasTime := strings.Cut(v, "@")
+		n, ok := math.ParseUint64(block)
+		if !ok {
+			return nil, fmt.Errorf("invalid block number: %q", block)
+		}
+		head.Block = n
+		if hasTime {
+			t, ok := math.ParseUint64(time)
+			if !ok {
+				return nil, fmt.Errorf("invalid timestamp: %q", time)
+			}
+			head.Time = t
+		}
+		heads = append(heads, head)
+	}
+	return heads, nil
+}
+
+// defaultForkHeads returns the genesis and the fork heads of both configurations.
+func defaultForkHeads(a, b ctypes.ChainConfigurator) []forkHead {
+	blocks := map[uint64]struct{}{0: {}}
+	times := map[uint64]struct{}{}
+	for _, c := range []ctypes.ChainConfigurator{a, b} {
+		for _, f := range confp.BlockForks(c) {
+			blocks[f] = struct{}{}
+		}
+		for _, f := range confp.TimeForks(c) {
+			times[f] = struct{}{}
+		}
+	}
+	var heads []forkHead
+	var last uint64
+	for n := range blocks {
+		heads = append(heads, forkHead{Block: n})
+		if n > last {
+			last = n
+		}
+	}
+	for t := range times {
+		heads = append(heads, forkHead{Block: last, Time: t})
+	}
+	sort.Slice(heads, func(i, j int) bool {
+		if heads[i].Block != heads[j].Block {
+			return heads[i].Block < heads[j].Block
+		}
+		return heads[i].Time < heads[j].Time
+	})
+	return heads
+}
+
+// diffChainspecs compares the chain and genesis values of the configurations,
+// and their fork ids at the given heads.
+func diffChainspecs(a, b ctypes.Configurator, heads []forkHead) (*chainspecDiff, error) {
+	d := &chainspecDiff{
+		Diffs:   []chainspecFieldDiff{},
+		ForkIDs: []forkIDDiff{},
+	}
+	for _, k := range []reflect.Type{
+		reflect.TypeOf((*ctypes.ChainConfigurator)(nil)).Elem(),
+		reflect.TypeOf((*ctypes.GenesisBlocker)(nil)).Elem(),
+	} {
+		diffs, err := diffGetters(k, a, b)
+		if err != nil {
+			return nil, err
+		}
+		d.Diffs = append(d.Diffs, diffs...)
+	}
+
+	genesisA, err := chainspecGenesisHash(a)
+	if err != nil {
+		return nil, err
+	}
+	genesisB, err := chainspecGenesisHash(b)
+	if err != nil {
+		return nil, err
+	}
+	if genesisA != genesisB {
+		d.Diffs = append(d.Diffs, chainspecFieldDiff{Kind: "genesis", Field: "GenesisHash", A: genesisA, B: genesisB})
+	}
+
+	if heads == nil {
+		heads = defaultForkHeads(a, b)
+	}
+	for _, head := range heads {
+		idA := forkid.NewID(a, genesisA, head.Block, head.Time)
+		idB := forkid.NewID(b, genesisB, head.Block, head.Time)
+		d.ForkIDs = append(d.ForkIDs, forkIDDiff{
+			forkHead: head,
+			A:        forkIDJSON{Hash: idA.Hash[:], Next: idA.Next},
+			B:        forkIDJSON{Hash: idB.Hash[:], Next: idB.Next},
+			Equal:    idA == idB,
+		})
+	}
+
+	d.Equal = len(d.Diffs) == 0
+	for _, id := range d.ForkIDs {
+		d.Equal = d.Equal && id.Equal
+	}
+	return d, nil
+}
+
+// diffGetters compares the values returned by the argument-less getters of the interface type.
+func diffGetters(k reflect.Type, a, b interface{}) ([]chainspecFieldDiff, error) {
+	var diffs []chainspecFieldDiff
+	for i := 0; i < k.NumMethod(); i++ {
+		method := k.Method(i)
+		if !strings.HasPrefix(method.Name, "Get") || method.Type.NumIn() > 0 || method.Type.NumOut() != 1 {
+			continue
+		}
+		va := diffValue(reflect.ValueOf(a).MethodByName(method.Name).Call(nil)[0].Interface())
+		vb := diffValue(reflect.ValueOf(b).MethodByName(method.Name).Call(nil)[0].Interface())
+		ja, err := json.Marshal(va)
+		if err != nil {
+			return nil, err
+		}
+		jb, err := json.Marshal(vb)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(ja, jb) {
+			continue
+		}
+		diffs = append(diffs, chainspecFieldDiff{
+			Kind:  diffKind(k, method.Name),
+			Field: strings.TrimPrefix(method.Name, "Get"),
+			A:     va,
+			B:     vb,
+		})
+	}
+	return diffs, nil
+}
+
+// diffValue returns the value in a form suitable for comparison and printing.
+func diffValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *uint64:
+		if t == nil {
+			return nil
+		}
+		return *t
+	case []byte:
+		return hexutil.Bytes(t)
+	case ctypes.ConsensusEngineT:
+		return t.String()
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	return v
+}
+
+func diffKind(k reflect.Type, name string) string {
+	switch {
+	case k == reflect.TypeOf((*ctypes.GenesisBlocker)(nil)).Elem():
+		return "genesis"
+	case strings.HasSuffix(name, "Transition") || strings.HasSuffix(name, "TransitionTime"):
+		return "transition"
+	case strings.HasSuffix(name, "Schedule"):
+		return "schedule"
+	case strings.HasPrefix(name, "GetEthash"), strings.HasPrefix(name, "GetClique"),
+		strings.HasPrefix(name, "GetLyra2"), name == "GetConsensusEngineType":
+		return "consensus"
+	}
+	return "chain"
+}
+
+// chainspecGenesisHash returns the hash of the configuration's genesis block.
+func chainspecGenesisHash(c ctypes.Configurator) (h common.Hash, err error) {
+	g, ok := c.(*genesisT.Genesis)
+	if !ok {
+		g = &genesisT.Genesis{Config: &coregeth.CoreGethChainConfig{}}
+		if err := confp.Crush(g, c, true); err != nil {
+			return h, err
+		}
+	}
+	return core.GenesisToBlock(g, nil).Hash(), nil
+}
+
+func printChainspecDiff(d *chainspecDiff) {
+	for _, f := range d.Diffs {
+		fmt.Printf("%s %s: %v != %v\n", f.Kind, f.Field, printDiffValue(f.A), printDiffValue(f.B))
+	}
+	for _, id := range d.ForkIDs {
+		mark := "=="
+		if !id.Equal {
+			mark = "!="
+		}
+		fmt.Printf("forkid %d@%d: %s/%d %s %s/%d\n", id.Block, id.Time, id.A.Hash, id.A.Next, mark, id.B.Hash, id.B.Next)
+	}
+	if d.Equal {
+		fmt.Println("Equal")
+	}
+}
+
+func printDiffValue(v interface{}) interface{} {
+	if v == nil {
+		return "-"
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice {
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return v
+}
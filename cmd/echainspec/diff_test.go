@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffChainspecs(t *testing.T) {
+	d, err := diffChainspecs(defaultChainspecValues["classic"], defaultChainspecValues["classic"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Equal || len(d.Diffs) != 0 {
+		t.Errorf("classic not equal to itself: %v", d.Diffs)
+	}
+	if len(d.ForkIDs) == 0 {
+		t.Error("no fork ids compared")
+	}
+
+	d, err = diffChainspecs(defaultChainspecValues["classic"], defaultChainspecValues["mordor"], []forkHead{{Block: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Equal {
+		t.Fatal("classic equal to mordor")
+	}
+	fields := map[string]string{}
+	for _, f := range d.Diffs {
+		fields[f.Field] = f.Kind
+	}
+	for field, kind := range map[string]string{
+		"ChainID":                    "chain",
+		"EIP155Transition":           "transition",
+		"EthashECIP1017EraRounds":    "consensus",
+		"GenesisHash":                "genesis",
+		"GenesisSealerEthereumNonce": "genesis",
+	} {
+		if fields[field] != kind {
+			t.Errorf("%s: want kind %q, got %q", field, kind, fields[field])
+		}
+	}
+	if len(d.ForkIDs) != 1 || d.ForkIDs[0].Equal {
+		t.Errorf("want one unequal fork id, got %v", d.ForkIDs)
+	}
+}
+
+func TestParseForkHeads(t *testing.T) {
+	heads, err := parseForkHeads("0, 0x10,1150000@1681338455")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []forkHead{{Block: 0}, {Block: 16}, {Block: 1150000, Time: 1681338455}}
+	if !reflect.DeepEqual(heads, want) {
+		t.Errorf("want %v, got %v", want, heads)
+	}
+	if _, err := parseForkHeads("1@x"); err == nil {
+		t.Error("want error for invalid timestamp")
+	}
+}
@@ -9,10 +9,12 @@ import (
 
 	"github.com/yuriy0803/core-geth1/params"
 	"github.com/yuriy0803/core-geth1/params/confp"
+	"github.com/yuriy0803/core-geth1/params/types/besu"
 	"github.com/yuriy0803/core-geth1/params/types/coregeth"
 	"github.com/yuriy0803/core-geth1/params/types/ctypes"
 	"github.com/yuriy0803/core-geth1/params/types/genesisT"
 	"github.com/yuriy0803/core-geth1/params/types/goethereum"
+	"github.com/yuriy0803/core-geth1/params/types/parity"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -27,6 +29,10 @@ var (
 		"geth": &genesisT.Genesis{
 			Config: &goethereum.ChainConfig{},
 		},
+		"besu": &genesisT.Genesis{
+			Config: &besu.ChainConfig{},
+		},
+		"parity": &parity.ParityChainSpec{},
 		// "retesteth"
 	}
 )
@@ -120,18 +126,18 @@ func mustGetChainspecValue(ctx *cli.Context) error {
 }
 
 func convertf(ctx *cli.Context) error {
-	c, ok := chainspecFormatTypes[ctx.String(outputFormatFlag.Name)]
-	if !ok && ctx.String(outputFormatFlag.Name) == "" {
+	c, err := newChainspecValue(ctx.String(outputFormatFlag.Name))
+	if err != nil && ctx.String(outputFormatFlag.Name) == "" {
 		b, err := jsonMarshalPretty(globalChainspecValue)
 		if err != nil {
 			return err
 		}
 		fmt.Println(string(b))
 		return nil
-	} else if !ok {
+	} else if err != nil {
 		return errInvalidOutputFlag
 	}
-	err := confp.Crush(c, globalChainspecValue, true)
+	err = confp.Crush(c, globalChainspecValue, true)
 	if err != nil {
 		return err
 	}
@@ -190,6 +196,14 @@ EXAMPLES:
 
 		> {{.Name}} --inputf parity --file my-parity-spec.json --outputf [geth|coregeth]
 
+	Print a default Ethereum Classic network chain configuration as a Besu genesis.
+
+		> {{.Name}} --default classic --outputf besu
+
+	Compare an external chain configuration with the Mordor defaults, fork-by-fork, as JSON.
+
+		> {{.Name}} --inputf coregeth --file my-genesis.json diff --json mordor
+
 	Print a default Ethereum Classic network chain configuration in coregeth format:
 
 		> {{.Name}} --default classic --outputf coregeth
@@ -217,6 +231,7 @@ GLOBAL OPTIONS:
 		validateCommand,
 		forksCommand,
 		ipsCommand,
+		diffCommand,
 	}
 	app.Before = mustGetChainspecValue
 	app.Action = convertf
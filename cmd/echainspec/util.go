@@ -2,10 +2,9 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"os"
-	"strings"
+	"reflect"
 
 	"github.com/yuriy0803/core-geth1/params/types/ctypes"
 	"github.com/yuriy0803/core-geth1/params/types/genesisT"
@@ -19,44 +18,52 @@ func readInputData(ctx *cli.Context) ([]byte, error) {
 	return os.ReadFile(ctx.GlobalString(fileInFlag.Name))
 }
 
-func unmarshalChainSpec(format string, data []byte) (conf ctypes.Configurator, err error) {
-	conf, ok := chainspecFormatTypes[format]
+// newChainspecValue returns a new, empty value of the given chainspec format.
+func newChainspecValue(format string) (ctypes.Configurator, error) {
+	proto, ok := chainspecFormatTypes[format]
 	if !ok {
 		return nil, errInvalidChainspecValue
 	}
+	if g, ok := proto.(*genesisT.Genesis); ok {
+		return &genesisT.Genesis{
+			Config: reflect.New(reflect.TypeOf(g.Config).Elem()).Interface().(ctypes.ChainConfigurator),
+			Alloc:  genesisT.GenesisAlloc{},
+		}, nil
+	}
+	return reflect.New(reflect.TypeOf(proto).Elem()).Interface().(ctypes.Configurator), nil
+}
+
+func unmarshalChainSpec(format string, data []byte) (conf ctypes.Configurator, err error) {
+	conf, err = newChainspecValue(format)
+	if err != nil {
+		return nil, err
+	}
 	err = json.Unmarshal(data, conf)
 	if err != nil {
 		return conf, err
 	}
-	if !strings.Contains(format, "geth") {
+	t, ok := conf.(*genesisT.Genesis)
+	if !ok {
 		return
 	}
 	// Logic in params/types/gen_genesis.go already "auto-magically"
 	// handles genesis Config unmarshaling, and IT PREFERS COREGETH,
-	// and the two data types are not mutually exclusive (are overlapping).
+	// and the data types are not mutually exclusive (are overlapping).
 	// So we need to redo custom unmarshaling logic to enforce data type
 	// preference based on passed format value.
 	type dec struct {
 		Config ctypes.ChainConfigurator `json:"config"`
 	}
-	var d dec
-	configurator, ok := chainspecFormatTypes[format]
-	if !ok {
-		return nil, fmt.Errorf("unknown chainspec format type: %v", format)
-	}
-	switch t := configurator.(type) {
-	case *genesisT.Genesis:
-		d.Config = t.Config
-	default:
-		return nil, fmt.Errorf("unhandled chainspec type: %v %v", format, t)
+	fresh, err := newChainspecValue(format)
+	if err != nil {
+		return nil, err
 	}
-	t := chainspecFormatTypes[format].(*genesisT.Genesis)
+	d := dec{Config: fresh.(*genesisT.Genesis).Config}
 	err = json.Unmarshal(data, &d)
 	if err != nil {
 		return conf, err
 	}
 	t.Config = d.Config
-	conf = t
 	return
 }
 
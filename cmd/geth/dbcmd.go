@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -31,6 +32,7 @@ import (
 	"github.com/yuriy0803/core-geth1/common"
 	"github.com/yuriy0803/core-geth1/common/hexutil"
 	"github.com/yuriy0803/core-geth1/console/prompt"
+	"github.com/yuriy0803/core-geth1/core"
 	"github.com/yuriy0803/core-geth1/core/rawdb"
 	"github.com/yuriy0803/core-geth1/core/state/snapshot"
 	"github.com/yuriy0803/core-geth1/crypto"
@@ -67,7 +69,11 @@ Remove blockchain and state databases`,
 			dbDumpFreezerIndex,
 			dbImportCmd,
 			dbExportCmd,
+			dbExportAncientsCmd,
+			dbImportAncientsCmd,
 			dbMetadataCmd,
+			dbHistoryCmd,
+			dbCheckCmd,
 			dbCheckStateContentCmd,
 		},
 	}
@@ -184,6 +190,37 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "Exports the specified chain data to an RLP encoded stream, optionally gzip-compressed.",
 	}
+	dbExportAncientsCmd = &cli.Command{
+		Action:    exportAncients,
+		Name:      "export-ancients",
+		Usage:     "Exports the ancient chain segments into era1 archives",
+		ArgsUsage: "<dir> [<first> <last>]",
+		Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `
+geth db export-ancients <dir> [<first> <last>]
+
+Exports the headers, bodies, receipts and total difficulties of the ancient
+blocks [first, last], or of all of them, into era1 archives of one epoch (8192
+blocks) each, named <network>-<epoch>-<accumulator root>.era1. The SHA256
+checksums of the archives are recorded in <dir>/checksums.txt.`,
+	}
+	dbImportAncientsCmd = &cli.Command{
+		Action:    importAncients,
+		Name:      "import-ancients",
+		Usage:     "Imports era1 archives into the ancient store",
+		ArgsUsage: "<dir>",
+		Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `
+geth db import-ancients <dir>
+
+Imports the era1 archives of the network in the directory, as written by
+'geth db export-ancients', into the ancient store, continuing from its last
+block. The checksum of every archive, and the hash, parent hash, roots and
+total difficulty of every block are verified before any block is written.
+
+The database must not hold blocks beyond the ancient store, e.g. a new datadir
+initialized with 'geth init' or not at all.`,
+	}
 	dbMetadataCmd = &cli.Command{
 		Action: showMetaData,
 		Name:   "metadata",
@@ -193,6 +230,43 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbCheckRepairFlag = &cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Rebuild the inconsistent data which can be derived from the rest of the database",
+	}
+	dbCheckCmd = &cli.Command{
+		Action:    checkDatabase,
+		Name:      "check",
+		Usage:     "Check the consistency of the chain data in the database",
+		ArgsUsage: "[<start> [<count>]]",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			dbCheckRepairFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `
+geth db check [<start> [<count>]]
+
+Checks the canonical hashes, headers, bodies, receipts and transaction lookups
+of the blocks from <start>, <count> of them or all up to the head, for their
+consistency. From the first block, the continuity of the key-value store after
+the ancient store is checked too, and once the head is reached, whether the
+snapshot and the persisted state agree.
+
+An interrupted check can be resumed from the reported next block. With --repair,
+the canonical hashes, number mappings, transaction lookups and snapshot root
+which can be derived from the rest of the database are rewritten.`,
+	}
+	dbHistoryCmd = &cli.Command{
+		Action: showHistoryRange,
+		Name:   "history",
+		Usage:  "Shows the range of blocks whose bodies and receipts are retained",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command shows the range of blocks whose bodies and receipts are retained
+in the database. The ones below it were dropped by --history.expiry, while their
+headers are kept.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -690,6 +764,171 @@ func exportChaindata(ctx *cli.Context) error {
 	return utils.ExportChaindata(ctx.Args().Get(1), kind, exporter(db), stop)
 }
 
+// eraNetworkName returns the network name of the era1 archives, the one of the
+// selected network.
+func eraNetworkName(ctx *cli.Context) string {
+	for _, flag := range utils.NetworkFlags {
+		if name := flag.Names()[0]; ctx.IsSet(name) {
+			return name
+		}
+	}
+	return "mainnet"
+}
+
+func exportAncients(ctx *cli.Context) error {
+	if ctx.NArg() != 1 && ctx.NArg() != 3 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		stack, _  = makeConfigNode(ctx)
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+	)
+	defer stack.Close()
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during ancients export, stopping at next archive")
+		}
+		close(stop)
+	}()
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	if frozen == 0 {
+		return errors.New("the ancient store is empty")
+	}
+	first, last := uint64(0), frozen-1
+	if ctx.NArg() == 3 {
+		if first, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			return fmt.Errorf("invalid first block: %v", err)
+		}
+		if last, err = strconv.ParseUint(ctx.Args().Get(2), 10, 64); err != nil {
+			return fmt.Errorf("invalid last block: %v", err)
+		}
+	}
+	return utils.ExportAncients(db, ctx.Args().Get(0), eraNetworkName(ctx), first, last, stop)
+}
+
+func importAncients(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		stack, _  = makeConfigNode(ctx)
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+	)
+	defer stack.Close()
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during ancients import, stopping at next archive")
+		}
+		close(stop)
+	}()
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	return utils.ImportAncients(db, ctx.Args().Get(0), eraNetworkName(ctx), stop)
+}
+
+func checkDatabase(ctx *cli.Context) error {
+	if ctx.NArg() > 2 {
+		return fmt.Errorf("max 2 arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		config    core.CheckConfig
+		err       error
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+	)
+	if ctx.NArg() > 0 {
+		if config.Start, err = strconv.ParseUint(ctx.Args().Get(0), 10, 64); err != nil {
+			return fmt.Errorf("invalid start block: %v", err)
+		}
+	}
+	if ctx.NArg() > 1 {
+		if config.Count, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			return fmt.Errorf("invalid block count: %v", err)
+		}
+	}
+	config.Repair = ctx.Bool(dbCheckRepairFlag.Name)
+	config.OnIssue = func(issue *core.CheckIssue) {
+		log.Warn("Database inconsistency", "kind", issue.Kind, "number", issue.Number, "hash", issue.Hash, "detail", issue.Detail, "repaired", issue.Repaired)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during database check, stopping")
+		}
+		close(stop)
+	}()
+	db := utils.MakeChainDatabase(ctx, stack, !config.Repair)
+	defer db.Close()
+
+	start := time.Now()
+	result, err := core.CheckDatabase(db, config, stop)
+	if err != nil {
+		return err
+	}
+	var unrepaired int
+	for _, issue := range result.Issues {
+		if !issue.Repaired {
+			unrepaired++
+		}
+	}
+	log.Info("Checked database", "checked", result.Checked, "issues", len(result.Issues), "repaired", len(result.Issues)-unrepaired, "elapsed", common.PrettyDuration(time.Since(start)))
+	if !result.Done {
+		log.Info("Database check incomplete, resume with", "start", result.Next)
+	}
+	if unrepaired > 0 {
+		return fmt.Errorf("found %d inconsistencies", unrepaired)
+	}
+	return nil
+}
+
+func showHistoryRange(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	var data [][]string
+	tail, err := db.Tail()
+	if err != nil {
+		// Without an ancient store, no history is dropped.
+		tail = 0
+	}
+	data = append(data, []string{"bodies.First", fmt.Sprintf("%d", tail)})
+	data = append(data, []string{"receipts.First", fmt.Sprintf("%d", tail)})
+	if b := rawdb.ReadHeadBlock(db); b != nil {
+		data = append(data, []string{"bodies.Last", fmt.Sprintf("%d", b.NumberU64())})
+		data = append(data, []string{"receipts.Last", fmt.Sprintf("%d", b.NumberU64())})
+	}
+	if h := rawdb.ReadHeadHeader(db); h != nil {
+		data = append(data, []string{"headers.First", "0"})
+		data = append(data, []string{"headers.Last", fmt.Sprintf("%d", h.Number)})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Field", "Value"})
+	table.AppendBulk(data)
+	table.Render()
+	return nil
+}
+
 func showMetaData(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	defer stack.Close()
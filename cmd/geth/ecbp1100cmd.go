@@ -0,0 +1,314 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yuriy0803/core-geth1/cmd/utils"
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/math"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/internal/flags"
+	"github.com/yuriy0803/core-geth1/rlp"
+)
+
+var (
+	ecbp1100HeadFlag = &cli.Uint64Flag{
+		Name:  "head",
+		Usage: "Canonical block number to use as the current head, defaults to the head of the datadir chain",
+	}
+	ecbp1100JSONFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the decisions as JSON",
+	}
+	ecbp1100SpanFlag = &cli.Uint64Flag{
+		Name:  "span",
+		Usage: "Seconds from the common ancestor to the current head, defaults to the whole anti-gravity curve",
+	}
+	ecbp1100SpanStepFlag = &cli.Uint64Flag{
+		Name:  "span.step",
+		Usage: "Seconds between the spans of the anti-gravity curve",
+		Value: 1800,
+	}
+	ecbp1100SpanMaxFlag = &cli.Uint64Flag{
+		Name:  "span.max",
+		Usage: "Largest span of the anti-gravity curve in seconds",
+		Value: 28800,
+	}
+	ecbp1100CurrentTDFlag = &cli.StringFlag{
+		Name:  "td.current",
+		Usage: "Total difficulty of the current chain segment since the common ancestor",
+	}
+	ecbp1100ProposedTDFlag = &cli.StringFlag{
+		Name:  "td.proposed",
+		Usage: "Total difficulty of the proposed chain segment since the common ancestor",
+	}
+	ecbp1100Command = &cli.Command{
+		Name:  "ecbp1100",
+		Usage: "Simulate ECBP1100 (MESS) artificial finality decisions offline",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "simulate",
+				Usage:     "Judge the reorgs proposed by competing blocks against the local chain",
+				ArgsUsage: "<filename>",
+				Action:    ecbp1100Simulate,
+				Flags: flags.Merge([]cli.Flag{
+					ecbp1100HeadFlag,
+					ecbp1100JSONFlag,
+				}, utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth ecbp1100 simulate <filename>
+
+Reads an RLP encoded file (optionally gzipped) of competing blocks, as written
+by 'geth export', and runs the ECBP1100 (MESS) check of the reorg each of them
+would propose against the current head of the local chain. Blocks must follow
+their parents, which are either earlier in the file or in the local database.
+
+Nothing is imported into the local chain. Use --head to judge the blocks against
+an older canonical head, e.g. the head at the time of an attack.`,
+			},
+			{
+				Name:   "synthetic",
+				Usage:  "Judge reorgs of synthetic segment spans and total difficulties",
+				Action: ecbp1100Synthetic,
+				Flags: []cli.Flag{
+					ecbp1100SpanFlag,
+					ecbp1100SpanStepFlag,
+					ecbp1100SpanMaxFlag,
+					ecbp1100CurrentTDFlag,
+					ecbp1100ProposedTDFlag,
+				},
+				Description: `
+geth ecbp1100 synthetic [--span <seconds>] [--td.current <td> --td.proposed <td>]
+
+Prints the anti-gravity, the total difficulty ratio a proposed chain segment
+needs over the current one, for the given span of the current segment since the
+common ancestor, or for each span of the curve from 0 to --span.max.
+
+If the total difficulties of both segments since the common ancestor are given,
+the command also prints whether the reorg is accepted.`,
+			},
+		},
+	}
+)
+
+// ecbp1100SimulatedDecision is the ECBP1100 decision on the reorg proposed by a
+// competing block.
+type ecbp1100SimulatedDecision struct {
+	*core.ECBP1100Decision
+	Heavier bool `json:"heavier"` // Whether the proposed chain has more total difficulty
+}
+
+func ecbp1100Simulate(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	current := rawdb.ReadHeadHeader(db)
+	if ctx.IsSet(ecbp1100HeadFlag.Name) {
+		number := ctx.Uint64(ecbp1100HeadFlag.Name)
+		current = rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number), number)
+	}
+	if current == nil {
+		return errors.New("current head not found")
+	}
+	blocks, err := readECBP1100Blocks(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	decisions, err := simulateECBP1100(db, current, blocks)
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(ecbp1100JSONFlag.Name) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(decisions)
+	}
+	var accepted int
+	for _, d := range decisions {
+		status := "rejected"
+		if d.Accepted {
+			status = "accepted"
+			accepted++
+		}
+		fmt.Printf("block %d %s: %s common=%d span=%v tdratio=%.6f antigravity=%.6f heavier=%t\n",
+			d.ProposedNumber, d.Proposed.TerminalString(), status, d.CommonNumber,
+			common.PrettyDuration(time.Duration(d.Span)*time.Second), d.TDRatio, d.AntiGravity, d.Heavier)
+	}
+	fmt.Printf("Judged %d reorgs against head %d %s: %d accepted, %d rejected\n",
+		len(decisions), current.Number, current.Hash().TerminalString(), accepted, len(decisions)-accepted)
+	return nil
+}
+
+// readECBP1100Blocks reads the RLP encoded blocks from the given file.
+func readECBP1100Blocks(fn string) ([]*types.Block, error) {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	var blocks []*types.Block
+	for {
+		var b types.Block
+		if err := stream.Decode(&b); err == io.EOF {
+			return blocks, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("at block %d: %v", len(blocks), err)
+		}
+		blocks = append(blocks, &b)
+	}
+}
+
+// simulateECBP1100 judges the reorgs proposed by the given blocks against the
+// current head of the database chain, without writing to the database. Blocks
+// which are canonical or extend the current head do not propose any reorg.
+func simulateECBP1100(db ethdb.Reader, current *types.Header, blocks []*types.Block) ([]*ecbp1100SimulatedDecision, error) {
+	var (
+		headers   = make(map[common.Hash]*types.Header)
+		tds       = make(map[common.Hash]*big.Int)
+		ancestors = make(map[common.Hash]*types.Header)
+	)
+	getTD := func(hash common.Hash, number uint64) *big.Int {
+		if td, ok := tds[hash]; ok {
+			return td
+		}
+		return rawdb.ReadTd(db, hash, number)
+	}
+	// commonAncestor returns the most recent ancestor of the header which is
+	// canonical up to the current head.
+	commonAncestor := func(header *types.Header) *types.Header {
+		for header != nil {
+			if ancestor, ok := ancestors[header.Hash()]; ok {
+				return ancestor
+			}
+			number := header.Number.Uint64()
+			if number <= current.Number.Uint64() && rawdb.ReadCanonicalHash(db, number) == header.Hash() {
+				return header
+			}
+			if number == 0 {
+				return nil
+			}
+			parent, ok := headers[header.ParentHash]
+			if !ok {
+				parent = rawdb.ReadHeader(db, header.ParentHash, number-1)
+			}
+			header = parent
+		}
+		return nil
+	}
+	currentTD := getTD(current.Hash(), current.Number.Uint64())
+	if currentTD == nil {
+		return nil, fmt.Errorf("missing total difficulty of head %d %x", current.Number, current.Hash())
+	}
+	decisions := []*ecbp1100SimulatedDecision{}
+	for _, block := range blocks {
+		header := block.Header()
+		if header.Number.Sign() == 0 {
+			continue
+		}
+		parentTD := getTD(header.ParentHash, header.Number.Uint64()-1)
+		if parentTD == nil {
+			return nil, fmt.Errorf("block %d %x: unknown parent %x", header.Number, header.Hash(), header.ParentHash)
+		}
+		hash := header.Hash()
+		headers[hash] = header
+		tds[hash] = new(big.Int).Add(parentTD, header.Difficulty)
+
+		ancestor := commonAncestor(header)
+		if ancestor == nil {
+			return nil, fmt.Errorf("block %d %x: no common ancestor with the local chain", header.Number, hash)
+		}
+		ancestors[hash] = ancestor
+		if ancestor.Hash() == hash || ancestor.Hash() == current.Hash() {
+			continue
+		}
+		decisions = append(decisions, &ecbp1100SimulatedDecision{
+			ECBP1100Decision: core.EvaluateECBP1100(ancestor, current, header, getTD),
+			Heavier:          tds[hash].Cmp(currentTD) > 0,
+		})
+	}
+	return decisions, nil
+}
+
+func ecbp1100Synthetic(ctx *cli.Context) error {
+	var currentTD, proposedTD *big.Int
+	if ctx.IsSet(ecbp1100CurrentTDFlag.Name) || ctx.IsSet(ecbp1100ProposedTDFlag.Name) {
+		var ok bool
+		if currentTD, ok = math.ParseBig256(ctx.String(ecbp1100CurrentTDFlag.Name)); !ok || currentTD.Sign() <= 0 {
+			utils.Fatalf("Invalid --%s, a positive total difficulty is required", ecbp1100CurrentTDFlag.Name)
+		}
+		if proposedTD, ok = math.ParseBig256(ctx.String(ecbp1100ProposedTDFlag.Name)); !ok || proposedTD.Sign() < 0 {
+			utils.Fatalf("Invalid --%s, a total difficulty is required", ecbp1100ProposedTDFlag.Name)
+		}
+	}
+	spans := []uint64{ctx.Uint64(ecbp1100SpanFlag.Name)}
+	if !ctx.IsSet(ecbp1100SpanFlag.Name) {
+		step := ctx.Uint64(ecbp1100SpanStepFlag.Name)
+		if step == 0 {
+			utils.Fatalf("Invalid --%s, a positive step is required", ecbp1100SpanStepFlag.Name)
+		}
+		spans = spans[:0]
+		for span := uint64(0); span <= ctx.Uint64(ecbp1100SpanMaxFlag.Name); span += step {
+			spans = append(spans, span)
+		}
+	}
+	var tdRatio float64
+	if currentTD != nil {
+		tdRatio, _ = new(big.Float).Quo(new(big.Float).SetInt(proposedTD), new(big.Float).SetInt(currentTD)).Float64()
+		fmt.Printf("tdratio=%.6f\n", tdRatio)
+	}
+	for _, span := range spans {
+		line := fmt.Sprintf("span=%v antigravity=%.6f", common.PrettyDuration(time.Duration(span)*time.Second), core.ECBP1100AntiGravity(span))
+		if currentTD != nil {
+			status := "rejected"
+			if core.ECBP1100Accepts(span, currentTD, proposedTD) {
+				status = "accepted"
+			}
+			line += " " + status
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
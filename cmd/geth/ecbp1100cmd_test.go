@@ -0,0 +1,86 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/consensus/ethash"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/core/vm"
+	"github.com/yuriy0803/core-geth1/params"
+)
+
+func TestSimulateECBP1100(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := core.MustCommitGenesis(db, genesis)
+
+	chain, err := core.NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	local, _ := core.GenerateChain(genesis.Config, genesisB, engine, db, 1000, func(i int, gen *core.BlockGen) {
+		gen.OffsetTime(0)
+	})
+	if _, err := chain.InsertChain(local); err != nil {
+		t.Fatal(err)
+	}
+	ancestor := local[699]
+	competing, _ := core.GenerateChain(genesis.Config, ancestor, engine, db, 300, func(i int, gen *core.BlockGen) {
+		gen.OffsetTime(-7)
+	})
+	// Canonical blocks and chain extensions do not propose reorgs.
+	blocks := append(types.Blocks{local[10]}, competing...)
+	extension, _ := core.GenerateChain(genesis.Config, local[len(local)-1], engine, db, 1, nil)
+	blocks = append(blocks, extension...)
+
+	head := chain.CurrentHeader()
+	decisions, err := simulateECBP1100(db, head, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decisions) != len(competing) {
+		t.Fatalf("decision count mismatch: have %d, want %d", len(decisions), len(competing))
+	}
+	for i, d := range decisions {
+		if d.Proposed != competing[i].Hash() || d.CommonAncestor != ancestor.Hash() || d.Current != head.Hash() {
+			t.Fatalf("decision %d: unexpected reorg %d -> %d from %d", i, d.CurrentNumber, d.ProposedNumber, d.CommonNumber)
+		}
+	}
+	last := decisions[len(decisions)-1]
+	if last.Accepted || !last.Heavier {
+		t.Errorf("heavier competing chain accepted=%t heavier=%t, want rejected heavier chain", last.Accepted, last.Heavier)
+	}
+	// Nothing may be imported into the local chain.
+	if chain.CurrentHeader().Hash() != head.Hash() || rawdb.ReadHeader(db, last.Proposed, uint64(last.ProposedNumber)) != nil {
+		t.Error("simulation modified the local chain")
+	}
+	// The simulation must agree with the chain itself.
+	chain.EnableArtificialFinality(true)
+	chain.InsertChain(competing)
+	have := chain.ECBP1100Decisions()
+	if len(have) == 0 || have[len(have)-1].Accepted != last.Accepted {
+		t.Error("simulated decision differs from the chain decision")
+	}
+}
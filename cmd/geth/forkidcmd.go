@@ -0,0 +1,184 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yuriy0803/core-geth1/cmd/utils"
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/forkid"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/eth/protocols/eth"
+	"github.com/yuriy0803/core-geth1/internal/flags"
+	"github.com/yuriy0803/core-geth1/params"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+	"github.com/yuriy0803/core-geth1/params/types/genesisT"
+)
+
+var (
+	forkidGenesisFlag = &cli.StringFlag{
+		Name:  "genesis",
+		Usage: "Genesis JSON file of the local chain configuration",
+	}
+	forkidBlockFlag = &cli.Uint64Flag{
+		Name:  "block",
+		Usage: "Local head block number, defaults to the head of the datadir chain, or 0",
+	}
+	forkidTimeFlag = &cli.Uint64Flag{
+		Name:  "time",
+		Usage: "Local head timestamp, defaults to the head of the datadir chain, or 0",
+	}
+	forkidJSONFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the reports as JSON",
+	}
+	forkidCommand = &cli.Command{
+		Action:    forkidReport,
+		Name:      "forkid",
+		Usage:     "Explain the compatibility of remote fork IDs with the local chain configuration",
+		ArgsUsage: "<hash[:next]|enr> (<hash[:next]|enr> ... )",
+		Flags: flags.Merge([]cli.Flag{
+			utils.DataDirFlag,
+			forkidGenesisFlag,
+			forkidBlockFlag,
+			forkidTimeFlag,
+			forkidJSONFlag,
+		}, utils.NetworkFlags),
+		Description: `
+The forkid command explains, for each remote fork ID, which local fork checksum
+it matches and which fork block or time causes the chains to split.
+
+Remote fork IDs are given as <hash>[:<next>], e.g. 0xbe46d57c:13189133, or as
+"enr:" node records advertising the eth protocol.
+
+The local chain configuration is read from the --genesis file, the network
+preset, or the datadir, in that order. Use admin_forkidReport to create the
+reports for a running node.`,
+	}
+)
+
+func forkidReport(ctx *cli.Context) error {
+	if ctx.Args().Len() == 0 {
+		utils.Fatalf("This command requires at least one fork ID or node record.")
+	}
+	ids := make([]forkid.ID, 0, ctx.Args().Len())
+	for _, s := range ctx.Args().Slice() {
+		id, err := eth.ParseForkID(s)
+		if err != nil {
+			utils.Fatalf("Invalid fork ID %q: %v", s, err)
+		}
+		ids = append(ids, id)
+	}
+	config, genesis, head, time, err := forkidChain(ctx)
+	if err != nil {
+		utils.Fatalf("Failed to read chain configuration: %v", err)
+	}
+	if ctx.IsSet(forkidBlockFlag.Name) {
+		head = ctx.Uint64(forkidBlockFlag.Name)
+	}
+	if ctx.IsSet(forkidTimeFlag.Name) {
+		time = ctx.Uint64(forkidTimeFlag.Name)
+	}
+
+	reports := make([]*forkid.Report, len(ids))
+	for i, id := range ids {
+		reports[i] = forkid.NewReport(config, genesis, head, time, id)
+	}
+	if ctx.Bool(forkidJSONFlag.Name) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	local := forkid.NewID(config, genesis, head, time)
+	fmt.Printf("Local fork ID at %d@%d: %#x:%d\n", head, time, local.Hash, local.Next)
+	for i, r := range reports {
+		fmt.Printf("\nRemote %s: %s:%d\n", ctx.Args().Get(i), r.Hash, r.Next)
+		if r.Accepted {
+			fmt.Println("  accepted")
+		} else {
+			fmt.Printf("  rejected: %s\n", r.Error)
+		}
+		if r.Matched || len(r.Passed) > 0 {
+			if len(r.Passed) == 0 {
+				fmt.Println("  matches:  genesis")
+			} else {
+				last := r.Passed[len(r.Passed)-1]
+				fmt.Printf("  matches:  %s after fork %d (%s)\n", last.Hash, last.Number, strings.Join(last.Transitions, ", "))
+			}
+		}
+		if r.Split != nil {
+			fmt.Printf("  split:    %d\n", r.Split.Number)
+		}
+		fmt.Printf("  reason:   %s\n", r.Reason)
+	}
+	return nil
+}
+
+// forkidChain returns the local chain configuration, genesis hash, and the head
+// block number and timestamp if known.
+func forkidChain(ctx *cli.Context) (ctypes.ChainConfigurator, common.Hash, uint64, uint64, error) {
+	var genesis *genesisT.Genesis
+	switch {
+	case ctx.IsSet(forkidGenesisFlag.Name):
+		data, err := os.ReadFile(ctx.String(forkidGenesisFlag.Name))
+		if err != nil {
+			return nil, common.Hash{}, 0, 0, err
+		}
+		genesis = new(genesisT.Genesis)
+		if err := genesis.UnmarshalJSON(data); err != nil {
+			return nil, common.Hash{}, 0, 0, err
+		}
+	case utils.IsNetworkPreset(ctx):
+		if genesis = utils.MakeGenesis(ctx); genesis == nil {
+			genesis = params.DefaultGenesisBlock()
+		}
+	}
+	if genesis != nil {
+		return genesis.Config, core.GenesisToBlock(genesis, nil).Hash(), 0, 0, nil
+	}
+
+	// Read the configuration and head of the chain in the datadir.
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db, err := stack.OpenDatabase("chaindata", 0, 0, "", true)
+	if err != nil {
+		return nil, common.Hash{}, 0, 0, err
+	}
+	defer db.Close()
+
+	hash := rawdb.ReadCanonicalHash(db, 0)
+	if hash == (common.Hash{}) {
+		return nil, common.Hash{}, 0, 0, errors.New("no genesis in the datadir, use --genesis or a network preset")
+	}
+	config := rawdb.ReadChainConfig(db, hash)
+	if config == nil {
+		return nil, common.Hash{}, 0, 0, errors.New("no chain configuration in the datadir")
+	}
+	var head, time uint64
+	if header := rawdb.ReadHeadHeader(db); header != nil {
+		head, time = header.Number.Uint64(), header.Time
+	}
+	return config, hash, head, time, nil
+}
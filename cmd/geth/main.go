@@ -99,6 +99,9 @@ var (
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.HistoryExpiryFlag,
+		utils.HistoryStateDiffsFlag,
+		utils.TraceIndexFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -137,6 +140,10 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
+		utils.MinerStratumFlag,
+		utils.MinerStratumDifficultyFlag,
+		utils.MinerStratumMaxSessionsFlag,
+		utils.MinerStratumMaxSessionsIPFlag,
 		utils.MinerNewPayloadTimeout,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
@@ -180,27 +187,48 @@ var (
 		utils.AuthPortFlag,
 		utils.AuthVirtualHostsFlag,
 		utils.JWTSecretFlag,
+		utils.AuthMethodsAllowFlag,
+		utils.AuthMethodsDenyFlag,
+		utils.AuthRateLimitFlag,
+		utils.AuthRateBurstFlag,
+		utils.AuthMethodCostsFlag,
 		utils.HTTPVirtualHostsFlag,
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GraphQLTracingFlag,
 		utils.HTTPApiFlag,
 		utils.HTTPPathPrefixFlag,
+		utils.HTTPMethodsAllowFlag,
+		utils.HTTPMethodsDenyFlag,
+		utils.HTTPRateLimitFlag,
+		utils.HTTPRateBurstFlag,
+		utils.HTTPMethodCostsFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.WSPathPrefixFlag,
+		utils.WSMethodsAllowFlag,
+		utils.WSMethodsDenyFlag,
+		utils.WSRateLimitFlag,
+		utils.WSRateBurstFlag,
+		utils.WSMethodCostsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCGlobalTraceFilterCapFlag,
 		utils.AllowUnprotectedTxs,
 		utils.BatchRequestLimit,
 		utils.BatchResponseMaxSize,
+		utils.RPCAccessLogFlag,
+		utils.RPCAccessLogFileFlag,
+		utils.RPCAccessLogSampleFlag,
+		utils.RPCAccessLogSlowFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -260,6 +288,10 @@ func init() {
 		snapshotCommand,
 		// See verkle.go
 		verkleCommand,
+		// See forkidcmd.go
+		forkidCommand,
+		// See ecbp1100cmd.go
+		ecbp1100Command,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -20,7 +20,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/yuriy0803/core-geth1/cmd/utils"
@@ -150,6 +154,42 @@ as the backend data source, making this command a lot faster.
 
 The argument is interpreted as block number or hash. If none is provided, the latest
 block is used.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the state of a block from the snapshot into a state file",
+				ArgsUsage: "<file> [<blockHash> | <blockNum>]",
+				Action:    exportState,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth snapshot export <file> [<blockHash> | <blockNum>]
+will write the flat accounts, storage slots and contract codes of the state of
+the given block, or of the head block if none is provided, into a state file. The
+state must be within the snapshot, i.e. of one of the last 128 blocks.
+
+The state file is made up of snappy-compressed chunks followed by a manifest of
+their hashes. The hash of the manifest is printed once done, and authenticates
+the whole file.
+`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import the state of a block from a state file",
+				ArgsUsage: "<file> [<manifest hash>]",
+				Action:    importState,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth snapshot import <file> [<manifest hash>]
+will import the state of a state file, as written by 'geth snapshot export', so
+that the node starts from its block without syncing the state from peers. The
+state trie is rebuilt from the flat state, and its root is verified against the
+block before the node is pointed at it. If the manifest hash is given, the file
+is verified against it too.
+
+The chain up to the block of the state must already be present, e.g. imported
+with 'geth db import-ancients', and the database must not hold any state beyond
+the genesis.
 `,
 			},
 		},
@@ -605,3 +645,94 @@ func checkAccount(ctx *cli.Context) error {
 	log.Info("Checked the snapshot journalled storage", "time", common.PrettyDuration(time.Since(start)))
 	return nil
 }
+
+// stateInterrupt returns a channel closed once the process is interrupted, and
+// a function to release it.
+func stateInterrupt(msg string) (chan struct{}, func()) {
+	var (
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+	)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info(msg)
+		}
+		close(stop)
+	}()
+	return stop, func() {
+		signal.Stop(interrupt)
+		close(interrupt)
+	}
+}
+
+func exportState(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	var header *types.Header
+	if ctx.NArg() == 2 {
+		arg := ctx.Args().Get(1)
+		if hashish(arg) {
+			hash := common.HexToHash(arg)
+			if number := rawdb.ReadHeaderNumber(db, hash); number != nil {
+				header = rawdb.ReadHeader(db, hash, *number)
+			}
+		} else {
+			number, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return err
+			}
+			header = rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number), number)
+		}
+		if header == nil {
+			return fmt.Errorf("block %s not found", arg)
+		}
+	} else if header = rawdb.ReadHeadHeader(db); header == nil {
+		return errors.New("no head block found")
+	}
+	stop, release := stateInterrupt("Interrupted during state export, stopping")
+	defer release()
+
+	manifest, err := utils.ExportState(db, ctx.Args().First(), header, stop)
+	if err != nil {
+		log.Error("Failed to export state", "err", err)
+		return err
+	}
+	fmt.Printf("Manifest hash: %#x\n", manifest.ID())
+	return nil
+}
+
+func importState(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var manifestHash common.Hash
+	if ctx.NArg() == 2 {
+		arg := ctx.Args().Get(1)
+		if !hashish(arg) {
+			return fmt.Errorf("invalid manifest hash %s", arg)
+		}
+		manifestHash = common.HexToHash(arg)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	stop, release := stateInterrupt("Interrupted during state import, stopping")
+	defer release()
+
+	if err := utils.ImportState(db, ctx.Args().First(), manifestHash, stop); err != nil {
+		log.Error("Failed to import state", "err", err)
+		return err
+	}
+	return nil
+}
@@ -109,7 +109,7 @@ var (
 	}
 	DBEngineFlag = &cli.StringFlag{
 		Name:     "db.engine",
-		Usage:    "Backing database implementation to use ('pebble' or 'leveldb')",
+		Usage:    "Backing database implementation to use (" + strings.Join(rawdb.DefaultBackends.Names(), ", ") + ")",
 		Value:    node.DefaultConfig.DBEngine,
 		Category: flags.EthCategory,
 	}
@@ -118,6 +118,11 @@ var (
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	AncientRemoteFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.remote",
+		Usage:    "Endpoint (IPC path, HTTP or WebSocket URL) of a remote ancient store to use instead of --datadir.ancient",
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -285,6 +290,21 @@ var (
 		Value:    ethconfig.Defaults.TxLookupLimit,
 		Category: flags.EthCategory,
 	}
+	HistoryExpiryFlag = &cli.Uint64Flag{
+		Name:     "history.expiry",
+		Usage:    "Block number below which frozen block bodies and receipts are dropped, headers are kept (0 = keep all)",
+		Category: flags.EthCategory,
+	}
+	HistoryStateDiffsFlag = &cli.BoolFlag{
+		Name:     "history.statediffs",
+		Usage:    "Record per-block reverse state diffs, serving the historical state of a full node by reverting the nearest retained state (alternative to --gcmode=archive)",
+		Category: flags.EthCategory,
+	}
+	TraceIndexFlag = &cli.BoolFlag{
+		Name:     "trace.index",
+		Usage:    "Index the addresses of the block traces for fast trace_filter queries (requires --gcmode=archive)",
+		Category: flags.EthCategory,
+	}
 	LightKDFFlag = &cli.BoolFlag{
 		Name:     "lightkdf",
 		Usage:    "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -620,6 +640,28 @@ var (
 		Usage:    "Disable remote sealing verification",
 		Category: flags.MinerCategory,
 	}
+	MinerStratumFlag = &cli.StringFlag{
+		Name:     "miner.stratum",
+		Usage:    "Listening address of the Stratum server for remote miners (EthereumStratum/1.0.0 and stratum-proxy)",
+		Category: flags.MinerCategory,
+	}
+	MinerStratumDifficultyFlag = &cli.Uint64Flag{
+		Name:     "miner.stratum.diff",
+		Usage:    "Share difficulty of the Stratum workers not setting their own with a \"d=<difficulty>\" password",
+		Value:    ethconfig.Defaults.Miner.StratumDifficulty,
+		Category: flags.MinerCategory,
+	}
+	MinerStratumMaxSessionsFlag = &cli.IntFlag{
+		Name:     "miner.stratum.maxsessions",
+		Usage:    "Maximum number of miners connected to the Stratum server",
+		Value:    ethconfig.Defaults.Miner.StratumMaxSessions,
+		Category: flags.MinerCategory,
+	}
+	MinerStratumMaxSessionsIPFlag = &cli.IntFlag{
+		Name:     "miner.stratum.maxsessions.ip",
+		Usage:    "Maximum number of miners connected to the Stratum server from an IP address (0 = unlimited)",
+		Category: flags.MinerCategory,
+	}
 	MinerNewPayloadTimeout = &cli.DurationFlag{
 		Name:     "miner.newpayload-timeout",
 		Usage:    "Specify the maximum time allowance for creating a new payload",
@@ -678,6 +720,12 @@ var (
 		Value:    ethconfig.Defaults.RPCTxFeeCap,
 		Category: flags.APICategory,
 	}
+	RPCGlobalTraceFilterCapFlag = &cli.Uint64Flag{
+		Name:     "rpc.tracefiltercap",
+		Usage:    "Sets a cap on the number of traces returned by trace_filter (0 = no cap)",
+		Value:    ethconfig.Defaults.RPCTraceFilterCap,
+		Category: flags.APICategory,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = &cli.StringFlag{
 		Name:     "authrpc.addr",
@@ -702,6 +750,31 @@ var (
 		Usage:    "Path to a JWT secret to use for authenticated RPC endpoints",
 		Category: flags.APICategory,
 	}
+	AuthMethodsAllowFlag = &cli.StringFlag{
+		Name:     "authrpc.methods.allow",
+		Usage:    "Comma separated list of methods served over the authenticated APIs, all if empty (e.g. engine_*,eth_*)",
+		Category: flags.APICategory,
+	}
+	AuthMethodsDenyFlag = &cli.StringFlag{
+		Name:     "authrpc.methods.deny",
+		Usage:    "Comma separated list of methods not served over the authenticated APIs (e.g. debug_trace*)",
+		Category: flags.APICategory,
+	}
+	AuthRateLimitFlag = &cli.Float64Flag{
+		Name:     "authrpc.ratelimit",
+		Usage:    "Cost units per second a client may spend on the authenticated APIs, keyed by JWT subject or IP (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	AuthRateBurstFlag = &cli.Float64Flag{
+		Name:     "authrpc.ratelimit.burst",
+		Usage:    "Cost units a client may spend at once on the authenticated APIs, also the maximum cost of a call (default = one second of the rate limit)",
+		Category: flags.APICategory,
+	}
+	AuthMethodCostsFlag = &cli.StringFlag{
+		Name:     "authrpc.ratelimit.costs",
+		Usage:    "Comma separated list of method costs on the authenticated APIs, 1 if not listed (e.g. debug_trace*=100,eth_getLogs=2)",
+		Category: flags.APICategory,
+	}
 
 	// Logging and debug settings
 	EthStatsURLFlag = &cli.StringFlag{
@@ -785,6 +858,31 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	HTTPMethodsAllowFlag = &cli.StringFlag{
+		Name:     "http.methods.allow",
+		Usage:    "Comma separated list of methods served over the HTTP-RPC interface, all if empty (e.g. eth_*,net_version)",
+		Category: flags.APICategory,
+	}
+	HTTPMethodsDenyFlag = &cli.StringFlag{
+		Name:     "http.methods.deny",
+		Usage:    "Comma separated list of methods not served over the HTTP-RPC interface (e.g. debug_trace*)",
+		Category: flags.APICategory,
+	}
+	HTTPRateLimitFlag = &cli.Float64Flag{
+		Name:     "http.ratelimit",
+		Usage:    "Cost units per second a client may spend on the HTTP-RPC interface, keyed by JWT subject or IP (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	HTTPRateBurstFlag = &cli.Float64Flag{
+		Name:     "http.ratelimit.burst",
+		Usage:    "Cost units a client may spend at once on the HTTP-RPC interface, also the maximum cost of a call (default = one second of the rate limit)",
+		Category: flags.APICategory,
+	}
+	HTTPMethodCostsFlag = &cli.StringFlag{
+		Name:     "http.ratelimit.costs",
+		Usage:    "Comma separated list of method costs on the HTTP-RPC interface, 1 if not listed (e.g. debug_trace*=100,eth_getLogs=2)",
+		Category: flags.APICategory,
+	}
 	GraphQLEnabledFlag = &cli.BoolFlag{
 		Name:     "graphql",
 		Usage:    "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -796,6 +894,11 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	GraphQLTracingFlag = &cli.BoolFlag{
+		Name:     "graphql.tracing",
+		Usage:    "Enable tracing transactions with the native tracers over GraphQL",
+		Category: flags.APICategory,
+	}
 	GraphQLVirtualHostsFlag = &cli.StringFlag{
 		Name:     "graphql.vhosts",
 		Usage:    "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
@@ -837,6 +940,31 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	WSMethodsAllowFlag = &cli.StringFlag{
+		Name:     "ws.methods.allow",
+		Usage:    "Comma separated list of methods served over the WS-RPC interface, all if empty (e.g. eth_*,net_version)",
+		Category: flags.APICategory,
+	}
+	WSMethodsDenyFlag = &cli.StringFlag{
+		Name:     "ws.methods.deny",
+		Usage:    "Comma separated list of methods not served over the WS-RPC interface (e.g. debug_trace*)",
+		Category: flags.APICategory,
+	}
+	WSRateLimitFlag = &cli.Float64Flag{
+		Name:     "ws.ratelimit",
+		Usage:    "Cost units per second a client may spend on the WS-RPC interface, keyed by JWT subject or IP (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	WSRateBurstFlag = &cli.Float64Flag{
+		Name:     "ws.ratelimit.burst",
+		Usage:    "Cost units a client may spend at once on the WS-RPC interface, also the maximum cost of a call (default = one second of the rate limit)",
+		Category: flags.APICategory,
+	}
+	WSMethodCostsFlag = &cli.StringFlag{
+		Name:     "ws.ratelimit.costs",
+		Usage:    "Comma separated list of method costs on the WS-RPC interface, 1 if not listed (e.g. debug_trace*=100,eth_getLogs=2)",
+		Category: flags.APICategory,
+	}
 	ExecFlag = &cli.StringFlag{
 		Name:     "exec",
 		Usage:    "Execute JavaScript statement",
@@ -864,6 +992,27 @@ var (
 		Value:    node.DefaultConfig.BatchResponseMaxSize,
 		Category: flags.APICategory,
 	}
+	RPCAccessLogFlag = &cli.BoolFlag{
+		Name:     "rpc.accesslog",
+		Usage:    "Enables the access log of the calls served over the HTTP-RPC and WS-RPC interfaces",
+		Category: flags.APICategory,
+	}
+	RPCAccessLogFileFlag = &cli.StringFlag{
+		Name:     "rpc.accesslog.file",
+		Usage:    "File the RPC access log is appended to as JSON lines (default = through the node log)",
+		Category: flags.APICategory,
+	}
+	RPCAccessLogSampleFlag = &cli.Float64Flag{
+		Name:     "rpc.accesslog.sample",
+		Usage:    "Fraction of the RPC calls written to the access log",
+		Value:    node.DefaultConfig.RPCAccessLog.SampleRate,
+		Category: flags.APICategory,
+	}
+	RPCAccessLogSlowFlag = &cli.DurationFlag{
+		Name:     "rpc.accesslog.slow",
+		Usage:    "Duration from which RPC calls are written to the access log regardless of sampling (0 = disabled)",
+		Category: flags.APICategory,
+	}
 	EnablePersonal = &cli.BoolFlag{
 		Name:     "rpc.enabledeprecatedpersonal",
 		Usage:    "Enables the (deprecated) personal namespace",
@@ -1125,17 +1274,13 @@ var (
 	DatabasePathFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		AncientRemoteFlag,
 		RemoteDBFlag,
 		HttpHeaderFlag,
+		DBEngineFlag,
 	}
 )
 
-func init() {
-	if rawdb.PebbleEnabled {
-		DatabasePathFlags = append(DatabasePathFlags, DBEngineFlag)
-	}
-}
-
 // MakeDataDir retrieves the currently requested data directory, terminating
 // if none (or the empty string) is specified. If the node is starting a testnet,
 // then a subdirectory of the specified datadir will be used.
@@ -1308,6 +1453,7 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(AuthVirtualHostsFlag.Name) {
 		cfg.AuthVirtualHosts = SplitAndTrim(ctx.String(AuthVirtualHostsFlag.Name))
 	}
+	setRPCPolicy(ctx, &cfg.AuthPolicy, AuthMethodsAllowFlag, AuthMethodsDenyFlag, AuthRateLimitFlag, AuthRateBurstFlag, AuthMethodCostsFlag)
 
 	if ctx.IsSet(HTTPCORSDomainFlag.Name) {
 		cfg.HTTPCors = SplitAndTrim(ctx.String(HTTPCORSDomainFlag.Name))
@@ -1324,6 +1470,8 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(HTTPPathPrefixFlag.Name) {
 		cfg.HTTPPathPrefix = ctx.String(HTTPPathPrefixFlag.Name)
 	}
+	setRPCPolicy(ctx, &cfg.HTTPPolicy, HTTPMethodsAllowFlag, HTTPMethodsDenyFlag, HTTPRateLimitFlag, HTTPRateBurstFlag, HTTPMethodCostsFlag)
+
 	if ctx.IsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.Bool(AllowUnprotectedTxs.Name)
 	}
@@ -1337,6 +1485,23 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCAccessLog configures the access log of the RPC servers from the command
+// line flags.
+func setRPCAccessLog(ctx *cli.Context, cfg *node.Config) {
+	if ctx.IsSet(RPCAccessLogFlag.Name) {
+		cfg.RPCAccessLog.Enabled = ctx.Bool(RPCAccessLogFlag.Name)
+	}
+	if ctx.IsSet(RPCAccessLogFileFlag.Name) {
+		cfg.RPCAccessLog.File = ctx.String(RPCAccessLogFileFlag.Name)
+	}
+	if ctx.IsSet(RPCAccessLogSampleFlag.Name) {
+		cfg.RPCAccessLog.SampleRate = ctx.Float64(RPCAccessLogSampleFlag.Name)
+	}
+	if ctx.IsSet(RPCAccessLogSlowFlag.Name) {
+		cfg.RPCAccessLog.SlowThreshold = ctx.Duration(RPCAccessLogSlowFlag.Name)
+	}
+}
+
 // setGraphQL creates the GraphQL listener interface string from the set
 // command line flags, returning empty if the GraphQL endpoint is disabled.
 func setGraphQL(ctx *cli.Context, cfg *node.Config) {
@@ -1346,6 +1511,9 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.String(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.IsSet(GraphQLTracingFlag.Name) {
+		cfg.GraphQLTracing = ctx.Bool(GraphQLTracingFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -1372,6 +1540,35 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(WSPathPrefixFlag.Name) {
 		cfg.WSPathPrefix = ctx.String(WSPathPrefixFlag.Name)
 	}
+	setRPCPolicy(ctx, &cfg.WSPolicy, WSMethodsAllowFlag, WSMethodsDenyFlag, WSRateLimitFlag, WSRateBurstFlag, WSMethodCostsFlag)
+}
+
+// setRPCPolicy configures the access policy of an RPC endpoint from the command line
+// flags.
+func setRPCPolicy(ctx *cli.Context, policy *node.RPCPolicy, allow, deny *cli.StringFlag, rate, burst *cli.Float64Flag, costs *cli.StringFlag) {
+	if ctx.IsSet(allow.Name) {
+		policy.AllowMethods = SplitAndTrim(ctx.String(allow.Name))
+	}
+	if ctx.IsSet(deny.Name) {
+		policy.DenyMethods = SplitAndTrim(ctx.String(deny.Name))
+	}
+	if ctx.IsSet(rate.Name) {
+		policy.RateLimit = ctx.Float64(rate.Name)
+	}
+	if ctx.IsSet(burst.Name) {
+		policy.RateBurst = ctx.Float64(burst.Name)
+	}
+	if ctx.IsSet(costs.Name) {
+		policy.MethodCosts = make(map[string]float64)
+		for _, entry := range SplitAndTrim(ctx.String(costs.Name)) {
+			method, value, ok := strings.Cut(entry, "=")
+			cost, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if !ok || err != nil || cost < 0 {
+				Fatalf("Invalid method cost %q in --%s", entry, costs.Name)
+			}
+			policy.MethodCosts[strings.TrimSpace(method)] = cost
+		}
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1592,6 +1789,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCAccessLog(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	SetDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1662,8 +1860,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	}
 	if ctx.IsSet(DBEngineFlag.Name) {
 		dbEngine := ctx.String(DBEngineFlag.Name)
-		if dbEngine != "leveldb" && dbEngine != "pebble" {
-			Fatalf("Invalid choice for db.engine '%s', allowed 'leveldb' or 'pebble'", dbEngine)
+		if !rawdb.DefaultBackends.Has(dbEngine) {
+			Fatalf("Invalid choice for db.engine '%s', allowed %s", dbEngine, strings.Join(rawdb.DefaultBackends.Names(), ", "))
 		}
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
@@ -1878,6 +2076,18 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerNewPayloadTimeout.Name) {
 		cfg.NewPayloadTimeout = ctx.Duration(MinerNewPayloadTimeout.Name)
 	}
+	if ctx.IsSet(MinerStratumFlag.Name) {
+		cfg.Stratum = ctx.String(MinerStratumFlag.Name)
+	}
+	if ctx.IsSet(MinerStratumDifficultyFlag.Name) {
+		cfg.StratumDifficulty = ctx.Uint64(MinerStratumDifficultyFlag.Name)
+	}
+	if ctx.IsSet(MinerStratumMaxSessionsFlag.Name) {
+		cfg.StratumMaxSessions = ctx.Int(MinerStratumMaxSessionsFlag.Name)
+	}
+	if ctx.IsSet(MinerStratumMaxSessionsIPFlag.Name) {
+		cfg.StratumMaxSessionsIP = ctx.Int(MinerStratumMaxSessionsIPFlag.Name)
+	}
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1999,9 +2209,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		cfg.DatabaseCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = MakeDatabaseHandles(ctx.Int(FDLimitFlag.Name))
+	CheckExclusive(ctx, AncientFlag, AncientRemoteFlag)
 	if ctx.IsSet(AncientFlag.Name) {
 		cfg.DatabaseFreezer = ctx.String(AncientFlag.Name)
 	}
+	if ctx.IsSet(AncientRemoteFlag.Name) {
+		cfg.DatabaseFreezerRemote = ctx.String(AncientRemoteFlag.Name)
+	}
 
 	if gcmode := ctx.String(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -2021,6 +2235,22 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(HistoryExpiryFlag.Name) {
+		cfg.HistoryExpiry = ctx.Uint64(HistoryExpiryFlag.Name)
+	}
+	if ctx.IsSet(HistoryStateDiffsFlag.Name) {
+		cfg.StateDiffs = ctx.Bool(HistoryStateDiffsFlag.Name)
+		if cfg.StateDiffs && cfg.NoPruning {
+			log.Warn("State diffs are redundant in archive mode, disabling", "flag", HistoryStateDiffsFlag.Name)
+			cfg.StateDiffs = false
+		}
+	}
+	if ctx.IsSet(TraceIndexFlag.Name) {
+		cfg.TraceIndex = ctx.Bool(TraceIndexFlag.Name)
+		if cfg.TraceIndex && !cfg.NoPruning {
+			log.Warn("The trace index requires the historical state of archive mode", "flag", TraceIndexFlag.Name)
+		}
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -2077,6 +2307,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.IsSet(RPCGlobalTraceFilterCapFlag.Name) {
+		cfg.RPCTraceFilterCap = ctx.Uint64(RPCGlobalTraceFilterCapFlag.Name)
+	}
 	if ctx.IsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.IsSet(DNSDiscoveryFlag.Name) {
@@ -2331,6 +2564,7 @@ func RegisterFilterAPI(stack *node.Node, backend ethapi.Backend, ethcfg *ethconf
 		Namespace: "eth",
 		Service:   filters.NewFilterAPI(filterSystem, isLightClient),
 	}})
+	stack.RegisterRPCCost("eth_getLogs", filterSystem.LogsCost)
 	return filterSystem
 }
 
@@ -2449,6 +2683,8 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 		chainDb = remotedb.New(client)
 	case ctx.String(SyncModeFlag.Name) == "light":
 		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "", readonly)
+	case ctx.IsSet(AncientRemoteFlag.Name):
+		chainDb, err = stack.OpenDatabaseWithFreezerRemote("chaindata", cache, handles, ctx.String(AncientRemoteFlag.Name), "", readonly)
 	default:
 		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.String(AncientFlag.Name), "", readonly)
 	}
@@ -2565,6 +2801,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
 		SnapshotLimit:       ethconfig.Defaults.SnapshotCache,
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
+		StateDiffs:          ctx.Bool(HistoryStateDiffsFlag.Name) && ctx.String(GCModeFlag.Name) != "archive",
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
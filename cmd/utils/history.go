@@ -0,0 +1,361 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/internal/era"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/trie"
+)
+
+// eraChecksumsFile is the file listing the SHA256 checksums of the era1
+// archives of a directory, in the format of sha256sum.
+const eraChecksumsFile = "checksums.txt"
+
+// ExportAncients exports the ancient blocks [first, last] into era1 archives
+// of one epoch each in the given directory, and records their checksums.
+func ExportAncients(db ethdb.Database, dir, network string, first, last uint64, interrupt chan struct{}) error {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	if first > last || last >= frozen {
+		return fmt.Errorf("invalid range [%d, %d], the ancient store holds %d blocks", first, last, frozen)
+	}
+	if tail, err := db.Tail(); err != nil {
+		return err
+	} else if first < tail {
+		return fmt.Errorf("blocks before %d are pruned from the ancient store", tail)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	checksums, err := readEraChecksums(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if checksums == nil {
+		checksums = make(map[string]string)
+	}
+	log.Info("Exporting ancients", "dir", dir, "network", network, "first", first, "last", last)
+
+	start := time.Now()
+	for epochStart := first - first%era.MaxEra1Size; epochStart <= last; epochStart += era.MaxEra1Size {
+		select {
+		case <-interrupt:
+			log.Info("Ancients exporting interrupted", "dir", dir, "exported", epochStart-first)
+			return writeEraChecksums(dir, checksums)
+		default:
+		}
+		from, to := epochStart, epochStart+era.MaxEra1Size-1
+		if from < first {
+			from = first
+		}
+		if to > last {
+			to = last
+		}
+		name, checksum, err := exportAncientEpoch(db, dir, network, from, to)
+		if err != nil {
+			return fmt.Errorf("error exporting blocks [%d, %d]: %w", from, to, err)
+		}
+		checksums[name] = checksum
+		log.Info("Exported era1 archive", "file", name, "first", from, "last", to, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	if err := writeEraChecksums(dir, checksums); err != nil {
+		return err
+	}
+	log.Info("Exported ancients", "dir", dir, "count", last-first+1, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// exportAncientEpoch writes the blocks [from, to] of a single epoch to an era1
+// archive, returning its file name and checksum.
+func exportAncientEpoch(db ethdb.Database, dir, network string, from, to uint64) (string, string, error) {
+	// The archive is named after its accumulator root, so write to a temporary
+	// file first.
+	f, err := os.CreateTemp(dir, "export-*.era1.tmp")
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	var (
+		hasher  = sha256.New()
+		writer  = bufio.NewWriter(io.MultiWriter(f, hasher))
+		builder = era.NewBuilder(writer)
+	)
+	for number := from; number <= to; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		header := rawdb.ReadHeader(db, hash, number)
+		body := rawdb.ReadBody(db, hash, number)
+		receipts := rawdb.ReadRawReceipts(db, hash, number)
+		td := rawdb.ReadTd(db, hash, number)
+		if header == nil || body == nil || receipts == nil || td == nil {
+			return "", "", fmt.Errorf("missing block %d", number)
+		}
+		if len(receipts) != len(body.Transactions) {
+			return "", "", fmt.Errorf("block %d: receipt count mismatch: have %d, want %d", number, len(receipts), len(body.Transactions))
+		}
+		// The ancient store keeps the receipts without the fields derived from the
+		// block, restore the ones of their consensus encoding.
+		for i, receipt := range receipts {
+			receipt.Type = body.Transactions[i].Type()
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		}
+		block := types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles).WithWithdrawals(body.Withdrawals)
+		if err := builder.Add(block, receipts, td); err != nil {
+			return "", "", err
+		}
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		return "", "", err
+	}
+	if err := writer.Flush(); err != nil {
+		return "", "", err
+	}
+	if err := f.Sync(); err != nil {
+		return "", "", err
+	}
+	name := era.Filename(network, int(from/era.MaxEra1Size), root)
+	if err := os.Rename(f.Name(), filepath.Join(dir, name)); err != nil {
+		return "", "", err
+	}
+	return name, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// ImportAncients imports the era1 archives of the given network in the
+// directory into the ancient store, appending to the blocks already present.
+// The checksum of every archive, and the hash, the parent hash, the roots and
+// the total difficulty of every block are verified before writing.
+func ImportAncients(db ethdb.Database, dir, network string, interrupt chan struct{}) error {
+	files, err := era.ReadDir(dir, network)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no era1 archives of network %s in %s", network, dir)
+	}
+	checksums, err := readEraChecksums(dir)
+	if err != nil {
+		return fmt.Errorf("error reading checksums: %w", err)
+	}
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	// The key-value store may only hold the genesis block, or blocks the ancient
+	// store already holds. Otherwise the imported blocks would conflict with them.
+	if number := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db)); number != nil && *number > 0 && *number >= frozen {
+		return fmt.Errorf("the database holds blocks up to %d beyond the ancient store, import into a new datadir", *number)
+	}
+	log.Info("Importing ancients", "dir", dir, "network", network, "archives", len(files), "ancients", frozen)
+
+	start := time.Now()
+	for _, name := range files {
+		select {
+		case <-interrupt:
+			log.Info("Ancients importing interrupted", "dir", dir, "ancients", frozen)
+			return nil
+		default:
+		}
+		path := filepath.Join(dir, name)
+		if err := verifyEraChecksum(path, checksums[name]); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		imported, err := importAncientEpoch(db, path, frozen)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if imported == 0 {
+			log.Info("Skipped imported era1 archive", "file", name)
+			continue
+		}
+		frozen += imported
+		log.Info("Imported era1 archive", "file", name, "count", imported, "ancients", frozen, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	log.Info("Imported ancients", "dir", dir, "ancients", frozen, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// importAncientEpoch verifies the blocks of an era1 archive and appends the
+// ones after the first frozen blocks to the ancient store, returning the
+// number of blocks written.
+func importAncientEpoch(db ethdb.Database, path string, frozen uint64) (uint64, error) {
+	e, err := era.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer e.Close()
+
+	first, count := e.Start(), e.Count()
+	if first+count <= frozen {
+		return 0, nil
+	}
+	if first > frozen {
+		return 0, fmt.Errorf("blocks [%d, %d] missing before the archive", frozen, first-1)
+	}
+	// Link the archive to the preceding blocks of the ancient store.
+	var (
+		parentHash common.Hash
+		parentTD   = new(big.Int)
+	)
+	if first > 0 {
+		parentHash = rawdb.ReadCanonicalHash(db, first-1)
+		if parentTD = rawdb.ReadTd(db, parentHash, first-1); parentTD == nil {
+			return 0, fmt.Errorf("missing block %d", first-1)
+		}
+	}
+	var (
+		blocks   = make([]*types.Block, 0, count)
+		receipts = make([]types.Receipts, 0, count)
+		hashes   = make([]common.Hash, 0, count)
+		tds      = make([]*big.Int, 0, count)
+	)
+	for number := first; number < first+count; number++ {
+		block, rs, td, err := e.GetBlockByNumber(number)
+		if err != nil {
+			return 0, err
+		}
+		if err := verifyAncientBlock(block, rs, td, parentHash, parentTD); err != nil {
+			return 0, fmt.Errorf("block %d: %w", number, err)
+		}
+		// Blocks present in the ancient store must be the same.
+		if number < frozen || number == 0 {
+			if have := rawdb.ReadCanonicalHash(db, number); have != (common.Hash{}) && have != block.Hash() {
+				return 0, fmt.Errorf("block %d: hash mismatch with the database: have %x, want %x", number, block.Hash(), have)
+			}
+		}
+		blocks, receipts = append(blocks, block), append(receipts, rs)
+		hashes, tds = append(hashes, block.Hash()), append(tds, td)
+		parentHash, parentTD = block.Hash(), td
+	}
+	want, err := e.Accumulator()
+	if err != nil {
+		return 0, err
+	}
+	if root, err := era.ComputeAccumulator(hashes, tds); err != nil {
+		return 0, err
+	} else if root != want {
+		return 0, fmt.Errorf("accumulator root mismatch: have %x, want %x", root, want)
+	}
+	skip := frozen - first
+	if _, err := rawdb.WriteAncientBlocks(db, blocks[skip:], receipts[skip:], tds[skip]); err != nil {
+		return 0, err
+	}
+	if err := db.Sync(); err != nil {
+		return 0, err
+	}
+	return count - skip, nil
+}
+
+// verifyAncientBlock checks the block links to its parent, its body and
+// receipts match its header, and its total difficulty extends its parent's.
+func verifyAncientBlock(block *types.Block, receipts types.Receipts, td *big.Int, parentHash common.Hash, parentTD *big.Int) error {
+	if block.NumberU64() > 0 && block.ParentHash() != parentHash {
+		return fmt.Errorf("parent hash mismatch: have %x, want %x", block.ParentHash(), parentHash)
+	}
+	if want := new(big.Int).Add(parentTD, block.Difficulty()); td.Cmp(want) != 0 {
+		return fmt.Errorf("total difficulty mismatch: have %v, want %v", td, want)
+	}
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != block.TxHash() {
+		return fmt.Errorf("transaction root mismatch: have %x, want %x", hash, block.TxHash())
+	}
+	if hash := types.CalcUncleHash(block.Uncles()); hash != block.UncleHash() {
+		return fmt.Errorf("uncle root mismatch: have %x, want %x", hash, block.UncleHash())
+	}
+	if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+		return fmt.Errorf("receipt root mismatch: have %x, want %x", hash, block.ReceiptHash())
+	}
+	return nil
+}
+
+// readEraChecksums reads the checksums of the era1 archives of the directory,
+// keyed by file name.
+func readEraChecksums(dir string) (map[string]string, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, eraChecksumsFile))
+	if err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]string)
+	for _, line := range strings.Split(string(blob), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed checksum line %q", line)
+		}
+		checksums[fields[1]] = fields[0]
+	}
+	return checksums, nil
+}
+
+// writeEraChecksums writes the checksums of the era1 archives of the directory.
+func writeEraChecksums(dir string, checksums map[string]string) error {
+	names := make([]string, 0, len(checksums))
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", checksums[name], name)
+	}
+	return os.WriteFile(filepath.Join(dir, eraChecksumsFile), []byte(b.String()), 0644)
+}
+
+// verifyEraChecksum checks the SHA256 checksum of the file.
+func verifyEraChecksum(path string, want string) error {
+	if want == "" {
+		return errors.New("checksum missing")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+	if have := hex.EncodeToString(hasher.Sum(nil)); have != want {
+		return fmt.Errorf("checksum mismatch: have %s, want %s", have, want)
+	}
+	return nil
+}
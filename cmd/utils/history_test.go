@@ -0,0 +1,170 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/consensus/ethash"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/internal/era"
+	"github.com/yuriy0803/core-geth1/params"
+	"github.com/yuriy0803/core-geth1/params/types/genesisT"
+	"github.com/yuriy0803/core-geth1/params/vars"
+)
+
+// newAncientsTestDatabase returns a database holding the given blocks in its
+// ancient store.
+func newAncientsTestDatabase(t *testing.T, blocks []*types.Block, receipts []types.Receipts) ethdb.Database {
+	t.Helper()
+
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if len(blocks) > 0 {
+		if _, err := rawdb.WriteAncientBlocks(db, blocks, receipts, blocks[0].Difficulty()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestExportImportAncients(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		config  = params.AllEthashProtocolChanges
+		signer  = types.LatestSigner(config)
+		genesis = &genesisT.Genesis{
+			Config:     config,
+			Difficulty: big.NewInt(131072),
+			Alloc:      genesisT.GenesisAlloc{address: {Balance: big.NewInt(1e18)}},
+		}
+		nonce uint64
+	)
+	_, blocks, receipts := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), era.MaxEra1Size+100, func(i int, gen *core.BlockGen) {
+		if i%500 != 0 {
+			return
+		}
+		// Include both legacy and typed transactions, and logs.
+		legacy, _ := types.SignTx(types.NewTransaction(nonce, common.Address{1}, big.NewInt(1), vars.TxGas, gen.BaseFee(), nil), signer, key)
+		dynamic, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   config.GetChainID(),
+			Nonce:     nonce + 1,
+			To:        &common.Address{2},
+			Gas:       100000,
+			GasFeeCap: gen.BaseFee(),
+			Data:      common.FromHex("6001600055"),
+		}), signer, key)
+		gen.AddTx(legacy)
+		gen.AddTx(dynamic)
+		nonce += 2
+	})
+	genesisBlock := core.GenesisToBlock(genesis, nil)
+	blocks = append([]*types.Block{genesisBlock}, blocks...)
+	receipts = append([]types.Receipts{nil}, receipts...)
+
+	var (
+		src = newAncientsTestDatabase(t, blocks, receipts)
+		dir = t.TempDir()
+	)
+	if err := ExportAncients(src, dir, "test", 0, uint64(len(blocks)-1), nil); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	files, err := era.ReadDir(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("wrong archive count: have %d, want 2", len(files))
+	}
+
+	// Import into a new database, continuing from the genesis block.
+	dst := newAncientsTestDatabase(t, blocks[:1], receipts[:1])
+	if err := ImportAncients(dst, dir, "test", nil); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if frozen, _ := dst.Ancients(); frozen != uint64(len(blocks)) {
+		t.Fatalf("wrong ancients: have %d, want %d", frozen, len(blocks))
+	}
+	for _, table := range []string{rawdb.ChainFreezerHashTable, rawdb.ChainFreezerHeaderTable, rawdb.ChainFreezerBodiesTable, rawdb.ChainFreezerReceiptTable, rawdb.ChainFreezerDifficultyTable} {
+		for number := uint64(0); number < uint64(len(blocks)); number += 97 {
+			want, _ := src.Ancient(table, number)
+			have, _ := dst.Ancient(table, number)
+			if !reflect.DeepEqual(have, want) {
+				t.Fatalf("table %s, block %d: mismatch after import", table, number)
+			}
+		}
+	}
+	// Importing again is a no-op.
+	if err := ImportAncients(dst, dir, "test", nil); err != nil {
+		t.Fatalf("repeated import failed: %v", err)
+	}
+	if frozen, _ := dst.Ancients(); frozen != uint64(len(blocks)) {
+		t.Fatalf("wrong ancients after repeated import: have %d, want %d", frozen, len(blocks))
+	}
+}
+
+func TestImportAncientsVerification(t *testing.T) {
+	genesis := &genesisT.Genesis{Config: params.AllEthashProtocolChanges, Difficulty: big.NewInt(131072)}
+	_, blocks, receipts := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 64, nil)
+	blocks = append([]*types.Block{core.GenesisToBlock(genesis, nil)}, blocks...)
+	receipts = append([]types.Receipts{nil}, receipts...)
+
+	// Export a chain with a wrong total difficulty.
+	dir := t.TempDir()
+	bad := newAncientsTestDatabase(t, blocks[:1], receipts[:1])
+	if _, err := rawdb.WriteAncientBlocks(bad, blocks[1:], receipts[1:], big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportAncients(bad, dir, "test", 0, uint64(len(blocks)-1), nil); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if err := ImportAncients(newAncientsTestDatabase(t, nil, nil), dir, "test", nil); err == nil {
+		t.Fatal("wrong total difficulty imported")
+	}
+
+	// Export the valid chain and corrupt the archive.
+	dir = t.TempDir()
+	if err := ExportAncients(newAncientsTestDatabase(t, blocks, receipts), dir, "test", 0, uint64(len(blocks)-1), nil); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	files, _ := era.ReadDir(dir, "test")
+	path := filepath.Join(dir, files[0])
+	blob, _ := os.ReadFile(path)
+	blob[len(blob)/2] ^= 0xff
+	os.WriteFile(path, blob, 0644)
+
+	dst := newAncientsTestDatabase(t, nil, nil)
+	if err := ImportAncients(dst, dir, "test", nil); err == nil {
+		t.Fatal("corrupted archive imported")
+	}
+	if frozen, _ := dst.Ancients(); frozen != 0 {
+		t.Fatalf("blocks of a corrupted archive imported: %d", frozen)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/state/snapshot"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/trie"
+)
+
+// ExportState exports the flat state of the block from the snapshot into a state
+// file, returning its manifest. The state must be within the snapshot tree, so
+// of one of the recent blocks.
+func ExportState(db ethdb.Database, fn string, header *types.Header, interrupt chan struct{}) (*snapshot.StateManifest, error) {
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return nil, errors.New("no head block found")
+	}
+	snapconfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapconfig, db, trie.NewDatabase(db), head.Root())
+	if err != nil {
+		return nil, err
+	}
+	if snaptree.Snapshot(header.Root) == nil {
+		return nil, fmt.Errorf("state of block %d not in the snapshot", header.Number)
+	}
+	log.Info("Exporting state", "file", fn, "number", header.Number, "hash", header.Hash(), "root", header.Root)
+
+	// Write to a temporary file first, so interrupted exports leave no file.
+	f, err := os.Create(fn + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	var (
+		start  = time.Now()
+		writer = bufio.NewWriter(f)
+	)
+	manifest, err := snapshot.ExportState(writer, snaptree, db, header, interrupt)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), fn); err != nil {
+		return nil, err
+	}
+	log.Info("Exported state", "file", fn, "accounts", manifest.Accounts, "slots", manifest.Slots, "codes", manifest.Codes,
+		"chunks", len(manifest.Chunks), "manifest", manifest.ID(), "elapsed", common.PrettyDuration(time.Since(start)))
+	return manifest, nil
+}
+
+// ImportState imports the state of a state file into a database without any, so
+// that the node starts from the block of the state. The header chain up to the
+// block, as well as the block itself, must already be present, for instance by
+// importing the ancients. If the manifest hash is given, the file is verified
+// against it.
+func ImportState(db ethdb.Database, fn string, manifestHash common.Hash, interrupt chan struct{}) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	manifest, err := snapshot.ReadStateManifest(f, stat.Size())
+	if err != nil {
+		return fmt.Errorf("error reading manifest: %w", err)
+	}
+	if id := manifest.ID(); manifestHash != (common.Hash{}) && id != manifestHash {
+		return fmt.Errorf("manifest hash mismatch: have %x, want %x", id, manifestHash)
+	}
+	// The state must belong to the local chain.
+	number, hash := manifest.Number, manifest.Hash
+	if rawdb.ReadCanonicalHash(db, number) != hash {
+		return fmt.Errorf("block %d (%x) of the state is not in the local chain, import the chain up to it first", number, hash)
+	}
+	header := rawdb.ReadHeader(db, hash, number)
+	if header == nil || !rawdb.HasBody(db, hash, number) {
+		return fmt.Errorf("block %d of the state missing", number)
+	}
+	if header.Root != manifest.Root {
+		return fmt.Errorf("state root mismatch with block %d: have %x, want %x", number, manifest.Root, header.Root)
+	}
+	// Only import into a database without state beyond the genesis.
+	if root := rawdb.ReadSnapshotRoot(db); root != (common.Hash{}) {
+		return errors.New("the database already holds a state snapshot, import into a new datadir")
+	}
+	if head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db)); head != nil && *head > 0 {
+		return fmt.Errorf("the database holds the state of block %d, import into a new datadir", *head)
+	}
+	// The trie nodes are written in the scheme of the existing state, the
+	// path-based state however also needs layer metadata the file lacks.
+	scheme := rawdb.ReadStateScheme(db)
+	if scheme == rawdb.PathScheme {
+		return errors.New("state import into a path-based database is not supported")
+	}
+	log.Info("Importing state", "file", fn, "number", number, "hash", hash, "root", manifest.Root,
+		"accounts", manifest.Accounts, "slots", manifest.Slots, "chunks", len(manifest.Chunks))
+
+	start := time.Now()
+	if err := snapshot.ImportState(db, scheme, f, manifest, interrupt); err != nil {
+		return err
+	}
+	// Start the chain from the block of the state.
+	rawdb.WriteHeadBlockHash(db, hash)
+	if head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db)); head == nil || *head < number {
+		rawdb.WriteHeadHeaderHash(db, hash)
+	}
+	if head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadFastBlockHash(db)); head == nil || *head < number {
+		rawdb.WriteHeadFastBlockHash(db, hash)
+	}
+	log.Info("Imported state", "file", fn, "number", number, "hash", hash, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/consensus/ethash"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/core/vm"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/params"
+	"github.com/yuriy0803/core-geth1/params/types/genesisT"
+	"github.com/yuriy0803/core-geth1/params/vars"
+)
+
+func TestExportImportState(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0x1000")
+		genesis  = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: genesisT.GenesisAlloc{
+				address: {Balance: big.NewInt(1e18)},
+				// NUMBER NUMBER SSTORE STOP
+				contract: {Balance: common.Big0, Code: []byte{0x43, 0x43, 0x55, 0x00}},
+			},
+			BaseFee: big.NewInt(vars.InitialBaseFee),
+		}
+		signer = types.LatestSigner(genesis.Config)
+	)
+	_, blocks, receipts := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 32, func(i int, gen *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(address), contract, common.Big0, 100000, gen.BaseFee(), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gen.AddTx(tx)
+	})
+	src := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(src, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create source chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop()
+
+	// Export the state of the head block and of an older one within the snapshot.
+	var (
+		dir  = t.TempDir()
+		head = blocks[len(blocks)-1]
+		file = filepath.Join(dir, "head.state")
+	)
+	manifest, err := ExportState(src, file, head.Header(), nil)
+	if err != nil {
+		t.Fatalf("failed to export state: %v", err)
+	}
+	if manifest.Root != head.Root() || manifest.Accounts != 3 || manifest.Slots != 32 || manifest.Codes != 1 {
+		t.Fatalf("wrong manifest: %+v", manifest)
+	}
+	if _, err := ExportState(src, filepath.Join(dir, "old.state"), blocks[15].Header(), nil); err != nil {
+		t.Fatalf("failed to export older state: %v", err)
+	}
+	// newDatabase returns a database holding the chain without any state.
+	newDatabase := func() ethdb.Database {
+		db := rawdb.NewMemoryDatabase()
+		td := new(big.Int).Set(core.MustCommitGenesis(db, genesis).Difficulty())
+		for i, block := range blocks {
+			td.Add(td, block.Difficulty())
+			rawdb.WriteBlock(db, block)
+			rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+			rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+			rawdb.WriteTd(db, block.Hash(), block.NumberU64(), td)
+		}
+		rawdb.WriteHeadHeaderHash(db, head.Hash())
+		return db
+	}
+	// Imports fail on a tampered file, a wrong manifest hash or a path-based
+	// database.
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob[len(blob)/2] ^= 0xff
+	tampered := filepath.Join(dir, "tampered.state")
+	if err := os.WriteFile(tampered, blob, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportState(newDatabase(), tampered, common.Hash{}, nil); err == nil {
+		t.Fatal("tampered state file imported")
+	}
+	if err := ImportState(newDatabase(), file, common.Hash{0x01}, nil); err == nil {
+		t.Fatal("state file imported with wrong manifest hash")
+	}
+	pathdb := newDatabase()
+	rawdb.WriteAccountTrieNode(pathdb, nil, []byte{0x80})
+	if err := ImportState(pathdb, file, manifest.ID(), nil); err == nil {
+		t.Fatal("state imported into a path-based database")
+	}
+	// A node starts from an imported state.
+	db := newDatabase()
+	if err := ImportState(db, file, manifest.ID(), nil); err != nil {
+		t.Fatalf("failed to import state: %v", err)
+	}
+	if err := ImportState(db, file, manifest.ID(), nil); err == nil {
+		t.Fatal("state imported twice")
+	}
+	chain, err = core.NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if current := chain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("wrong head block: have %d, want %d", current.Number, head.Number())
+	}
+	if chain.Snapshots() == nil || chain.Snapshots().Snapshot(head.Root()) == nil {
+		t.Fatal("snapshot of the imported state missing")
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open imported state: %v", err)
+	}
+	for n := int64(1); n <= 32; n++ {
+		if have := statedb.GetState(contract, common.BigToHash(big.NewInt(n))); have != common.BigToHash(big.NewInt(n)) {
+			t.Fatalf("wrong storage slot %d: have %x", n, have)
+		}
+	}
+	if nonce := statedb.GetNonce(address); nonce != 32 {
+		t.Fatalf("wrong nonce: have %d, want 32", nonce)
+	}
+}
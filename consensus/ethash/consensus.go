@@ -510,50 +510,48 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainHeaderReader, header *type
 		return errInvalidDifficulty
 	}
 	// Recompute the digest and PoW values
-	number := header.Number.Uint64()
+	digest, result := ethash.compute(header.Number.Uint64(), ethash.SealHash(header).Bytes(), header.Nonce.Uint64(), fulldag)
 
-	var (
-		digest []byte
-		result []byte
-	)
-	// If fast-but-heavy PoW verification was requested, use an ethash dataset
+	// Verify the calculated values against the ones provided in the header
+	if !bytes.Equal(header.MixDigest[:], digest) {
+		return errInvalidMixDigest
+	}
+	target := new(big.Int).Div(two256, header.Difficulty)
+	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
+		return errInvalidPoW
+	}
+	return nil
+}
+
+// compute computes the mix digest and PoW result of the nonce for the seal hash
+// of a block with the given number. If fast-but-heavy PoW verification is
+// requested, an ethash dataset is used if already generated, otherwise a cache.
+func (ethash *Ethash) compute(number uint64, hash []byte, nonce uint64, fulldag bool) (digest []byte, result []byte) {
 	if fulldag {
 		dataset := ethash.dataset(number, true)
 		if dataset.generated() {
-			digest, result = hashimotoFull(dataset.dataset, ethash.SealHash(header).Bytes(), header.Nonce.Uint64())
+			digest, result = hashimotoFull(dataset.dataset, hash, nonce)
 
 			// Datasets are unmapped in a finalizer. Ensure that the dataset stays alive
 			// until after the call to hashimotoFull so it's not unmapped while being used.
 			runtime.KeepAlive(dataset)
-		} else {
-			// Dataset not yet generated, don't hang, use a cache instead
-			fulldag = false
-		}
-	}
-	// If slow-but-light PoW verification was requested (or DAG not yet ready), use an ethash cache
-	if !fulldag {
-		cache := ethash.cache(number)
-		epochLength := calcEpochLength(number, ethash.config.ECIP1099Block)
-		epoch := calcEpoch(number, epochLength)
-		size := datasetSize(epoch)
-		if ethash.config.PowMode == ModeTest {
-			size = 32 * 1024
+			return digest, result
 		}
-		digest, result = hashimotoLight(size, cache.cache, ethash.SealHash(header).Bytes(), header.Nonce.Uint64())
-
-		// Caches are unmapped in a finalizer. Ensure that the cache stays alive
-		// until after the call to hashimotoLight so it's not unmapped while being used.
-		runtime.KeepAlive(cache)
+		// Dataset not yet generated, don't hang, use a cache instead
 	}
-	// Verify the calculated values against the ones provided in the header
-	if !bytes.Equal(header.MixDigest[:], digest) {
-		return errInvalidMixDigest
+	cache := ethash.cache(number)
+	epochLength := calcEpochLength(number, ethash.config.ECIP1099Block)
+	epoch := calcEpoch(number, epochLength)
+	size := datasetSize(epoch)
+	if ethash.config.PowMode == ModeTest {
+		size = 32 * 1024
 	}
-	target := new(big.Int).Div(two256, header.Difficulty)
-	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
-		return errInvalidPoW
-	}
-	return nil
+	digest, result = hashimotoLight(size, cache.cache, hash, nonce)
+
+	// Caches are unmapped in a finalizer. Ensure that the cache stays alive
+	// until after the call to hashimotoLight so it's not unmapped while being used.
+	runtime.KeepAlive(cache)
+	return digest, result
 }
 
 // Prepare implements consensus.Engine, initializing the difficulty field of a
@@ -22,6 +22,7 @@ import (
 	crand "crypto/rand"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"math/rand"
@@ -34,6 +35,7 @@ import (
 	"github.com/yuriy0803/core-geth1/common/hexutil"
 	"github.com/yuriy0803/core-geth1/consensus"
 	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/event"
 	exprand "golang.org/x/exp/rand"
 	"gonum.org/v1/gonum/stat/distuv"
 )
@@ -46,6 +48,7 @@ const (
 var (
 	errNoMiningWork      = errors.New("no mining work available yet")
 	errInvalidSealResult = errors.New("invalid or stale proof-of-work solution")
+	errEthashStopped     = errors.New("ethash stopped")
 )
 
 // makePoissonFakeDelay uses the ethash.threads value as a mean time (lambda)
@@ -254,6 +257,7 @@ type remoteSealer struct {
 	submitWorkCh chan *mineResult // Channel used for remote sealer to submit their mining result
 	fetchRateCh  chan chan uint64 // Channel used to gather submitted hash rate for local or remote sealer.
 	submitRateCh chan *hashrate   // Channel used for remote sealer to submit their mining hashrate
+	workFeed     event.Feed       // Feed of the work packages handed out to remote miners
 	requestExit  chan struct{}
 	exitCh       chan struct{}
 }
@@ -329,6 +333,7 @@ func (s *remoteSealer) loop() {
 			s.results = work.results
 			s.makeWork(work.block)
 			s.notifyWork()
+			s.workFeed.Send(s.currentWork)
 
 		case work := <-s.fetchWorkCh:
 			// Return current mining work to remote miner.
@@ -497,3 +502,106 @@ func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash,
 	s.ethash.config.Log.Warn("Work submitted is too old", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
 	return false
 }
+
+// GetWork returns the work package handed out to remote miners, see API.GetWork.
+func (ethash *Ethash) GetWork() ([4]string, error) {
+	if ethash.remote == nil {
+		return [4]string{}, errors.New("not supported")
+	}
+
+	var (
+		workCh = make(chan [4]string, 1)
+		errc   = make(chan error, 1)
+	)
+	select {
+	case ethash.remote.fetchWorkCh <- &sealWork{errc: errc, res: workCh}:
+	case <-ethash.remote.exitCh:
+		return [4]string{}, errEthashStopped
+	}
+	select {
+	case work := <-workCh:
+		return work, nil
+	case err := <-errc:
+		return [4]string{}, err
+	}
+}
+
+// SubmitWork submits the PoW solution of a remote miner, returning whether it
+// was accepted.
+func (ethash *Ethash) SubmitWork(nonce types.BlockNonce, hash, digest common.Hash) bool {
+	if ethash.remote == nil {
+		return false
+	}
+
+	var errc = make(chan error, 1)
+	select {
+	case ethash.remote.submitWorkCh <- &mineResult{
+		nonce:     nonce,
+		mixDigest: digest,
+		hash:      hash,
+		errc:      errc,
+	}:
+	case <-ethash.remote.exitCh:
+		return false
+	}
+	err := <-errc
+	return err == nil
+}
+
+// SubmitHashrate submits the hash rate of a remote miner, identified by an id
+// unique between miners.
+func (ethash *Ethash) SubmitHashrate(rate hexutil.Uint64, id common.Hash) bool {
+	if ethash.remote == nil {
+		return false
+	}
+
+	var done = make(chan struct{}, 1)
+	select {
+	case ethash.remote.submitRateCh <- &hashrate{done: done, rate: uint64(rate), id: id}:
+	case <-ethash.remote.exitCh:
+		return false
+	}
+
+	// Block until hash rate submitted successfully.
+	<-done
+	return true
+}
+
+// SubscribeWork subscribes to the work packages handed out to remote miners,
+// in the format returned by GetWork. The subscriber must keep up with the new
+// work, or it blocks the remote sealer.
+func (ethash *Ethash) SubscribeWork(ch chan<- [4]string) event.Subscription {
+	if ethash.remote == nil {
+		return event.NewSubscription(func(quit <-chan struct{}) error {
+			<-quit
+			return nil
+		})
+	}
+	return ethash.remote.workFeed.Subscribe(ch)
+}
+
+// ComputeWork computes the PoW of the nonce for the given work package, as
+// returned by GetWork. It returns the mix digest of the solution and the result
+// to compare against the target, which lets remote mining servers validate the
+// shares of their miners. Like the seal verification, it uses the verification
+// cache rather than the full dataset.
+func (ethash *Ethash) ComputeWork(work [4]string, nonce types.BlockNonce) (common.Hash, common.Hash, error) {
+	// If we're running a fake PoW, any nonce is a solution
+	if ethash.config.PowMode == ModeFake || ethash.config.PowMode == ModePoissonFake || ethash.config.PowMode == ModeFullFake {
+		return common.Hash{}, common.Hash{}, nil
+	}
+	// If we're running a shared PoW, delegate the computation to it
+	if ethash.shared != nil {
+		return ethash.shared.ComputeWork(work, nonce)
+	}
+	hash, err := hexutil.Decode(work[0])
+	if err != nil || len(hash) != common.HashLength {
+		return common.Hash{}, common.Hash{}, fmt.Errorf("invalid work hash %q", work[0])
+	}
+	number, err := hexutil.DecodeUint64(work[3])
+	if err != nil {
+		return common.Hash{}, common.Hash{}, fmt.Errorf("invalid work number %q: %v", work[3], err)
+	}
+	digest, result := ethash.compute(number, hash, nonce.Uint64(), false)
+	return common.BytesToHash(digest), common.BytesToHash(result), nil
+}
@@ -1,3 +1,5 @@
+//go:build cgo
+
 /**
  * Implementation of the Lyra2 Password Hashing Scheme (PHS).
  *
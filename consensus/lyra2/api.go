@@ -1,15 +1,11 @@
 package lyra2
 
 import (
-	"errors"
-
 	"github.com/yuriy0803/core-geth1/common"
 	"github.com/yuriy0803/core-geth1/common/hexutil"
 	"github.com/yuriy0803/core-geth1/core/types"
 )
 
-var errLyra2Stopped = errors.New("lyra2 stopped")
-
 // API exposes lyra2 related methods for the RPC interface.
 type API struct {
 	lyra2 *Lyra2
@@ -24,48 +20,14 @@ type API struct {
 //	result[2], 32 bytes hex encoded boundary condition ("target"), 2^256/difficulty
 //	result[3], hex encoded block number
 func (api *API) GetWork() ([4]string, error) {
-	if api.lyra2.remote == nil {
-		return [4]string{}, errors.New("not supported")
-	}
-
-	var (
-		workCh = make(chan [4]string, 1)
-		errc   = make(chan error, 1)
-	)
-	select {
-	case api.lyra2.remote.fetchWorkCh <- &sealWork{errc: errc, res: workCh}:
-	case <-api.lyra2.remote.exitCh:
-		return [4]string{}, errLyra2Stopped
-	}
-	select {
-	case work := <-workCh:
-		return work, nil
-	case err := <-errc:
-		return [4]string{}, err
-	}
+	return api.lyra2.GetWork()
 }
 
 // SubmitWork can be used by external miner to submit their POW solution.
 // It returns an indication if the work was accepted.
 // Note either an invalid solution, a stale work a non-existent work will return false.
 func (api *API) SubmitWork(nonce types.BlockNonce, hash, digest common.Hash) bool {
-	if api.lyra2.remote == nil {
-		return false
-	}
-
-	var errc = make(chan error, 1)
-	select {
-	case api.lyra2.remote.submitWorkCh <- &mineResult{
-		nonce:     nonce,
-		mixDigest: digest,
-		hash:      hash,
-		errc:      errc,
-	}:
-	case <-api.lyra2.remote.exitCh:
-		return false
-	}
-	err := <-errc
-	return err == nil
+	return api.lyra2.SubmitWork(nonce, hash, digest)
 }
 
 // SubmitHashrate can be used for remote miners to submit their hash rate.
@@ -75,20 +37,7 @@ func (api *API) SubmitWork(nonce types.BlockNonce, hash, digest common.Hash) boo
 // It accepts the miner hash rate and an identifier which must be unique
 // between nodes.
 func (api *API) SubmitHashRate(rate hexutil.Uint64, id common.Hash) bool {
-	if api.lyra2.remote == nil {
-		return false
-	}
-
-	var done = make(chan struct{}, 1)
-	select {
-	case api.lyra2.remote.submitRateCh <- &hashrate{done: done, rate: uint64(rate), id: id}:
-	case <-api.lyra2.remote.exitCh:
-		return false
-	}
-
-	// Block until hash rate submitted successfully.
-	<-done
-	return true
+	return api.lyra2.SubmitHashrate(rate, id)
 }
 
 // GetHashrate returns the current hashrate for local CPU miner and remote miner.
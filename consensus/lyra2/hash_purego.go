@@ -0,0 +1,10 @@
+//go:build !cgo || lyra2_purego
+
+package lyra2
+
+import "github.com/yuriy0803/core-geth1/common"
+
+// lyra2Hash computes the Lyra2 hash of the input with the given time cost.
+func lyra2Hash(in []byte, tcost int) common.Hash {
+	return hashGo(in, tcost)
+}
@@ -1,20 +1,12 @@
 package lyra2
 
-/*
-#cgo CFLAGS: -std=gnu99
-#include "Lyra2.h"
-#include <stdlib.h>
-*/
-import "C"
 import (
 	"encoding/binary"
 	"math/big"
 	"math/rand"
 	"sync"
 	"time"
-	"unsafe"
 
-	"github.com/yuriy0803/core-geth1/common"
 	"github.com/yuriy0803/core-geth1/consensus"
 	"github.com/yuriy0803/core-geth1/log"
 	"github.com/yuriy0803/core-geth1/metrics"
@@ -80,32 +72,12 @@ func NewTester(notify []string, noverify bool) *Lyra2 {
 	return lyra2
 }
 
+// calcHash computes the Lyra2 hash of the header bytes sealed with the given
+// nonce, overwriting their last 8 bytes. The cgo implementation is used by
+// default; the pure-Go one without cgo or with the lyra2_purego build tag.
 func (lyra2 *Lyra2) calcHash(headerBytes []byte, nonce uint64, tcost int) *big.Int {
-	var lyra2_ctx unsafe.Pointer = C.LYRA2_create()
-	result := lyra2.compute(lyra2_ctx, headerBytes, nonce, tcost)
-	C.LYRA2_destroy(lyra2_ctx)
-
-	return result.Big()
-}
-
-func bytesToHash(in unsafe.Pointer) common.Hash {
-	return *(*common.Hash)(in)
-}
-
-func (lyra2 *Lyra2) compute(ctx unsafe.Pointer, blockBytes []byte, nonce uint64, tcost int) common.Hash {
-	binary.BigEndian.PutUint64(blockBytes[len(blockBytes)-8:], nonce)
-
-	var in unsafe.Pointer = C.CBytes(blockBytes)
-	var out unsafe.Pointer = C.malloc(common.HashLength)
-
-	C.LYRA2(ctx, out, common.HashLength, in, C.int32_t(len(blockBytes)), C.int32_t(tcost))
-
-	hash := bytesToHash(out)
-
-	C.free(in)
-	C.free(out)
-
-	return hash
+	binary.BigEndian.PutUint64(headerBytes[len(headerBytes)-8:], nonce)
+	return lyra2Hash(headerBytes, tcost).Big()
 }
 
 func (lyra2 *Lyra2) Close() error {
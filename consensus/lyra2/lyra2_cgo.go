@@ -0,0 +1,31 @@
+//go:build cgo
+
+package lyra2
+
+/*
+#cgo CFLAGS: -std=gnu99
+#include "Lyra2.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"unsafe"
+
+	"github.com/yuriy0803/core-geth1/common"
+)
+
+// hashCgo computes the Lyra2 hash of the input with the given time cost using
+// the C implementation.
+func hashCgo(blockBytes []byte, tcost int) common.Hash {
+	var ctx unsafe.Pointer = C.LYRA2_create()
+	defer C.LYRA2_destroy(ctx)
+
+	var in unsafe.Pointer = C.CBytes(blockBytes)
+	var out unsafe.Pointer = C.malloc(common.HashLength)
+	defer C.free(in)
+	defer C.free(out)
+
+	C.LYRA2(ctx, out, common.HashLength, in, C.int32_t(len(blockBytes)), C.int32_t(tcost))
+
+	return *(*common.Hash)(out)
+}
@@ -0,0 +1,294 @@
+package lyra2
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sync"
+
+	"github.com/yuriy0803/core-geth1/common"
+)
+
+// This file is a Go port of Lyra2.c and Sponge.c, computing bit-identical
+// hashes without cgo.
+
+const (
+	blockLenInt64           = 12 // Sponge bitrate: 768 bits (=96 bytes, =12 uint64)
+	blockLenBlake2SafeInt64 = 8  // Block length not overwriting Blake2's IV: 512 bits
+	blockLenBlake2SafeBytes = blockLenBlake2SafeInt64 * 8
+	nRows                   = 16384                 // Rows of the memory matrix
+	nCols                   = 4                     // Columns of the memory matrix
+	rowLenInt64             = blockLenInt64 * nCols // Row length of the memory matrix
+)
+
+// blake2bIV is Blake2b's initialization vector.
+var blake2bIV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b,
+	0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f,
+	0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+// matrixPool caches the memory matrices, 6MB each, between hash computations.
+var matrixPool = sync.Pool{
+	New: func() interface{} {
+		matrix := make([]uint64, rowLenInt64*nRows)
+		return &matrix
+	},
+}
+
+// hashGo computes the Lyra2 hash of the input with the given time cost, the
+// same way as the LYRA2 function of Lyra2.c does with a 32 bytes key.
+func hashGo(pwd []byte, tcost int) common.Hash {
+	matrix := matrixPool.Get().(*[]uint64)
+	defer matrixPool.Put(matrix)
+
+	s := &sponge{matrix: *matrix}
+	s.initState()
+
+	// Absorb the password and the basil, padded with 10*1. The memory matrix is
+	// entirely overwritten during the setup phase, so no need to clear it.
+	nBlocksInput := (len(pwd)+6*8)/blockLenBlake2SafeBytes + 1
+	input := make([]byte, nBlocksInput*blockLenBlake2SafeBytes)
+	copy(input, pwd)
+	basil := input[len(pwd):]
+	binary.LittleEndian.PutUint64(basil[0:], common.HashLength) // kLen
+	binary.LittleEndian.PutUint64(basil[8:], uint64(len(pwd)))  // pwdlen
+	binary.LittleEndian.PutUint64(basil[16:], 0)                // saltlen
+	binary.LittleEndian.PutUint64(basil[24:], uint64(tcost))    // timeCost
+	binary.LittleEndian.PutUint64(basil[32:], nRows)            // nRows
+	binary.LittleEndian.PutUint64(basil[40:], nCols)            // nCols
+	basil[48] = 0x80
+	input[len(input)-1] ^= 0x01
+
+	for i := 0; i < nBlocksInput; i++ {
+		block := input[i*blockLenBlake2SafeBytes:]
+		for j := 0; j < blockLenBlake2SafeInt64; j++ {
+			s.state[j] ^= binary.LittleEndian.Uint64(block[j*8:])
+		}
+		blake2bLyra(&s.state)
+	}
+
+	// Setup phase: initialize the memory matrix
+	s.reducedSqueezeRow0()
+	s.reducedDuplexRow1()
+
+	var (
+		row    = 2 // Index of row to be processed
+		prev   = 1 // Index of prev (last row ever computed/modified)
+		rowa   = 0 // Index of row* (a previous row, deterministically picked during Setup and randomly picked while Wandering)
+		step   = 1 // Visitation step (used during Setup and Wandering phases)
+		window = 2 // Visitation window (used to define which rows can be revisited during Setup)
+		gap    = 1 // Modifier to the step, assuming the values 1 or -1
+	)
+	for row < nRows {
+		s.reducedDuplexRowSetup(prev, rowa, row)
+
+		rowa = (rowa + step) & (window - 1)
+		prev = row
+		row++
+
+		// Check if all rows in the window where visited
+		if rowa == 0 {
+			step = window + gap
+			window *= 2
+			gap = -gap
+		}
+	}
+
+	// Wandering phase: revisit pseudorandom rows of the memory matrix
+	row = 0
+	for tau := 1; tau <= tcost; tau++ {
+		step = nRows/2 - 1
+		if tau%2 == 0 {
+			step = -1
+		}
+		for {
+			rowa = int(s.state[0] & (nRows - 1))
+			s.reducedDuplexRow(prev, rowa, row)
+
+			prev = row
+			row = (row + step) & (nRows - 1)
+			if row == 0 {
+				break
+			}
+		}
+	}
+
+	// Wrap-up phase: absorb the last block of the memory matrix and squeeze the key
+	block := s.matrix[rowa*rowLenInt64:]
+	for j := 0; j < blockLenInt64; j++ {
+		s.state[j] ^= block[j]
+	}
+	blake2bLyra(&s.state)
+
+	var hash common.Hash
+	for j := 0; j < common.HashLength/8; j++ {
+		binary.LittleEndian.PutUint64(hash[j*8:], s.state[j])
+	}
+	return hash
+}
+
+// sponge is Blake2b's internal permutation in the form of a sponge, duplexing
+// the rows of the memory matrix.
+type sponge struct {
+	state  [16]uint64
+	matrix []uint64
+}
+
+// initState initializes the sponge state: the first 512 bits are zeros and the
+// remainder is Blake2b's IV.
+func (s *sponge) initState() {
+	for i := 0; i < 8; i++ {
+		s.state[i] = 0
+	}
+	copy(s.state[8:], blake2bIV[:])
+}
+
+// reducedSqueezeRow0 squeezes the first row, from the highest to the lowest
+// column, with the reduced-round permutation.
+func (s *sponge) reducedSqueezeRow0() {
+	out := (nCols - 1) * blockLenInt64
+	for i := 0; i < nCols; i++ {
+		copy(s.matrix[out:out+blockLenInt64], s.state[:blockLenInt64])
+		out -= blockLenInt64
+
+		reducedBlake2bLyra(&s.state)
+	}
+}
+
+// reducedDuplexRow1 duplexes the first row into the second one, from the
+// highest to the lowest column, with the reduced-round permutation.
+func (s *sponge) reducedDuplexRow1() {
+	var (
+		m   = s.matrix
+		in  = 0
+		out = rowLenInt64 + (nCols-1)*blockLenInt64
+	)
+	for i := 0; i < nCols; i++ {
+		// Absorb M[prev][col]
+		for j := 0; j < blockLenInt64; j++ {
+			s.state[j] ^= m[in+j]
+		}
+		reducedBlake2bLyra(&s.state)
+
+		// M[row][C-1-col] = M[prev][col] XOR rand
+		for j := 0; j < blockLenInt64; j++ {
+			m[out+j] = m[in+j] ^ s.state[j]
+		}
+		in += blockLenInt64
+		out -= blockLenInt64
+	}
+}
+
+// reducedDuplexRowSetup duplexes M[rowIn] [+] M[rowInOut] into M[rowOut], from
+// the highest to the lowest column, and into M[rowInOut] after rotation.
+func (s *sponge) reducedDuplexRowSetup(rowIn, rowInOut, rowOut int) {
+	var (
+		m     = s.matrix
+		in    = rowIn * rowLenInt64
+		inOut = rowInOut * rowLenInt64
+		out   = rowOut*rowLenInt64 + (nCols-1)*blockLenInt64
+	)
+	for i := 0; i < nCols; i++ {
+		var (
+			wordIn    = (*[blockLenInt64]uint64)(m[in:])
+			wordInOut = (*[blockLenInt64]uint64)(m[inOut:])
+			wordOut   = (*[blockLenInt64]uint64)(m[out:])
+		)
+		// Absorb M[prev] [+] M[row*]
+		for j := 0; j < blockLenInt64; j++ {
+			s.state[j] ^= wordIn[j] + wordInOut[j]
+		}
+		reducedBlake2bLyra(&s.state)
+
+		// M[row][col] = M[prev][col] XOR rand
+		for j := 0; j < blockLenInt64; j++ {
+			wordOut[j] = wordIn[j] ^ s.state[j]
+		}
+		// M[row*][col] = M[row*][col] XOR rotW(rand)
+		s.rotW(wordInOut)
+
+		in += blockLenInt64
+		inOut += blockLenInt64
+		out -= blockLenInt64
+	}
+}
+
+// reducedDuplexRow duplexes M[rowIn] [+] M[rowInOut] into M[rowOut], and into
+// M[rowInOut] after rotation.
+func (s *sponge) reducedDuplexRow(rowIn, rowInOut, rowOut int) {
+	var (
+		m     = s.matrix
+		in    = rowIn * rowLenInt64
+		inOut = rowInOut * rowLenInt64
+		out   = rowOut * rowLenInt64
+	)
+	for i := 0; i < nCols; i++ {
+		var (
+			wordIn    = (*[blockLenInt64]uint64)(m[in:])
+			wordInOut = (*[blockLenInt64]uint64)(m[inOut:])
+			wordOut   = (*[blockLenInt64]uint64)(m[out:])
+		)
+		// Absorb M[prev] [+] M[row*]
+		for j := 0; j < blockLenInt64; j++ {
+			s.state[j] ^= wordIn[j] + wordInOut[j]
+		}
+		reducedBlake2bLyra(&s.state)
+
+		// M[rowOut][col] = M[rowOut][col] XOR rand
+		for j := 0; j < blockLenInt64; j++ {
+			wordOut[j] ^= s.state[j]
+		}
+		// M[rowInOut][col] = M[rowInOut][col] XOR rotW(rand)
+		s.rotW(wordInOut)
+
+		in += blockLenInt64
+		inOut += blockLenInt64
+		out += blockLenInt64
+	}
+}
+
+// rotW XORs the block with the sponge bitrate rotated by one word.
+func (s *sponge) rotW(block *[blockLenInt64]uint64) {
+	block[0] ^= s.state[11]
+	for j := 1; j < blockLenInt64; j++ {
+		block[j] ^= s.state[j-1]
+	}
+}
+
+// g is Blake2b's G function.
+func g(a, b, c, d uint64) (uint64, uint64, uint64, uint64) {
+	a += b
+	d = bits.RotateLeft64(d^a, -32)
+	c += d
+	b = bits.RotateLeft64(b^c, -24)
+	a += b
+	d = bits.RotateLeft64(d^a, -16)
+	c += d
+	b = bits.RotateLeft64(b^c, -63)
+	return a, b, c, d
+}
+
+// roundLyra is one round of Blake2b's compression function.
+func roundLyra(v *[16]uint64) {
+	v[0], v[4], v[8], v[12] = g(v[0], v[4], v[8], v[12])
+	v[1], v[5], v[9], v[13] = g(v[1], v[5], v[9], v[13])
+	v[2], v[6], v[10], v[14] = g(v[2], v[6], v[10], v[14])
+	v[3], v[7], v[11], v[15] = g(v[3], v[7], v[11], v[15])
+	v[0], v[5], v[10], v[15] = g(v[0], v[5], v[10], v[15])
+	v[1], v[6], v[11], v[12] = g(v[1], v[6], v[11], v[12])
+	v[2], v[7], v[8], v[13] = g(v[2], v[7], v[8], v[13])
+	v[3], v[4], v[9], v[14] = g(v[3], v[4], v[9], v[14])
+}
+
+// blake2bLyra is Blake2b's compression function, with all 12 rounds.
+func blake2bLyra(v *[16]uint64) {
+	for i := 0; i < 12; i++ {
+		roundLyra(v)
+	}
+}
+
+// reducedBlake2bLyra is Blake2b's compression function reduced to one round.
+func reducedBlake2bLyra(v *[16]uint64) {
+	roundLyra(v)
+}
@@ -0,0 +1,35 @@
+package lyra2
+
+import (
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+)
+
+// Tests the pure-Go Lyra2 hashes against known answers of the C implementation,
+// so that they are verified in builds without cgo as well.
+func TestHashGo(t *testing.T) {
+	tests := []struct {
+		in    []byte
+		tcost int
+		want  common.Hash
+	}{
+		{[]byte{}, 1, common.HexToHash("c575dba05b662c7e994bc566d3e64518534b9320203d7ff4da3bf188e39d1929")},
+		{make([]byte, 8), 1, common.HexToHash("ebfaec8e189166caf83686e3e499016125ed0c40b49f8b991372ef128a8acad2")},
+		{[]byte("lyra2 test vector with a 40 bytes input"), 2, common.HexToHash("ee0640af4d753e7306b931a05eab2c4d781fb5fc219c085e5ba5b433d1e10a15")},
+		{make([]byte, 520), 1, common.HexToHash("4cadf60b6566fa2e38ae976e1f2762fdb210b285efec42e32c810a7f8796e874")},
+	}
+	for i, tt := range tests {
+		if have := hashGo(tt.in, tt.tcost); have != tt.want {
+			t.Errorf("test %d: hash mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
+
+func BenchmarkHashGo(b *testing.B) {
+	in := make([]byte, 512)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hashGo(in, 1)
+	}
+}
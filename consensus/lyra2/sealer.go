@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"math/rand"
@@ -19,6 +20,7 @@ import (
 	"github.com/yuriy0803/core-geth1/common/hexutil"
 	"github.com/yuriy0803/core-geth1/consensus"
 	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/event"
 	"github.com/yuriy0803/core-geth1/rlp"
 )
 
@@ -30,6 +32,7 @@ const (
 var (
 	errNoMiningWork      = errors.New("no mining work available yet")
 	errInvalidSealResult = errors.New("invalid or stale proof-of-work solution")
+	errLyra2Stopped      = errors.New("lyra2 stopped")
 )
 
 // Seal implements consensus.Engine, attempting to find a nonce that satisfies
@@ -213,6 +216,7 @@ type remoteSealer struct {
 	submitWorkCh chan *mineResult // Channel used for remote sealer to submit their mining result
 	fetchRateCh  chan chan uint64 // Channel used to gather submitted hash rate for local or remote sealer.
 	submitRateCh chan *hashrate   // Channel used for remote sealer to submit their mining hashrate
+	workFeed     event.Feed       // Feed of the work packages handed out to remote miners
 	requestExit  chan struct{}
 	exitCh       chan struct{}
 }
@@ -288,6 +292,7 @@ func (s *remoteSealer) loop() {
 			s.results = work.results
 			s.makeWork(work.block)
 			s.notifyWork()
+			s.workFeed.Send(s.currentWork)
 
 		case work := <-s.fetchWorkCh:
 			// Return current mining work to remote miner.
@@ -446,3 +451,97 @@ func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash,
 	s.lyra2.log.Warn("Work submitted is too old", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
 	return false
 }
+
+// GetWork returns the work package handed out to remote miners, see API.GetWork.
+func (lyra2 *Lyra2) GetWork() ([4]string, error) {
+	if lyra2.remote == nil {
+		return [4]string{}, errors.New("not supported")
+	}
+
+	var (
+		workCh = make(chan [4]string, 1)
+		errc   = make(chan error, 1)
+	)
+	select {
+	case lyra2.remote.fetchWorkCh <- &sealWork{errc: errc, res: workCh}:
+	case <-lyra2.remote.exitCh:
+		return [4]string{}, errLyra2Stopped
+	}
+	select {
+	case work := <-workCh:
+		return work, nil
+	case err := <-errc:
+		return [4]string{}, err
+	}
+}
+
+// SubmitWork submits the PoW solution of a remote miner, returning whether it
+// was accepted.
+func (lyra2 *Lyra2) SubmitWork(nonce types.BlockNonce, hash, digest common.Hash) bool {
+	if lyra2.remote == nil {
+		return false
+	}
+
+	var errc = make(chan error, 1)
+	select {
+	case lyra2.remote.submitWorkCh <- &mineResult{
+		nonce:     nonce,
+		mixDigest: digest,
+		hash:      hash,
+		errc:      errc,
+	}:
+	case <-lyra2.remote.exitCh:
+		return false
+	}
+	err := <-errc
+	return err == nil
+}
+
+// SubmitHashrate submits the hash rate of a remote miner, identified by an id
+// unique between miners.
+func (lyra2 *Lyra2) SubmitHashrate(rate hexutil.Uint64, id common.Hash) bool {
+	if lyra2.remote == nil {
+		return false
+	}
+
+	var done = make(chan struct{}, 1)
+	select {
+	case lyra2.remote.submitRateCh <- &hashrate{done: done, rate: uint64(rate), id: id}:
+	case <-lyra2.remote.exitCh:
+		return false
+	}
+
+	// Block until hash rate submitted successfully.
+	<-done
+	return true
+}
+
+// SubscribeWork subscribes to the work packages handed out to remote miners,
+// in the format returned by GetWork. The subscriber must keep up with the new
+// work, or it blocks the remote sealer.
+func (lyra2 *Lyra2) SubscribeWork(ch chan<- [4]string) event.Subscription {
+	if lyra2.remote == nil {
+		return event.NewSubscription(func(quit <-chan struct{}) error {
+			<-quit
+			return nil
+		})
+	}
+	return lyra2.remote.workFeed.Subscribe(ch)
+}
+
+// ComputeWork computes the PoW of the nonce for the given work package, as
+// returned by GetWork. Lyra2 has no mix digest, only the result to compare
+// against the target is returned, which lets remote mining servers validate the
+// shares of their miners.
+func (lyra2 *Lyra2) ComputeWork(work [4]string, nonce types.BlockNonce) (common.Hash, common.Hash, error) {
+	// If we're running a fake PoW, any nonce is a solution
+	if lyra2.fakeMode {
+		return common.Hash{}, common.Hash{}, nil
+	}
+	headerBytes, err := hex.DecodeString(work[1])
+	if err != nil || len(headerBytes) < 8 {
+		return common.Hash{}, common.Hash{}, fmt.Errorf("invalid work header %q", work[1])
+	}
+	result := lyra2.calcHash(headerBytes, nonce.Uint64(), 1)
+	return common.Hash{}, common.BigToHash(result), nil
+}
@@ -50,6 +50,7 @@ import (
 	"github.com/yuriy0803/core-geth1/params/types/genesisT"
 	"github.com/yuriy0803/core-geth1/rlp"
 	"github.com/yuriy0803/core-geth1/trie"
+	"github.com/yuriy0803/core-geth1/trie/triestate"
 	"golang.org/x/exp/slices"
 )
 
@@ -142,6 +143,9 @@ type CacheConfig struct {
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
+
+	HistoryExpiry uint64 // Block number below which frozen bodies and receipts are dropped (0 = keep all)
+	StateDiffs    bool   // Whether to record reverse state diffs for reconstructing pruned states
 }
 
 // defaultCacheConfig are the default caching values if none are specified by the
@@ -180,6 +184,8 @@ type BlockChain struct {
 	flushInterval atomic.Int64                     // Time interval (processing time) after which to flush a state
 	triedb        *trie.Database                   // The database handler for maintaining trie nodes.
 	stateCache    state.Database                   // State database to reuse between imports (contains state cache)
+	diffs         ethdb.AncientStore               // Freezer of the state diffs of the frozen blocks, nil if not recorded
+	diffsLock     sync.Mutex                       // Serializes the moves of state diffs into the freezer
 
 	// txLookupLimit is the maximum number of blocks from head whose tx indices
 	// are reserved:
@@ -187,6 +193,7 @@ type BlockChain struct {
 	//  * N:   means N block limit [HEAD-N+1, HEAD] and delete extra indexes
 	//  * nil: disable tx reindexer/deleter, but still index new blocks
 	txLookupLimit uint64
+	txIndexLock   sync.Mutex // Serializes the transaction (un)indexing with the history expiry
 
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
@@ -230,6 +237,10 @@ type BlockChain struct {
 
 	artificialFinalityNoDisable     *int32 // manual override prevents disabling artificial finality feature activation
 	artificialFinalityEnabledStatus int32  // toggles artificial finality features; will be always 1 if artificialFinalityForce=1
+
+	ecbp1100Feed      event.Feed          // feed of the ECBP1100 (MESS) reorg decisions
+	ecbp1100Decisions []*ECBP1100Decision // recent ECBP1100 (MESS) reorg decisions, oldest first
+	ecbp1100Lock      sync.Mutex          // protects ecbp1100Decisions
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -240,10 +251,16 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *genesis
 		cacheConfig = defaultCacheConfig
 	}
 	// Open trie database with provided config
-	triedb := trie.NewDatabaseWithConfig(db, &trie.Config{
+	trieConfig := &trie.Config{
 		Cache:     cacheConfig.TrieCleanLimit,
 		Preimages: cacheConfig.Preimages,
-	})
+	}
+	if cacheConfig.StateDiffs {
+		trieConfig.OnUpdate = func(root common.Hash, parent common.Hash, block uint64, states *triestate.Set) {
+			writeStateDiff(db, block, root, parent, states)
+		}
+	}
+	triedb := trie.NewDatabaseWithConfig(db, trieConfig)
 	// Setup the genesis block, commit the provided genesis specification
 	// to database if the genesis block is not present yet, or load the
 	// stored one from database.
@@ -293,6 +310,15 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *genesis
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
 	}
+	// Open the freezer of the state diffs if they are recorded, unless the
+	// database has no ancient store to freeze the blocks into.
+	if cacheConfig.StateDiffs {
+		if ancient, err := db.AncientDatadir(); err == nil && ancient != "" {
+			if bc.diffs, err = rawdb.NewStateDiffFreezer(ancient, false); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	bc.currentBlock.Store(nil)
 	bc.currentSnapBlock.Store(nil)
@@ -432,6 +458,16 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *genesis
 		bc.wg.Add(1)
 		go bc.maintainTxIndex()
 	}
+	// Start the history expiry if required.
+	if bc.cacheConfig.HistoryExpiry > 0 {
+		bc.wg.Add(1)
+		go bc.maintainHistory()
+	}
+	// Start freezing the state diffs along with the blocks if required.
+	if bc.diffs != nil {
+		bc.wg.Add(1)
+		go bc.maintainStateDiffs()
+	}
 	return bc, nil
 }
 
@@ -982,6 +1018,11 @@ func (bc *BlockChain) Stop() {
 	if err := bc.stateCache.TrieDB().Close(); err != nil {
 		log.Error("Failed to close trie db", "err", err)
 	}
+	if bc.diffs != nil {
+		if err := bc.diffs.Close(); err != nil {
+			log.Error("Failed to close state diff freezer", "err", err)
+		}
+	}
 	log.Info("Blockchain stopped")
 }
 
@@ -1375,8 +1416,9 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	// Find the next state trie we need to commit
 	chosen := current - TriesInMemory
 	flushInterval := time.Duration(bc.flushInterval.Load())
-	// If we exceeded time allowance, flush an entire trie to disk
-	if bc.gcproc > flushInterval {
+	// If we exceeded time allowance, or state diffs need a checkpoint to be
+	// reverted from, flush an entire trie to disk
+	if bc.gcproc > flushInterval || (bc.cacheConfig.StateDiffs && chosen >= bc.lastWrite+stateDiffCheckpoint) {
 		// If the header is missing (canonical chain behind), we're reorging a low
 		// diff sidechain. Suspend committing until this operation is completed.
 		header := bc.GetHeaderByNumber(chosen)
@@ -2376,10 +2418,15 @@ func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
 	return false
 }
 
-// indexBlocks reindexes or unindexes transactions depending on user configuration
+// indexBlocks reindexes or unindexes transactions depending on user configuration.
+// Blocks dropped by history expiry have no bodies, their transactions can be
+// neither indexed nor unindexed.
 func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{}) {
 	defer func() { close(done) }()
 
+	bc.txIndexLock.Lock()
+	defer bc.txIndexLock.Unlock()
+
 	// The tail flag is not existent, it means the node is just initialized
 	// and all blocks(may from ancient store) are not indexed yet.
 	if tail == nil {
@@ -2387,7 +2434,7 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 		if bc.txLookupLimit != 0 && head >= bc.txLookupLimit {
 			from = head - bc.txLookupLimit + 1
 		}
-		rawdb.IndexTransactions(bc.db, from, head+1, bc.quit)
+		rawdb.IndexTransactions(bc.db, bc.retainedFrom(from), head+1, bc.quit)
 		return
 	}
 	// The tail flag is existent, but the whole chain is required to be indexed.
@@ -2400,18 +2447,27 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 			if end > head+1 {
 				end = head + 1
 			}
-			rawdb.IndexTransactions(bc.db, 0, end, bc.quit)
+			rawdb.IndexTransactions(bc.db, bc.retainedFrom(0), end, bc.quit)
 		}
 		return
 	}
 	// Update the transaction index to the new chain state
 	if head-bc.txLookupLimit+1 < *tail {
 		// Reindex a part of missing indices and rewind index tail to HEAD-limit
-		rawdb.IndexTransactions(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit)
+		rawdb.IndexTransactions(bc.db, bc.retainedFrom(head-bc.txLookupLimit+1), *tail, bc.quit)
 	} else {
 		// Unindex a part of stale indices and forward index tail to HEAD-limit
-		rawdb.UnindexTransactions(bc.db, *tail, head-bc.txLookupLimit+1, bc.quit)
+		rawdb.UnindexTransactions(bc.db, bc.retainedFrom(*tail), head-bc.txLookupLimit+1, bc.quit)
+	}
+}
+
+// retainedFrom returns the given block number, or the first block whose body
+// is retained by history expiry if it is higher.
+func (bc *BlockChain) retainedFrom(number uint64) uint64 {
+	if tail := bc.HistoryTail(); tail > number {
+		return tail
 	}
+	return number
 }
 
 // maintainTxIndex is responsible for the construction and deletion of the
@@ -2457,6 +2513,60 @@ func (bc *BlockChain) maintainTxIndex() {
 	}
 }
 
+// maintainHistory is responsible for dropping the bodies and receipts of the
+// blocks below the configured history expiry from the ancient store, once they
+// are frozen. Headers are retained, so the chain can still be verified.
+func (bc *BlockChain) maintainHistory() {
+	defer bc.wg.Done()
+
+	headCh := make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	bc.expireHistory()
+	for {
+		select {
+		case <-headCh:
+			if bc.expireHistory() {
+				return
+			}
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// expireHistory drops the frozen bodies and receipts below the history expiry,
+// reporting whether all of them were dropped. The transaction lookups of the
+// dropped blocks are retained, so that the transactions are still known to be
+// below the history tail rather than unknown.
+func (bc *BlockChain) expireHistory() bool {
+	bc.txIndexLock.Lock()
+	defer bc.txIndexLock.Unlock()
+
+	frozen, err := bc.db.Ancients()
+	if err != nil {
+		// The database has no ancient store, nothing to drop.
+		return true
+	}
+	target := bc.cacheConfig.HistoryExpiry
+	if target > frozen {
+		target = frozen
+	}
+	if target > bc.HistoryTail() {
+		start := time.Now()
+		if _, err := bc.db.TruncateTail(target); err != nil {
+			log.Error("Failed to drop expired history", "tail", target, "err", err)
+			return true
+		}
+		log.Info("Dropped expired history", "tail", target, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return target == bc.cacheConfig.HistoryExpiry
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
@@ -9,13 +9,46 @@ import (
 	"time"
 
 	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
 	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/event"
 	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/metrics"
 )
 
 // errReorgFinality represents an error caused by artificial finality mechanisms.
 var errReorgFinality = errors.New("finality-enforced invalid new chain")
 
+// ecbp1100DecisionsLimit is the number of recent ECBP1100 (MESS) reorg decisions kept.
+const ecbp1100DecisionsLimit = 128
+
+var (
+	ecbp1100EnabledGauge     = metrics.NewRegisteredGauge("chain/ecbp1100/enabled", nil)
+	ecbp1100EvaluatedMeter   = metrics.NewRegisteredMeter("chain/ecbp1100/evaluated", nil)
+	ecbp1100AcceptedMeter    = metrics.NewRegisteredMeter("chain/ecbp1100/accepted", nil)
+	ecbp1100RejectedMeter    = metrics.NewRegisteredMeter("chain/ecbp1100/rejected", nil)
+	ecbp1100TDRatioGauge     = metrics.NewRegisteredGaugeFloat64("chain/ecbp1100/tdratio", nil)
+	ecbp1100AntiGravityGauge = metrics.NewRegisteredGaugeFloat64("chain/ecbp1100/antigravity", nil)
+)
+
+// ECBP1100Decision is the outcome of the ECBP1100 (MESS) evaluation of a reorg
+// from the current head to a proposed one. The reorg is accepted if the total
+// difficulty ratio of the proposed segment over the current one, since their
+// common ancestor, reaches the anti-gravity required for the current segment span.
+type ECBP1100Decision struct {
+	Time           hexutil.Uint64 `json:"time"`
+	CommonAncestor common.Hash    `json:"commonAncestor"`
+	CommonNumber   hexutil.Uint64 `json:"commonNumber"`
+	Current        common.Hash    `json:"current"`
+	CurrentNumber  hexutil.Uint64 `json:"currentNumber"`
+	Proposed       common.Hash    `json:"proposed"`
+	ProposedNumber hexutil.Uint64 `json:"proposedNumber"`
+	Span           hexutil.Uint64 `json:"span"` // Seconds from the common ancestor to the current head
+	TDRatio        float64        `json:"tdRatio"`
+	AntiGravity    float64        `json:"antiGravity"`
+	Accepted       bool           `json:"accepted"`
+}
+
 // ArtificialFinalityNoDisable overrides toggling of AF features, forcing it on.
 // n  = 1 : ON
 // n != 1 : OFF
@@ -68,9 +101,11 @@ func (bc *BlockChain) EnableArtificialFinality(enable bool, logValues ...interfa
 	if enable {
 		statusLog = "Enabled"
 		atomic.StoreInt32(&bc.artificialFinalityEnabledStatus, 1)
+		ecbp1100EnabledGauge.Update(1)
 	} else {
 		statusLog = "Disabled"
 		atomic.StoreInt32(&bc.artificialFinalityEnabledStatus, 0)
+		ecbp1100EnabledGauge.Update(0)
 	}
 	if !bc.chainConfig.IsEnabled(bc.chainConfig.GetECBP1100Transition, bc.CurrentHeader().Number) {
 		// Don't log anything if the config hasn't enabled it yet.
@@ -90,17 +125,125 @@ func (bc *BlockChain) IsArtificialFinalityEnabled() bool {
 	return atomic.LoadInt32(&bc.artificialFinalityEnabledStatus) == 1
 }
 
+// IsArtificialFinalityActive returns whether the artificial finality features
+// are both enabled and activated by the chain configuration at the current head.
+func (bc *BlockChain) IsArtificialFinalityActive() bool {
+	return bc.IsArtificialFinalityEnabled() &&
+		bc.chainConfig.IsEnabled(bc.chainConfig.GetECBP1100Transition, bc.CurrentHeader().Number)
+}
+
+// SubscribeECBP1100DecisionEvent registers a subscription of ECBP1100DecisionEvent.
+func (bc *BlockChain) SubscribeECBP1100DecisionEvent(ch chan<- ECBP1100DecisionEvent) event.Subscription {
+	return bc.scope.Track(bc.ecbp1100Feed.Subscribe(ch))
+}
+
+// ECBP1100Decisions returns the recent ECBP1100 (MESS) reorg decisions, oldest first.
+func (bc *BlockChain) ECBP1100Decisions() []*ECBP1100Decision {
+	bc.ecbp1100Lock.Lock()
+	defer bc.ecbp1100Lock.Unlock()
+
+	return append([]*ECBP1100Decision{}, bc.ecbp1100Decisions...)
+}
+
+// reportECBP1100 records the ECBP1100 (MESS) decision on the reorg from the
+// current head to the proposed one, updating the metrics and notifying the
+// subscribers.
+func (bc *BlockChain) reportECBP1100(commonAncestor, current, proposed *types.Header, accepted bool) {
+	decision := newECBP1100Decision(commonAncestor, current, proposed, bc.GetTd, accepted)
+
+	ecbp1100EvaluatedMeter.Mark(1)
+	if accepted {
+		ecbp1100AcceptedMeter.Mark(1)
+	} else {
+		ecbp1100RejectedMeter.Mark(1)
+	}
+	ecbp1100TDRatioGauge.Update(decision.TDRatio)
+	ecbp1100AntiGravityGauge.Update(decision.AntiGravity)
+
+	bc.ecbp1100Lock.Lock()
+	if len(bc.ecbp1100Decisions) == ecbp1100DecisionsLimit {
+		copy(bc.ecbp1100Decisions, bc.ecbp1100Decisions[1:])
+		bc.ecbp1100Decisions = bc.ecbp1100Decisions[:ecbp1100DecisionsLimit-1]
+	}
+	bc.ecbp1100Decisions = append(bc.ecbp1100Decisions, decision)
+	bc.ecbp1100Lock.Unlock()
+
+	bc.ecbp1100Feed.Send(ECBP1100DecisionEvent{Decision: decision})
+}
+
+// EvaluateECBP1100 evaluates, without touching any chain, the ECBP1100 (MESS)
+// artificial finality of the reorg from the current head to the proposed one,
+// given their common ancestor and a total difficulty lookup knowing all three
+// headers and the parent of the proposed one.
+func EvaluateECBP1100(commonAncestor, current, proposed *types.Header, getTD func(common.Hash, uint64) *big.Int) *ECBP1100Decision {
+	accepted := ecbp1100(commonAncestor, current, proposed, getTD) == nil
+	return newECBP1100Decision(commonAncestor, current, proposed, getTD, accepted)
+}
+
+// newECBP1100Decision assembles the ECBP1100 (MESS) decision on the reorg from
+// the current head to the proposed one.
+func newECBP1100Decision(commonAncestor, current, proposed *types.Header, getTD func(common.Hash, uint64) *big.Int, accepted bool) *ECBP1100Decision {
+	span := current.Time - commonAncestor.Time
+	return &ECBP1100Decision{
+		Time:           hexutil.Uint64(time.Now().Unix()),
+		CommonAncestor: commonAncestor.Hash(),
+		CommonNumber:   hexutil.Uint64(commonAncestor.Number.Uint64()),
+		Current:        current.Hash(),
+		CurrentNumber:  hexutil.Uint64(current.Number.Uint64()),
+		Proposed:       proposed.Hash(),
+		ProposedNumber: hexutil.Uint64(proposed.Number.Uint64()),
+		Span:           hexutil.Uint64(span),
+		TDRatio:        ecbp1100TDRatio(commonAncestor, current, proposed, getTD),
+		AntiGravity:    ECBP1100AntiGravity(span),
+		Accepted:       accepted,
+	}
+}
+
+// ECBP1100AntiGravity returns the minimum total difficulty ratio of a proposed
+// chain segment over the current one required by ECBP1100 (MESS), given the span
+// in seconds of the current segment since the common ancestor.
+func ECBP1100AntiGravity(span uint64) float64 {
+	antiGravity, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(ecbp1100PolynomialV(new(big.Int).SetUint64(span))),
+		new(big.Float).SetInt(ecbp1100PolynomialVCurveFunctionDenominator),
+	).Float64()
+	return antiGravity
+}
+
+// ECBP1100Accepts returns whether ECBP1100 (MESS) accepts a reorg given the span
+// in seconds of the current segment since the common ancestor, and the total
+// difficulties of the current and proposed segments since the common ancestor.
+func ECBP1100Accepts(span uint64, currentSubchainTD, proposedSubchainTD *big.Int) bool {
+	got, want := ecbp1100Weights(span, currentSubchainTD, proposedSubchainTD)
+	return got.Cmp(want) >= 0
+}
+
+// ecbp1100Weights returns the scaled total difficulty of the proposed segment
+// and the one it needs to reach to be accepted.
+func ecbp1100Weights(span uint64, localSubchainTD, proposedSubchainTD *big.Int) (got, want *big.Int) {
+	// if proposed_subchain_td * CURVE_FUNCTION_DENOMINATOR < get_curve_function_numerator(proposed.Time - commonAncestor.Time) * local_subchain_td.
+	eq := ecbp1100PolynomialV(new(big.Int).SetUint64(span))
+	want = eq.Mul(eq, localSubchainTD)
+	got = new(big.Int).Mul(proposedSubchainTD, ecbp1100PolynomialVCurveFunctionDenominator)
+	return got, want
+}
+
 // getTDRatio is a helper function returning the total difficulty ratio of
 // proposed over current chain segments.
-// nolint:unused
 func (bc *BlockChain) getTDRatio(commonAncestor, current, proposed *types.Header) float64 {
+	return ecbp1100TDRatio(commonAncestor, current, proposed, bc.GetTd)
+}
+
+// ecbp1100TDRatio returns the total difficulty ratio of proposed over current
+// chain segments, using the given total difficulty lookup.
+func ecbp1100TDRatio(commonAncestor, current, proposed *types.Header, getTD func(common.Hash, uint64) *big.Int) float64 {
 	// Get the total difficulty ratio of the proposed chain segment over the existing one.
-	commonAncestorTD := bc.GetTd(commonAncestor.Hash(), commonAncestor.Number.Uint64())
+	commonAncestorTD := getTD(commonAncestor.Hash(), commonAncestor.Number.Uint64())
 
-	proposedParentTD := bc.GetTd(proposed.ParentHash, proposed.Number.Uint64()-1)
+	proposedParentTD := getTD(proposed.ParentHash, proposed.Number.Uint64()-1)
 	proposedTD := new(big.Int).Add(proposed.Difficulty, proposedParentTD)
 
-	localTD := bc.GetTd(current.Hash(), current.Number.Uint64())
+	localTD := getTD(current.Hash(), current.Number.Uint64())
 
 	tdRatio, _ := new(big.Float).Quo(
 		new(big.Float).SetInt(new(big.Int).Sub(proposedTD, commonAncestorTD)),
@@ -119,15 +262,11 @@ func ecbp1100(commonAncestor, current, proposed *types.Header, getTDFunc func(co
 	proposedTD := new(big.Int).Add(proposed.Difficulty, proposedParentTD)
 	localTD := getTDFunc(current.Hash(), current.Number.Uint64())
 
-	// if proposed_subchain_td * CURVE_FUNCTION_DENOMINATOR < get_curve_function_numerator(proposed.Time - commonAncestor.Time) * local_subchain_td.
 	proposedSubchainTD := new(big.Int).Sub(proposedTD, commonAncestorTD)
 	localSubchainTD := new(big.Int).Sub(localTD, commonAncestorTD)
 
 	xBig := big.NewInt(int64(current.Time - commonAncestor.Time))
-	eq := ecbp1100PolynomialV(xBig)
-	want := eq.Mul(eq, localSubchainTD)
-
-	got := new(big.Int).Mul(proposedSubchainTD, ecbp1100PolynomialVCurveFunctionDenominator)
+	got, want := ecbp1100Weights(xBig.Uint64(), localSubchainTD, proposedSubchainTD)
 
 	if got.Cmp(want) < 0 {
 		prettyRatio, _ := new(big.Float).Quo(
@@ -245,6 +245,71 @@ func TestAFKnownBlock(t *testing.T) {
 	}
 }
 
+// TestECBP1100Decisions tests that the reorgs evaluated by ECBP1100 (MESS) are
+// recorded and announced to the subscribers, explaining their outcome.
+func TestECBP1100Decisions(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.EnableArtificialFinality(true)
+
+	decisions := make(chan ECBP1100DecisionEvent, 1024)
+	sub := chain.SubscribeECBP1100DecisionEvent(decisions)
+	defer sub.Unsubscribe()
+
+	easy, _ := GenerateChain(genesis.Config, genesisB, engine, db, 1000, func(i int, gen *BlockGen) {
+		gen.OffsetTime(0)
+	})
+	if _, err := chain.InsertChain(easy); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(chain.ECBP1100Decisions()); n != 0 {
+		t.Fatalf("decisions recorded without reorgs: have %d, want 0", n)
+	}
+	ancestor := easy[699]
+	hard, _ := GenerateChain(genesis.Config, ancestor, engine, db, 300, func(i int, gen *BlockGen) {
+		gen.OffsetTime(-7)
+	})
+	chain.InsertChain(hard)
+	if chain.CurrentBlock().Hash() != easy[len(easy)-1].Hash() {
+		t.Fatal("hard chain got chain head, should be rejected")
+	}
+
+	recorded := chain.ECBP1100Decisions()
+	if len(recorded) == 0 {
+		t.Fatal("no decisions recorded")
+	}
+	last := recorded[len(recorded)-1]
+	if last.Accepted {
+		t.Error("reorg accepted, want rejected")
+	}
+	if last.CommonAncestor != ancestor.Hash() || uint64(last.CommonNumber) != ancestor.NumberU64() {
+		t.Errorf("common ancestor mismatch: have %d %x, want %d %x", last.CommonNumber, last.CommonAncestor, ancestor.NumberU64(), ancestor.Hash())
+	}
+	if last.Current != easy[len(easy)-1].Hash() {
+		t.Errorf("current head mismatch: have %x, want %x", last.Current, easy[len(easy)-1].Hash())
+	}
+	if last.Span == 0 || last.AntiGravity <= 1 || last.TDRatio <= 1 || last.TDRatio >= last.AntiGravity {
+		t.Errorf("unexpected rejected decision: span %d, td ratio %f, anti-gravity %f", last.Span, last.TDRatio, last.AntiGravity)
+	}
+	if have := len(decisions); have != len(recorded) {
+		t.Fatalf("decision events mismatch: have %d, want %d", have, len(recorded))
+	}
+	for _, decision := range recorded {
+		if ev := <-decisions; ev.Decision != decision {
+			t.Errorf("decision event mismatch: have %v, want %v", ev.Decision, decision)
+		}
+	}
+}
+
 // TestEcbp1100PolynomialV tests the general shape and return values of the ECBP1100 polynomial curve.
 // It makes sure domain values above the 'cap' do indeed get limited, as well
 // as sanity check some normal domain values.
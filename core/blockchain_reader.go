@@ -376,6 +376,16 @@ func (bc *BlockChain) TxLookupLimit() uint64 {
 	return bc.txLookupLimit
 }
 
+// HistoryTail retrieves the number of the first block whose body and receipts
+// are retained, the ones below having been dropped by history expiry.
+func (bc *BlockChain) HistoryTail() uint64 {
+	tail, err := bc.db.Tail()
+	if err != nil {
+		return 0
+	}
+	return tail
+}
+
 // TrieDB retrieves the low level trie database used for data storage.
 func (bc *BlockChain) TrieDB() *trie.Database {
 	return bc.triedb
@@ -2678,6 +2678,76 @@ func TestTransactionIndices(t *testing.T) {
 	}
 }
 
+func TestHistoryExpiry(t *testing.T) {
+	// Configure and generate a sample block chain
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(100000000000000000)
+		gspec   = &genesisT.Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   genesisT.GenesisAlloc{address: {Balance: funds}},
+			BaseFee: big.NewInt(vars.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 128, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), vars.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	ancientDb, _ := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	defer ancientDb.Close()
+
+	genesisBlock := MustCommitGenesis(rawdb.NewMemoryDatabase(), gspec)
+	rawdb.WriteAncientBlocks(ancientDb, append([]*types.Block{genesisBlock}, blocks...), append([]types.Receipts{{}}, receipts...), big.NewInt(0))
+	rawdb.IndexTransactions(ancientDb, 0, 129, nil)
+
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.HistoryExpiry = 64
+	chain, err := NewBlockChain(ancientDb, &cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if !chain.expireHistory() {
+		t.Fatal("history expiry not completed")
+	}
+	if tail := chain.HistoryTail(); tail != 64 {
+		t.Fatalf("history tail mismatch: have %d, want 64", tail)
+	}
+	for _, block := range blocks {
+		number, hash := block.NumberU64(), block.Hash()
+		if chain.GetHeader(hash, number) == nil {
+			t.Fatalf("block %d: header missing", number)
+		}
+		pruned := number < 64
+		if body := rawdb.ReadBody(ancientDb, hash, number); (body == nil) != pruned {
+			t.Fatalf("block %d: body presence mismatch: have %v, want %v", number, body != nil, !pruned)
+		}
+		if receipts := rawdb.ReadRawReceipts(ancientDb, hash, number); (receipts == nil) != pruned {
+			t.Fatalf("block %d: receipts presence mismatch: have %v, want %v", number, receipts != nil, !pruned)
+		}
+		// The transactions of the dropped blocks remain indexed.
+		for _, tx := range block.Transactions() {
+			if entry := rawdb.ReadTxLookupEntry(ancientDb, tx.Hash()); entry == nil || *entry != number {
+				t.Fatalf("block %d: lookup mismatch: have %v", number, entry)
+			}
+		}
+	}
+	if tail := rawdb.ReadTxIndexTail(ancientDb); tail == nil || *tail != 0 {
+		t.Fatalf("transaction index tail mismatch after expiry: have %v, want 0", tail)
+	}
+	// Transactions can only be indexed for the retained blocks.
+	chain.indexBlocks(nil, 128, make(chan struct{}))
+	if tail := rawdb.ReadTxIndexTail(ancientDb); tail == nil || *tail != 64 {
+		t.Fatalf("transaction index tail mismatch: have %v, want 64", tail)
+	}
+}
+
 func TestSkipStaleTxIndicesInSnapSync(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
@@ -0,0 +1,370 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/trie"
+)
+
+// Kinds of the inconsistencies reported by CheckDatabase.
+const (
+	CheckMissingCanonicalHash = "missing-canonical-hash" // No canonical hash for a block number
+	CheckMissingHeader        = "missing-header"         // No header for a canonical hash
+	CheckHeaderMismatch       = "header-mismatch"        // Header not hashing to its canonical hash
+	CheckMissingHeaderNumber  = "missing-header-number"  // No hash to number mapping for a header
+	CheckParentMismatch       = "parent-mismatch"        // Header not linked to the previous canonical block
+	CheckMissingTd            = "missing-td"             // No total difficulty for a header
+	CheckMissingBody          = "missing-body"           // No body for a header
+	CheckBodyMismatch         = "body-mismatch"          // Body not matching the roots of its header
+	CheckMissingReceipts      = "missing-receipts"       // No receipts for a body
+	CheckReceiptsMismatch     = "receipts-mismatch"      // Receipts not matching the root of their header
+	CheckMissingTxLookup      = "missing-tx-lookup"      // Indexed transaction without lookup entry
+	CheckFreezerAhead         = "freezer-ahead"          // Ancient store holding blocks beyond the head
+	CheckFreezerGap           = "freezer-gap"            // Key-value store not continuing the ancient store
+	CheckMissingState         = "missing-state"          // No state of the recent blocks
+	CheckSnapshotMismatch     = "snapshot-mismatch"      // Snapshot not matching the state of the recent blocks
+)
+
+// checkStateWindow is the number of recent blocks among which the persisted
+// state and snapshot roots are looked up.
+const checkStateWindow = 2 * TriesInMemory
+
+// CheckIssue is an inconsistency found in the database.
+type CheckIssue struct {
+	Kind     string      `json:"kind"`
+	Number   uint64      `json:"number"`
+	Hash     common.Hash `json:"hash"`
+	Detail   string      `json:"detail"`
+	Repaired bool        `json:"repaired"`
+}
+
+func (issue *CheckIssue) String() string {
+	return fmt.Sprintf("%s at block %d (%x): %s", issue.Kind, issue.Number, issue.Hash, issue.Detail)
+}
+
+// CheckConfig configures a database check.
+type CheckConfig struct {
+	Start     uint64            // First block to check
+	Count     uint64            // Number of blocks to check, 0 for all up to the head
+	MaxIssues int               // Number of issues after which to stop, 0 for no limit
+	Repair    bool              // Whether to rebuild the inconsistent data which can be derived
+	OnIssue   func(*CheckIssue) // Callback invoked for every issue as it is found
+}
+
+// CheckResult is the outcome of a database check. A check which was limited
+// or interrupted can be resumed from the next block.
+type CheckResult struct {
+	Start   uint64        `json:"start"`   // First checked block
+	Next    uint64        `json:"next"`    // Block to resume the check from
+	Head    uint64        `json:"head"`    // Head block of the database
+	Frozen  uint64        `json:"frozen"`  // Number of blocks in the ancient store
+	Checked uint64        `json:"checked"` // Number of checked blocks
+	Done    bool          `json:"done"`    // Whether all blocks up to the head were checked
+	Issues  []*CheckIssue `json:"issues"`
+}
+
+// CheckDatabase checks the consistency of the chain data stored in the database:
+// the canonical hashes, headers, bodies, receipts and transaction lookups of the
+// blocks, the continuity of the key-value store after the ancient store and,
+// once the head is reached, the agreement of the snapshot with the persisted
+// state. With repair enabled, the inconsistent data which can be derived from
+// the rest of the database is rewritten, which is only safe on a database no
+// running chain is writing to.
+func CheckDatabase(db ethdb.Database, config CheckConfig, interrupt <-chan struct{}) (*CheckResult, error) {
+	headHash := rawdb.ReadHeadBlockHash(db)
+	headNumber := rawdb.ReadHeaderNumber(db, headHash)
+	if headNumber == nil {
+		return nil, errors.New("head block missing")
+	}
+	c := &checker{
+		db:     db,
+		config: config,
+		result: &CheckResult{Start: config.Start, Next: config.Start, Head: *headNumber, Issues: []*CheckIssue{}},
+		txTail: rawdb.ReadTxIndexTail(db),
+	}
+	// Databases without an ancient store have neither frozen nor dropped blocks.
+	if frozen, err := db.Ancients(); err == nil {
+		c.result.Frozen = frozen
+	}
+	if tail, err := db.Tail(); err == nil {
+		c.historyTail = tail
+	}
+	if config.Start > c.result.Head {
+		return nil, fmt.Errorf("start block %d beyond head %d", config.Start, c.result.Head)
+	}
+	if config.Start == 0 {
+		c.checkFreezerBoundary()
+	}
+	end := c.result.Head
+	if config.Count > 0 && config.Start+config.Count-1 < end {
+		end = config.Start + config.Count - 1
+	}
+	var (
+		parent common.Hash
+		start  = time.Now()
+		logged = time.Now()
+	)
+	if config.Start > 0 {
+		parent = rawdb.ReadCanonicalHash(db, config.Start-1)
+	}
+	for number := config.Start; number <= end; number++ {
+		select {
+		case <-interrupt:
+			return c.result, nil
+		default:
+		}
+		if c.limitReached() {
+			return c.result, nil
+		}
+		parent = c.checkBlock(number, parent)
+		c.result.Next, c.result.Checked = number+1, c.result.Checked+1
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Checking database", "number", number, "last", end, "issues", len(c.result.Issues), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if end == c.result.Head {
+		c.checkState()
+		c.result.Done = true
+	}
+	return c.result, nil
+}
+
+// checker holds the state of a database check.
+type checker struct {
+	db          ethdb.Database
+	config      CheckConfig
+	result      *CheckResult
+	historyTail uint64  // First block whose body and receipts are retained
+	txTail      *uint64 // First block whose transactions are indexed, nil if none
+}
+
+// report records an issue.
+func (c *checker) report(issue *CheckIssue) {
+	c.result.Issues = append(c.result.Issues, issue)
+	if c.config.OnIssue != nil {
+		c.config.OnIssue(issue)
+	}
+}
+
+// limitReached reports whether the maximum number of issues was found.
+func (c *checker) limitReached() bool {
+	return c.config.MaxIssues > 0 && len(c.result.Issues) >= c.config.MaxIssues
+}
+
+// checkFreezerBoundary checks the key-value store continues the chain where
+// the ancient store ends.
+func (c *checker) checkFreezerBoundary() {
+	frozen, head := c.result.Frozen, c.result.Head
+	if frozen == 0 {
+		return
+	}
+	if frozen-1 > head {
+		c.report(&CheckIssue{
+			Kind:   CheckFreezerAhead,
+			Number: frozen - 1,
+			Hash:   rawdb.ReadCanonicalHash(c.db, frozen-1),
+			Detail: fmt.Sprintf("ancient store holds blocks up to %d beyond the head %d", frozen-1, head),
+		})
+		return
+	}
+	if frozen > head {
+		return
+	}
+	hash := rawdb.ReadCanonicalHash(c.db, frozen)
+	header := rawdb.ReadHeader(c.db, hash, frozen)
+	switch {
+	case header == nil:
+		c.report(&CheckIssue{Kind: CheckFreezerGap, Number: frozen, Hash: hash, Detail: "first block after the ancient store missing"})
+	case header.ParentHash != rawdb.ReadCanonicalHash(c.db, frozen-1):
+		c.report(&CheckIssue{Kind: CheckFreezerGap, Number: frozen, Hash: hash, Detail: "first block after the ancient store not linked to the last frozen block"})
+	}
+}
+
+// checkBlock checks the data of a canonical block, returning its hash.
+func (c *checker) checkBlock(number uint64, parent common.Hash) common.Hash {
+	hash := rawdb.ReadCanonicalHash(c.db, number)
+	if hash == (common.Hash{}) {
+		issue := &CheckIssue{Kind: CheckMissingCanonicalHash, Number: number, Detail: "canonical hash missing"}
+		if c.config.Repair {
+			hash = c.repairCanonicalHash(issue, number, parent)
+		}
+		c.report(issue)
+		if hash == (common.Hash{}) {
+			return hash
+		}
+	}
+	header := rawdb.ReadHeader(c.db, hash, number)
+	if header == nil {
+		c.report(&CheckIssue{Kind: CheckMissingHeader, Number: number, Hash: hash, Detail: "header missing"})
+		return hash
+	}
+	if have := header.Hash(); have != hash {
+		c.report(&CheckIssue{Kind: CheckHeaderMismatch, Number: number, Hash: hash, Detail: fmt.Sprintf("header hashes to %x", have)})
+	}
+	if number > 0 && parent != (common.Hash{}) && header.ParentHash != parent {
+		c.report(&CheckIssue{Kind: CheckParentMismatch, Number: number, Hash: hash, Detail: fmt.Sprintf("parent hash %x, canonical parent %x", header.ParentHash, parent)})
+	}
+	if have := rawdb.ReadHeaderNumber(c.db, hash); have == nil || *have != number {
+		issue := &CheckIssue{Kind: CheckMissingHeaderNumber, Number: number, Hash: hash, Detail: "hash to number mapping missing"}
+		if c.config.Repair {
+			rawdb.WriteHeaderNumber(c.db, hash, number)
+			issue.Repaired = true
+		}
+		c.report(issue)
+	}
+	if rawdb.ReadTd(c.db, hash, number) == nil {
+		c.report(&CheckIssue{Kind: CheckMissingTd, Number: number, Hash: hash, Detail: "total difficulty missing"})
+	}
+	// Bodies and receipts below the history tail were dropped on purpose.
+	if number < c.historyTail {
+		return hash
+	}
+	body := rawdb.ReadBody(c.db, hash, number)
+	if body == nil {
+		c.report(&CheckIssue{Kind: CheckMissingBody, Number: number, Hash: hash, Detail: "body missing"})
+		return hash
+	}
+	if root := types.DeriveSha(types.Transactions(body.Transactions), trie.NewStackTrie(nil)); root != header.TxHash {
+		c.report(&CheckIssue{Kind: CheckBodyMismatch, Number: number, Hash: hash, Detail: fmt.Sprintf("transaction root %x, header %x", root, header.TxHash)})
+	}
+	if root := types.CalcUncleHash(body.Uncles); root != header.UncleHash {
+		c.report(&CheckIssue{Kind: CheckBodyMismatch, Number: number, Hash: hash, Detail: fmt.Sprintf("uncle hash %x, header %x", root, header.UncleHash)})
+	}
+	c.checkReceipts(header, body)
+	c.checkTxLookups(number, hash, body)
+	return hash
+}
+
+// checkReceipts checks the receipts of a block match its header.
+func (c *checker) checkReceipts(header *types.Header, body *types.Body) {
+	number, hash := header.Number.Uint64(), header.Hash()
+	receipts := rawdb.ReadRawReceipts(c.db, hash, number)
+	if receipts == nil {
+		c.report(&CheckIssue{Kind: CheckMissingReceipts, Number: number, Hash: hash, Detail: "receipts missing"})
+		return
+	}
+	if len(receipts) != len(body.Transactions) {
+		c.report(&CheckIssue{Kind: CheckReceiptsMismatch, Number: number, Hash: hash, Detail: fmt.Sprintf("%d receipts for %d transactions", len(receipts), len(body.Transactions))})
+		return
+	}
+	// The stored receipts lack the fields of their consensus encoding derived
+	// from the block, restore them to compute the root.
+	for i, receipt := range receipts {
+		receipt.Type = body.Transactions[i].Type()
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != header.ReceiptHash {
+		c.report(&CheckIssue{Kind: CheckReceiptsMismatch, Number: number, Hash: hash, Detail: fmt.Sprintf("receipt root %x, header %x", root, header.ReceiptHash)})
+	}
+}
+
+// checkTxLookups checks the transactions of an indexed block have lookup
+// entries pointing to it.
+func (c *checker) checkTxLookups(number uint64, hash common.Hash, body *types.Body) {
+	if c.txTail == nil || number < *c.txTail {
+		return
+	}
+	var missing int
+	for _, tx := range body.Transactions {
+		if have := rawdb.ReadTxLookupEntry(c.db, tx.Hash()); have == nil || *have != number {
+			missing++
+		}
+	}
+	if missing == 0 {
+		return
+	}
+	issue := &CheckIssue{Kind: CheckMissingTxLookup, Number: number, Hash: hash, Detail: fmt.Sprintf("%d of %d transactions not indexed", missing, len(body.Transactions))}
+	if c.config.Repair {
+		hashes := make([]common.Hash, len(body.Transactions))
+		for i, tx := range body.Transactions {
+			hashes[i] = tx.Hash()
+		}
+		rawdb.WriteTxLookupEntries(c.db, number, hashes)
+		issue.Repaired = true
+	}
+	c.report(issue)
+}
+
+// repairCanonicalHash marks the only stored header of the given number linked
+// to the canonical parent as canonical, returning its hash.
+func (c *checker) repairCanonicalHash(issue *CheckIssue, number uint64, parent common.Hash) common.Hash {
+	if parent == (common.Hash{}) || number < c.result.Frozen {
+		return common.Hash{}
+	}
+	var found []common.Hash
+	for _, hash := range rawdb.ReadAllHashes(c.db, number) {
+		if header := rawdb.ReadHeader(c.db, hash, number); header != nil && header.ParentHash == parent {
+			found = append(found, hash)
+		}
+	}
+	if len(found) != 1 {
+		return common.Hash{}
+	}
+	rawdb.WriteCanonicalHash(c.db, found[0], number)
+	issue.Hash, issue.Repaired = found[0], true
+	return found[0]
+}
+
+// checkState checks the state of a recent block is persisted, and the snapshot
+// is the state of a recent block.
+func (c *checker) checkState() {
+	var (
+		head        = c.result.Head
+		headHash    = rawdb.ReadCanonicalHash(c.db, head)
+		_, pathRoot = rawdb.ReadAccountTrieNode(c.db, nil)
+		roots       = make(map[common.Hash]bool)
+		persisted   bool
+	)
+	for i := uint64(0); i < checkStateWindow && i <= head; i++ {
+		header := rawdb.ReadHeader(c.db, rawdb.ReadCanonicalHash(c.db, head-i), head-i)
+		if header == nil {
+			continue
+		}
+		roots[header.Root] = true
+		if header.Root == types.EmptyRootHash || header.Root == pathRoot || rawdb.HasLegacyTrieNode(c.db, header.Root) {
+			persisted = true
+		}
+	}
+	if !persisted {
+		c.report(&CheckIssue{Kind: CheckMissingState, Number: head, Hash: headHash, Detail: fmt.Sprintf("no state of the last %d blocks persisted", checkStateWindow)})
+	}
+	if rawdb.ReadSnapshotDisabled(c.db) {
+		return
+	}
+	root := rawdb.ReadSnapshotRoot(c.db)
+	if root == (common.Hash{}) || roots[root] {
+		return
+	}
+	issue := &CheckIssue{Kind: CheckSnapshotMismatch, Number: head, Hash: headHash, Detail: fmt.Sprintf("snapshot root %x is not the state of the last %d blocks", root, checkStateWindow)}
+	if c.config.Repair {
+		// Without a root, the snapshot is regenerated on the next start.
+		rawdb.DeleteSnapshotRoot(c.db)
+		issue.Repaired = true
+	}
+	c.report(issue)
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/consensus/ethash"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/core/vm"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/params"
+	"github.com/yuriy0803/core-geth1/params/types/genesisT"
+	"github.com/yuriy0803/core-geth1/params/vars"
+)
+
+func TestCheckDatabase(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &genesisT.Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   genesisT.GenesisAlloc{address: {Balance: big.NewInt(100000000000000000)}},
+			BaseFee: big.NewInt(vars.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+		db     = rawdb.NewMemoryDatabase()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 64, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), vars.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop()
+	rawdb.WriteTxIndexTail(db, 0)
+
+	check := func(config CheckConfig, want ...string) *CheckResult {
+		t.Helper()
+		result, err := CheckDatabase(db, config, nil)
+		if err != nil {
+			t.Fatalf("check failed: %v", err)
+		}
+		if len(result.Issues) != len(want) {
+			t.Fatalf("wrong issues: have %v, want %v", result.Issues, want)
+		}
+		for i, issue := range result.Issues {
+			if issue.Kind != want[i] {
+				t.Fatalf("wrong issue %d: have %v, want %s", i, issue, want[i])
+			}
+			if issue.Repaired != config.Repair && issue.Kind != CheckMissingReceipts {
+				t.Fatalf("issue %v: wrong repair status", issue)
+			}
+		}
+		return result
+	}
+	if result := check(CheckConfig{}); !result.Done || result.Checked != 65 || result.Next != 65 {
+		t.Fatalf("wrong result: %+v", result)
+	}
+	// Checks can be resumed.
+	if result := check(CheckConfig{Count: 32}); result.Done || result.Next != 32 {
+		t.Fatalf("wrong limited result: %+v", result)
+	}
+	if result := check(CheckConfig{Start: 32}); !result.Done || result.Checked != 33 {
+		t.Fatalf("wrong resumed result: %+v", result)
+	}
+
+	// Break the derived data, which can be repaired.
+	rawdb.DeleteTxLookupEntry(db, blocks[4].Transactions()[0].Hash())
+	rawdb.DeleteCanonicalHash(db, 10)
+	check(CheckConfig{}, CheckMissingTxLookup, CheckMissingCanonicalHash)
+	check(CheckConfig{Repair: true}, CheckMissingTxLookup, CheckMissingCanonicalHash)
+	check(CheckConfig{})
+
+	// Break the block data, which can't.
+	rawdb.DeleteReceipts(db, blocks[19].Hash(), 20)
+	check(CheckConfig{Repair: true}, CheckMissingReceipts)
+	if result := check(CheckConfig{MaxIssues: 1}, CheckMissingReceipts); result.Done || result.Next != 21 {
+		t.Fatalf("wrong result after the issue limit: %+v", result)
+	}
+}
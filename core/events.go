@@ -41,3 +41,7 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ECBP1100DecisionEvent is posted when ECBP1100 (MESS) artificial finality
+// evaluated a reorg.
+type ECBP1100DecisionEvent struct{ Decision *ECBP1100Decision }
@@ -171,7 +171,11 @@ func (f *ForkChoice) ReorgNeeded(current *types.Header, extern *types.Header) (b
 		return reorg, err
 	}
 
-	if err := ecbp1100(commonHeader, current, extern, f.chain.GetTd); err != nil {
+	err = ecbp1100(commonHeader, current, extern, f.chain.GetTd)
+	if bc, ok := f.chain.(*BlockChain); ok && commonHeader.Hash() != current.Hash() {
+		bc.reportECBP1100(commonHeader, current, extern, err == nil)
+	}
+	if err != nil {
 		reorg = false
 		log.Warn("Reorg disallowed", "error", err)
 	} else if current.Number.Uint64()-commonHeader.Number.Uint64() > 2 {
@@ -0,0 +1,161 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/params/confp"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+)
+
+// Fork is a fork of the local chain configuration.
+type Fork struct {
+	Number      uint64        `json:"number"`         // Block number or timestamp of the fork
+	Time        bool          `json:"time"`           // Whether the fork is activated by timestamp
+	Hash        hexutil.Bytes `json:"hash,omitempty"` // Fork checksum once the fork is passed
+	Transitions []string      `json:"transitions"`    // Configuration transitions activated by the fork
+}
+
+// Report explains how a remote fork ID relates to the local chain configuration.
+type Report struct {
+	Hash      hexutil.Bytes `json:"hash"`            // Remote fork checksum
+	Next      uint64        `json:"next"`            // Remote next fork
+	LocalHash hexutil.Bytes `json:"localHash"`       // Local fork checksum at the head
+	LocalNext uint64        `json:"localNext"`       // Local next fork at the head
+	Accepted  bool          `json:"accepted"`        // Whether the local filter accepts the remote ID
+	Error     string        `json:"error,omitempty"` // Reason of the local filter to reject the remote ID
+	Matched   bool          `json:"matched"`         // Whether the remote checksum matches the local forks
+	Passed    []Fork        `json:"passed"`          // Local forks included in the remote checksum
+	Split     *Fork         `json:"split,omitempty"` // Fork at which the chains split, if known
+	Reason    string        `json:"reason"`          // Human readable explanation
+}
+
+// ParseID parses a fork ID in the <hash>[:<next>] form, e.g. 0xbe46d57c:13189133.
+func ParseID(s string) (ID, error) {
+	var id ID
+	hash, next, hasNext := strings.Cut(strings.TrimSpace(s), ":")
+	b, err := hexutil.Decode(hash)
+	if err != nil || len(b) != len(id.Hash) {
+		return id, fmt.Errorf("invalid fork hash %q", hash)
+	}
+	copy(id.Hash[:], b)
+	if hasNext {
+		if id.Next, err = strconv.ParseUint(next, 0, 64); err != nil {
+			return id, fmt.Errorf("invalid fork next %q", next)
+		}
+	}
+	return id, nil
+}
+
+// NewReport creates a report explaining which local fork checksum the remote ID
+// matches and which fork splits the chains, as seen by a local node at the given head.
+func NewReport(config ctypes.ChainConfigurator, genesis common.Hash, head, time uint64, id ID) *Report {
+	local := NewID(config, genesis, head, time)
+	r := &Report{
+		Hash:      id.Hash[:],
+		Next:      id.Next,
+		LocalHash: local.Hash[:],
+		LocalNext: local.Next,
+		Accepted:  true,
+		Passed:    []Fork{},
+	}
+	if err := newFilter(config, genesis, func() (uint64, uint64) { return head, time })(id); err != nil {
+		r.Accepted, r.Error = false, err.Error()
+	}
+
+	forks := reportForks(config, genesis)
+
+	// Check whether the remote checksum matches the local forks passed up to some point.
+	for i := 0; i <= len(forks); i++ {
+		sum := checksumToBytes(crc32.ChecksumIEEE(genesis[:]))
+		if i > 0 {
+			copy(sum[:], forks[i-1].Hash)
+		}
+		if sum != id.Hash {
+			continue
+		}
+		r.Matched = true
+		r.Passed = forks[:i]
+
+		var next *Fork
+		if i < len(forks) {
+			next = &forks[i]
+		}
+		switch {
+		case next == nil && id.Next == 0:
+			r.Reason = "remote is on the same fork, no further forks are known"
+		case next != nil && next.Number == id.Next:
+			r.Reason = fmt.Sprintf("remote is on the same fork, next fork at %d", id.Next)
+		case next != nil && (id.Next == 0 || next.Number < id.Next):
+			r.Split = next
+			r.Reason = fmt.Sprintf("remote does not schedule local fork at %d (%s)", next.Number, strings.Join(next.Transitions, ", "))
+		default:
+			r.Split = &Fork{Number: id.Next, Time: id.Next > timestampThreshold, Transitions: []string{}}
+			r.Reason = fmt.Sprintf("remote schedules fork at %d, unknown locally", id.Next)
+		}
+		return r
+	}
+
+	// Check whether the remote checksum matches the local forks with a single fork missing.
+	for skip := range forks {
+		hash := crc32.ChecksumIEEE(genesis[:])
+		for i, fork := range forks {
+			if i == skip {
+				continue
+			}
+			hash = checksumUpdate(hash, fork.Number)
+			if i < skip || checksumToBytes(hash) != id.Hash {
+				continue
+			}
+			r.Passed = append(append([]Fork{}, forks[:skip]...), forks[skip+1:i+1]...)
+			r.Split = &forks[skip]
+			r.Reason = fmt.Sprintf("remote has not activated local fork at %d (%s)", forks[skip].Number, strings.Join(forks[skip].Transitions, ", "))
+			return r
+		}
+	}
+	r.Reason = "remote checksum matches no local fork checksum, the genesis or a fork block differs"
+	return r
+}
+
+// reportForks returns the local forks, with the transitions activated by each
+// and the fork checksums.
+func reportForks(config ctypes.ChainConfigurator, genesis common.Hash) []Fork {
+	var (
+		forksByBlock, forksByTime = gatherForks(config)
+		byBlock, byTime           = confp.ForkTransitions(config)
+		forks                     []Fork
+	)
+	for _, n := range forksByBlock {
+		forks = append(forks, Fork{Number: n, Transitions: byBlock[n]})
+	}
+	for _, n := range forksByTime {
+		forks = append(forks, Fork{Number: n, Time: true, Transitions: byTime[n]})
+	}
+	hash := crc32.ChecksumIEEE(genesis[:])
+	for i := range forks {
+		hash = checksumUpdate(hash, forks[i].Number)
+		sum := checksumToBytes(hash)
+		forks[i].Hash = sum[:]
+	}
+	return forks
+}
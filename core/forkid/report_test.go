@@ -0,0 +1,114 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"hash/crc32"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/params"
+)
+
+func TestNewReport(t *testing.T) {
+	// Checksum of the Classic forks up to Mystique, without Thanos.
+	forks, _ := gatherForks(params.ClassicChainConfig)
+	withoutThanos := crc32.ChecksumIEEE(params.MainnetGenesisHash[:])
+	for _, fork := range forks {
+		if fork == 11_700_000 {
+			continue
+		}
+		withoutThanos = checksumUpdate(withoutThanos, fork)
+		if fork == 14_525_000 {
+			break
+		}
+	}
+
+	tests := []struct {
+		id       ID
+		accepted bool
+		matched  bool
+		passed   int
+		split    uint64
+	}{
+		// Remote is on the same fork as the local node.
+		{id: ID{Hash: checksumToBytes(0x7fd1bb25), Next: 19_250_000}, accepted: true, matched: true, passed: 11},
+		// Remote does not schedule Spiral.
+		{id: ID{Hash: checksumToBytes(0x7fd1bb25), Next: 0}, accepted: true, matched: true, passed: 11, split: 19_250_000},
+		// Remote schedules an unknown fork.
+		{id: ID{Hash: checksumToBytes(0x7fd1bb25), Next: 20_000_000}, accepted: true, matched: true, passed: 11, split: 19_250_000},
+		// Remote is syncing, and is not aware of Spiral.
+		{id: ID{Hash: checksumToBytes(0x9007bfcc), Next: 11_700_000}, accepted: true, matched: true, passed: 8},
+		// Remote did not activate Thanos.
+		{id: ID{Hash: checksumToBytes(withoutThanos), Next: 19_250_000}, accepted: false, passed: 10, split: 11_700_000},
+		// Remote is on another chain.
+		{id: ID{Hash: checksumToBytes(0xdeadbeef), Next: 0}, accepted: false},
+	}
+	for i, tt := range tests {
+		r := NewReport(params.ClassicChainConfig, params.MainnetGenesisHash, 15_000_000, 0, tt.id)
+		if r.Accepted != tt.accepted {
+			t.Errorf("test %d: accepted mismatch: have %v, want %v (%s)", i, r.Accepted, tt.accepted, r.Error)
+		}
+		if r.Matched != tt.matched {
+			t.Errorf("test %d: matched mismatch: have %v, want %v", i, r.Matched, tt.matched)
+		}
+		if len(r.Passed) != tt.passed {
+			t.Errorf("test %d: passed forks mismatch: have %d, want %d", i, len(r.Passed), tt.passed)
+		}
+		switch {
+		case tt.split == 0 && r.Split != nil:
+			t.Errorf("test %d: unexpected split at %d: %s", i, r.Split.Number, r.Reason)
+		case tt.split != 0 && (r.Split == nil || r.Split.Number != tt.split):
+			t.Errorf("test %d: split mismatch: have %v, want %d (%s)", i, r.Split, tt.split, r.Reason)
+		}
+	}
+}
+
+func TestNewReportTransitions(t *testing.T) {
+	r := NewReport(params.ClassicChainConfig, params.MainnetGenesisHash, 0, 0, ID{Hash: checksumToBytes(0xfc64ec04), Next: 0})
+	if r.Split == nil || r.Split.Number != 1_150_000 {
+		t.Fatalf("split mismatch: have %v, want 1150000", r.Split)
+	}
+	var homestead bool
+	for _, name := range r.Split.Transitions {
+		homestead = homestead || name == "EIP2Transition"
+	}
+	if !homestead {
+		t.Errorf("homestead transitions missing: %v", r.Split.Transitions)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		id    ID
+		err   bool
+	}{
+		{input: "0xfc64ec04", id: ID{Hash: checksumToBytes(0xfc64ec04)}},
+		{input: "0xbe46d57c:13189133", id: ID{Hash: checksumToBytes(0xbe46d57c), Next: 13189133}},
+		{input: "0xbe46d57c:0xc9400d", id: ID{Hash: checksumToBytes(0xbe46d57c), Next: 13189133}},
+		{input: "0xbe46d5", err: true},
+		{input: "0xbe46d57c:x", err: true},
+	} {
+		id, err := ParseID(tt.input)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want error %v", tt.input, err, tt.err)
+		}
+		if err == nil && id != tt.id {
+			t.Errorf("%s: id mismatch: have %x, want %x", tt.input, id, tt.id)
+		}
+	}
+}
@@ -179,3 +179,18 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// ReadTraceIndexBits retrieves the compressed bit vector of the blocks within the
+// given section whose traces the address appears in, or nil if it appears in none.
+func ReadTraceIndexBits(db ethdb.KeyValueReader, addr common.Address, section uint64, head common.Hash) []byte {
+	data, _ := db.Get(traceIndexKey(addr, section, head))
+	return data
+}
+
+// WriteTraceIndexBits stores the compressed bit vector of the blocks within the
+// given section whose traces the address appears in.
+func WriteTraceIndexBits(db ethdb.KeyValueWriter, addr common.Address, section uint64, head common.Hash, bits []byte) {
+	if err := db.Put(traceIndexKey(addr, section, head), bits); err != nil {
+		log.Crit("Failed to store trace index bits", "err", err)
+	}
+}
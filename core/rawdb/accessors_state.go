@@ -137,6 +137,117 @@ func WritePersistentStateID(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadStateDiff retrieves the reverse diff of the state transition of the given
+// block into the given state root.
+func ReadStateDiff(db ethdb.KeyValueReader, number uint64, root common.Hash) []byte {
+	data, _ := db.Get(stateDiffKey(number, root))
+	return data
+}
+
+// WriteStateDiff stores the reverse diff of the state transition of the given
+// block into the given state root.
+func WriteStateDiff(db ethdb.KeyValueWriter, number uint64, root common.Hash, diff []byte) {
+	if err := db.Put(stateDiffKey(number, root), diff); err != nil {
+		log.Crit("Failed to store state diff", "err", err)
+	}
+}
+
+// DeleteStateDiff deletes the reverse diff of the state transition of the given
+// block into the given state root.
+func DeleteStateDiff(db ethdb.KeyValueWriter, number uint64, root common.Hash) {
+	if err := db.Delete(stateDiffKey(number, root)); err != nil {
+		log.Crit("Failed to delete state diff", "err", err)
+	}
+}
+
+// DeleteStateDiffs deletes the reverse diffs of the state transitions of all the
+// blocks below the given number, side chains included.
+func DeleteStateDiffs(db ethdb.KeyValueStore, limit uint64) {
+	it := db.NewIterator(stateDiffPrefix, nil)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(stateDiffPrefix)+8+common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(stateDiffPrefix):]) >= limit {
+			break
+		}
+		if err := batch.Delete(key); err != nil {
+			log.Crit("Failed to delete state diff", "err", err)
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete state diffs", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete state diffs", "err", err)
+	}
+}
+
+// ReadStateDiffTail retrieves the number of the oldest block whose state diff is
+// in the key-value store, nil if there is none.
+func ReadStateDiffTail(db ethdb.Iteratee) *uint64 {
+	it := db.NewIterator(stateDiffPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == len(stateDiffPrefix)+8+common.HashLength {
+			number := binary.BigEndian.Uint64(key[len(stateDiffPrefix):])
+			return &number
+		}
+	}
+	return nil
+}
+
+// ReadStateDiffFreezerBase retrieves the number of the block whose state diff is
+// the first item of the state diff freezer.
+func ReadStateDiffFreezerBase(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(stateDiffFreezerBaseKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateDiffFreezerBase stores the number of the block whose state diff is
+// the first item of the state diff freezer.
+func WriteStateDiffFreezerBase(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(stateDiffFreezerBaseKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the state diff freezer base", "err", err)
+	}
+}
+
+// ReadFrozenStateDiff retrieves the state diff at the given position of the state
+// diff freezer.
+func ReadFrozenStateDiff(db ethdb.AncientReaderOp, id uint64) []byte {
+	blob, err := db.Ancient(stateDiffTable, id)
+	if err != nil {
+		return nil
+	}
+	return blob
+}
+
+// WriteFrozenStateDiffs appends state diffs to the state diff freezer, the first
+// one at the given position.
+func WriteFrozenStateDiffs(db ethdb.AncientWriter, id uint64, diffs [][]byte) error {
+	_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i, diff := range diffs {
+			if err := op.AppendRaw(stateDiffTable, id+uint64(i), diff); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
 // ReadTrieJournal retrieves the serialized in-memory trie nodes of layers saved at
 // the last shutdown.
 func ReadTrieJournal(db ethdb.KeyValueReader) []byte {
@@ -171,6 +171,17 @@ func DeleteLegacyTrieNode(db ethdb.KeyValueWriter, hash common.Hash) {
 	}
 }
 
+// ReadStateScheme reads the state scheme of the persistent state, or the
+// hash scheme if the database holds no path-based state.
+func ReadStateScheme(db ethdb.KeyValueReader) string {
+	// The root node of the account trie is always present in a database
+	// holding path-based state.
+	if blob, _ := ReadAccountTrieNode(db, nil); len(blob) != 0 {
+		return PathScheme
+	}
+	return HashScheme
+}
+
 // HasTrieNode checks the trie node presence with the provided node info and
 // the associated node hash.
 func HasTrieNode(db ethdb.KeyValueReader, owner common.Hash, path []byte, hash common.Hash, scheme string) bool {
@@ -46,6 +46,14 @@ var chainFreezerNoSnappy = map[string]bool{
 	ChainFreezerDifficultyTable: true,
 }
 
+// chainFreezerPrunable is the set of chain freezer tables whose old items can
+// be dropped by history expiry. Headers, hashes and difficulties are retained
+// to keep the chain verifiable.
+var chainFreezerPrunable = map[string]bool{
+	ChainFreezerBodiesTable:  true,
+	ChainFreezerReceiptTable: true,
+}
+
 const (
 	// stateHistoryTableSize defines the maximum size of freezer data files.
 	stateHistoryTableSize = 2 * 1000 * 1000 * 1000
@@ -66,10 +74,24 @@ var stateHistoryFreezerNoSnappy = map[string]bool{
 	stateHistoryStorageData:  false,
 }
 
+const (
+	// stateDiffTableSize defines the maximum size of state diff freezer data files.
+	stateDiffTableSize = 2 * 1000 * 1000 * 1000
+
+	// stateDiffTable indicates the name of the freezer state diff table.
+	stateDiffTable = "diffs"
+)
+
+var stateDiffFreezerNoSnappy = map[string]bool{
+	stateDiffTable: false,
+}
+
 // The list of identifiers of ancient stores.
 var (
 	chainFreezerName = "chain" // the folder name of chain segment ancient store.
 	stateFreezerName = "state" // the folder name of reverse diff ancient store.
+
+	stateDiffFreezerName = "statediffs" // the folder name of the frozen state diffs of canonical blocks.
 )
 
 // freezers the collections of all builtin freezers.
@@ -79,3 +101,9 @@ var freezers = []string{chainFreezerName, stateFreezerName}
 func NewStateHistoryFreezer(ancientDir string, readOnly bool) (*ResettableFreezer, error) {
 	return NewResettableFreezer(filepath.Join(ancientDir, stateFreezerName), "eth/db/state", readOnly, stateHistoryTableSize, stateHistoryFreezerNoSnappy)
 }
+
+// NewStateDiffFreezer initializes the freezer for the state diffs of the frozen
+// canonical blocks.
+func NewStateDiffFreezer(ancientDir string, readOnly bool) (*Freezer, error) {
+	return NewFreezer(filepath.Join(ancientDir, stateDiffFreezerName), "eth/db/statediff", readOnly, stateDiffTableSize, stateDiffFreezerNoSnappy)
+}
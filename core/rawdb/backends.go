@@ -0,0 +1,141 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/ethdb/bbolt"
+	"github.com/yuriy0803/core-geth1/ethdb/leveldb"
+)
+
+// BackendOpenFn opens a disk-based key-value store within the given directory.
+type BackendOpenFn func(file string, cache int, handles int, namespace string, readonly bool) (ethdb.KeyValueStore, error)
+
+// BackendExistsFn reports whether the directory holds a database of a backend.
+type BackendExistsFn func(file string) bool
+
+type backend struct {
+	open   BackendOpenFn
+	exists BackendExistsFn
+}
+
+// DefaultBackends is the collection of key-value database engines a node can
+// be backed by, selected through the database type of the OpenOptions.
+var DefaultBackends = backendDirectory{elems: make(map[string]backend)}
+
+// backendDirectory provides functionality to lookup a key-value database engine
+// by name, as well as to detect the engine of an existing database.
+type backendDirectory struct {
+	elems map[string]backend
+	lock  sync.RWMutex
+}
+
+// Register registers a key-value database engine by name, meaning that users
+// can select it as the backing database. The exists function detects databases
+// previously created by the engine, so it must not claim those of any other.
+func (d *backendDirectory) Register(name string, open BackendOpenFn, exists BackendExistsFn) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.elems[name] = backend{open: open, exists: exists}
+}
+
+// Names returns the names of all the registered engines in alphabetical order.
+func (d *backendDirectory) Names() []string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.names()
+}
+
+// Has reports whether an engine of the given name is registered.
+func (d *backendDirectory) Has(name string) bool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	_, ok := d.elems[name]
+	return ok
+}
+
+// Detect returns the name of the engine which created the database within the
+// given directory, or the empty string if there's none.
+func (d *backendDirectory) Detect(file string) string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	for _, name := range d.names() {
+		if d.elems[name].exists(file) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Open opens the key-value store of the engine with the given name.
+func (d *backendDirectory) Open(name string, file string, cache int, handles int, namespace string, readonly bool) (ethdb.KeyValueStore, error) {
+	d.lock.RLock()
+	elem, ok := d.elems[name]
+	d.lock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown db.engine %v", name)
+	}
+	return elem.open(file, cache, handles, namespace, readonly)
+}
+
+// names returns the sorted engine names, the caller must hold the lock.
+func (d *backendDirectory) names() []string {
+	names := make([]string, 0, len(d.elems))
+	for name := range d.elems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	DefaultBackends.Register(dbLeveldb, func(file string, cache int, handles int, namespace string, readonly bool) (ethdb.KeyValueStore, error) {
+		return leveldb.New(file, cache, handles, namespace, readonly)
+	}, func(file string) bool {
+		return hasPreexistingDb(file) == dbLeveldb
+	})
+	DefaultBackends.Register(dbBbolt, func(file string, cache int, handles int, namespace string, readonly bool) (ethdb.KeyValueStore, error) {
+		return bbolt.New(file, cache, handles, namespace, readonly)
+	}, bbolt.Exists)
+}
+
+// hasPreexistingDb checks the given data directory whether a database of one of
+// the LSM engines is already instantiated at that location, and if so, returns
+// the type of database (or the empty string).
+func hasPreexistingDb(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err != nil {
+		return "" // No pre-existing db
+	}
+	if matches, err := filepath.Glob(filepath.Join(path, "OPTIONS*")); len(matches) > 0 || err != nil {
+		if err != nil {
+			panic(err) // only possible if the pattern is malformed
+		}
+		return dbPebble
+	}
+	return dbLeveldb
+}
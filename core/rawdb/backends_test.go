@@ -0,0 +1,76 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/ethdb/memorydb"
+)
+
+func TestOpenRegisteredBackends(t *testing.T) {
+	dir := t.TempDir()
+
+	// Create a database with an explicitly chosen engine.
+	db, err := Open(OpenOptions{Type: dbBbolt, Directory: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	WriteHeadBlockHash(db, common.Hash{0x01})
+	db.Close()
+
+	// Reopening must detect the engine, and refuse any conflicting choice.
+	if kind := DefaultBackends.Detect(dir); kind != dbBbolt {
+		t.Fatalf("wrong detected engine: have %q, want %q", kind, dbBbolt)
+	}
+	if db, err = Open(OpenOptions{Directory: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if hash := ReadHeadBlockHash(db); hash != (common.Hash{0x01}) {
+		t.Fatalf("wrong head hash: have %x", hash)
+	}
+	db.Close()
+
+	if _, err := Open(OpenOptions{Type: dbLeveldb, Directory: dir}); err == nil {
+		t.Fatal("conflicting engine choice accepted")
+	}
+	if _, err := Open(OpenOptions{Type: "nonexistent", Directory: t.TempDir()}); err == nil {
+		t.Fatal("unknown engine accepted")
+	}
+
+	// Third-party engines can be registered and selected.
+	var opened bool
+	DefaultBackends.Register("testmem", func(string, int, int, string, bool) (ethdb.KeyValueStore, error) {
+		opened = true
+		return memorydb.New(), nil
+	}, func(string) bool { return false })
+	defer func() {
+		DefaultBackends.lock.Lock()
+		delete(DefaultBackends.elems, "testmem")
+		DefaultBackends.lock.Unlock()
+	}()
+
+	if db, err = Open(OpenOptions{Type: "testmem", Directory: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if !opened {
+		t.Fatal("registered engine not used")
+	}
+}
@@ -22,7 +22,6 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -204,6 +203,30 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 		printChainMetadata(db)
 		return nil, err
 	}
+	return newFreezerDatabase(db, frdb, ancient)
+}
+
+// NewDatabaseWithFreezerRemote creates a high level database on top of a given
+// key-value data store with a remote freezer, reached over RPC at the given
+// endpoint, moving immutable chain segments into cold storage.
+func NewDatabaseWithFreezerRemote(db ethdb.KeyValueStore, endpoint string, readonly bool) (ethdb.Database, error) {
+	client, err := NewFreezerRemoteClient(endpoint, readonly)
+	if err != nil {
+		printChainMetadata(db)
+		return nil, err
+	}
+	frdb, err := newFreezerDatabase(db, newChainFreezerWithStore(client, readonly), "")
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	log.Info("Using remote ancient store", "endpoint", endpoint)
+	return frdb, nil
+}
+
+// newFreezerDatabase cross validates the key-value data store and the chain
+// freezer, and combines them into a high level database.
+func newFreezerDatabase(db ethdb.KeyValueStore, frdb *chainFreezer, ancient string) (ethdb.Database, error) {
 	// Since the freezer can be stored separately from the user's key-value database,
 	// there's a fairly high probability that the user requests invalid combinations
 	// of the freezer and database. Ensure that we don't shoot ourselves in the foot
@@ -324,37 +347,24 @@ func NewLevelDBDatabase(file string, cache int, handles int, namespace string, r
 const (
 	dbPebble  = "pebble"
 	dbLeveldb = "leveldb"
+	dbBbolt   = "bbolt"
 )
 
-// hasPreexistingDb checks the given data directory whether a database is already
-// instantiated at that location, and if so, returns the type of database (or the
-// empty string).
-func hasPreexistingDb(path string) string {
-	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err != nil {
-		return "" // No pre-existing db
-	}
-	if matches, err := filepath.Glob(filepath.Join(path, "OPTIONS*")); len(matches) > 0 || err != nil {
-		if err != nil {
-			panic(err) // only possible if the pattern is malformed
-		}
-		return dbPebble
-	}
-	return dbLeveldb
-}
-
 // OpenOptions contains the options to apply when opening a database.
 // OBS: If AncientsDirectory is empty, it indicates that no freezer is to be used.
 type OpenOptions struct {
-	Type              string // "leveldb" | "pebble"
+	Type              string // "leveldb" | "pebble" | "bbolt" | any engine registered in DefaultBackends
 	Directory         string // the datadir
 	AncientsDirectory string // the ancients-dir
+	AncientsRemote    string // the remote ancient store endpoint, overriding the ancients-dir
 	Namespace         string // the namespace for database relevant metrics
 	Cache             int    // the capacity(in megabytes) of the data caching
 	Handles           int    // number of files to be open simultaneously
 	ReadOnly          bool
 }
 
-// openKeyValueDatabase opens a disk-based key-value database, e.g. leveldb or pebble.
+// openKeyValueDatabase opens a disk-based key-value database of any of the
+// engines registered in DefaultBackends, e.g. leveldb or pebble.
 //
 //	                      type == null          type != null
 //	                   +----------------------------------------
@@ -362,36 +372,38 @@ type OpenOptions struct {
 //	db is existent     |  from db         |  specified type (if compatible)
 func openKeyValueDatabase(o OpenOptions) (ethdb.Database, error) {
 	// Reject any unsupported database type
-	if len(o.Type) != 0 && o.Type != dbLeveldb && o.Type != dbPebble {
+	if o.Type == dbPebble && !PebbleEnabled {
+		return nil, errors.New("db.engine 'pebble' not supported on this platform")
+	}
+	if len(o.Type) != 0 && !DefaultBackends.Has(o.Type) {
 		return nil, fmt.Errorf("unknown db.engine %v", o.Type)
 	}
 	// Retrieve any pre-existing database's type and use that or the requested one
 	// as long as there's no conflict between the two types
-	existingDb := hasPreexistingDb(o.Directory)
+	existingDb := DefaultBackends.Detect(o.Directory)
 	if len(existingDb) != 0 && len(o.Type) != 0 && o.Type != existingDb {
 		return nil, fmt.Errorf("db.engine choice was %v but found pre-existing %v database in specified data directory", o.Type, existingDb)
 	}
-	if o.Type == dbPebble || existingDb == dbPebble {
+	kind := o.Type
+	if len(kind) == 0 {
+		kind = existingDb
+	}
+	if len(kind) != 0 {
+		log.Info(fmt.Sprintf("Using %s as the backing database", kind))
+	} else {
+		// No pre-existing database, no user-requested one either. Default to Pebble
+		// on supported platforms and LevelDB on anything else.
+		kind = dbLeveldb
 		if PebbleEnabled {
-			log.Info("Using pebble as the backing database")
-			return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
-		} else {
-			return nil, errors.New("db.engine 'pebble' not supported on this platform")
+			kind = dbPebble
 		}
+		log.Info(fmt.Sprintf("Defaulting to %s as the backing database", kind))
 	}
-	if o.Type == dbLeveldb || existingDb == dbLeveldb {
-		log.Info("Using leveldb as the backing database")
-		return NewLevelDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
-	}
-	// No pre-existing database, no user-requested one either. Default to Pebble
-	// on supported platforms and LevelDB on anything else.
-	if PebbleEnabled {
-		log.Info("Defaulting to pebble as the backing database")
-		return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
-	} else {
-		log.Info("Defaulting to leveldb as the backing database")
-		return NewLevelDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
+	kvdb, err := DefaultBackends.Open(kind, o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
+	if err != nil {
+		return nil, err
 	}
+	return NewDatabase(kvdb), nil
 }
 
 // Open opens both a disk-based key-value database such as leveldb or pebble, but also
@@ -404,10 +416,15 @@ func Open(o OpenOptions) (ethdb.Database, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(o.AncientsDirectory) == 0 {
+	var frdb ethdb.Database
+	switch {
+	case len(o.AncientsRemote) != 0:
+		frdb, err = NewDatabaseWithFreezerRemote(kvdb, o.AncientsRemote, o.ReadOnly)
+	case len(o.AncientsDirectory) != 0:
+		frdb, err = NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly)
+	default:
 		return kvdb, nil
 	}
-	frdb, err := NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly)
 	if err != nil {
 		kvdb.Close()
 		return nil, err
@@ -470,6 +487,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		traceIndex      stat
+		stateDiffs      stat
 		beaconHeaders   stat
 		cliqueSnaps     stat
 
@@ -524,6 +543,12 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, traceIndexPrefix) && len(key) == (len(traceIndexPrefix)+common.AddressLength+8+common.HashLength):
+			traceIndex.Add(size)
+		case bytes.HasPrefix(key, TraceIndexTablePrefix):
+			traceIndex.Add(size)
+		case bytes.HasPrefix(key, stateDiffPrefix) && len(key) == (len(stateDiffPrefix)+8+common.HashLength):
+			stateDiffs.Add(size)
 		case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
 			beaconHeaders.Add(size)
 		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
@@ -541,7 +566,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			for _, meta := range [][]byte{
 				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, headFinalizedBlockKey,
 				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
-				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
+				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey, stateDiffFreezerBaseKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
 			} {
 				if bytes.Equal(key, meta) {
@@ -570,6 +595,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Trace index", traceIndex.Size(), traceIndex.Count()},
+		{"Key-Value store", "State diffs", stateDiffs.Size(), stateDiffs.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
@@ -35,3 +35,11 @@ func NewPebbleDBDatabase(file string, cache int, handles int, namespace string,
 	}
 	return NewDatabase(db), nil
 }
+
+func init() {
+	DefaultBackends.Register(dbPebble, func(file string, cache int, handles int, namespace string, readonly bool) (ethdb.KeyValueStore, error) {
+		return pebble.New(file, cache, handles, namespace, readonly)
+	}, func(file string) bool {
+		return hasPreexistingDb(file) == dbPebble
+	})
+}
@@ -0,0 +1,350 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/rlp"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+// The RPC methods of the remote freezer server API, e.g. the one of
+// cmd/ancient-store-mem.
+const (
+	FreezerMethodHasAncient   = "freezer_hasAncient"
+	FreezerMethodAncient      = "freezer_ancient"
+	FreezerMethodAncientRange = "freezer_ancientRange"
+	FreezerMethodAncients     = "freezer_ancients"
+	FreezerMethodTail         = "freezer_tail"
+	FreezerMethodAncientSize  = "freezer_ancientSize"
+	FreezerMethodAppendRaw    = "freezer_appendRaw"
+	FreezerMethodTruncateHead = "freezer_truncateHead"
+	FreezerMethodTruncateTail = "freezer_truncateTail"
+	FreezerMethodSync         = "freezer_sync"
+)
+
+const (
+	// freezerRemoteRetries is the number of times a request failing because the
+	// remote freezer is unreachable is retried before giving up.
+	freezerRemoteRetries = 8
+
+	// freezerRemoteRetryDelay is the delay before retrying a failed request,
+	// doubled on every attempt up to freezerRemoteMaxRetryDelay.
+	freezerRemoteRetryDelay    = 250 * time.Millisecond
+	freezerRemoteMaxRetryDelay = 8 * time.Second
+
+	// freezerRemoteBatchItems and freezerRemoteBatchSize are the maximum number
+	// of items, and their total size, appended in one batch request.
+	freezerRemoteBatchItems = 500
+	freezerRemoteBatchSize  = 4 * 1024 * 1024
+)
+
+var errFreezerRemoteClosed = errors.New("remote freezer client closed")
+
+// FreezerRemoteClient is an ancient store keeping the ancient data in a remote
+// freezer, reached over RPC (IPC, HTTP or WebSocket). Requests failing because
+// the remote freezer is unreachable are retried, reconnecting to it.
+type FreezerRemoteClient struct {
+	client   *rpc.Client
+	readonly bool
+
+	writeLock sync.RWMutex // Protects the ancient data from concurrent writes
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewFreezerRemoteClient connects to the remote freezer at the given endpoint.
+func NewFreezerRemoteClient(endpoint string, readonly bool) (*FreezerRemoteClient, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	c := &FreezerRemoteClient{
+		client:   client,
+		readonly: readonly,
+		quit:     make(chan struct{}),
+	}
+	// Ensure the remote freezer is reachable and serves the freezer API.
+	if _, err := c.Ancients(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// retry runs the request, retrying it with a backoff while the remote freezer
+// is unreachable.
+func (c *FreezerRemoteClient) retry(method string, request func() error) error {
+	delay := freezerRemoteRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := request()
+		if err == nil || !isFreezerRemoteUnreachable(err) || attempt == freezerRemoteRetries {
+			return err
+		}
+		log.Warn("Remote freezer unreachable, retrying", "method", method, "attempt", attempt+1, "delay", delay, "err", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-c.quit:
+			timer.Stop()
+			return errFreezerRemoteClosed
+		}
+		if delay *= 2; delay > freezerRemoteMaxRetryDelay {
+			delay = freezerRemoteMaxRetryDelay
+		}
+	}
+}
+
+// isFreezerRemoteUnreachable returns whether the request error is caused by the
+// connection to the remote freezer, rather than by the request itself. The RPC
+// client reconnects on the next request after a connection failure.
+func isFreezerRemoteUnreachable(err error) bool {
+	var (
+		rpcErr  rpc.Error
+		httpErr rpc.HTTPError
+	)
+	switch {
+	case errors.Is(err, rpc.ErrClientQuit), errors.As(err, &rpcErr):
+		return false
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode >= http.StatusInternalServerError
+	case errors.As(err, new(*json.UnmarshalTypeError)), errors.As(err, new(*json.SyntaxError)):
+		return false
+	}
+	return true
+}
+
+// call performs the RPC request, retrying it while the remote freezer is unreachable.
+func (c *FreezerRemoteClient) call(result interface{}, method string, args ...interface{}) error {
+	return c.retry(method, func() error {
+		return c.client.CallContext(context.Background(), result, method, args...)
+	})
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (c *FreezerRemoteClient) HasAncient(kind string, number uint64) (bool, error) {
+	var res bool
+	err := c.call(&res, FreezerMethodHasAncient, kind, number)
+	return res, err
+}
+
+// Ancient retrieves an ancient binary blob from the remote freezer.
+func (c *FreezerRemoteClient) Ancient(kind string, number uint64) ([]byte, error) {
+	var res []byte
+	err := c.call(&res, FreezerMethodAncient, kind, number)
+	return res, err
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index 'start'.
+func (c *FreezerRemoteClient) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	var res [][]byte
+	err := c.call(&res, FreezerMethodAncientRange, kind, start, count, maxBytes)
+	return res, err
+}
+
+// Ancients returns the ancient item numbers in the remote freezer.
+func (c *FreezerRemoteClient) Ancients() (uint64, error) {
+	var res uint64
+	err := c.call(&res, FreezerMethodAncients)
+	return res, err
+}
+
+// Tail returns the number of first stored item in the remote freezer.
+func (c *FreezerRemoteClient) Tail() (uint64, error) {
+	var res uint64
+	err := c.call(&res, FreezerMethodTail)
+	return res, err
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (c *FreezerRemoteClient) AncientSize(kind string) (uint64, error) {
+	var res uint64
+	err := c.call(&res, FreezerMethodAncientSize, kind)
+	return res, err
+}
+
+// ReadAncients runs the given read operation while ensuring that no writes take
+// place through this client.
+func (c *FreezerRemoteClient) ReadAncients(fn func(ethdb.AncientReaderOp) error) (err error) {
+	c.writeLock.RLock()
+	defer c.writeLock.RUnlock()
+
+	return fn(c)
+}
+
+// ModifyAncients runs the given write operation. The appended items are sent to
+// the remote freezer in batches; if the operation fails, the items already sent
+// are truncated away.
+func (c *FreezerRemoteClient) ModifyAncients(fn func(ethdb.AncientWriteOp) error) (writeSize int64, err error) {
+	if c.readonly {
+		return 0, errReadOnly
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	prevItem, err := c.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			if _, terr := c.truncateHead(prevItem); terr != nil {
+				log.Error("Remote freezer rollback failed", "head", prevItem, "err", terr)
+			}
+		}
+	}()
+	batch := &freezerRemoteBatch{client: c}
+	if err := fn(batch); err != nil {
+		return 0, err
+	}
+	if err := batch.flush(); err != nil {
+		return 0, err
+	}
+	return batch.size, nil
+}
+
+// TruncateHead discards all but the first n ancient data from the remote freezer,
+// returning the previous head.
+func (c *FreezerRemoteClient) TruncateHead(n uint64) (uint64, error) {
+	if c.readonly {
+		return 0, errReadOnly
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	return c.truncateHead(n)
+}
+
+func (c *FreezerRemoteClient) truncateHead(n uint64) (uint64, error) {
+	old, err := c.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	if old <= n {
+		return old, nil
+	}
+	return old, c.call(nil, FreezerMethodTruncateHead, n)
+}
+
+// TruncateTail discards the first n ancient data from the remote freezer,
+// returning the previous tail.
+func (c *FreezerRemoteClient) TruncateTail(n uint64) (uint64, error) {
+	if c.readonly {
+		return 0, errReadOnly
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	old, err := c.Tail()
+	if err != nil {
+		return 0, err
+	}
+	if old >= n {
+		return old, nil
+	}
+	return old, c.call(nil, FreezerMethodTruncateTail, n)
+}
+
+// Sync flushes the ancient data of the remote freezer to its storage.
+func (c *FreezerRemoteClient) Sync() error {
+	if c.readonly {
+		return nil
+	}
+	return c.call(nil, FreezerMethodSync)
+}
+
+// MigrateTable is not supported by remote freezers.
+func (c *FreezerRemoteClient) MigrateTable(string, func([]byte) ([]byte, error)) error {
+	return errNotSupported
+}
+
+// Close terminates the connection to the remote freezer, leaving the remote
+// freezer itself running since it may be shared by several nodes.
+func (c *FreezerRemoteClient) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.quit)
+		c.client.Close()
+	})
+	return nil
+}
+
+// freezerRemoteBatch is the write operation of the remote freezer, sending the
+// appended items in batch requests.
+type freezerRemoteBatch struct {
+	client  *FreezerRemoteClient
+	pending []rpc.BatchElem
+	bytes   int   // Total size of the pending items
+	size    int64 // Total size of the appended items
+}
+
+// Append adds an RLP-encoded item.
+func (b *freezerRemoteBatch) Append(kind string, number uint64, item interface{}) error {
+	blob, err := rlp.EncodeToBytes(item)
+	if err != nil {
+		return err
+	}
+	return b.AppendRaw(kind, number, blob)
+}
+
+// AppendRaw adds an item without RLP-encoding it.
+func (b *freezerRemoteBatch) AppendRaw(kind string, number uint64, item []byte) error {
+	b.pending = append(b.pending, rpc.BatchElem{
+		Method: FreezerMethodAppendRaw,
+		Args:   []interface{}{kind, number, common.CopyBytes(item)},
+		Result: new(json.RawMessage),
+	})
+	b.bytes += len(item)
+	b.size += int64(len(item))
+
+	if len(b.pending) >= freezerRemoteBatchItems || b.bytes >= freezerRemoteBatchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush sends the pending items to the remote freezer.
+func (b *freezerRemoteBatch) flush() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	err := b.client.retry(FreezerMethodAppendRaw, func() error {
+		for i := range b.pending {
+			b.pending[i].Error = nil
+		}
+		return b.client.client.BatchCallContext(context.Background(), b.pending)
+	})
+	if err != nil {
+		return err
+	}
+	for _, elem := range b.pending {
+		if elem.Error != nil {
+			return elem.Error
+		}
+	}
+	b.pending, b.bytes = b.pending[:0], 0
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/cmd/ancient-store-mem/lib"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/ethdb/memorydb"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+// startFreezerRemote serves the mock freezer over IPC at the given path,
+// returning a function stopping the server.
+func startFreezerRemote(t *testing.T, path string, mock *lib.MemFreezerRemoteServerAPI) func() {
+	t.Helper()
+
+	listener, server, err := rpc.StartIPCEndpoint(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("freezer", mock); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		listener.Close()
+		server.Stop()
+	}
+}
+
+// freezerRemoteTestItem returns the item of the given table and number.
+func freezerRemoteTestItem(kind string, number uint64) []byte {
+	return []byte{kind[0], byte(number), byte(number >> 8)}
+}
+
+// appendFreezerRemoteTestItems appends the items of all chain freezer tables
+// for the given number of blocks.
+func appendFreezerRemoteTestItems(op ethdb.AncientWriteOp, from, count uint64) error {
+	for number := from; number < from+count; number++ {
+		for _, kind := range []string{ChainFreezerHashTable, ChainFreezerHeaderTable, ChainFreezerBodiesTable, ChainFreezerReceiptTable, ChainFreezerDifficultyTable} {
+			if err := op.AppendRaw(kind, number, freezerRemoteTestItem(kind, number)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestFreezerRemoteClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "freezer.ipc")
+	stop := startFreezerRemote(t, path, lib.NewMemFreezerRemoteServerAPI())
+	defer stop()
+
+	client, err := NewFreezerRemoteClient(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Append more items than fit into a single batch request.
+	const items = freezerRemoteBatchItems
+	if _, err := client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return appendFreezerRemoteTestItems(op, 0, items)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if frozen, err := client.Ancients(); err != nil || frozen != items {
+		t.Fatalf("wrong ancients: have %d (%v), want %d", frozen, err, items)
+	}
+	if blob, err := client.Ancient(ChainFreezerHeaderTable, 42); err != nil || !bytes.Equal(blob, freezerRemoteTestItem(ChainFreezerHeaderTable, 42)) {
+		t.Fatalf("wrong item: have %x (%v)", blob, err)
+	}
+	blobs, err := client.AncientRange(ChainFreezerBodiesTable, 10, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 5 {
+		t.Fatalf("wrong range length: have %d, want 5", len(blobs))
+	}
+	for i, blob := range blobs {
+		if !bytes.Equal(blob, freezerRemoteTestItem(ChainFreezerBodiesTable, uint64(10+i))) {
+			t.Fatalf("wrong range item %d: %x", i, blob)
+		}
+	}
+	if blobs, err := client.AncientRange(ChainFreezerBodiesTable, 10, 5, 1); err != nil || len(blobs) != 1 {
+		t.Fatalf("wrong limited range length: have %d (%v), want 1", len(blobs), err)
+	}
+
+	// A failing write operation must not leave any item behind.
+	errFail := errors.New("fail")
+	if _, err := client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		if err := appendFreezerRemoteTestItems(op, items, items); err != nil {
+			return err
+		}
+		return errFail
+	}); err != errFail {
+		t.Fatalf("wrong error: have %v, want %v", err, errFail)
+	}
+	if frozen, _ := client.Ancients(); frozen != items {
+		t.Fatalf("failed write not rolled back: have %d ancients, want %d", frozen, items)
+	}
+	if _, err := client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return op.AppendRaw(ChainFreezerHashTable, items+1, nil)
+	}); err == nil {
+		t.Fatal("out of order write succeeded")
+	}
+
+	// Truncate both ends.
+	if old, err := client.TruncateHead(100); err != nil || old != items {
+		t.Fatalf("wrong truncated head: have %d (%v), want %d", old, err, items)
+	}
+	if old, err := client.TruncateTail(10); err != nil || old != 0 {
+		t.Fatalf("wrong truncated tail: have %d (%v), want 0", old, err)
+	}
+	if frozen, _ := client.Ancients(); frozen != 100 {
+		t.Fatalf("wrong ancients after truncation: have %d, want 100", frozen)
+	}
+	if tail, _ := client.Tail(); tail != 10 {
+		t.Fatalf("wrong tail after truncation: have %d, want 10", tail)
+	}
+	if ok, _ := client.HasAncient(ChainFreezerBodiesTable, 9); ok {
+		t.Fatal("item below the tail not deleted")
+	}
+	if ok, _ := client.HasAncient(ChainFreezerHashTable, 9); !ok {
+		t.Fatal("retained item below the tail deleted")
+	}
+	if ok, _ := client.HasAncient(ChainFreezerHashTable, 100); ok {
+		t.Fatal("item above the head not deleted")
+	}
+	if err := client.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFreezerRemoteClientReadonly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "freezer.ipc")
+	stop := startFreezerRemote(t, path, lib.NewMemFreezerRemoteServerAPI())
+	defer stop()
+
+	client, err := NewFreezerRemoteClient(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := client.ModifyAncients(func(ethdb.AncientWriteOp) error { return nil }); err != errReadOnly {
+		t.Fatalf("wrong write error: have %v, want %v", err, errReadOnly)
+	}
+	if _, err := client.TruncateHead(0); err != errReadOnly {
+		t.Fatalf("wrong truncation error: have %v, want %v", err, errReadOnly)
+	}
+}
+
+func TestFreezerRemoteClientReconnect(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "freezer.ipc")
+		mock = lib.NewMemFreezerRemoteServerAPI()
+		stop = startFreezerRemote(t, path, mock)
+	)
+	client, err := NewFreezerRemoteClient(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return appendFreezerRemoteTestItems(op, 0, 10)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Restart the remote freezer, the client must reconnect to it.
+	stop()
+	stop = startFreezerRemote(t, path, mock)
+	defer stop()
+
+	if frozen, err := client.Ancients(); err != nil || frozen != 10 {
+		t.Fatalf("wrong ancients after restart: have %d (%v), want 10", frozen, err)
+	}
+	if _, err := client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return appendFreezerRemoteTestItems(op, 10, 10)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if blob, err := client.Ancient(ChainFreezerReceiptTable, 15); err != nil || !bytes.Equal(blob, freezerRemoteTestItem(ChainFreezerReceiptTable, 15)) {
+		t.Fatalf("wrong item after restart: have %x (%v)", blob, err)
+	}
+	// Requests must give up once the client is closed.
+	stop()
+	client.Close()
+	if _, err := client.Ancients(); err == nil {
+		t.Fatal("request to a closed client succeeded")
+	}
+}
+
+func TestDatabaseWithFreezerRemote(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "freezer.ipc")
+	stop := startFreezerRemote(t, path, lib.NewMemFreezerRemoteServerAPI())
+	defer stop()
+
+	db, err := NewDatabaseWithFreezerRemote(memorydb.New(), path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	blocks := makeTestBlocks(10, 2)
+	receipts := makeTestReceipts(10, 2)
+	if _, err := WriteAncientBlocks(db, blocks, receipts, big.NewInt(100)); err != nil {
+		t.Fatal(err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 10 {
+		t.Fatalf("wrong ancients: have %d, want 10", frozen)
+	}
+	for _, block := range blocks {
+		number := block.NumberU64()
+		if hash := ReadCanonicalHash(db, number); hash != block.Hash() {
+			t.Fatalf("block %d: wrong canonical hash %x", number, hash)
+		}
+		if header := ReadHeader(db, block.Hash(), number); header == nil || header.Hash() != block.Hash() {
+			t.Fatalf("block %d: header not found", number)
+		}
+		if body := ReadBody(db, block.Hash(), number); body == nil || len(body.Transactions) != 2 {
+			t.Fatalf("block %d: body not found", number)
+		}
+	}
+}
@@ -283,6 +283,61 @@ func TestFreezerReadonlyValidate(t *testing.T) {
 	}
 }
 
+// This checks that truncating the tail only affects the prunable tables, also
+// after reopening the freezer.
+func TestFreezerPrunableTail(t *testing.T) {
+	var (
+		tables   = map[string]bool{"a": true, "b": true}
+		prunable = map[string]bool{"a": true}
+		dir      = t.TempDir()
+	)
+	f, err := newFreezer(dir, "", false, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := 0; i < 10; i++ {
+			if err := op.AppendRaw("a", uint64(i), getChunk(1024, i)); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", uint64(i), getChunk(1024, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	check := func(f *Freezer) {
+		t.Helper()
+		if tail, _ := f.Tail(); tail != 5 {
+			t.Fatalf("wrong tail: have %d, want 5", tail)
+		}
+		for i := uint64(0); i < 10; i++ {
+			if _, err := f.Ancient("a", i); (err != nil) != (i < 5) {
+				t.Fatalf("prunable table, item %d: unexpected error %v", i, err)
+			}
+			if _, err := f.Ancient("b", i); err != nil {
+				t.Fatalf("retained table, item %d: %v", i, err)
+			}
+		}
+	}
+	_, err = f.TruncateTail(5)
+	require.NoError(t, err)
+	check(f)
+	require.NoError(t, f.Close())
+
+	// Reopening repairs, or validates in readonly mode, only the prunable tails.
+	for _, readonly := range []bool{false, true} {
+		f, err = newFreezer(dir, "", readonly, 2049, tables, prunable)
+		if err != nil {
+			t.Fatal("can't reopen freezer", err)
+		}
+		check(f)
+		require.NoError(t, f.Close())
+	}
+}
+
 func newFreezerForTesting(t *testing.T, tables map[string]bool) (*Freezer, string) {
 	t.Helper()
 
@@ -0,0 +1,440 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/internal/era/e2store"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/rlp"
+	"github.com/yuriy0803/core-geth1/trie"
+)
+
+// A state file is an e2store file holding the flat state at a block, made up of
+// a version entry, the state chunks, the manifest and finally an index entry
+// pointing to the manifest.
+const (
+	stateFileTypeVersion  uint16 = 0x3265
+	stateFileTypeChunk    uint16 = 0x5301
+	stateFileTypeManifest uint16 = 0x5302
+	stateFileTypeIndex    uint16 = 0x5303
+
+	// stateFileIndexSize is the size of the index entry, holding the offset
+	// of the manifest entry as a little-endian uint64.
+	stateFileIndexSize = 8 + 8
+
+	// stateChunkSize is the uncompressed size at which state chunks are cut.
+	stateChunkSize = 4 * 1024 * 1024
+)
+
+var errStateFileInterrupted = errors.New("interrupted")
+
+// StateManifest describes the flat state held by a state file.
+type StateManifest struct {
+	Root     common.Hash // State root of the block
+	Number   uint64      // Number of the block
+	Hash     common.Hash // Hash of the block
+	Accounts uint64      // Number of accounts
+	Slots    uint64      // Number of storage slots
+	Codes    uint64      // Number of contract codes
+	Chunks   []StateChunk
+}
+
+// StateChunk locates a chunk of the state file.
+type StateChunk struct {
+	Offset uint64      // Offset of the chunk entry within the file
+	Hash   common.Hash // Keccak256 hash of the compressed chunk
+}
+
+// ID returns the hash of the manifest, which authenticates the whole file.
+func (m *StateManifest) ID() common.Hash {
+	blob, err := rlp.EncodeToBytes(m)
+	if err != nil {
+		panic(err) // can't happen, all the fields are encodable
+	}
+	return crypto.Keccak256Hash(blob)
+}
+
+// stateChunkAccount is an account of a state chunk along with its storage. An
+// account with too many slots for a single chunk is continued in the next one,
+// with its data left empty.
+type stateChunkAccount struct {
+	Hash    common.Hash
+	Account []byte // Account in 'slim RLP' encoding, empty if continued
+	Code    []byte // Contract code, only present the first time it's seen
+	Slots   []stateChunkSlot
+}
+
+// stateChunkSlot is a storage slot of a state chunk.
+type stateChunkSlot struct {
+	Hash  common.Hash
+	Value []byte
+}
+
+// stateFileWriter writes the chunks of a state file, tracking their offsets.
+type stateFileWriter struct {
+	w        *e2store.Writer
+	offset   uint64
+	manifest *StateManifest
+
+	chunk []stateChunkAccount
+	size  int
+}
+
+// write writes an entry, advancing the offset.
+func (w *stateFileWriter) write(typ uint16, value []byte) error {
+	n, err := w.w.Write(typ, value)
+	w.offset += uint64(n)
+	return err
+}
+
+// add adds an account or a continuation of its storage to the current chunk.
+func (w *stateFileWriter) add(account stateChunkAccount) {
+	w.chunk = append(w.chunk, account)
+	w.size += common.HashLength + len(account.Account) + len(account.Code)
+	for _, slot := range account.Slots {
+		w.size += common.HashLength + len(slot.Value)
+	}
+}
+
+// flush writes the current chunk compressed, if it's not empty.
+func (w *stateFileWriter) flush() error {
+	if len(w.chunk) == 0 {
+		return nil
+	}
+	blob, err := rlp.EncodeToBytes(w.chunk)
+	if err != nil {
+		return err
+	}
+	blob = snappy.Encode(nil, blob)
+	w.manifest.Chunks = append(w.manifest.Chunks, StateChunk{Offset: w.offset, Hash: crypto.Keccak256Hash(blob)})
+	w.chunk, w.size = nil, 0
+	return w.write(stateFileTypeChunk, blob)
+}
+
+// ExportState writes the flat state of the block to a state file, iterating the
+// snapshot tree. Its root must be within the tree.
+func ExportState(w io.Writer, t *Tree, db ethdb.KeyValueReader, header *types.Header, interrupt chan struct{}) (*StateManifest, error) {
+	accIt, err := t.AccountIterator(header.Root, common.Hash{})
+	if err != nil {
+		return nil, err
+	}
+	defer accIt.Release()
+
+	fw := &stateFileWriter{
+		w:        e2store.NewWriter(w),
+		manifest: &StateManifest{Root: header.Root, Number: header.Number.Uint64(), Hash: header.Hash()},
+	}
+	if err := fw.write(stateFileTypeVersion, nil); err != nil {
+		return nil, err
+	}
+	var (
+		codes  = make(map[common.Hash]struct{})
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for accIt.Next() {
+		select {
+		case <-interrupt:
+			return nil, errStateFileInterrupted
+		default:
+		}
+		account, err := types.FullAccount(accIt.Account())
+		if err != nil {
+			return nil, err
+		}
+		var (
+			entry = stateChunkAccount{Hash: accIt.Hash(), Account: common.CopyBytes(accIt.Account())}
+			size  = common.HashLength + len(entry.Account)
+		)
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != types.EmptyCodeHash {
+			if _, ok := codes[codeHash]; !ok {
+				if entry.Code = rawdb.ReadCode(db, codeHash); len(entry.Code) == 0 {
+					return nil, fmt.Errorf("code %x of account %x missing", codeHash, accIt.Hash())
+				}
+				codes[codeHash] = struct{}{}
+				size += len(entry.Code)
+				fw.manifest.Codes++
+			}
+		}
+		fw.manifest.Accounts++
+
+		if account.Root != types.EmptyRootHash {
+			stIt, err := t.StorageIterator(header.Root, accIt.Hash(), common.Hash{})
+			if err != nil {
+				return nil, err
+			}
+			for stIt.Next() {
+				// Cut the chunk once full, continuing the storage in the next one
+				if fw.size+size >= stateChunkSize {
+					fw.add(entry)
+					if err := fw.flush(); err != nil {
+						stIt.Release()
+						return nil, err
+					}
+					entry, size = stateChunkAccount{Hash: accIt.Hash()}, common.HashLength
+				}
+				entry.Slots = append(entry.Slots, stateChunkSlot{Hash: stIt.Hash(), Value: common.CopyBytes(stIt.Slot())})
+				size += common.HashLength + len(stIt.Slot())
+				fw.manifest.Slots++
+			}
+			err = stIt.Error()
+			stIt.Release()
+			if err != nil {
+				return nil, err
+			}
+		}
+		fw.add(entry)
+		if fw.size >= stateChunkSize {
+			if err := fw.flush(); err != nil {
+				return nil, err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting state in progress", "at", accIt.Hash(), "accounts", fw.manifest.Accounts,
+				"slots", fw.manifest.Slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return nil, err
+	}
+	if err := fw.flush(); err != nil {
+		return nil, err
+	}
+	// Append the manifest along with the index pointing to it
+	blob, err := rlp.EncodeToBytes(fw.manifest)
+	if err != nil {
+		return nil, err
+	}
+	var index [8]byte
+	binary.LittleEndian.PutUint64(index[:], fw.offset)
+	if err := fw.write(stateFileTypeManifest, blob); err != nil {
+		return nil, err
+	}
+	if err := fw.write(stateFileTypeIndex, index[:]); err != nil {
+		return nil, err
+	}
+	return fw.manifest, nil
+}
+
+// ReadStateManifest reads the manifest of a state file of the given size.
+func ReadStateManifest(r io.ReaderAt, size int64) (*StateManifest, error) {
+	reader := e2store.NewReader(r)
+	if version, _, err := reader.ReadAt(0); err != nil {
+		return nil, err
+	} else if version.Type != stateFileTypeVersion {
+		return nil, errors.New("not a state file")
+	}
+	if size < stateFileIndexSize {
+		return nil, errors.New("state file truncated")
+	}
+	index, _, err := reader.ReadAt(size - stateFileIndexSize)
+	if err != nil {
+		return nil, err
+	}
+	if index.Type != stateFileTypeIndex || len(index.Value) != 8 {
+		return nil, errors.New("state file index missing")
+	}
+	entry, _, err := reader.ReadAt(int64(binary.LittleEndian.Uint64(index.Value)))
+	if err != nil {
+		return nil, err
+	}
+	if entry.Type != stateFileTypeManifest {
+		return nil, errors.New("state file manifest missing")
+	}
+	manifest := new(StateManifest)
+	if err := rlp.DecodeBytes(entry.Value, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// readStateChunk reads a chunk of a state file, verifying its hash.
+func readStateChunk(reader *e2store.Reader, chunk StateChunk) ([]stateChunkAccount, error) {
+	entry, _, err := reader.ReadAt(int64(chunk.Offset))
+	if err != nil {
+		return nil, err
+	}
+	if entry.Type != stateFileTypeChunk {
+		return nil, fmt.Errorf("unexpected entry type %#x", entry.Type)
+	}
+	if hash := crypto.Keccak256Hash(entry.Value); hash != chunk.Hash {
+		return nil, fmt.Errorf("hash mismatch: have %x, want %x", hash, chunk.Hash)
+	}
+	blob, err := snappy.Decode(nil, entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []stateChunkAccount
+	if err := rlp.DecodeBytes(blob, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// ImportState writes the flat state of a state file into the snapshot and
+// rebuilds the state trie from it. The storage roots of all accounts and the
+// state root are verified against the manifest, and only then the snapshot is
+// marked as complete at the manifest root.
+func ImportState(db ethdb.Database, scheme string, r io.ReaderAt, manifest *StateManifest, interrupt chan struct{}) error {
+	var (
+		reader = e2store.NewReader(r)
+		batch  = db.NewBatch()
+		nodeFn = func(owner common.Hash, path []byte, hash common.Hash, blob []byte) {
+			rawdb.WriteTrieNode(batch, owner, path, hash, blob, scheme)
+		}
+		accTrie = trie.NewStackTrie(nodeFn)
+
+		current  *types.StateAccount // Account of the storage being imported
+		stTrie   *trie.StackTrie     // Storage trie of the current account
+		prevAcc  common.Hash
+		prevSlot common.Hash
+
+		accounts, slots, codes uint64
+
+		start  = time.Now()
+		logged = time.Now()
+	)
+	// finish verifies the storage root of the current account and inserts it
+	// into the account trie.
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		root := types.EmptyRootHash
+		if stTrie != nil {
+			var err error
+			if root, err = stTrie.Commit(); err != nil {
+				return err
+			}
+		}
+		if root != current.Root {
+			return fmt.Errorf("account %x: storage root mismatch: have %x, want %x", prevAcc, root, current.Root)
+		}
+		blob, err := rlp.EncodeToBytes(current)
+		if err != nil {
+			return err
+		}
+		current, stTrie = nil, nil
+		return accTrie.Update(prevAcc[:], blob)
+	}
+	for i, chunk := range manifest.Chunks {
+		select {
+		case <-interrupt:
+			return errStateFileInterrupted
+		default:
+		}
+		entries, err := readStateChunk(reader, chunk)
+		if err != nil {
+			return fmt.Errorf("chunk %d: %w", i, err)
+		}
+		for _, entry := range entries {
+			if len(entry.Account) == 0 {
+				// Continued storage of the current account
+				if current == nil || entry.Hash != prevAcc {
+					return fmt.Errorf("chunk %d: unexpected storage continuation of account %x", i, entry.Hash)
+				}
+			} else {
+				if err := finish(); err != nil {
+					return err
+				}
+				if accounts > 0 && bytes.Compare(entry.Hash[:], prevAcc[:]) <= 0 {
+					return fmt.Errorf("chunk %d: account %x out of order", i, entry.Hash)
+				}
+				if current, err = types.FullAccount(entry.Account); err != nil {
+					return fmt.Errorf("chunk %d: account %x: %w", i, entry.Hash, err)
+				}
+				rawdb.WriteAccountSnapshot(batch, entry.Hash, entry.Account)
+				prevAcc, prevSlot = entry.Hash, common.Hash{}
+				accounts++
+
+				if len(entry.Code) > 0 {
+					codeHash := crypto.Keccak256Hash(entry.Code)
+					if codeHash != common.BytesToHash(current.CodeHash) {
+						return fmt.Errorf("account %x: code hash mismatch: have %x, want %x", entry.Hash, codeHash, current.CodeHash)
+					}
+					rawdb.WriteCode(batch, codeHash, entry.Code)
+					codes++
+				}
+			}
+			for j, slot := range entry.Slots {
+				if (j > 0 || stTrie != nil) && bytes.Compare(slot.Hash[:], prevSlot[:]) <= 0 {
+					return fmt.Errorf("account %x: slot %x out of order", entry.Hash, slot.Hash)
+				}
+				if len(slot.Value) == 0 {
+					return fmt.Errorf("account %x: slot %x empty", entry.Hash, slot.Hash)
+				}
+				if stTrie == nil {
+					stTrie = trie.NewStackTrieWithOwner(nodeFn, entry.Hash)
+				}
+				if err := stTrie.Update(slot.Hash[:], slot.Value); err != nil {
+					return err
+				}
+				rawdb.WriteStorageSnapshot(batch, entry.Hash, slot.Hash, slot.Value)
+				prevSlot = slot.Hash
+				slots++
+			}
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing state in progress", "chunk", i, "chunks", len(manifest.Chunks),
+				"accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := finish(); err != nil {
+		return err
+	}
+	if accounts != manifest.Accounts || slots != manifest.Slots || codes != manifest.Codes {
+		return fmt.Errorf("state size mismatch: have %d accounts, %d slots, %d codes, want %d, %d, %d",
+			accounts, slots, codes, manifest.Accounts, manifest.Slots, manifest.Codes)
+	}
+	root, err := accTrie.Commit()
+	if err != nil {
+		return err
+	}
+	if root != manifest.Root {
+		return fmt.Errorf("state root mismatch: have %x, want %x", root, manifest.Root)
+	}
+	// The state is complete, mark the snapshot as generated
+	rawdb.WriteSnapshotRoot(batch, root)
+	journalProgress(batch, nil, nil)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Imported state", "root", root, "accounts", accounts, "slots", slots, "codes", codes,
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,423 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/state"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/ethdb/memorydb"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/rlp"
+	"github.com/yuriy0803/core-geth1/trie"
+	"github.com/yuriy0803/core-geth1/trie/trienode"
+	"github.com/yuriy0803/core-geth1/trie/triestate"
+)
+
+const (
+	// stateDiffCheckpoint is the maximum number of blocks between two states
+	// flushed to disk when recording state diffs, bounding the number of diffs
+	// to revert for reconstructing any historical state.
+	stateDiffCheckpoint = 1024
+
+	// stateDiffRevertLimit is the maximum number of state diffs reverted for
+	// reconstructing a historical state.
+	stateDiffRevertLimit = 2 * stateDiffCheckpoint
+
+	// stateDiffFreezeBatch is the maximum number of state diffs moved into the
+	// state diff freezer at once.
+	stateDiffFreezeBatch = 1024
+)
+
+var errStateDiffUnavailable = errors.New("historical state not available")
+
+// stateDiff is the reverse diff of a state transition, holding the original
+// values of all the mutated accounts and storage slots.
+type stateDiff struct {
+	Parent     common.Hash // State root before the transition
+	Accounts   []stateDiffAccount
+	Incomplete []common.Address // Accounts whose storage was too large to record
+}
+
+// stateDiffAccount is the original value of a mutated account in 'slim RLP'
+// encoding, or empty if the account was not present.
+type stateDiffAccount struct {
+	Address common.Address
+	Blob    []byte
+	Slots   []stateDiffSlot
+}
+
+// stateDiffSlot is the original value of a mutated storage slot in prefix-zero
+// trimmed RLP encoding, or empty if the slot was not present.
+type stateDiffSlot struct {
+	Hash common.Hash
+	Blob []byte
+}
+
+// newStateDiff flattens the original values of a state transition into a state
+// diff in deterministic order.
+func newStateDiff(parent common.Hash, states *triestate.Set) *stateDiff {
+	diff := &stateDiff{Parent: parent}
+	for addr, blob := range states.Accounts {
+		account := stateDiffAccount{Address: addr, Blob: blob}
+		for hash, blob := range states.Storages[addr] {
+			account.Slots = append(account.Slots, stateDiffSlot{Hash: hash, Blob: blob})
+		}
+		sort.Slice(account.Slots, func(i, j int) bool {
+			return bytes.Compare(account.Slots[i].Hash[:], account.Slots[j].Hash[:]) < 0
+		})
+		diff.Accounts = append(diff.Accounts, account)
+	}
+	sort.Slice(diff.Accounts, func(i, j int) bool {
+		return bytes.Compare(diff.Accounts[i].Address[:], diff.Accounts[j].Address[:]) < 0
+	})
+	for addr := range states.Incomplete {
+		diff.Incomplete = append(diff.Incomplete, addr)
+	}
+	sort.Slice(diff.Incomplete, func(i, j int) bool {
+		return bytes.Compare(diff.Incomplete[i][:], diff.Incomplete[j][:]) < 0
+	})
+	return diff
+}
+
+// sets returns the original values of the accounts and storage slots in the
+// format expected by triestate.Apply.
+func (diff *stateDiff) sets() (map[common.Address][]byte, map[common.Address]map[common.Hash][]byte) {
+	var (
+		accounts = make(map[common.Address][]byte, len(diff.Accounts))
+		storages = make(map[common.Address]map[common.Hash][]byte)
+	)
+	for _, account := range diff.Accounts {
+		accounts[account.Address] = account.Blob
+		if len(account.Slots) == 0 {
+			continue
+		}
+		slots := make(map[common.Hash][]byte, len(account.Slots))
+		for _, slot := range account.Slots {
+			slots[slot.Hash] = slot.Blob
+		}
+		storages[account.Address] = slots
+	}
+	return accounts, storages
+}
+
+// writeStateDiff stores the reverse diff of the state transition of a block from
+// parent to root, overwriting any previous transition into the same root.
+func writeStateDiff(db ethdb.KeyValueWriter, number uint64, root common.Hash, parent common.Hash, states *triestate.Set) {
+	blob, err := rlp.EncodeToBytes(newStateDiff(parent, states))
+	if err != nil {
+		panic(err) // can't happen, all the fields are encodable
+	}
+	rawdb.WriteStateDiff(db, number, root, blob)
+}
+
+// readStateDiff retrieves the reverse diff of the state transition of a block
+// into root, from the key-value store or else the state diff freezer, which
+// only holds the diffs of canonical blocks.
+func (bc *BlockChain) readStateDiff(number uint64, root common.Hash) (*stateDiff, error) {
+	blob := rawdb.ReadStateDiff(bc.db, number, root)
+	if len(blob) == 0 && bc.diffs != nil {
+		if base := rawdb.ReadStateDiffFreezerBase(bc.db); base != nil && number >= *base {
+			blob = rawdb.ReadFrozenStateDiff(bc.diffs, number-*base)
+		}
+	}
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("%w: state diff into %x missing", errStateDiffUnavailable, root)
+	}
+	diff := new(stateDiff)
+	if err := rlp.DecodeBytes(blob, diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// maintainStateDiffs is responsible for moving the state diffs of the blocks
+// frozen into the ancient store to the state diff freezer, so they don't stay in
+// the key-value store forever, and for dropping them along with the expired
+// history.
+func (bc *BlockChain) maintainStateDiffs() {
+	defer bc.wg.Done()
+
+	headCh := make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		if err := bc.freezeStateDiffs(); err != nil {
+			log.Error("Failed to freeze state diffs", "err", err)
+		}
+		select {
+		case <-headCh:
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// freezeStateDiffs moves the state diffs of the canonical blocks in the ancient
+// store into the state diff freezer, and deletes all the diffs at these heights
+// from the key-value store. The frozen diffs below the history tail are dropped.
+func (bc *BlockChain) freezeStateDiffs() error {
+	bc.diffsLock.Lock()
+	defer bc.diffsLock.Unlock()
+
+	frozen, err := bc.db.Ancients()
+	if err != nil {
+		return err
+	}
+	base := rawdb.ReadStateDiffFreezerBase(bc.db)
+	if base == nil {
+		// Start with the oldest diff recorded, older blocks don't have any
+		tail := rawdb.ReadStateDiffTail(bc.db)
+		if tail == nil || *tail >= frozen {
+			return nil
+		}
+		if history := bc.HistoryTail(); *tail < history {
+			*tail = history
+		}
+		rawdb.WriteStateDiffFreezerBase(bc.db, *tail)
+		base = tail
+	}
+	items, err := bc.diffs.Ancients()
+	if err != nil {
+		return err
+	}
+	// Drop the diffs of the blocks unfrozen by a rewind of the chain
+	if *base+items > frozen {
+		var head uint64
+		if frozen > *base {
+			head = frozen - *base
+		}
+		if _, err := bc.diffs.TruncateHead(head); err != nil {
+			return err
+		}
+		items = head
+	}
+	for next := *base + items; next < frozen; {
+		select {
+		case <-bc.quit:
+			return nil
+		default:
+		}
+		limit := next + stateDiffFreezeBatch
+		if limit > frozen {
+			limit = frozen
+		}
+		// Blocks without state transition have no diff, store them empty
+		diffs := make([][]byte, 0, limit-next)
+		for number := next; number < limit; number++ {
+			header := bc.GetHeaderByNumber(number)
+			if header == nil {
+				return fmt.Errorf("header %d missing", number)
+			}
+			diffs = append(diffs, rawdb.ReadStateDiff(bc.db, number, header.Root))
+		}
+		if err := rawdb.WriteFrozenStateDiffs(bc.diffs, next-*base, diffs); err != nil {
+			return err
+		}
+		if err := bc.diffs.Sync(); err != nil {
+			return err
+		}
+		rawdb.DeleteStateDiffs(bc.db, limit)
+		items, next = items+uint64(len(diffs)), limit
+	}
+	// Delete the diffs of the side chains and any left over by a crash
+	rawdb.DeleteStateDiffs(bc.db, frozen)
+
+	if tail := bc.HistoryTail(); tail > *base {
+		if tail-*base > items {
+			tail = *base + items
+		}
+		if _, err := bc.diffs.TruncateTail(tail - *base); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revertedNodeDB is a database serving the trie nodes produced by reverting
+// state diffs from memory, and any other trie node retained by the chain from
+// the live trie database.
+type revertedNodeDB struct {
+	ethdb.Database
+	nodes *memorydb.Database // Trie nodes of the reverted states
+	live  trie.Reader        // Reader of the trie nodes retained by the chain
+}
+
+// Has retrieves if a key is present in the reverted nodes, the trie nodes
+// retained in memory or the chain database.
+func (db *revertedNodeDB) Has(key []byte) (bool, error) {
+	if ok, _ := db.nodes.Has(key); ok {
+		return true, nil
+	}
+	if len(key) == common.HashLength {
+		if blob, _ := db.live.Node(common.Hash{}, nil, common.BytesToHash(key)); len(blob) > 0 {
+			return true, nil
+		}
+	}
+	return db.Database.Has(key)
+}
+
+// Get retrieves the given key from the reverted nodes, the trie nodes retained
+// in memory or the chain database.
+func (db *revertedNodeDB) Get(key []byte) ([]byte, error) {
+	if blob, err := db.nodes.Get(key); err == nil {
+		return blob, nil
+	}
+	if len(key) == common.HashLength {
+		if blob, _ := db.live.Node(common.Hash{}, nil, common.BytesToHash(key)); len(blob) > 0 {
+			return blob, nil
+		}
+	}
+	return db.Database.Get(key)
+}
+
+// revertedTrie adapts a trie to the interface expected by triestate.Apply.
+type revertedTrie struct {
+	*trie.Trie
+}
+
+// Commit collects all dirty nodes in the trie, returning the new root hash. An
+// empty hash is returned in case of failure, failing the root verification.
+func (t revertedTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet) {
+	root, nodes, err := t.Trie.Commit(collectLeaf)
+	if err != nil {
+		return common.Hash{}, nil
+	}
+	return root, nodes
+}
+
+// revertedTrieLoader opens the tries of the states being reverted.
+type revertedTrieLoader struct {
+	db *trie.Database
+}
+
+// OpenTrie opens the main account trie.
+func (l *revertedTrieLoader) OpenTrie(root common.Hash) (triestate.Trie, error) {
+	tr, err := trie.New(trie.StateTrieID(root), l.db)
+	if err != nil {
+		return nil, err
+	}
+	return revertedTrie{tr}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account.
+func (l *revertedTrieLoader) OpenStorageTrie(stateRoot common.Hash, addrHash, root common.Hash) (triestate.Trie, error) {
+	tr, err := trie.New(trie.StorageTrieID(stateRoot, addrHash, root), l.db)
+	if err != nil {
+		return nil, err
+	}
+	return revertedTrie{tr}, nil
+}
+
+// StateAtHeader returns a mutable state based on the state root of the given
+// header. If the state was pruned but the chain records state diffs, it is
+// reconstructed in memory by reverting the diffs from the nearest newer state
+// retained.
+func (bc *BlockChain) StateAtHeader(header *types.Header) (*state.StateDB, error) {
+	statedb, err := bc.StateAt(header.Root)
+	if err == nil || !bc.cacheConfig.StateDiffs {
+		return statedb, err
+	}
+	statedb, revertErr := bc.revertState(header)
+	if revertErr != nil {
+		return nil, fmt.Errorf("%v (%v)", err, revertErr)
+	}
+	return statedb, nil
+}
+
+// revertState reconstructs the state of a canonical block by reverting the state
+// diffs from the nearest newer state retained by the chain.
+func (bc *BlockChain) revertState(header *types.Header) (*state.StateDB, error) {
+	number := header.Number.Uint64()
+	if bc.GetCanonicalHash(number) != header.Hash() {
+		return nil, fmt.Errorf("%w: block %d is not canonical", errStateDiffUnavailable, number)
+	}
+	// Trie nodes are read by hash from the live trie database
+	if bc.triedb.Scheme() != rawdb.HashScheme {
+		return nil, fmt.Errorf("%w: unsupported state scheme %s", errStateDiffUnavailable, bc.triedb.Scheme())
+	}
+	// Find the nearest retained state, collecting the headers to revert
+	var (
+		head    = bc.CurrentBlock().Number.Uint64()
+		headers []*types.Header
+	)
+	for n := number + 1; ; n++ {
+		if n > head || n-number > stateDiffRevertLimit {
+			return nil, fmt.Errorf("%w: no retained state within %d blocks", errStateDiffUnavailable, stateDiffRevertLimit)
+		}
+		next := bc.GetHeaderByNumber(n)
+		if next == nil {
+			return nil, fmt.Errorf("%w: header %d missing", errStateDiffUnavailable, n)
+		}
+		headers = append(headers, next)
+		if bc.HasState(next.Root) {
+			break
+		}
+	}
+	base := headers[len(headers)-1]
+	live, err := bc.triedb.Reader(base.Root)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		db     = &revertedNodeDB{Database: bc.db, nodes: memorydb.New(), live: live}
+		loader = &revertedTrieLoader{db: trie.NewDatabase(db)}
+	)
+	for i := len(headers) - 1; i >= 0; i-- {
+		post, prev := headers[i], header
+		if i > 0 {
+			prev = headers[i-1]
+		}
+		if post.Root == prev.Root {
+			continue // Empty transition, no diff recorded
+		}
+		diff, err := bc.readStateDiff(post.Number.Uint64(), post.Root)
+		if err != nil {
+			return nil, err
+		}
+		if diff.Parent != prev.Root {
+			return nil, fmt.Errorf("%w: state diff of block %d has parent %x, want %x", errStateDiffUnavailable, post.Number, diff.Parent, prev.Root)
+		}
+		if len(diff.Incomplete) > 0 {
+			return nil, fmt.Errorf("%w: state diff of block %d incomplete", errStateDiffUnavailable, post.Number)
+		}
+		accounts, storages := diff.sets()
+		nodes, err := triestate.Apply(prev.Root, post.Root, accounts, storages, loader)
+		if err != nil {
+			return nil, err
+		}
+		for _, subset := range nodes {
+			for _, node := range subset {
+				if !node.IsDeleted() {
+					rawdb.WriteLegacyTrieNode(db.nodes, node.Hash, node.Blob)
+				}
+			}
+		}
+	}
+	return state.New(header.Root, state.NewDatabase(db), nil)
+}
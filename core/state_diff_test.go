@@ -0,0 +1,181 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/consensus/ethash"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/core/vm"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/params"
+	"github.com/yuriy0803/core-geth1/params/types/genesisT"
+	"github.com/yuriy0803/core-geth1/params/vars"
+)
+
+var stateDiffTestContract = common.HexToAddress("0x1000")
+
+// newStateDiffTestChain generates a chain creating a new account and updating
+// the storage of a contract with the block number in every block.
+func newStateDiffTestChain(blocks int) (*genesisT.Genesis, []*types.Block) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: genesisT.GenesisAlloc{
+				address: {Balance: big.NewInt(100000000000000000)},
+				// NUMBER PUSH1 0 SSTORE STOP
+				stateDiffTestContract: {Balance: common.Big0, Code: []byte{0x43, 0x60, 0x00, 0x55, 0x00}},
+			},
+			BaseFee: big.NewInt(vars.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, chain, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), blocks, func(i int, block *BlockGen) {
+		transfer, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.BigToAddress(big.NewInt(int64(0x2000+i))), big.NewInt(1000), vars.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(transfer)
+		call, err := types.SignTx(types.NewTransaction(block.TxNonce(address), stateDiffTestContract, common.Big0, 100000, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(call)
+	})
+	return gspec, chain
+}
+
+// checkRevertedState checks the state of a block reconstructed from state diffs.
+func checkRevertedState(t *testing.T, bc *BlockChain, number uint64) {
+	t.Helper()
+
+	header := bc.GetHeaderByNumber(number)
+	if _, err := bc.StateAt(header.Root); err == nil {
+		t.Fatalf("state of block %d not pruned", number)
+	}
+	statedb, err := bc.StateAtHeader(header)
+	if err != nil {
+		t.Fatalf("failed to reconstruct state of block %d: %v", number, err)
+	}
+	if have := statedb.GetState(stateDiffTestContract, common.Hash{}); have != common.BigToHash(header.Number) {
+		t.Fatalf("block %d: wrong storage: have %x", number, have)
+	}
+	if root := statedb.IntermediateRoot(true); root != header.Root {
+		t.Fatalf("block %d: wrong state root: have %x, want %x", number, root, header.Root)
+	}
+	created := common.BigToAddress(big.NewInt(int64(0x2000 + number - 1)))
+	if !statedb.Exist(created) {
+		t.Fatalf("block %d: created account missing", number)
+	}
+	if statedb.Exist(common.BigToAddress(big.NewInt(int64(0x2000 + number)))) {
+		t.Fatalf("block %d: future account present", number)
+	}
+}
+
+func TestStateDiffRevert(t *testing.T) {
+	gspec, chain := newStateDiffTestChain(2 * TriesInMemory)
+
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.StateDiffs = true
+	bc, err := NewBlockChain(rawdb.NewMemoryDatabase(), &cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer bc.Stop()
+
+	if _, err := bc.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, number := range []uint64{1, 64, TriesInMemory - 1} {
+		checkRevertedState(t, bc, number)
+	}
+	// Without state diffs pruned states are not available.
+	bc.cacheConfig.StateDiffs = false
+	if _, err := bc.StateAtHeader(bc.GetHeaderByNumber(1)); err == nil {
+		t.Fatal("pruned state available without state diffs")
+	}
+}
+
+func TestStateDiffFreezer(t *testing.T) {
+	gspec, chain := newStateDiffTestChain(2 * TriesInMemory)
+
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.StateDiffs = true
+	bc, err := NewBlockChain(db, &cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer bc.Stop()
+
+	if _, err := bc.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Freeze the blocks older than 64 blocks and their state diffs.
+	type freezer interface {
+		Freeze(threshold uint64) error
+	}
+	if err := db.(freezer).Freeze(64); err != nil {
+		t.Fatalf("failed to freeze blocks: %v", err)
+	}
+	frozen, _ := db.Ancients()
+	if err := bc.freezeStateDiffs(); err != nil {
+		t.Fatalf("failed to freeze state diffs: %v", err)
+	}
+	if base := rawdb.ReadStateDiffFreezerBase(db); base == nil {
+		t.Fatal("state diff freezer base missing")
+	} else if *base != 1 {
+		t.Fatalf("wrong state diff freezer base: have %d, want 1", *base)
+	}
+	if items, _ := bc.diffs.Ancients(); items != frozen-1 {
+		t.Fatalf("wrong number of frozen state diffs: have %d, want %d", items, frozen-1)
+	}
+	for _, block := range chain {
+		number := block.NumberU64()
+		if blob := rawdb.ReadStateDiff(db, number, block.Root()); (len(blob) == 0) != (number < frozen) {
+			t.Fatalf("block %d: state diff presence in key-value store mismatch: have %v, want %v", number, len(blob) != 0, number >= frozen)
+		}
+	}
+	for _, number := range []uint64{1, 64, TriesInMemory - 1} {
+		checkRevertedState(t, bc, number)
+	}
+	// The frozen state diffs are dropped along with the expired history.
+	if _, err := db.TruncateTail(100); err != nil {
+		t.Fatalf("failed to drop history: %v", err)
+	}
+	if err := bc.freezeStateDiffs(); err != nil {
+		t.Fatalf("failed to freeze state diffs: %v", err)
+	}
+	if tail, _ := bc.diffs.Tail(); tail != 99 {
+		t.Fatalf("wrong state diff freezer tail: have %d, want 99", tail)
+	}
+	if _, err := bc.StateAtHeader(bc.GetHeaderByNumber(64)); err == nil {
+		t.Fatal("state reconstructed from dropped state diffs")
+	}
+	checkRevertedState(t, bc, TriesInMemory-1)
+}
@@ -25,7 +25,9 @@ import (
 	"strings"
 
 	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/forkid"
 	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/eth/protocols/eth"
 	"github.com/yuriy0803/core-geth1/rlp"
 	"github.com/yuriy0803/core-geth1/rpc"
 )
@@ -148,6 +150,25 @@ func (api *AdminAPI) Ecbp1100(blockNr rpc.BlockNumber) (bool, error) {
 			api.eth.blockchain.CurrentBlock().Number), err
 }
 
+// ForkidReport explains, for each of the given remote fork IDs, which local fork
+// checksum it matches and which fork splits the chains at the current head.
+// Fork IDs are given as <hash>[:<next>], or as "enr:" node records advertising
+// the `eth` protocol.
+func (api *AdminAPI) ForkidReport(ids []string) ([]*forkid.Report, error) {
+	chain := api.eth.BlockChain()
+	head := chain.CurrentHeader()
+
+	reports := make([]*forkid.Report, 0, len(ids))
+	for _, s := range ids {
+		id, err := eth.ParseForkID(s)
+		if err != nil {
+			return nil, fmt.Errorf("fork id %q: %w", s, err)
+		}
+		reports = append(reports, forkid.NewReport(chain.Config(), chain.Genesis().Hash(), head.Number.Uint64(), head.Time, id))
+	}
+	return reports, nil
+}
+
 // MaxPeers sets the maximum peer limit for the protocol manager and the p2p server.
 func (api *AdminAPI) MaxPeers(n int) (bool, error) {
 	api.eth.handler.maxPeers = n
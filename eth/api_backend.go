@@ -207,7 +207,7 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 	if header == nil {
 		return nil, nil, errors.New("header not found")
 	}
-	stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+	stateDb, err := b.eth.BlockChain().StateAtHeader(header)
 	return stateDb, header, err
 }
 
@@ -226,7 +226,7 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 		if blockNrOrHash.RequireCanonical && b.eth.blockchain.GetCanonicalHash(header.Number.Uint64()) != hash {
 			return nil, nil, errors.New("hash is not currently canonical")
 		}
-		stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+		stateDb, err := b.eth.BlockChain().StateAtHeader(header)
 		return stateDb, header, err
 	}
 	return nil, nil, errors.New("invalid arguments; neither block nor hash specified")
@@ -382,11 +382,25 @@ func (b *EthAPIBackend) RPCTxFeeCap() float64 {
 	return b.eth.config.RPCTxFeeCap
 }
 
+func (b *EthAPIBackend) RPCTraceFilterCap() uint64 {
+	return b.eth.config.RPCTraceFilterCap
+}
+
 func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.eth.bloomIndexer.Sections()
 	return vars.BloomBitsBlocks, sections
 }
 
+// TraceIndexStatus returns the section size of the trace index, and the number
+// of sections indexed so far.
+func (b *EthAPIBackend) TraceIndexStatus() (uint64, uint64) {
+	if b.eth.traceIndexer == nil {
+		return vars.TraceIndexBlocks, 0
+	}
+	sections, _, _ := b.eth.traceIndexer.Sections()
+	return vars.TraceIndexBlocks, sections
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
@@ -401,6 +415,12 @@ func (b *EthAPIBackend) CurrentHeader() *types.Header {
 	return b.eth.blockchain.CurrentHeader()
 }
 
+// ArtificialFinalityEnabled returns whether the ECBP1100 (MESS) artificial
+// finality is currently active.
+func (b *EthAPIBackend) ArtificialFinalityEnabled() bool {
+	return b.eth.blockchain.IsArtificialFinalityActive()
+}
+
 func (b *EthAPIBackend) Miner() *miner.Miner {
 	return b.eth.Miner()
 }
@@ -17,6 +17,7 @@
 package eth
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -24,14 +25,18 @@ import (
 
 	"github.com/yuriy0803/core-geth1/common"
 	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/core"
 	"github.com/yuriy0803/core-geth1/core/rawdb"
 	"github.com/yuriy0803/core-geth1/core/state"
 	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/ethdb"
 	"github.com/yuriy0803/core-geth1/internal/ethapi"
 	"github.com/yuriy0803/core-geth1/log"
 	"github.com/yuriy0803/core-geth1/rlp"
 	"github.com/yuriy0803/core-geth1/rpc"
 	"github.com/yuriy0803/core-geth1/trie"
+	"golang.org/x/exp/slices"
 )
 
 // DebugAPI is the collection of Ethereum full node APIs for debugging the
@@ -257,6 +262,12 @@ func storageRangeAt(st state.Trie, start []byte, maxResult int) (StorageRangeRes
 	return result, nil
 }
 
+// Ecbp1100Decisions returns the recent ECBP1100 (MESS) artificial finality
+// decisions on reorgs, oldest first, explaining why each was accepted or rejected.
+func (api *DebugAPI) Ecbp1100Decisions() []*core.ECBP1100Decision {
+	return api.eth.blockchain.ECBP1100Decisions()
+}
+
 // GetModifiedAccountsByNumber returns all accounts that have changed between the
 // two blocks specified. A change is defined as a difference in nonce, balance,
 // code hash, or storage hash.
@@ -348,6 +359,429 @@ func (api *DebugAPI) getModifiedAccounts(startBlock, endBlock *types.Block) ([]c
 	return dirty, nil
 }
 
+const (
+	// StateDiffMaxResults is the maximum number of accounts returned per call of
+	// debug_getStateDiff.
+	StateDiffMaxResults = 256
+
+	// StateDiffMaxStorageResults is the maximum number of storage slots returned
+	// per account and call of debug_getStateDiff.
+	StateDiffMaxStorageResults = 1024
+)
+
+const (
+	// defaultStateDiffTimeout is the default runtime limit of a state diff call.
+	defaultStateDiffTimeout = 5 * time.Second
+
+	// maxStateDiffTimeout is the maximum runtime limit of a state diff call.
+	maxStateDiffTimeout = time.Minute
+)
+
+// StateDiffConfig holds the paging and runtime limits of debug_getStateDiff.
+type StateDiffConfig struct {
+	Start        *common.Hash `json:"start"`        // Hash of the account to start from
+	StartSlot    *common.Hash `json:"startSlot"`    // Hash of the storage slot of the start account to start from
+	Limit        int          `json:"limit"`        // Maximum number of accounts returned
+	StorageLimit int          `json:"storageLimit"` // Maximum number of storage slots returned per account
+	Timeout      *string      `json:"timeout"`      // Runtime limit, 5s by default
+}
+
+// StateDiffResult is the result of a debug_getStateDiff API call.
+type StateDiffResult struct {
+	Accounts []*AccountDiff `json:"accounts"`
+	Next     *common.Hash   `json:"next"`     // Hash of the account to continue from, nil if complete
+	NextSlot *common.Hash   `json:"nextSlot"` // Hash of the storage slot of the next account to continue from, nil to start with its first slot
+}
+
+// AccountDiff is the change of an account between two states.
+type AccountDiff struct {
+	Address *common.Address   `json:"address"` // nil if the preimage is unknown
+	Hash    common.Hash       `json:"hash"`
+	Before  *AccountDiffState `json:"before"` // nil if the account was created
+	After   *AccountDiffState `json:"after"`  // nil if the account was deleted
+	Storage storageDiffMap    `json:"storage"`
+}
+
+// AccountDiffState is the state of an account on one side of a diff.
+type AccountDiffState struct {
+	Balance  *hexutil.Big   `json:"balance"`
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	CodeHash common.Hash    `json:"codeHash"`
+}
+
+type storageDiffMap map[common.Hash]storageDiffEntry
+
+type storageDiffEntry struct {
+	Key    *common.Hash `json:"key"` // nil if the preimage is unknown
+	Before common.Hash  `json:"before"`
+	After  common.Hash  `json:"after"`
+}
+
+// GetStateDiff returns the accounts changed between the states of two blocks,
+// with their balance, nonce and code hash before and after, along with their
+// changed storage slots. If addresses are given, only those are compared.
+//
+// The accounts are returned in the order of their hashes, at most limit of them
+// per call, and the storage slots of an account at most storageLimit of them per
+// call. Calls exceeding the runtime limit return the accounts diffed so far. The
+// account and storage slot hashes to continue from are returned along with the
+// results if incomplete, an account being split across calls if its storage is.
+func (api *DebugAPI) GetStateDiff(ctx context.Context, from, to rpc.BlockNumberOrHash, addresses *[]common.Address, config *StateDiffConfig) (*StateDiffResult, error) {
+	var (
+		start     common.Hash
+		startSlot common.Hash
+		limit     = StateDiffMaxResults
+		slots     = StateDiffMaxStorageResults
+		timeout   = defaultStateDiffTimeout
+		err       error
+	)
+	if config != nil {
+		if config.Start != nil {
+			start = *config.Start
+		}
+		if config.StartSlot != nil {
+			startSlot = *config.StartSlot
+		}
+		if config.Limit > 0 && config.Limit < StateDiffMaxResults {
+			limit = config.Limit
+		}
+		if config.StorageLimit > 0 && config.StorageLimit < StateDiffMaxStorageResults {
+			slots = config.StorageLimit
+		}
+		if config.Timeout != nil {
+			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+				return nil, err
+			}
+			if timeout > maxStateDiffTimeout {
+				timeout = maxStateDiffTimeout
+			}
+		}
+	}
+	oldTrie, err := api.stateTrie(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := api.stateTrie(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	differ := &stateDiffer{oldTrie: oldTrie, newTrie: newTrie, slots: slots, preimages: api.eth.ChainDb()}
+	if addresses != nil {
+		return differ.diffAddresses(ctx, *addresses, start, startSlot, limit)
+	}
+	return differ.diff(ctx, start, startSlot, limit)
+}
+
+// stateTrie opens the account trie of the state of the given block, which may
+// be reconstructed from state diffs.
+func (api *DebugAPI) stateTrie(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*stateDiffTrie, error) {
+	header, err := api.eth.APIBackend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	statedb, err := api.eth.blockchain.StateAtHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	return openStateDiffTrie(statedb.Database().TrieDB(), header.Root)
+}
+
+// stateDiffTrie is an account trie along with its database, to open storage
+// tries.
+type stateDiffTrie struct {
+	*trie.StateTrie
+	db   *trie.Database
+	root common.Hash
+}
+
+func openStateDiffTrie(db *trie.Database, root common.Hash) (*stateDiffTrie, error) {
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), db)
+	if err != nil {
+		return nil, err
+	}
+	return &stateDiffTrie{StateTrie: tr, db: db, root: root}, nil
+}
+
+// storageTrie opens the storage trie of an account, nil if not present.
+func (t *stateDiffTrie) storageTrie(hash common.Hash, account *types.StateAccount) (*trie.StateTrie, error) {
+	root := types.EmptyRootHash
+	if account != nil {
+		root = account.Root
+	}
+	return trie.NewStateTrie(trie.StorageTrieID(t.root, hash, root), t.db)
+}
+
+// stateDiffer diffs the accounts and storage of two states.
+type stateDiffer struct {
+	oldTrie   *stateDiffTrie
+	newTrie   *stateDiffTrie
+	slots     int                  // Maximum number of storage slots returned per account
+	preimages ethdb.KeyValueReader // Database of the preimages not known by the tries
+}
+
+// diff walks the accounts changed between the two states from the given hash,
+// the storage of the first account from the given slot hash.
+func (d *stateDiffer) diff(ctx context.Context, start, startSlot common.Hash, limit int) (*StateDiffResult, error) {
+	result := &StateDiffResult{Accounts: []*AccountDiff{}}
+	err := diffLeaves(d.oldTrie.StateTrie, d.newTrie.StateTrie, start[:], func(key, before, after []byte) (bool, error) {
+		hash := common.BytesToHash(key)
+		if len(result.Accounts) >= limit {
+			result.Next = &hash
+			return false, nil
+		}
+		prev, err := decodeDiffAccount(before)
+		if err != nil {
+			return false, err
+		}
+		post, err := decodeDiffAccount(after)
+		if err != nil {
+			return false, err
+		}
+		var from common.Hash
+		if hash == start {
+			from = startSlot
+		}
+		account, nextSlot, err := d.diffAccount(ctx, hash, prev, post, from)
+		if err != nil {
+			return false, d.interrupted(result, hash, err)
+		}
+		result.Accounts = append(result.Accounts, account)
+		if nextSlot != nil {
+			result.Next, result.NextSlot = &hash, nextSlot
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// diffAddresses compares the given accounts between the two states, in the order
+// of their hashes from the given one, the storage of that account from the given
+// slot hash.
+func (d *stateDiffer) diffAddresses(ctx context.Context, addresses []common.Address, start, startSlot common.Hash, limit int) (*StateDiffResult, error) {
+	hashes := make([]common.Hash, 0, len(addresses))
+	for _, addr := range addresses {
+		hashes = append(hashes, crypto.Keccak256Hash(addr.Bytes()))
+	}
+	slices.SortFunc(hashes, func(a, b common.Hash) int { return a.Cmp(b) })
+	hashes = slices.Compact(hashes)
+
+	result := &StateDiffResult{Accounts: []*AccountDiff{}}
+	for _, hash := range hashes {
+		if hash.Cmp(start) < 0 {
+			continue
+		}
+		if len(result.Accounts) >= limit {
+			result.Next = &hash
+			break
+		}
+		prev, err := d.oldTrie.GetAccountByHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		post, err := d.newTrie.GetAccountByHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil && post != nil && prev.Root == post.Root && prev.Nonce == post.Nonce &&
+			prev.Balance.Cmp(post.Balance) == 0 && bytes.Equal(prev.CodeHash, post.CodeHash) {
+			continue
+		}
+		if prev == nil && post == nil {
+			continue
+		}
+		var from common.Hash
+		if hash == start {
+			from = startSlot
+		}
+		account, nextSlot, err := d.diffAccount(ctx, hash, prev, post, from)
+		if err != nil {
+			if err := d.interrupted(result, hash, err); err != nil {
+				return nil, err
+			}
+			break
+		}
+		result.Accounts = append(result.Accounts, account)
+		if nextSlot != nil {
+			result.Next, result.NextSlot = &hash, nextSlot
+			break
+		}
+	}
+	return result, nil
+}
+
+// interrupted ends the result before the account whose diffing failed if the
+// runtime limit was exceeded, as long as some progress was made.
+func (d *stateDiffer) interrupted(result *StateDiffResult, hash common.Hash, err error) error {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if len(result.Accounts) == 0 {
+		return fmt.Errorf("runtime limit exceeded diffing account %x", hash)
+	}
+	result.Next = &hash
+	return nil
+}
+
+// diffAccount diffs an account along with its storage between the two states,
+// from the given slot hash. If the storage diff exceeds the slot limit, or the
+// runtime limit after some progress, the hash of the slot to continue from is
+// returned along with the partial diff.
+func (d *stateDiffer) diffAccount(ctx context.Context, hash common.Hash, prev, post *types.StateAccount, start common.Hash) (*AccountDiff, *common.Hash, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	diff := &AccountDiff{
+		Hash:    hash,
+		Before:  newAccountDiffState(prev),
+		After:   newAccountDiffState(post),
+		Storage: storageDiffMap{},
+	}
+	if preimage := d.preimage(hash[:]); preimage != nil {
+		addr := common.BytesToAddress(preimage)
+		diff.Address = &addr
+	}
+	oldStorage, err := d.oldTrie.storageTrie(hash, prev)
+	if err != nil {
+		return nil, nil, err
+	}
+	newStorage, err := d.newTrie.storageTrie(hash, post)
+	if err != nil {
+		return nil, nil, err
+	}
+	if oldStorage.Hash() == newStorage.Hash() {
+		return diff, nil, nil
+	}
+	var next *common.Hash
+	err = diffLeaves(oldStorage, newStorage, start[:], func(key, before, after []byte) (bool, error) {
+		if len(diff.Storage) >= d.slots {
+			slot := common.BytesToHash(key)
+			next = &slot
+			return false, nil
+		}
+		if err := ctx.Err(); err != nil {
+			if len(diff.Storage) == 0 {
+				return false, err
+			}
+			slot := common.BytesToHash(key)
+			next = &slot
+			return false, nil
+		}
+		entry := storageDiffEntry{}
+		if preimage := d.preimage(key); preimage != nil {
+			key := common.BytesToHash(preimage)
+			entry.Key = &key
+		}
+		for _, slot := range []struct {
+			blob  []byte
+			value *common.Hash
+		}{{before, &entry.Before}, {after, &entry.After}} {
+			if len(slot.blob) == 0 {
+				continue
+			}
+			_, content, _, err := rlp.Split(slot.blob)
+			if err != nil {
+				return false, err
+			}
+			*slot.value = common.BytesToHash(content)
+		}
+		diff.Storage[common.BytesToHash(key)] = entry
+		return true, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return diff, next, nil
+}
+
+// preimage returns the preimage of a trie key, nil if unknown.
+func (d *stateDiffer) preimage(key []byte) []byte {
+	if preimage := d.newTrie.GetKey(key); preimage != nil {
+		return preimage
+	}
+	return rawdb.ReadPreimage(d.preimages, common.BytesToHash(key))
+}
+
+func newAccountDiffState(account *types.StateAccount) *AccountDiffState {
+	if account == nil {
+		return nil
+	}
+	return &AccountDiffState{
+		Balance:  (*hexutil.Big)(account.Balance),
+		Nonce:    hexutil.Uint64(account.Nonce),
+		CodeHash: common.BytesToHash(account.CodeHash),
+	}
+}
+
+// decodeDiffAccount decodes an account trie leaf, nil if not present.
+func decodeDiffAccount(blob []byte) (*types.StateAccount, error) {
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	account := new(types.StateAccount)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// diffLeaves walks the leaves differing between two tries in key order from the
+// given key, calling fn with their old and new values, empty if not present,
+// until it returns false. The leaves are found with difference iterators in
+// both directions, skipping the subtries the two have in common.
+func diffLeaves(oldTrie, newTrie *trie.StateTrie, start []byte, fn func(key, before, after []byte) (bool, error)) error {
+	var iters [4]trie.NodeIterator
+	for i, tr := range []*trie.StateTrie{oldTrie, newTrie, newTrie, oldTrie} {
+		it, err := tr.NodeIterator(start)
+		if err != nil {
+			return err
+		}
+		iters[i] = it
+	}
+	var (
+		created, _ = trie.NewDifferenceIterator(iters[0], iters[1])
+		deleted, _ = trie.NewDifferenceIterator(iters[2], iters[3])
+		newIt      = trie.NewIterator(created)
+		oldIt      = trie.NewIterator(deleted)
+		hasNew     = newIt.Next()
+		hasOld     = oldIt.Next()
+	)
+	for hasNew || hasOld {
+		var key, before, after []byte
+		switch {
+		case !hasOld || (hasNew && bytes.Compare(newIt.Key, oldIt.Key) < 0):
+			key, after = common.CopyBytes(newIt.Key), common.CopyBytes(newIt.Value)
+			hasNew = newIt.Next()
+		case !hasNew || bytes.Compare(oldIt.Key, newIt.Key) < 0:
+			key, before = common.CopyBytes(oldIt.Key), common.CopyBytes(oldIt.Value)
+			hasOld = oldIt.Next()
+		default:
+			key, before, after = common.CopyBytes(newIt.Key), common.CopyBytes(oldIt.Value), common.CopyBytes(newIt.Value)
+			hasNew, hasOld = newIt.Next(), oldIt.Next()
+		}
+		// Leaves moved within the trie are visited without changing
+		if bytes.Equal(before, after) {
+			continue
+		}
+		if ok, err := fn(key, before, after); !ok || err != nil {
+			return err
+		}
+	}
+	if newIt.Err != nil {
+		return newIt.Err
+	}
+	return oldIt.Err
+}
+
 // GetAccessibleState returns the first number where the node has accessible
 // state on disk. Note this being the post-state of that block and the pre-state
 // of the next block.
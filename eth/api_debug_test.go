@@ -18,6 +18,7 @@ package eth
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -288,3 +289,162 @@ func BenchmarkTestValueEquivalenceAlot(b *testing.B) {
 	}
 }
 */
+
+func TestStateDiff(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sdb     = state.NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), &trie.Config{Preimages: true})
+		changed = common.Address{0x01}
+		deleted = common.Address{0x02}
+		same    = common.Address{0x03}
+		created = common.Address{0x04}
+	)
+	commit := func(fn func(statedb *state.StateDB)) common.Hash {
+		statedb, _ := state.New(types.EmptyRootHash, sdb, nil)
+		fn(statedb)
+		root, err := statedb.Commit(0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sdb.TrieDB().Commit(root, false); err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+	oldRoot := commit(func(statedb *state.StateDB) {
+		statedb.SetBalance(changed, big.NewInt(1))
+		statedb.SetState(changed, common.Hash{0x01}, common.Hash{0x01})
+		statedb.SetState(changed, common.Hash{0x02}, common.Hash{0x02})
+		statedb.SetBalance(deleted, big.NewInt(2))
+		statedb.SetNonce(same, 1)
+	})
+	newRoot := commit(func(statedb *state.StateDB) {
+		statedb.SetBalance(changed, big.NewInt(1))
+		statedb.SetState(changed, common.Hash{0x01}, common.Hash{0x03})
+		statedb.SetState(changed, common.Hash{0x03}, common.Hash{0x04})
+		statedb.SetNonce(same, 1)
+		statedb.SetBalance(created, big.NewInt(5))
+	})
+	oldTrie, err := openStateDiffTrie(sdb.TrieDB(), oldRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTrie, err := openStateDiffTrie(sdb.TrieDB(), newRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	differ := &stateDiffer{oldTrie: oldTrie, newTrie: newTrie, slots: StateDiffMaxStorageResults, preimages: rawdb.NewMemoryDatabase()}
+
+	result, err := differ.diff(context.Background(), common.Hash{}, common.Hash{}, StateDiffMaxResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Next != nil || len(result.Accounts) != 3 {
+		t.Fatalf("wrong result: %s", dumper.Sdump(result))
+	}
+	diffs := make(map[common.Address]*AccountDiff)
+	for i, account := range result.Accounts {
+		if i > 0 && account.Hash.Cmp(result.Accounts[i-1].Hash) <= 0 {
+			t.Fatalf("accounts out of order: %s", dumper.Sdump(result))
+		}
+		diffs[*account.Address] = account
+	}
+	if diff := diffs[deleted]; diff == nil || diff.Before.Balance.ToInt().Int64() != 2 || diff.After != nil {
+		t.Fatalf("wrong deleted account diff: %s", dumper.Sdump(diff))
+	}
+	if diff := diffs[created]; diff == nil || diff.Before != nil || diff.After.Balance.ToInt().Int64() != 5 {
+		t.Fatalf("wrong created account diff: %s", dumper.Sdump(diff))
+	}
+	want := map[common.Hash]storageDiffEntry{
+		common.Hash{0x01}: {Before: common.Hash{0x01}, After: common.Hash{0x03}},
+		common.Hash{0x02}: {Before: common.Hash{0x02}},
+		common.Hash{0x03}: {After: common.Hash{0x04}},
+	}
+	diff := diffs[changed]
+	if diff == nil || diff.Before.Balance.ToInt().Int64() != 1 || len(diff.Storage) != len(want) {
+		t.Fatalf("wrong changed account diff: %s", dumper.Sdump(diff))
+	}
+	for _, entry := range diff.Storage {
+		if entry.Key == nil || want[*entry.Key].Before != entry.Before || want[*entry.Key].After != entry.After {
+			t.Fatalf("wrong storage diff: %s", dumper.Sdump(diff.Storage))
+		}
+	}
+	// Results can be paged.
+	first, err := differ.diff(context.Background(), common.Hash{}, common.Hash{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Accounts) != 2 || first.Next == nil || *first.Next != result.Accounts[2].Hash {
+		t.Fatalf("wrong first page: %s", dumper.Sdump(first))
+	}
+	rest, err := differ.diff(context.Background(), *first.Next, common.Hash{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest.Accounts) != 1 || rest.Next != nil || rest.Accounts[0].Hash != result.Accounts[2].Hash {
+		t.Fatalf("wrong last page: %s", dumper.Sdump(rest))
+	}
+	// Only the given accounts are compared.
+	filtered, err := differ.diffAddresses(context.Background(), []common.Address{same, changed, changed}, common.Hash{}, common.Hash{}, StateDiffMaxResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Accounts) != 1 || *filtered.Accounts[0].Address != changed || len(filtered.Accounts[0].Storage) != 3 {
+		t.Fatalf("wrong filtered result: %s", dumper.Sdump(filtered))
+	}
+	// The storage of an account can be split across pages.
+	differ.slots = 2
+	for _, page := range []func(start, startSlot common.Hash) (*StateDiffResult, error){
+		func(start, startSlot common.Hash) (*StateDiffResult, error) {
+			return differ.diff(context.Background(), start, startSlot, StateDiffMaxResults)
+		},
+		func(start, startSlot common.Hash) (*StateDiffResult, error) {
+			return differ.diffAddresses(context.Background(), []common.Address{changed}, start, startSlot, StateDiffMaxResults)
+		},
+	} {
+		var (
+			start, startSlot common.Hash
+			storage          = make(map[common.Hash]storageDiffEntry)
+			pages            int
+		)
+		for ; ; pages++ {
+			res, err := page(start, startSlot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, account := range res.Accounts {
+				if *account.Address != changed {
+					continue
+				}
+				if len(account.Storage) > 2 {
+					t.Fatalf("storage slot limit exceeded: %s", dumper.Sdump(account))
+				}
+				for hash, entry := range account.Storage {
+					if _, ok := storage[hash]; ok {
+						t.Fatalf("storage slot %x returned twice", hash)
+					}
+					storage[hash] = entry
+				}
+			}
+			if res.Next == nil {
+				break
+			}
+			start, startSlot = *res.Next, common.Hash{}
+			if res.NextSlot != nil {
+				startSlot = *res.NextSlot
+			}
+		}
+		if pages != 1 || len(storage) != len(want) {
+			t.Fatalf("wrong paged storage diff after %d pages: %s", pages+1, dumper.Sdump(storage))
+		}
+	}
+	differ.slots = StateDiffMaxStorageResults
+
+	// Diffs exceeding the runtime limit fail without progress.
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	if _, err := differ.diff(ctx, common.Hash{}, common.Hash{}, StateDiffMaxResults); err == nil {
+		t.Fatal("diff succeeded after the runtime limit")
+	}
+}
@@ -45,12 +45,14 @@ import (
 	"github.com/yuriy0803/core-geth1/eth/gasprice"
 	"github.com/yuriy0803/core-geth1/eth/protocols/eth"
 	"github.com/yuriy0803/core-geth1/eth/protocols/snap"
+	"github.com/yuriy0803/core-geth1/eth/tracers"
 	"github.com/yuriy0803/core-geth1/ethdb"
 	"github.com/yuriy0803/core-geth1/event"
 	"github.com/yuriy0803/core-geth1/internal/ethapi"
 	"github.com/yuriy0803/core-geth1/internal/shutdowncheck"
 	"github.com/yuriy0803/core-geth1/log"
 	"github.com/yuriy0803/core-geth1/miner"
+	"github.com/yuriy0803/core-geth1/miner/stratum"
 	"github.com/yuriy0803/core-geth1/node"
 	"github.com/yuriy0803/core-geth1/p2p"
 	"github.com/yuriy0803/core-geth1/p2p/dnsdisc"
@@ -91,6 +93,7 @@ type Ethereum struct {
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
+	traceIndexer      *core.ChainIndexer // Trace indexer operating during block imports, nil if disabled
 
 	APIBackend *EthAPIBackend
 
@@ -134,7 +137,15 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	log.Info("Allocated trie memory caches", "clean", common.StorageSize(config.TrieCleanCache)*1024*1024, "dirty", common.StorageSize(config.TrieDirtyCache)*1024*1024)
 
 	// Assemble the Ethereum object
-	chainDb, err := stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
+	if config.DatabaseFreezerRemote != "" {
+		chainDb, err = stack.OpenDatabaseWithFreezerRemote("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezerRemote, "eth/db/chaindata/", false)
+	} else {
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -211,6 +222,8 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			HistoryExpiry:       config.HistoryExpiry,
+			StateDiffs:          config.StateDiffs && !config.NoPruning,
 		}
 	)
 	// Override the chain config with provided settings.
@@ -301,6 +314,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	eth.APIBackend.gpo = gasprice.NewOracle(eth.APIBackend, gpoParams)
 
+	if config.TraceIndex {
+		eth.traceIndexer = tracers.NewTraceIndexer(eth.APIBackend, vars.TraceIndexBlocks, vars.TraceIndexConfirms)
+		eth.traceIndexer.Start(eth.blockchain)
+	}
+
 	// Setup DNS discovery iterators.
 	dnsclient := dnsdisc.NewClient(dnsdisc.Config{})
 	eth.ethDialCandidates, err = dnsclient.NewIterator(eth.config.EthDiscoveryURLs...)
@@ -320,6 +338,23 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	stack.RegisterProtocols(eth.Protocols())
 	stack.RegisterLifecycle(eth)
 
+	// Serve the remote sealer of the proof-of-work engine over Stratum
+	if config.Miner.Stratum != "" {
+		sealer, ok := eth.engine.(stratum.Sealer)
+		if b, isBeacon := eth.engine.(*beacon.Beacon); isBeacon {
+			sealer, ok = b.InnerEngine().(stratum.Sealer)
+		}
+		if !ok {
+			return nil, errors.New("stratum server requires a proof-of-work consensus engine")
+		}
+		stack.RegisterLifecycle(stratum.New(sealer, stratum.Config{
+			Addr:          config.Miner.Stratum,
+			Difficulty:    config.Miner.StratumDifficulty,
+			MaxSessions:   config.Miner.StratumMaxSessions,
+			MaxSessionsIP: config.Miner.StratumMaxSessionsIP,
+		}))
+	}
+
 	// Successful startup; push a marker and check previous unclean shutdowns.
 	eth.shutdownTracker.MarkStartup()
 
@@ -540,6 +575,7 @@ func (s *Ethereum) Synced() bool                       { return s.handler.accept
 func (s *Ethereum) SetSynced()                         { s.handler.acceptTxs.Store(true) }
 func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
 func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
+func (s *Ethereum) TraceIndexer() *core.ChainIndexer   { return s.traceIndexer }
 func (s *Ethereum) Merger() *consensus.Merger          { return s.merger }
 func (s *Ethereum) SyncMode() downloader.SyncMode {
 	mode, _ := s.handler.chainSync.modeAndLocalHead()
@@ -590,6 +626,9 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.traceIndexer != nil {
+		s.traceIndexer.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Close()
 	s.miner.Close()
@@ -94,6 +94,7 @@ var Defaults = Config{
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
 	RPCTxFeeCap:        1, // 1 ether
+	RPCTraceFilterCap:  10000,
 }
 
 func init() {
@@ -139,6 +140,9 @@ type Config struct {
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	TraceIndex    bool   `toml:",omitempty"` // Whether to index the addresses of the block traces for trace_filter
+	HistoryExpiry uint64 `toml:",omitempty"` // Block number below which frozen bodies and receipts are dropped (0 = keep all)
+	StateDiffs    bool   `toml:",omitempty"` // Whether to record reverse state diffs for serving the state pruned by full nodes
 
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
@@ -210,6 +214,9 @@ type Config struct {
 	// send-transaction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCTraceFilterCap is the maximum number of traces returned by trace_filter.
+	RPCTraceFilterCap uint64
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *ctypes.TrustedCheckpoint `toml:",omitempty"`
 
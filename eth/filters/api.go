@@ -421,6 +421,31 @@ func (api *FilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*type
 	return returnLogs(logs), err
 }
 
+// logsCostBlocks is the number of blocks spanned by a unit of log query cost.
+const logsCostBlocks = 1000
+
+// LogsCost returns the cost of an eth_getLogs call with the given parameters, as
+// the number of started ranges of logsCostBlocks blocks it queries. Block tags are
+// resolved to the current head. Invalid queries cost a single unit, they fail anyway.
+func (sys *FilterSystem) LogsCost(params json.RawMessage) float64 {
+	var args []FilterCriteria
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 || args[0].BlockHash != nil {
+		return 1
+	}
+	head := sys.backend.CurrentHeader().Number.Int64()
+	resolve := func(number *big.Int) int64 {
+		if number == nil || number.Sign() < 0 || number.Int64() > head {
+			return head
+		}
+		return number.Int64()
+	}
+	span := resolve(args[0].ToBlock) - resolve(args[0].FromBlock) + 1
+	if span <= logsCostBlocks {
+		return 1
+	}
+	return float64((span + logsCostBlocks - 1) / logsCostBlocks)
+}
+
 // UninstallFilter removes the filter with the given filter id.
 func (api *FilterAPI) UninstallFilter(id rpc.ID) bool {
 	api.filtersMu.Lock()
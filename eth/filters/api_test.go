@@ -19,9 +19,12 @@ package filters
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
 	"github.com/yuriy0803/core-geth1/rpc"
 )
 
@@ -183,3 +186,32 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
 }
+
+func TestLogsCost(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		_, sys = newTestFilterSystem(t, db, Config{})
+		head   = &types.Header{Number: big.NewInt(5000)}
+	)
+	rawdb.WriteHeader(db, head)
+	rawdb.WriteHeadBlockHash(db, head.Hash())
+
+	for i, test := range []struct {
+		params string
+		want   float64
+	}{
+		{`[{}]`, 1},
+		{`[{"blockHash":"0x0000000000000000000000000000000000000000000000000000000000000001"}]`, 1},
+		{`[{"fromBlock":"0x0","toBlock":"0x3e7"}]`, 1},
+		{`[{"fromBlock":"0x0","toBlock":"0x3e8"}]`, 2},
+		{`[{"fromBlock":"earliest"}]`, 6},
+		{`[{"fromBlock":"earliest","toBlock":"pending"}]`, 6},
+		{`[{"fromBlock":"0x1000","toBlock":"0x100000"}]`, 1},
+		{`[{"fromBlock":"0x10","toBlock":"0x1"}]`, 1},
+		{`invalid`, 1},
+	} {
+		if have := sys.LogsCost(json.RawMessage(test.params)); have != test.want {
+			t.Errorf("test %d: wrong cost: have %v, want %v", i, have, test.want)
+		}
+	}
+}
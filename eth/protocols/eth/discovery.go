@@ -17,6 +17,8 @@
 package eth
 
 import (
+	"strings"
+
 	"github.com/yuriy0803/core-geth1/core"
 	"github.com/yuriy0803/core-geth1/core/forkid"
 	"github.com/yuriy0803/core-geth1/p2p/enode"
@@ -36,6 +38,28 @@ func (e enrEntry) ENRKey() string {
 	return "eth"
 }
 
+// NodeForkID returns the fork ID advertised by the `eth` entry of the node record.
+func NodeForkID(n *enode.Node) (forkid.ID, error) {
+	var entry enrEntry
+	if err := n.Load(&entry); err != nil {
+		return forkid.ID{}, err
+	}
+	return entry.ForkID, nil
+}
+
+// ParseForkID parses a fork ID given as <hash>[:<next>], or reads it from the
+// `eth` entry of an "enr:" node record.
+func ParseForkID(s string) (forkid.ID, error) {
+	if !strings.HasPrefix(s, "enr:") {
+		return forkid.ParseID(s)
+	}
+	n, err := enode.Parse(enode.ValidSchemes, s)
+	if err != nil {
+		return forkid.ID{}, err
+	}
+	return NodeForkID(n)
+}
+
 // StartENRUpdater starts the `eth` ENR updater loop, which listens for chain
 // head events and updates the requested node record whenever a fork is passed.
 func StartENRUpdater(chain *core.BlockChain, ln *enode.LocalNode) {
@@ -132,6 +132,14 @@ type NodeInfo struct {
 	Genesis    common.Hash              `json:"genesis"`    // SHA3 hash of the host's genesis block
 	Config     ctypes.ChainConfigurator `json:"config"`     // Chain configuration for the fork rules
 	Head       common.Hash              `json:"head"`       // Hex hash of the host's best owned block
+	History    HistoryRange             `json:"history"`    // Range of blocks whose bodies and receipts are retained
+}
+
+// HistoryRange is the range of blocks whose bodies and receipts are retained
+// by the host, the ones below having been dropped by history expiry.
+type HistoryRange struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
 }
 
 // nodeInfo retrieves some `eth` protocol metadata about the running host node.
@@ -145,6 +153,7 @@ func nodeInfo(chain *core.BlockChain, network uint64) *NodeInfo {
 		Genesis:    chain.Genesis().Hash(),
 		Config:     chain.Config(),
 		Head:       hash,
+		History:    HistoryRange{First: chain.HistoryTail(), Last: head.Number.Uint64()},
 	}
 }
 
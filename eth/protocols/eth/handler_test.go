@@ -628,3 +628,47 @@ func testGetBlockReceipts(t *testing.T, protocol uint) {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
+
+// Tests that queries for bodies and receipts dropped by history expiry are
+// answered with an empty response.
+func TestServePrunedHistory(t *testing.T) {
+	gspec := &genesisT.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  genesisT.GenesisAlloc{testAddr: {Balance: big.NewInt(100_000_000_000_000_000)}},
+	}
+	signer := types.HomesteadSigner{}
+	_, blocks, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 16, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testAddr), common.Address{0x01}, big.NewInt(1), vars.TxGas, block.BaseFee(), nil), signer, testKey)
+		block.AddTx(tx)
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rawdb.WriteAncientBlocks(db, append([]*types.Block{core.MustCommitGenesis(rawdb.NewMemoryDatabase(), gspec)}, blocks...), append([]types.Receipts{{}}, receipts...), big.NewInt(0))
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	if _, err := db.TruncateTail(8); err != nil {
+		t.Fatal(err)
+	}
+
+	retained := []common.Hash{blocks[8].Hash(), blocks[9].Hash()}
+	if bodies := ServiceGetBlockBodiesQuery(chain, retained); len(bodies) != 2 {
+		t.Fatalf("wrong number of retained bodies: have %d, want 2", len(bodies))
+	}
+	if receipts := ServiceGetReceiptsQuery(chain, retained); len(receipts) != 2 {
+		t.Fatalf("wrong number of retained receipts: have %d, want 2", len(receipts))
+	}
+	// Block 7 is dropped, the responses are empty even for the retained blocks.
+	query := []common.Hash{blocks[6].Hash(), blocks[7].Hash(), blocks[8].Hash()}
+	if bodies := ServiceGetBlockBodiesQuery(chain, query); len(bodies) != 0 {
+		t.Fatalf("pruned bodies served: %d", len(bodies))
+	}
+	if receipts := ServiceGetReceiptsQuery(chain, query); len(receipts) != 0 {
+		t.Fatalf("pruned receipts served: %d", len(receipts))
+	}
+}
@@ -214,6 +214,10 @@ func handleGetBlockBodies66(backend Backend, msg Decoder, peer *Peer) error {
 
 // ServiceGetBlockBodiesQuery assembles the response to a body query. It is
 // exposed to allow external packages to test protocol behavior.
+//
+// Queries for bodies dropped by history expiry are answered with an empty
+// response, as the protocol has no message to signal pruned history. The peer
+// treats it like any unavailable data, and requests it from other peers.
 func ServiceGetBlockBodiesQuery(chain *core.BlockChain, query GetBlockBodiesPacket) []rlp.RawValue {
 	// Gather blocks until the fetch or network limits is reached
 	var (
@@ -228,6 +232,9 @@ func ServiceGetBlockBodiesQuery(chain *core.BlockChain, query GetBlockBodiesPack
 		if data := chain.GetBodyRLP(hash); len(data) != 0 {
 			bodies = append(bodies, data)
 			bytes += len(data)
+		} else if isPrunedHistory(chain, hash) {
+			prunedHistoryMeter.Mark(1)
+			return nil
 		}
 	}
 	return bodies
@@ -280,8 +287,22 @@ func handleGetReceipts66(backend Backend, msg Decoder, peer *Peer) error {
 	return peer.ReplyReceiptsRLP(query.RequestId, response)
 }
 
+// isPrunedHistory reports whether the body and receipts of the block were
+// dropped by history expiry.
+func isPrunedHistory(chain *core.BlockChain, hash common.Hash) bool {
+	tail := chain.HistoryTail()
+	if tail == 0 {
+		return false
+	}
+	header := chain.GetHeaderByHash(hash)
+	return header != nil && header.Number.Uint64() < tail
+}
+
 // ServiceGetReceiptsQuery assembles the response to a receipt query. It is
 // exposed to allow external packages to test protocol behavior.
+//
+// Like body queries, queries for receipts dropped by history expiry are answered
+// with an empty response.
 func ServiceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket) []rlp.RawValue {
 	// Gather state data until the fetch or network limits is reached
 	var (
@@ -296,6 +317,10 @@ func ServiceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket) []
 		// Retrieve the requested block's receipts
 		results := chain.GetReceiptsByHash(hash)
 		if results == nil {
+			if isPrunedHistory(chain, hash) {
+				prunedHistoryMeter.Mark(1)
+				return nil
+			}
 			if header := chain.GetHeaderByHash(hash); header == nil || header.ReceiptHash != types.EmptyRootHash {
 				continue
 			}
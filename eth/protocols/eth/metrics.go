@@ -21,6 +21,10 @@ import "github.com/yuriy0803/core-geth1/metrics"
 // meters stores ingress and egress handshake meters.
 var meters bidirectionalMeters
 
+// prunedHistoryMeter measures the number of body and receipt queries answered
+// with an empty response, as they requested history dropped by history expiry.
+var prunedHistoryMeter = metrics.NewRegisteredMeter("eth/protocols/eth/egress/pruned", nil)
+
 // bidirectionalMeters stores ingress and egress handshake meters.
 type bidirectionalMeters struct {
 	ingress *hsMeters
@@ -110,6 +110,14 @@ func (eth *Ethereum) StateAtBlock(ctx context.Context, block *types.Block, reexe
 				return statedb, noopReleaser, nil
 			}
 		}
+		// Reconstruct the state from the recorded state diffs if the chain has
+		// them, which is much cheaper than re-executing the blocks. The state is
+		// held in memory, isolated from the live database.
+		if !eth.blockchain.HasState(block.Root()) {
+			if statedb, err = eth.blockchain.StateAtHeader(block.Header()); err == nil {
+				return statedb, noopReleaser, nil
+			}
+		}
 		// Database does not have the state for the given block, try to regenerate
 		for i := uint64(0); i < reexec; i++ {
 			if err := ctx.Err(); err != nil {
@@ -85,6 +85,7 @@ type Backend interface {
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
 	GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error)
 	RPCGasCap() uint64
+	RPCTraceFilterCap() uint64
 	ChainConfig() ctypes.ChainConfigurator
 	Engine() consensus.Engine
 	ChainDb() ethdb.Database
@@ -1006,10 +1007,8 @@ func (api *API) TraceCallMany(ctx context.Context, txs []ethapi.TransactionArgs,
 // be tracer dependent.
 func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
 	var (
-		tracer    Tracer
-		err       error
-		timeout   = defaultTraceTimeout
-		txContext = core.NewEVMTxContext(message)
+		tracer Tracer
+		err    error
 	)
 	if config == nil {
 		config = &TraceConfig{}
@@ -1022,6 +1021,20 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 			return nil, err
 		}
 	}
+	if _, err := api.applyTracedMessage(ctx, message, txctx, vmctx, statedb, tracer, config); err != nil {
+		return nil, err
+	}
+	return tracer.GetResult()
+}
+
+// applyTracedMessage executes the given message in the provided environment with
+// the tracer, aborting the execution once the configured timeout expires.
+func (api *API) applyTracedMessage(ctx context.Context, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, tracer Tracer, config *TraceConfig) (*core.ExecutionResult, error) {
+	var (
+		err       error
+		timeout   = defaultTraceTimeout
+		txContext = core.NewEVMTxContext(message)
+	)
 	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer, NoBaseFee: true})
 
 	// Define a meaningful timeout of a single transaction trace
@@ -1048,15 +1061,17 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 	if traceStateCapturer, ok := tracer.(vm.EVMLogger_StateCapturer); ok {
 		traceStateCapturer.CapturePreEVM(vmenv)
 	}
-	if _, err = core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit)); err != nil {
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit))
+	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
-	return tracer.GetResult()
+	return result, nil
 }
 
 // APIs return the collection of RPC services the tracer package offers.
 func APIs(backend Backend) []rpc.API {
 	debugAPI := NewAPI(backend)
+	traceAPI := NewTraceAPI(debugAPI)
 
 	// Append all the local APIs and return
 	return []rpc.API{
@@ -1066,7 +1081,11 @@ func APIs(backend Backend) []rpc.API {
 		},
 		{
 			Namespace: "trace",
-			Service:   NewTraceAPI(debugAPI),
+			Service:   traceAPI,
+		},
+		{
+			Namespace: "trace",
+			Service:   NewTraceSubscriptionAPI(traceAPI),
 		},
 	}
 }
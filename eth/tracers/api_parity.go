@@ -31,12 +31,12 @@ import (
 
 // TraceFilterArgs represents the arguments for a call.
 type TraceFilterArgs struct {
-	FromBlock   hexutil.Uint64  `json:"fromBlock,omitempty"`   // Trace from this starting block
-	ToBlock     hexutil.Uint64  `json:"toBlock,omitempty"`     // Trace utill this end block
-	FromAddress *common.Address `json:"fromAddress,omitempty"` // Sent from these addresses
-	ToAddress   *common.Address `json:"toAddress,omitempty"`   // Sent to these addresses
-	After       uint64          `json:"after,omitempty"`       // The offset trace number
-	Count       uint64          `json:"count,omitempty"`       // Integer number of traces to display in a batch
+	FromBlock   *rpc.BlockNumber `json:"fromBlock,omitempty"`   // Trace from this starting block
+	ToBlock     *rpc.BlockNumber `json:"toBlock,omitempty"`     // Trace utill this end block
+	FromAddress []common.Address `json:"fromAddress,omitempty"` // Sent from these addresses
+	ToAddress   []common.Address `json:"toAddress,omitempty"`   // Sent to these addresses
+	After       uint64           `json:"after,omitempty"`       // The offset trace number
+	Count       uint64           `json:"count,omitempty"`       // Integer number of traces to display in a batch
 }
 
 // ParityTrace A trace in the desired format (Parity/OpenEtherum) See: https://Parity.github.io/wiki/JSONRPC-trace-module
@@ -55,9 +55,9 @@ type ParityTrace struct {
 
 // TraceRewardAction An Parity formatted trace reward action
 type TraceRewardAction struct {
-	Value      *hexutil.Big    `json:"value,omitempty"`
 	Author     *common.Address `json:"author,omitempty"`
 	RewardType string          `json:"rewardType,omitempty"`
+	Value      *hexutil.Big    `json:"value,omitempty"`
 }
 
 // setTraceConfigDefaultTracer sets the default tracer to "callTracerParity" if none set
@@ -129,6 +129,8 @@ func decorateNestedTraceResponse(res interface{}, tracer string) interface{} {
 		out["trace"] = res
 	} else if tracer == "stateDiffTracer" {
 		out["stateDiff"] = res
+	} else if tracer == "vmTraceTracer" {
+		out["vmTrace"] = res
 	} else {
 		return res
 	}
@@ -220,7 +222,7 @@ func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber, config *
 		if err := json.Unmarshal(result.Result.(json.RawMessage), &tmp); err != nil {
 			return nil, err
 		}
-		if *config.Tracer == "stateDiffTracer" {
+		if *config.Tracer == "stateDiffTracer" || *config.Tracer == "vmTraceTracer" {
 			results = append(results, tmp)
 		} else {
 			results = append(results, tmp.([]interface{})...)
@@ -243,19 +245,6 @@ func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash, config *
 	return api.debugAPI.TraceTransaction(ctx, hash, config)
 }
 
-// Filter configures a new tracer according to the provided configuration, and
-// executes all the transactions contained within. The return value will be one item
-// per transaction, dependent on the requested tracer.
-func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs, config *TraceConfig) (*rpc.Subscription, error) {
-	config = setTraceConfigDefaultTracer(config)
-
-	// Fetch the block interval that we want to trace
-	start := rpc.BlockNumber(args.FromBlock)
-	end := rpc.BlockNumber(args.ToBlock)
-
-	return api.debugAPI.TraceChain(ctx, start, end, config)
-}
-
 // Call lets you trace a given eth_call. It collects the structured logs created during the execution of EVM
 // if the given transaction was added on top of the provided block and returns them as a JSON object.
 // You can provide -2 as a block number to trace on top of the pending block.
@@ -0,0 +1,382 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+var errTraceFilterTracer = errors.New("trace filter only supports the callTracerParity tracer")
+
+// traceFilter matches flattened Parity traces by sender and recipient, and
+// paginates the matching traces.
+type traceFilter struct {
+	from, to map[common.Address]struct{}
+	after    uint64 // Number of matching traces to skip
+	count    uint64 // Number of matching traces to return, 0 for all
+	matched  uint64 // Number of traces matched so far
+}
+
+func newTraceFilter(args TraceFilterArgs) *traceFilter {
+	f := &traceFilter{
+		from:  make(map[common.Address]struct{}),
+		to:    make(map[common.Address]struct{}),
+		after: args.After,
+		count: args.Count,
+	}
+	for _, addr := range args.FromAddress {
+		f.from[addr] = struct{}{}
+	}
+	for _, addr := range args.ToAddress {
+		f.to[addr] = struct{}{}
+	}
+	return f
+}
+
+// traceFilterFields are the fields of a flattened Parity trace the filter
+// addresses are matched against.
+type traceFilterFields struct {
+	Type   string `json:"type"`
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+		Author        *common.Address `json:"author"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+}
+
+// addresses returns the sender and recipient of the trace. Like OpenEthereum,
+// the recipient of a create is the created contract, a suicide is sent from the
+// destructed contract to the refund address, and a reward is sent to its author
+// from no sender.
+func (fields *traceFilterFields) addresses() (from, to *common.Address) {
+	switch fields.Type {
+	case "call":
+		from, to = fields.Action.From, fields.Action.To
+	case "create":
+		from = fields.Action.From
+		if fields.Result != nil {
+			to = fields.Result.Address
+		}
+	case "suicide":
+		from, to = fields.Action.Address, fields.Action.RefundAddress
+	case "reward":
+		to = fields.Action.Author
+	}
+	return from, to
+}
+
+// matches returns whether the trace is sent from and to the filter addresses.
+func (f *traceFilter) matches(trace json.RawMessage) (bool, error) {
+	var fields traceFilterFields
+	if err := json.Unmarshal(trace, &fields); err != nil {
+		return false, err
+	}
+	from, to := fields.addresses()
+	return matchesAddress(f.from, from) && matchesAddress(f.to, to), nil
+}
+
+func matchesAddress(set map[common.Address]struct{}, addr *common.Address) bool {
+	if len(set) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	_, ok := set[*addr]
+	return ok
+}
+
+// page counts a matching trace, returning whether it is part of the requested
+// page and whether the page is complete.
+func (f *traceFilter) page() (include bool, done bool) {
+	f.matched++
+	if f.matched <= f.after {
+		return false, false
+	}
+	return true, f.count > 0 && f.matched >= f.after+f.count
+}
+
+// filterRange returns the first and last blocks to filter, both defaulting to the latest block.
+func (api *TraceAPI) filterRange(ctx context.Context, args TraceFilterArgs) (*types.Block, *types.Block, error) {
+	start, end := rpc.LatestBlockNumber, rpc.LatestBlockNumber
+	if args.FromBlock != nil {
+		start = *args.FromBlock
+	}
+	if args.ToBlock != nil {
+		end = *args.ToBlock
+	}
+	from, err := api.debugAPI.blockByNumber(ctx, start)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := api.debugAPI.blockByNumber(ctx, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", to.NumberU64(), from.NumberU64())
+	}
+	return from, to, nil
+}
+
+// filter traces the blocks from and to, both included, and calls fn with every
+// flattened trace matching the filter in chain order, until the requested page
+// is complete or fn fails.
+func (api *TraceAPI) filter(ctx context.Context, from, to *types.Block, filter *traceFilter, config *TraceConfig, fn func(trace json.RawMessage) error) error {
+	// Only trace the blocks involving the filter addresses within the range
+	// covered by the trace index.
+	blocks, unindexed, err := api.indexedBlocks(from.NumberU64(), to.NumberU64(), filter)
+	if err != nil {
+		return err
+	}
+	for _, number := range blocks {
+		block, err := api.debugAPI.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return err
+		}
+		var txs []*txTraceResult
+		if len(block.Transactions()) > 0 {
+			if txs, err = api.debugAPI.traceBlock(ctx, block, config); err != nil {
+				return err
+			}
+		}
+		done, err := api.filterBlock(ctx, number, txs, filter, config, fn)
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+	if unindexed > to.NumberU64() {
+		return nil
+	}
+	if unindexed > from.NumberU64() {
+		if from, err = api.debugAPI.blockByNumber(ctx, rpc.BlockNumber(unindexed)); err != nil {
+			return err
+		}
+	}
+	// Trace the remaining blocks in full. The chain tracer excludes its start
+	// block, trace from the parent instead.
+	// The genesis block has neither transactions nor rewards to trace.
+	start := from
+	if from.NumberU64() > 0 {
+		parent, err := api.debugAPI.blockByHash(ctx, from.ParentHash())
+		if err != nil {
+			return err
+		}
+		start = parent
+	}
+	if start.NumberU64() == to.NumberU64() {
+		return nil
+	}
+	closed := make(chan interface{})
+	resCh := api.debugAPI.traceChain(start, to, config, closed)
+	defer func() {
+		// Abort the chain tracer, and consume its pending results so it can tear down.
+		close(closed)
+		go func() {
+			for range resCh {
+			}
+		}()
+	}()
+
+	next := start.NumberU64() + 1
+	for res := range resCh {
+		// The chain tracer skips the blocks without transactions, these still have rewards.
+		for ; next <= uint64(res.Block); next++ {
+			var txs []*txTraceResult
+			if next == uint64(res.Block) {
+				txs = res.Traces
+			}
+			done, err := api.filterBlock(ctx, next, txs, filter, config, fn)
+			if err != nil || done {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+	if next <= to.NumberU64() {
+		return fmt.Errorf("tracing aborted at block #%d", next)
+	}
+	return nil
+}
+
+// flattenTraces returns the flattened Parity traces of the transactions.
+func flattenTraces(txs []*txTraceResult) ([]json.RawMessage, error) {
+	var traces []json.RawMessage
+	for _, result := range txs {
+		if result.Error != "" {
+			return nil, errors.New(result.Error)
+		}
+		var frames []json.RawMessage
+		if err := json.Unmarshal(result.Result.(json.RawMessage), &frames); err != nil {
+			return nil, err
+		}
+		traces = append(traces, frames...)
+	}
+	return traces, nil
+}
+
+// filterBlock matches the transaction traces and the rewards of the block against
+// the filter, returning whether the requested page is complete.
+func (api *TraceAPI) filterBlock(ctx context.Context, number uint64, txs []*txTraceResult, filter *traceFilter, config *TraceConfig, fn func(trace json.RawMessage) error) (bool, error) {
+	block, err := api.debugAPI.blockByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return false, err
+	}
+	traces, err := flattenTraces(txs)
+	if err != nil {
+		return false, err
+	}
+	reward, err := api.traceBlockReward(ctx, block, config)
+	if err != nil {
+		return false, err
+	}
+	uncleRewards, err := api.traceBlockUncleRewards(ctx, block, config)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range append([]*ParityTrace{reward}, uncleRewards...) {
+		blob, err := json.Marshal(r)
+		if err != nil {
+			return false, err
+		}
+		traces = append(traces, blob)
+	}
+
+	for _, trace := range traces {
+		ok, err := filter.matches(trace)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+		include, done := filter.page()
+		if include {
+			if err := fn(trace); err != nil {
+				return false, err
+			}
+		}
+		if done {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Filter returns the flattened traces of the blocks in the given range, sent
+// from and to the given addresses, skipping the first After matching traces and
+// returning at most Count traces. The number of traces returned is capped by the
+// RPCTraceFilterCap of the backend, larger results have to be paged.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs, config *TraceConfig) ([]interface{}, error) {
+	config = setTraceConfigDefaultTracer(config)
+	if *config.Tracer != "callTracerParity" {
+		return nil, errTraceFilterTracer
+	}
+	limit := api.debugAPI.backend.RPCTraceFilterCap()
+	if limit > 0 && args.Count > limit {
+		return nil, fmt.Errorf("trace count %d exceeds the limit of %d", args.Count, limit)
+	}
+	from, to, err := api.filterRange(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	results := []interface{}{}
+	err = api.filter(ctx, from, to, newTraceFilter(args), config, func(trace json.RawMessage) error {
+		if limit > 0 && uint64(len(results)) >= limit {
+			return fmt.Errorf("trace filter results exceed the limit of %d, use after and count to page them", limit)
+		}
+		results = append(results, trace)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// TraceSubscriptionAPI is the collection of trace subscriptions. It is served in
+// the trace namespace next to TraceAPI, since the trace_filter method and the
+// "filter" subscription share their name.
+type TraceSubscriptionAPI struct {
+	traceAPI *TraceAPI
+}
+
+// NewTraceSubscriptionAPI creates a new API definition for the trace subscriptions.
+func NewTraceSubscriptionAPI(traceAPI *TraceAPI) *TraceSubscriptionAPI {
+	return &TraceSubscriptionAPI{traceAPI: traceAPI}
+}
+
+// Filter streams the traces returned by trace_filter, one notification per trace.
+func (api *TraceSubscriptionAPI) Filter(ctx context.Context, args TraceFilterArgs, config *TraceConfig) (*rpc.Subscription, error) {
+	config = setTraceConfigDefaultTracer(config)
+	if *config.Tracer != "callTracerParity" {
+		return nil, errTraceFilterTracer
+	}
+	from, to, err := api.traceAPI.filterRange(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	// Tracing a chain is a **long** operation, only do with subscriptions
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	filterCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-notifier.Closed():
+		case <-sub.Err():
+		case <-filterCtx.Done():
+		}
+		cancel()
+	}()
+	go func() {
+		defer cancel()
+		err := api.traceAPI.filter(filterCtx, from, to, newTraceFilter(args), config, func(trace json.RawMessage) error {
+			notifier.Notify(sub.ID, trace)
+			return nil
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Warn("Trace filter subscription failed", "from", from.NumberU64(), "to", to.NumberU64(), "err", err)
+		}
+	}()
+	return sub, nil
+}
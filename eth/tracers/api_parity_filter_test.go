@@ -0,0 +1,577 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/consensus"
+	"github.com/yuriy0803/core-geth1/consensus/ethash"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/state"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/core/vm"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/eth/tracers"
+	_ "github.com/yuriy0803/core-geth1/eth/tracers/native"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/params"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+	"github.com/yuriy0803/core-geth1/params/types/genesisT"
+	"github.com/yuriy0803/core-geth1/params/vars"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+// filterTestBackend is a tracer backend serving a generated chain. The trace
+// filter tests live in an external package, as the native tracers depend on
+// the tracers package.
+type filterTestBackend struct {
+	chaindb        ethdb.Database
+	chain          *core.BlockChain
+	traceFilterCap uint64
+}
+
+func (b *filterTestBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *filterTestBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.PendingBlockNumber || number == rpc.LatestBlockNumber {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *filterTestBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *filterTestBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.PendingBlockNumber || number == rpc.LatestBlockNumber {
+		return b.chain.GetBlockByNumber(b.chain.CurrentBlock().Number.Uint64()), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *filterTestBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	tx, hash, blockNumber, index := rawdb.ReadTransaction(b.chaindb, txHash)
+	return tx, hash, blockNumber, index, nil
+}
+
+func (b *filterTestBackend) RPCGasCap() uint64                     { return 25000000 }
+func (b *filterTestBackend) RPCTraceFilterCap() uint64             { return b.traceFilterCap }
+func (b *filterTestBackend) ChainConfig() ctypes.ChainConfigurator { return b.chain.Config() }
+func (b *filterTestBackend) Engine() consensus.Engine              { return b.chain.Engine() }
+func (b *filterTestBackend) ChainDb() ethdb.Database               { return b.chaindb }
+
+func (b *filterTestBackend) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, readOnly bool, preferDisk bool) (*state.StateDB, tracers.StateReleaseFunc, error) {
+	statedb, err := b.chain.StateAt(block.Root())
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, func() {}, nil
+}
+
+func (b *filterTestBackend) StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*core.Message, vm.BlockContext, *state.StateDB, tracers.StateReleaseFunc, error) {
+	parent := b.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, vm.BlockContext{}, nil, nil, errors.New("parent block not found")
+	}
+	statedb, release, err := b.StateAtBlock(ctx, parent, reexec, nil, true, false)
+	if err != nil {
+		return nil, vm.BlockContext{}, nil, nil, err
+	}
+	// Recompute transactions up to the target index.
+	signer := types.MakeSigner(b.chain.Config(), block.Number(), block.Time())
+	for idx, tx := range block.Transactions() {
+		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
+		context := core.NewEVMBlockContext(block.Header(), b.chain, nil)
+		if idx == txIndex {
+			return msg, context, statedb, release, nil
+		}
+		vmenv := vm.NewEVM(context, core.NewEVMTxContext(msg), statedb, b.chain.Config(), vm.Config{})
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+			return nil, vm.BlockContext{}, nil, nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		statedb.Finalise(vmenv.ChainConfig().IsEnabled(vmenv.ChainConfig().GetEIP161dTransition, block.Number()))
+	}
+	return nil, vm.BlockContext{}, nil, nil, fmt.Errorf("transaction index %d out of range for block %#x", txIndex, block.Hash())
+}
+
+type filterTestAccount struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+// traceFilterSummary identifies a flattened trace in the filter results.
+type traceFilterSummary struct {
+	Block        uint64
+	Type         string
+	From, To     common.Address
+	TraceAddress []int
+}
+
+func summarizeTraces(t *testing.T, traces []interface{}) []traceFilterSummary {
+	t.Helper()
+	var summaries []traceFilterSummary
+	for _, trace := range traces {
+		blob, err := json.Marshal(trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields struct {
+			Type   string `json:"type"`
+			Action struct {
+				From          *common.Address `json:"from"`
+				To            *common.Address `json:"to"`
+				Address       *common.Address `json:"address"`
+				RefundAddress *common.Address `json:"refundAddress"`
+				Author        *common.Address `json:"author"`
+			} `json:"action"`
+			Result *struct {
+				Address *common.Address `json:"address"`
+			} `json:"result"`
+			BlockNumber  uint64 `json:"blockNumber"`
+			TraceAddress []int  `json:"traceAddress"`
+		}
+		if err := json.Unmarshal(blob, &fields); err != nil {
+			t.Fatal(err)
+		}
+		s := traceFilterSummary{Block: fields.BlockNumber, Type: fields.Type, TraceAddress: fields.TraceAddress}
+		for _, addr := range []*common.Address{fields.Action.From, fields.Action.Address} {
+			if addr != nil {
+				s.From = *addr
+			}
+		}
+		for _, addr := range []*common.Address{fields.Action.To, fields.Action.RefundAddress, fields.Action.Author} {
+			if addr != nil {
+				s.To = *addr
+			}
+		}
+		if fields.Type == "create" && fields.Result != nil {
+			s.To = *fields.Result.Address
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+func newTraceFilterBackend(t *testing.T) (*filterTestBackend, []filterTestAccount, common.Address, common.Address, common.Address) {
+	// The keys are fixed, so that the traces match the recorded OpenEthereum output.
+	accounts := make([]filterTestAccount, 3)
+	for i, hex := range []string{
+		"b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291",
+		"8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a",
+		"49a7b37aa6f6645917e7b807e9d1c00d4fa71f18343b0d4122a4d2df64dd6fee",
+	} {
+		key, _ := crypto.HexToECDSA(hex)
+		accounts[i] = filterTestAccount{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+	}
+	var (
+		coinbase  = common.HexToAddress("0xc014ba5e")
+		forwarder = common.HexToAddress("0xf0f0")
+		created   = crypto.CreateAddress(accounts[0].addr, 1)
+	)
+	// The forwarder contract calls accounts[2] with the received value.
+	code := append(append([]byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x34, 0x73}, accounts[2].addr.Bytes()...), 0x5a, 0xf1, 0x00)
+	genesis := &genesisT.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: genesisT.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(vars.Ether)},
+			accounts[1].addr: {Balance: big.NewInt(vars.Ether)},
+			forwarder:        {Code: code, Balance: big.NewInt(0)},
+		},
+	}
+	signer := types.HomesteadSigner{}
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 3, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(coinbase)
+		switch i {
+		case 0:
+			// Block 1: transfer from accounts[0] to accounts[1].
+			tx, _ := types.SignTx(types.NewTransaction(0, accounts[1].addr, big.NewInt(1000), vars.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+		case 2:
+			// Block 3: call accounts[2] through the forwarder from accounts[1], and a contract creation by accounts[0].
+			tx, _ := types.SignTx(types.NewTransaction(0, forwarder, big.NewInt(100), 100000, b.BaseFee(), nil), signer, accounts[1].key)
+			b.AddTx(tx)
+			tx, _ = types.SignTx(types.NewContractCreation(1, big.NewInt(0), 100000, b.BaseFee(), []byte{0x00}), signer, accounts[0].key)
+			b.AddTx(tx)
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	return &filterTestBackend{chaindb: db, chain: chain}, accounts, coinbase, forwarder, created
+}
+
+func TestTraceFilter(t *testing.T) {
+	t.Parallel()
+
+	backend, accounts, coinbase, forwarder, created := newTraceFilterBackend(t)
+	defer backend.chain.Stop()
+	api := tracers.NewTraceAPI(tracers.NewAPI(backend))
+
+	var (
+		transfer = traceFilterSummary{Block: 1, Type: "call", From: accounts[0].addr, To: accounts[1].addr, TraceAddress: []int{}}
+		reward1  = traceFilterSummary{Block: 1, Type: "reward", To: coinbase, TraceAddress: []int{}}
+		reward2  = traceFilterSummary{Block: 2, Type: "reward", To: coinbase, TraceAddress: []int{}}
+		forward  = traceFilterSummary{Block: 3, Type: "call", From: accounts[1].addr, To: forwarder, TraceAddress: []int{}}
+		subcall  = traceFilterSummary{Block: 3, Type: "call", From: forwarder, To: accounts[2].addr, TraceAddress: []int{0}}
+		create   = traceFilterSummary{Block: 3, Type: "create", From: accounts[0].addr, To: created, TraceAddress: []int{}}
+		reward3  = traceFilterSummary{Block: 3, Type: "reward", To: coinbase, TraceAddress: []int{}}
+	)
+	block := func(n rpc.BlockNumber) *rpc.BlockNumber { return &n }
+	var cases = []struct {
+		args tracers.TraceFilterArgs
+		want []traceFilterSummary
+	}{
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(0), ToBlock: block(3)},
+			want: []traceFilterSummary{transfer, reward1, reward2, forward, subcall, create, reward3},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(2)},
+			want: []traceFilterSummary{reward2, forward, subcall, create, reward3},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), ToBlock: block(1)},
+			want: []traceFilterSummary{transfer, reward1},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), FromAddress: []common.Address{accounts[0].addr}},
+			want: []traceFilterSummary{transfer, create},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), FromAddress: []common.Address{accounts[0].addr}, ToAddress: []common.Address{accounts[1].addr}},
+			want: []traceFilterSummary{transfer},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), ToAddress: []common.Address{coinbase, created}},
+			want: []traceFilterSummary{reward1, reward2, create, reward3},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), FromAddress: []common.Address{forwarder, coinbase}},
+			want: []traceFilterSummary{subcall},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), ToAddress: []common.Address{accounts[2].addr}},
+			want: []traceFilterSummary{subcall},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), After: 1, Count: 3},
+			want: []traceFilterSummary{reward1, reward2, forward},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), ToAddress: []common.Address{coinbase}, After: 2},
+			want: []traceFilterSummary{reward3},
+		},
+		{
+			args: tracers.TraceFilterArgs{FromBlock: block(1), After: 7},
+			want: nil,
+		},
+	}
+	for i, tc := range cases {
+		traces, err := api.Filter(context.Background(), tc.args, nil)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if have := summarizeTraces(t, traces); !reflect.DeepEqual(have, tc.want) {
+			t.Errorf("case %d: traces mismatch\nhave: %+v\nwant: %+v", i, have, tc.want)
+		}
+	}
+
+	if _, err := api.Filter(context.Background(), tracers.TraceFilterArgs{FromBlock: block(3), ToBlock: block(1)}, nil); err == nil {
+		t.Error("expected error for reversed block range")
+	}
+	tracer := "stateDiffTracer"
+	if _, err := api.Filter(context.Background(), tracers.TraceFilterArgs{}, &tracers.TraceConfig{Tracer: &tracer}); err == nil {
+		t.Error("expected error for unsupported tracer")
+	}
+
+	// Results beyond the cap are rejected, unless paged within it.
+	backend.traceFilterCap = 3
+	if _, err := api.Filter(context.Background(), tracers.TraceFilterArgs{FromBlock: block(1)}, nil); err == nil {
+		t.Error("expected error for results exceeding the cap")
+	}
+	if _, err := api.Filter(context.Background(), tracers.TraceFilterArgs{FromBlock: block(1), Count: 4}, nil); err == nil {
+		t.Error("expected error for count exceeding the cap")
+	}
+	traces, err := api.Filter(context.Background(), tracers.TraceFilterArgs{FromBlock: block(1), After: 4, Count: 3}, nil)
+	if err != nil {
+		t.Fatalf("paged filter failed: %v", err)
+	}
+	if have, want := summarizeTraces(t, traces), []traceFilterSummary{subcall, create, reward3}; !reflect.DeepEqual(have, want) {
+		t.Errorf("paged traces mismatch\nhave: %+v\nwant: %+v", have, want)
+	}
+	if traces, err = api.Filter(context.Background(), tracers.TraceFilterArgs{FromBlock: block(1), ToBlock: block(1)}, nil); err != nil || len(traces) != 2 {
+		t.Errorf("filter within the cap failed: %d traces, %v", len(traces), err)
+	}
+}
+
+// TestTraceFilterParityOutput tests the traces against the recorded output of
+// OpenEthereum in testdata/trace_filter, served as a response and streamed over
+// a subscription.
+func TestTraceFilterParityOutput(t *testing.T) {
+	t.Parallel()
+
+	backend, _, _, _, _ := newTraceFilterBackend(t)
+	defer backend.chain.Stop()
+
+	traceAPI := tracers.NewTraceAPI(tracers.NewAPI(backend))
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("trace", traceAPI); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("trace", tracers.NewTraceSubscriptionAPI(traceAPI)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	files, err := filepath.Glob("testdata/trace_filter/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no trace filter fixtures")
+	}
+	for _, file := range files {
+		blob, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fixture struct {
+			Request tracers.TraceFilterArgs `json:"request"`
+			Result  []json.RawMessage       `json:"result"`
+		}
+		if err := json.Unmarshal(blob, &fixture); err != nil {
+			t.Fatalf("%s: invalid fixture: %v", file, err)
+		}
+		want := make([]string, len(fixture.Result))
+		for i, trace := range fixture.Result {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, trace); err != nil {
+				t.Fatal(err)
+			}
+			want[i] = compact.String()
+		}
+		// The response is the array of the traces.
+		var response json.RawMessage
+		if err := client.Call(&response, "trace_filter", fixture.Request); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if have, want := string(response), "["+strings.Join(want, ",")+"]"; have != want {
+			t.Errorf("%s: response mismatch\nhave: %s\nwant: %s", file, have, want)
+		}
+		// The subscription notifies the traces one by one.
+		ch := make(chan json.RawMessage)
+		sub, err := client.Subscribe(context.Background(), "trace", ch, "filter", fixture.Request)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		for i := range want {
+			select {
+			case trace := <-ch:
+				if string(trace) != want[i] {
+					t.Errorf("%s: notification %d mismatch\nhave: %s\nwant: %s", file, i, trace, want[i])
+				}
+			case err := <-sub.Err():
+				t.Fatalf("%s: %v", file, err)
+			case <-time.After(10 * time.Second):
+				t.Fatalf("%s: timeout, have %d traces, want %d", file, i, len(want))
+			}
+		}
+		select {
+		case trace := <-ch:
+			t.Errorf("%s: unexpected notification %s", file, trace)
+		case <-time.After(50 * time.Millisecond):
+		}
+		sub.Unsubscribe()
+	}
+}
+
+func TestTraceFilterSubscription(t *testing.T) {
+	t.Parallel()
+
+	backend, accounts, _, _, _ := newTraceFilterBackend(t)
+	defer backend.chain.Stop()
+
+	traceAPI := tracers.NewTraceAPI(tracers.NewAPI(backend))
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("trace", traceAPI); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("trace", tracers.NewTraceSubscriptionAPI(traceAPI)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	from := rpc.BlockNumber(1)
+	args := tracers.TraceFilterArgs{FromBlock: &from, FromAddress: []common.Address{accounts[0].addr}}
+
+	// The plain method and the subscription share their name.
+	var want []interface{}
+	if err := client.Call(&want, "trace_filter", args); err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 2 {
+		t.Fatalf("trace_filter: have %d traces, want 2", len(want))
+	}
+	ch := make(chan interface{})
+	sub, err := client.Subscribe(context.Background(), "trace", ch, "filter", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	var have []interface{}
+	for len(have) < len(want) {
+		select {
+		case trace := <-ch:
+			have = append(have, trace)
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout, have %d traces, want %d", len(have), len(want))
+		}
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("subscription traces mismatch\nhave: %v\nwant: %v", have, want)
+	}
+}
+
+// indexFilterTestBackend is a tracer backend maintaining a trace index, which
+// records the blocks whose state is requested for tracing.
+type indexFilterTestBackend struct {
+	*filterTestBackend
+	indexer *core.ChainIndexer
+	size    uint64
+
+	lock   sync.Mutex
+	states []uint64
+}
+
+func (b *indexFilterTestBackend) TraceIndexStatus() (uint64, uint64) {
+	sections, _, _ := b.indexer.Sections()
+	return b.size, sections
+}
+
+func (b *indexFilterTestBackend) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, readOnly bool, preferDisk bool) (*state.StateDB, tracers.StateReleaseFunc, error) {
+	b.lock.Lock()
+	b.states = append(b.states, block.NumberU64())
+	b.lock.Unlock()
+	return b.filterTestBackend.StateAtBlock(ctx, block, reexec, base, readOnly, preferDisk)
+}
+
+func waitTraceIndex(t *testing.T, indexer *core.ChainIndexer, sections uint64) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if have, _, _ := indexer.Sections(); have >= sections {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %d trace index sections", sections)
+		}
+	}
+}
+
+func TestTraceFilterIndex(t *testing.T) {
+	t.Parallel()
+
+	backend, accounts, coinbase, forwarder, created := newTraceFilterBackend(t)
+	defer backend.chain.Stop()
+
+	// Index the first section of two blocks, leaving blocks 2 and 3 unindexed.
+	const size = 2
+	indexer := tracers.NewTraceIndexer(backend, size, 1)
+	indexer.Start(backend.chain)
+	waitTraceIndex(t, indexer, 1)
+	indexer.Close()
+
+	// The index survives restarts.
+	indexer = tracers.NewTraceIndexer(backend, size, 1)
+	defer indexer.Close()
+	if sections, _, _ := indexer.Sections(); sections != 1 {
+		t.Fatalf("reopened trace index sections mismatch: have %d, want 1", sections)
+	}
+	indexed := &indexFilterTestBackend{filterTestBackend: backend, indexer: indexer, size: size}
+
+	var (
+		api        = tracers.NewTraceAPI(tracers.NewAPI(backend))
+		indexedAPI = tracers.NewTraceAPI(tracers.NewAPI(indexed))
+		block      = func(n rpc.BlockNumber) *rpc.BlockNumber { return &n }
+	)
+	for i, args := range []tracers.TraceFilterArgs{
+		{FromBlock: block(0), ToBlock: block(3)},
+		{FromBlock: block(0), FromAddress: []common.Address{accounts[0].addr}},
+		{FromBlock: block(1), FromAddress: []common.Address{accounts[1].addr}},
+		{FromBlock: block(0), FromAddress: []common.Address{accounts[0].addr}, ToAddress: []common.Address{accounts[1].addr}},
+		{FromBlock: block(0), ToAddress: []common.Address{coinbase, created}},
+		{FromBlock: block(0), FromAddress: []common.Address{forwarder}, ToAddress: []common.Address{accounts[2].addr}},
+		{FromBlock: block(2), ToBlock: block(2), ToAddress: []common.Address{coinbase}},
+		{FromBlock: block(0), ToAddress: []common.Address{coinbase}, After: 1, Count: 1},
+	} {
+		want, err := api.Filter(context.Background(), args, nil)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		have, err := indexedAPI.Filter(context.Background(), args, nil)
+		if err != nil {
+			t.Fatalf("case %d: indexed: %v", i, err)
+		}
+		if !reflect.DeepEqual(summarizeTraces(t, have), summarizeTraces(t, want)) {
+			t.Errorf("case %d: indexed traces mismatch\nhave: %v\nwant: %v", i, summarizeTraces(t, have), summarizeTraces(t, want))
+		}
+	}
+
+	// Only the indexed blocks involving the filter addresses are traced.
+	indexed.states = nil
+	args := tracers.TraceFilterArgs{FromBlock: block(0), ToBlock: block(1), FromAddress: []common.Address{accounts[2].addr}}
+	if traces, err := indexedAPI.Filter(context.Background(), args, nil); err != nil || len(traces) != 0 {
+		t.Fatalf("unexpected traces %v, err %v", traces, err)
+	}
+	args = tracers.TraceFilterArgs{FromBlock: block(0), ToBlock: block(1), ToAddress: []common.Address{accounts[1].addr}}
+	if traces, err := indexedAPI.Filter(context.Background(), args, nil); err != nil || len(traces) != 1 {
+		t.Fatalf("unexpected traces %v, err %v", traces, err)
+	}
+	if want := []uint64{0}; !reflect.DeepEqual(indexed.states, want) {
+		t.Errorf("traced states mismatch: have %v, want %v", indexed.states, want)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/state"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/core/vm"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+// replayTracers are the tracers producing each of the replay trace types.
+var replayTracers = map[string]string{
+	"trace":     "callTracerParity",
+	"stateDiff": "stateDiffTracer",
+	"vmTrace":   "vmTraceTracer",
+}
+
+// replayOmittedFields are the fields of the flattened traces OpenEthereum does
+// not return from the replay methods.
+var replayOmittedFields = []string{"blockHash", "blockNumber", "transactionHash", "transactionPosition"}
+
+// TraceReplayResult is the OpenEthereum formatted result of a replayed
+// transaction. The trace types which were not requested are empty.
+type TraceReplayResult struct {
+	Output          hexutil.Bytes     `json:"output"`
+	StateDiff       json.RawMessage   `json:"stateDiff"`
+	Trace           []json.RawMessage `json:"trace"`
+	VmTrace         json.RawMessage   `json:"vmTrace"`
+	TransactionHash *common.Hash      `json:"transactionHash,omitempty"`
+}
+
+// replayTracerConfig returns the mux tracer configuration running the tracers
+// of the requested trace types.
+func replayTracerConfig(traceTypes []string) (json.RawMessage, error) {
+	config := make(map[string]json.RawMessage)
+	for _, typ := range traceTypes {
+		tracer, ok := replayTracers[typ]
+		if !ok {
+			return nil, fmt.Errorf("unknown trace type %q", typ)
+		}
+		config[tracer] = json.RawMessage("{}")
+	}
+	return json.Marshal(config)
+}
+
+// replayTx executes the given message in the provided environment, running the
+// tracers of the mux tracer configuration.
+func (api *TraceAPI) replayTx(ctx context.Context, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, tracerConfig json.RawMessage, config *TraceConfig) (*TraceReplayResult, error) {
+	tracer, err := DefaultDirectory.New("muxTracer", txctx, tracerConfig)
+	if err != nil {
+		return nil, err
+	}
+	result, err := api.debugAPI.applyTracedMessage(ctx, message, txctx, vmctx, statedb, tracer, config)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := tracer.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	var traces map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &traces); err != nil {
+		return nil, err
+	}
+	replay := &TraceReplayResult{
+		Output:    result.ReturnData,
+		StateDiff: traces[replayTracers["stateDiff"]],
+		Trace:     []json.RawMessage{},
+		VmTrace:   traces[replayTracers["vmTrace"]],
+	}
+	if replay.Output == nil {
+		replay.Output = []byte{}
+	}
+	if blob, ok := traces[replayTracers["trace"]]; ok {
+		var frames []map[string]json.RawMessage
+		if err := json.Unmarshal(blob, &frames); err != nil {
+			return nil, err
+		}
+		for _, frame := range frames {
+			for _, field := range replayOmittedFields {
+				delete(frame, field)
+			}
+			trace, err := json.Marshal(frame)
+			if err != nil {
+				return nil, err
+			}
+			replay.Trace = append(replay.Trace, trace)
+		}
+	}
+	return replay, nil
+}
+
+// ReplayTransaction replays the transaction, returning the requested trace types
+// out of "trace", "stateDiff" and "vmTrace".
+func (api *TraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string, config *TraceConfig) (*TraceReplayResult, error) {
+	tracerConfig, err := replayTracerConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &TraceConfig{}
+	}
+	tx, blockHash, blockNumber, index, err := api.debugAPI.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	// Only mined txes are supported
+	if tx == nil {
+		return nil, errTxNotFound
+	}
+	// It shouldn't happen in practice.
+	if blockNumber == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	reexec := defaultTraceReexec
+	if config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	block, err := api.debugAPI.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	msg, vmctx, statedb, release, err := api.debugAPI.backend.StateAtTransaction(ctx, block, int(index), reexec)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	txctx := &Context{
+		BlockHash:   blockHash,
+		BlockNumber: block.Number(),
+		TxIndex:     int(index),
+		TxHash:      hash,
+	}
+	return api.replayTx(ctx, msg, txctx, vmctx, statedb, tracerConfig, config)
+}
+
+// ReplayBlockTransactions replays all the transactions of the block, returning
+// the requested trace types out of "trace", "stateDiff" and "vmTrace".
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string, config *TraceConfig) ([]*TraceReplayResult, error) {
+	tracerConfig, err := replayTracerConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &TraceConfig{}
+	}
+	block, err := api.debugAPI.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	results := []*TraceReplayResult{}
+	if len(block.Transactions()) == 0 {
+		return results, nil
+	}
+	parent, err := api.debugAPI.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	reexec := defaultTraceReexec
+	if config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, release, err := api.debugAPI.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		chainConfig = api.debugAPI.backend.ChainConfig()
+		blockHash   = block.Hash()
+		// EIP161d is what yuriy0803/core-geth1 calls eip158.
+		isEIP161D = chainConfig.IsEnabled(chainConfig.GetEIP161dTransition, block.Number())
+		blockCtx  = core.NewEVMBlockContext(block.Header(), api.debugAPI.chainContext(ctx), nil)
+		signer    = types.MakeSigner(chainConfig, block.Number(), block.Time())
+	)
+	for i, tx := range block.Transactions() {
+		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
+		txctx := &Context{
+			BlockHash:   blockHash,
+			BlockNumber: block.Number(),
+			TxIndex:     i,
+			TxHash:      tx.Hash(),
+		}
+		result, err := api.replayTx(ctx, msg, txctx, blockCtx, statedb, tracerConfig, config)
+		if err != nil {
+			return nil, err
+		}
+		hash := tx.Hash()
+		result.TransactionHash = &hash
+		results = append(results, result)
+
+		// Finalize the state so any modifications are written to the trie
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		statedb.Finalise(isEIP161D)
+	}
+	return results, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/eth/tracers"
+)
+
+// replayVMTrace is the decoded vmTrace of a replayed transaction.
+type replayVMTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []struct {
+		Cost uint64 `json:"cost"`
+		Ex   *struct {
+			Mem *struct {
+				Data hexutil.Bytes `json:"data"`
+				Off  uint64        `json:"off"`
+			} `json:"mem"`
+			Push  []*hexutil.Big `json:"push"`
+			Store *struct {
+				Key *hexutil.Big `json:"key"`
+				Val *hexutil.Big `json:"val"`
+			} `json:"store"`
+			Used uint64 `json:"used"`
+		} `json:"ex"`
+		Pc  uint64         `json:"pc"`
+		Sub *replayVMTrace `json:"sub"`
+	} `json:"ops"`
+}
+
+func TestTraceReplay(t *testing.T) {
+	t.Parallel()
+
+	backend, accounts, _, forwarder, _ := newTraceFilterBackend(t)
+	defer backend.chain.Stop()
+	api := tracers.NewTraceAPI(tracers.NewAPI(backend))
+	txs := backend.chain.GetBlockByNumber(3).Transactions()
+
+	results, err := api.ReplayBlockTransactions(context.Background(), 3, []string{"trace", "vmTrace", "stateDiff"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results mismatch: have %d, want 2", len(results))
+	}
+	for i, result := range results {
+		if result.TransactionHash == nil || *result.TransactionHash != txs[i].Hash() {
+			t.Errorf("result %d: transaction hash mismatch: have %v, want %x", i, result.TransactionHash, txs[i].Hash())
+		}
+	}
+
+	// The call through the forwarder has a call trace and a sub call trace,
+	// without the block and transaction fields.
+	forward := results[0]
+	if len(forward.Trace) != 2 {
+		t.Fatalf("forward traces mismatch: have %d, want 2", len(forward.Trace))
+	}
+	for _, trace := range forward.Trace {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(trace, &fields); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{"blockHash", "blockNumber", "transactionHash", "transactionPosition"} {
+			if _, ok := fields[field]; ok {
+				t.Errorf("unexpected trace field %q: %s", field, trace)
+			}
+		}
+	}
+	var diff map[common.Address]json.RawMessage
+	if err := json.Unmarshal(forward.StateDiff, &diff); err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []common.Address{accounts[1].addr, accounts[2].addr} {
+		if _, ok := diff[addr]; !ok {
+			t.Errorf("state diff of %x missing: %s", addr, forward.StateDiff)
+		}
+	}
+
+	// The forwarder executes PUSH1 0 (x4), CALLVALUE, PUSH20, GAS, CALL and STOP.
+	var vmTrace replayVMTrace
+	if err := json.Unmarshal(forward.VmTrace, &vmTrace); err != nil {
+		t.Fatal(err)
+	}
+	state, err := backend.chain.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := state.GetCode(forwarder); !reflect.DeepEqual([]byte(vmTrace.Code), code) {
+		t.Errorf("vmTrace code mismatch: have %x, want %x", vmTrace.Code, code)
+	}
+	wantPcs := []uint64{0, 2, 4, 6, 8, 9, 30, 31, 32}
+	if len(vmTrace.Ops) != len(wantPcs) {
+		t.Fatalf("vmTrace ops mismatch: have %d, want %d", len(vmTrace.Ops), len(wantPcs))
+	}
+	for i, op := range vmTrace.Ops {
+		if op.Pc != wantPcs[i] {
+			t.Errorf("op %d: pc mismatch: have %d, want %d", i, op.Pc, wantPcs[i])
+		}
+		if op.Ex == nil {
+			t.Fatalf("op %d: effects missing", i)
+		}
+		// The cost of the call includes the gas it forwards, partly refunded.
+		if i > 0 && i != 7 && vmTrace.Ops[i-1].Ex.Used != op.Ex.Used+op.Cost {
+			t.Errorf("op %d: gas mismatch: have %d remaining after cost %d, previous %d", i, op.Ex.Used, op.Cost, vmTrace.Ops[i-1].Ex.Used)
+		}
+	}
+	recipient := hexutil.EncodeBig(new(big.Int).SetBytes(accounts[2].addr.Bytes()))
+	for i, want := range map[int][]string{0: {"0x0"}, 4: {"0x64"}, 5: {recipient}, 7: {"0x1"}, 8: {}} {
+		have := []string{}
+		for _, item := range vmTrace.Ops[i].Ex.Push {
+			have = append(have, item.String())
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("op %d: pushed items mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	if vmTrace.Ops[7].Sub != nil {
+		t.Errorf("call to an account without code has a sub trace: %+v", vmTrace.Ops[7].Sub)
+	}
+
+	// The contract creation executes STOP and deploys no code.
+	if err := json.Unmarshal(results[1].VmTrace, &vmTrace); err != nil {
+		t.Fatal(err)
+	}
+	if vmTrace.Code.String() != "0x00" || len(vmTrace.Ops) != 1 {
+		t.Errorf("creation vmTrace mismatch: %s", results[1].VmTrace)
+	}
+	if len(results[1].Output) != 0 {
+		t.Errorf("creation output mismatch: have %x, want empty", results[1].Output)
+	}
+
+	// Replaying a single transaction returns the requested trace types only.
+	replay, err := api.ReplayTransaction(context.Background(), txs[0].Hash(), []string{"trace"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replay.Trace, forward.Trace) {
+		t.Errorf("replayed traces mismatch\nhave: %s\nwant: %s", replay.Trace, forward.Trace)
+	}
+	blob, err := json.Marshal(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if string(fields["output"]) != `"0x"` || string(fields["stateDiff"]) != "null" || string(fields["vmTrace"]) != "null" {
+		t.Errorf("unexpected replay result: %s", blob)
+	}
+	if _, ok := fields["transactionHash"]; ok {
+		t.Errorf("unexpected transaction hash: %s", blob)
+	}
+
+	if _, err := api.ReplayTransaction(context.Background(), txs[0].Hash(), []string{"trace", "unknown"}, nil); err == nil {
+		t.Error("unknown trace type accepted")
+	}
+}
@@ -125,6 +125,10 @@ func (b *testBackend) RPCGasCap() uint64 {
 	return 25000000
 }
 
+func (b *testBackend) RPCTraceFilterCap() uint64 {
+	return 0
+}
+
 func (b *testBackend) ChainConfig() ctypes.ChainConfigurator {
 	return b.chainConfig
 }
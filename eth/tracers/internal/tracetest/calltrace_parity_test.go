@@ -340,3 +340,91 @@ func jsonEqualStateDiff(x, y interface{}) bool {
 	}
 	return reflect.DeepEqual(xTrace, yTrace)
 }
+
+// vmTraceTest defines a single test to check the OpenEthereum formatted VM
+// trace against.
+type vmTraceTest struct {
+	Genesis *genesisT.Genesis       `json:"genesis"`
+	Context *callContext            `json:"context"`
+	Input   *ethapi.TransactionArgs `json:"input"`
+	Result  json.RawMessage         `json:"result"`
+}
+
+func vmTraceTracerTestRunner(tracerName string, filename string, dirPath string, t testing.TB) error {
+	blob, err := os.ReadFile(filepath.Join("testdata", dirPath, filename))
+	if err != nil {
+		return fmt.Errorf("failed to read testcase: %v", err)
+	}
+	test := new(vmTraceTest)
+	if err := json.Unmarshal(blob, test); err != nil {
+		return fmt.Errorf("failed to parse testcase: %v", err)
+	}
+	msg, err := test.Input.ToMessage(uint64(test.Context.GasLimit), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %v", err)
+	}
+	txContext := vm.TxContext{
+		Origin:   msg.From,
+		GasPrice: msg.GasPrice,
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        uint64(test.Context.Time),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+
+	tracer, err := tracers.DefaultDirectory.New(tracerName, new(tracers.Context), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create vm tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Tracer: tracer})
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	if _, err = st.TransitionDb(); err != nil {
+		return fmt.Errorf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve trace result: %v", err)
+	}
+	var have, want interface{}
+	if err := json.Unmarshal(res, &have); err != nil {
+		return fmt.Errorf("failed to unmarshal trace result: %v", err)
+	}
+	if err := json.Unmarshal(test.Result, &want); err != nil {
+		return fmt.Errorf("failed to unmarshal expected result: %v", err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		for _, l := range deep.Equal(have, want) {
+			t.Logf("%s", l)
+		}
+		t.Fatalf("trace mismatch: \nhave %s\nwant %s", res, test.Result)
+	}
+	return nil
+}
+
+// TestVMTraceTracerNative runs the VM trace tracer against the OpenEthereum
+// formatted traces of the test harness.
+func TestVMTraceTracerNative(t *testing.T) {
+	files, err := os.ReadDir(filepath.Join("testdata", "vm_trace"))
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(file.Name(), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			if err := vmTraceTracerTestRunner("vmTraceTracer", file.Name(), "vm_trace", t); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -65,6 +65,16 @@ func (t *muxTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Add
 	}
 }
 
+// CapturePreEVM implements vm.EVMLogger_StateCapturer, passing the EVM on to the
+// tracers capturing the state before the execution.
+func (t *muxTracer) CapturePreEVM(env *vm.EVM) {
+	for _, t := range t.tracers {
+		if t, ok := t.(vm.EVMLogger_StateCapturer); ok {
+			t.CapturePreEVM(env)
+		}
+	}
+}
+
 // CaptureEnd is called after the call finishes to finalize the tracing.
 func (t *muxTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
 	for _, t := range t.tracers {
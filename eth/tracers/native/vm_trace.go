@@ -0,0 +1,290 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/holiman/uint256"
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/core/vm"
+	"github.com/yuriy0803/core-geth1/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("vmTraceTracer", newVMTraceTracer, false)
+}
+
+// vmTrace is the OpenEthereum formatted execution trace of a call frame.
+type vmTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []*vmTraceOp  `json:"ops"`
+}
+
+// vmTraceOp is an executed instruction. Its effects are nil if the instruction
+// failed, and its sub trace is the execution of the call frame it entered.
+type vmTraceOp struct {
+	Cost uint64     `json:"cost"`
+	Ex   *vmTraceEx `json:"ex"`
+	Pc   uint64     `json:"pc"`
+	Sub  *vmTrace   `json:"sub"`
+}
+
+// vmTraceEx holds the effects of an executed instruction: the stack items it
+// pushed, the memory and storage it wrote, and the gas remaining afterwards.
+type vmTraceEx struct {
+	Mem   *vmTraceMem    `json:"mem"`
+	Push  []*hexutil.Big `json:"push"`
+	Store *vmTraceStore  `json:"store"`
+	Used  uint64         `json:"used"`
+}
+
+type vmTraceMem struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  uint64        `json:"off"`
+}
+
+type vmTraceStore struct {
+	Key *hexutil.Big `json:"key"`
+	Val *hexutil.Big `json:"val"`
+}
+
+// vmTraceFrame is a call frame being traced. The effects of its last instruction
+// are only known at the next one, or once the frame exits.
+type vmTraceFrame struct {
+	trace   *vmTrace
+	op      *vmTraceOp // last instruction, pending its effects
+	gas     uint64     // gas remaining before the last instruction
+	pushes  int        // number of stack items pushed by the last instruction
+	memOff  uint64     // offset of the memory written by the last instruction
+	memSize uint64     // size of the memory written by the last instruction
+}
+
+type vmTraceTracer struct {
+	env       *vm.EVM
+	root      *vmTrace
+	frames    []*vmTraceFrame
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newVMTraceTracer returns a native go tracer which records the OpenEthereum
+// formatted VM execution trace of a tx, and implements vm.EVMLogger.
+func newVMTraceTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &vmTraceTracer{}, nil
+}
+
+func (t *vmTraceTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *vmTraceTracer) CaptureTxEnd(restGas uint64) {}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *vmTraceTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.root = t.enter(to, create, input)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *vmTraceTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit()
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *vmTraceTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	frame.finish(scope, gas)
+
+	step := &vmTraceOp{Cost: cost, Pc: pc}
+	frame.trace.Ops = append(frame.trace.Ops, step)
+	if err != nil {
+		// The instruction failed before its execution.
+		return
+	}
+	step.Ex = new(vmTraceEx)
+	frame.op, frame.gas, frame.pushes = step, gas, vmTracePushes(op)
+	frame.memOff, frame.memSize = vmTraceMemoryWritten(op, scope.Stack.Data())
+
+	if data := scope.Stack.Data(); op == vm.SSTORE && len(data) >= 2 {
+		step.Ex.Store = &vmTraceStore{
+			Key: (*hexutil.Big)(data[len(data)-1].ToBig()),
+			Val: (*hexutil.Big)(data[len(data)-2].ToBig()),
+		}
+	}
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *vmTraceTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	// A reverting instruction executed successfully, the others have no effects.
+	if frame := t.frames[len(t.frames)-1]; frame.op != nil && !errors.Is(err, vm.ErrExecutionReverted) {
+		frame.op.Ex, frame.op = nil, nil
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *vmTraceTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	var parent *vmTraceOp
+	if len(t.frames) > 0 {
+		parent = t.frames[len(t.frames)-1].op
+	}
+	sub := t.enter(to, typ == vm.CREATE || typ == vm.CREATE2, input)
+	if parent != nil && typ != vm.SELFDESTRUCT && len(sub.Code) > 0 {
+		parent.Sub = sub
+	}
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *vmTraceTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	t.exit()
+}
+
+// enter starts tracing a call frame executing the code of the given account, or
+// the init code of a contract creation.
+func (t *vmTraceTracer) enter(to common.Address, create bool, input []byte) *vmTrace {
+	trace := &vmTrace{Ops: []*vmTraceOp{}}
+	if create {
+		trace.Code = common.CopyBytes(input)
+	} else {
+		trace.Code = t.env.StateDB.GetCode(to)
+	}
+	t.frames = append(t.frames, &vmTraceFrame{trace: trace})
+	return trace
+}
+
+// exit finishes tracing the current call frame.
+func (t *vmTraceTracer) exit() {
+	if len(t.frames) == 0 {
+		return
+	}
+	t.frames[len(t.frames)-1].finish(nil, 0)
+	t.frames = t.frames[:len(t.frames)-1]
+}
+
+// finish records the effects of the last instruction of the frame, given the
+// frame scope and gas remaining after it. Without a scope, the frame exited
+// after the instruction.
+func (f *vmTraceFrame) finish(scope *vm.ScopeContext, gas uint64) {
+	if f.op == nil {
+		return
+	}
+	ex := f.op.Ex
+	ex.Push = []*hexutil.Big{}
+	if scope == nil {
+		ex.Used = f.gas - f.op.Cost
+		f.op = nil
+		return
+	}
+	ex.Used = gas
+	if data := scope.Stack.Data(); f.pushes <= len(data) {
+		for _, item := range data[len(data)-f.pushes:] {
+			ex.Push = append(ex.Push, (*hexutil.Big)(item.ToBig()))
+		}
+	}
+	if f.memSize > 0 && f.memOff+f.memSize <= uint64(scope.Memory.Len()) {
+		ex.Mem = &vmTraceMem{
+			Data: scope.Memory.GetCopy(int64(f.memOff), int64(f.memSize)),
+			Off:  f.memOff,
+		}
+	}
+	f.op = nil
+}
+
+// vmTracePushes returns the number of stack items pushed by the instruction.
+// Like OpenEthereum, the DUP and SWAP instructions push all the items they move.
+func vmTracePushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.PUSH1 && op <= vm.PUSH32:
+		return 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return 0
+	}
+	switch op {
+	case vm.STOP, vm.POP, vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.TSTORE, vm.JUMP, vm.JUMPI, vm.JUMPDEST,
+		vm.CALLDATACOPY, vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY, vm.MCOPY,
+		vm.RETURN, vm.REVERT, vm.SELFDESTRUCT, vm.INVALID:
+		return 0
+	}
+	return 1
+}
+
+// vmTraceMemoryWritten returns the offset and size of the memory the instruction
+// writes, given the stack before its execution.
+func vmTraceMemoryWritten(op vm.OpCode, stack []uint256.Int) (uint64, uint64) {
+	back := func(n int) *uint256.Int {
+		if n >= len(stack) {
+			return new(uint256.Int)
+		}
+		return &stack[len(stack)-1-n]
+	}
+	var off, size *uint256.Int
+	switch op {
+	case vm.MSTORE:
+		off, size = back(0), uint256.NewInt(32)
+	case vm.MSTORE8:
+		off, size = back(0), uint256.NewInt(1)
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY, vm.MCOPY:
+		off, size = back(0), back(2)
+	case vm.EXTCODECOPY:
+		off, size = back(1), back(3)
+	case vm.CALL, vm.CALLCODE:
+		off, size = back(5), back(6)
+	case vm.DELEGATECALL, vm.STATICCALL:
+		off, size = back(4), back(5)
+	default:
+		return 0, 0
+	}
+	if !off.IsUint64() || !size.IsUint64() {
+		return 0, 0
+	}
+	return off.Uint64(), size.Uint64()
+}
+
+// GetResult returns the json-encoded VM execution trace, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *vmTraceTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.root)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *vmTraceTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/bitutil"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/ethdb"
+)
+
+const (
+	// traceIndexThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	traceIndexThrottling = 100 * time.Millisecond
+)
+
+// traceIndexBackend is implemented by the backends maintaining a trace index
+// created by NewTraceIndexer.
+type traceIndexBackend interface {
+	// TraceIndexStatus returns the section size of the trace index, and the
+	// number of sections indexed so far.
+	TraceIndexStatus() (uint64, uint64)
+}
+
+// TraceIndexer implements a core.ChainIndexer, building up an index of the
+// addresses appearing as senders, recipients, created or destructed contracts,
+// and reward authors in the Parity traces of the canonical blocks. The index
+// permits trace_filter to only trace the blocks involving the filtered addresses.
+type TraceIndexer struct {
+	api     *TraceAPI                 // tracing API used to trace the indexed blocks
+	db      ethdb.Database            // database instance to write index data into
+	size    uint64                    // section size to generate the index for
+	section uint64                    // section number being processed currently
+	head    common.Hash               // hash of the last header processed
+	blocks  map[common.Address][]byte // bit vectors of the section blocks each address appears in
+}
+
+// NewTraceIndexer returns a chain indexer that generates the trace index for the
+// canonical chain. Tracing the blocks requires their parent state, so the index
+// is meant for archive nodes.
+func NewTraceIndexer(backend Backend, size, confirms uint64) *core.ChainIndexer {
+	db := backend.ChainDb()
+	indexer := &TraceIndexer{
+		api:  NewTraceAPI(NewAPI(backend)),
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.TraceIndexTablePrefix))
+
+	return core.NewChainIndexer(db, table, indexer, size, confirms, traceIndexThrottling, "traceindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new trace index section.
+func (t *TraceIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	t.section, t.head, t.blocks = section, common.Hash{}, make(map[common.Address][]byte)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, tracing the block of the header
+// and adding the addresses appearing in its traces into the index.
+func (t *TraceIndexer) Process(ctx context.Context, header *types.Header) error {
+	block, err := t.api.debugAPI.blockByHash(ctx, header.Hash())
+	if err != nil {
+		return err
+	}
+	if len(block.Transactions()) > 0 {
+		tracer := "callTracerParity"
+		txs, err := t.api.debugAPI.traceBlock(ctx, block, &TraceConfig{Tracer: &tracer})
+		if err != nil {
+			return fmt.Errorf("failed to trace block #%d: %w", block.NumberU64(), err)
+		}
+		traces, err := flattenTraces(txs)
+		if err != nil {
+			return err
+		}
+		for _, trace := range traces {
+			if err := t.addTrace(block.NumberU64(), trace); err != nil {
+				return err
+			}
+		}
+	}
+	// The rewards are not traced, their authors are the coinbases.
+	t.add(block.NumberU64(), block.Coinbase())
+	for _, uncle := range block.Uncles() {
+		t.add(block.NumberU64(), uncle.Coinbase)
+	}
+	t.head = header.Hash()
+	return nil
+}
+
+// addTrace adds the sender and recipient of the flattened trace into the index.
+func (t *TraceIndexer) addTrace(number uint64, trace json.RawMessage) error {
+	var fields traceFilterFields
+	if err := json.Unmarshal(trace, &fields); err != nil {
+		return err
+	}
+	from, to := fields.addresses()
+	for _, addr := range []*common.Address{from, to} {
+		if addr != nil {
+			t.add(number, *addr)
+		}
+	}
+	return nil
+}
+
+// add marks the address as appearing in the given block.
+func (t *TraceIndexer) add(number uint64, addr common.Address) {
+	bits, ok := t.blocks[addr]
+	if !ok {
+		bits = make([]byte, (t.size+7)/8)
+		t.blocks[addr] = bits
+	}
+	i := number - t.section*t.size
+	bits[i/8] |= 1 << (7 - i%8)
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the trace index section
+// and writing it out into the database.
+func (t *TraceIndexer) Commit() error {
+	batch := t.db.NewBatch()
+	for addr, bits := range t.blocks {
+		rawdb.WriteTraceIndexBits(batch, addr, t.section, t.head, bitutil.CompressBytes(bits))
+	}
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (t *TraceIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// indexedBlocks returns the blocks between first and last, both included, which
+// the trace index covers and whose traces involve the filter addresses, and the
+// first block after the range covered by the trace index. Without a trace index
+// or filter addresses, no blocks are covered.
+func (api *TraceAPI) indexedBlocks(first, last uint64, filter *traceFilter) ([]uint64, uint64, error) {
+	backend, ok := api.debugAPI.backend.(traceIndexBackend)
+	if !ok || (len(filter.from) == 0 && len(filter.to) == 0) {
+		return nil, first, nil
+	}
+	size, sections := backend.TraceIndexStatus()
+	if sections*size <= first {
+		return nil, first, nil
+	}
+	var (
+		db     = api.debugAPI.backend.ChainDb()
+		length = int((size + 7) / 8)
+		blocks []uint64
+	)
+	for section := first / size; section < sections && section*size <= last; section++ {
+		head := rawdb.ReadCanonicalHash(db, (section+1)*size-1)
+
+		// A trace matches if it is sent from any of the senders and to any of
+		// the recipients, so the block must involve one of each.
+		candidates := make([]byte, length)
+		for i := range candidates {
+			candidates[i] = 0xff
+		}
+		for _, addrs := range []map[common.Address]struct{}{filter.from, filter.to} {
+			if len(addrs) == 0 {
+				continue
+			}
+			involved := make([]byte, length)
+			for addr := range addrs {
+				data := rawdb.ReadTraceIndexBits(db, addr, section, head)
+				if data == nil {
+					continue
+				}
+				bits, err := bitutil.DecompressBytes(data, length)
+				if err != nil {
+					return nil, 0, err
+				}
+				bitutil.ORBytes(involved, involved, bits)
+			}
+			bitutil.ANDBytes(candidates, candidates, involved)
+		}
+		for i := uint64(0); i < size; i++ {
+			number := section*size + i
+			if number < first || number > last {
+				continue
+			}
+			if candidates[i/8]&(1<<(7-i%8)) != 0 {
+				blocks = append(blocks, number)
+			}
+		}
+	}
+	return blocks, sections * size, nil
+}
@@ -308,6 +308,9 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"BlockReceipts": {
+			func(t *testing.T) { testBlockReceipts(t, client) },
+		},
 	}
 
 	t.Parallel()
@@ -744,6 +747,37 @@ func testTransactionSender(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testBlockReceipts(t *testing.T, client *rpc.Client) {
+	ec := NewClient(client)
+	ctx := context.Background()
+
+	// The receipts of block #2 match the ones of its transactions.
+	receipts, err := ec.BlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 2 {
+		t.Fatalf("wrong receipt count: have %d, want 2", len(receipts))
+	}
+	for i, tx := range []*types.Transaction{testTx1, testTx2} {
+		want, err := ec.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(receipts[i], want) {
+			t.Fatalf("receipt %d mismatch: have %+v, want %+v", i, receipts[i], want)
+		}
+	}
+	// The receipts of an empty block are an empty list.
+	if receipts, err := ec.BlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(1)); err != nil || len(receipts) != 0 {
+		t.Fatalf("wrong receipts of empty block: %v, %v", receipts, err)
+	}
+	// Unknown blocks are reported as not found.
+	if _, err := ec.BlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(3)); err != ethereum.NotFound {
+		t.Fatalf("unknown block: have %v, want %v", err, ethereum.NotFound)
+	}
+}
+
 func sendTransaction(ec *Client) error {
 	chainID, err := ec.ChainID(context.Background())
 	if err != nil {
@@ -1099,6 +1133,7 @@ var allRPCMethods = []string{
 	"admin_datadir",
 	"admin_ecbp1100",
 	"admin_exportChain",
+	"admin_forkidReport",
 	"admin_importChain",
 	"admin_maxPeers",
 	"admin_nodeInfo",
@@ -1120,8 +1155,11 @@ var allRPCMethods = []string{
 	"debug_cpuProfile",
 	"debug_dbAncient",
 	"debug_dbAncients",
+	"debug_dbCheck",
 	"debug_dbGet",
 	"debug_dumpBlock",
+	"debug_ecbp1100Decisions",
+	"debug_estimateGasVerbose",
 	"debug_freeOSMemory",
 	"debug_gcStats",
 	"debug_getAccessibleState",
@@ -1132,6 +1170,7 @@ var allRPCMethods = []string{
 	"debug_getRawHeader",
 	"debug_getRawReceipts",
 	"debug_getRawTransaction",
+	"debug_getStateDiff",
 	"debug_goTrace",
 	"debug_intermediateRoots",
 	"debug_memStats",
@@ -1181,6 +1220,7 @@ var allRPCMethods = []string{
 	"eth_getBalance",
 	"eth_getBlockByHash",
 	"eth_getBlockByNumber",
+	"eth_getBlockReceipts",
 	"eth_getBlockTransactionCountByHash",
 	"eth_getBlockTransactionCountByNumber",
 	"eth_getCode",
@@ -1222,6 +1262,7 @@ var allRPCMethods = []string{
 	"eth_sendTransaction",
 	"eth_sign",
 	"eth_signTransaction",
+	"eth_simulate",
 	"eth_submitHashrate",
 	"eth_submitWork",
 	"eth_subscribe",
@@ -1260,6 +1301,8 @@ var allRPCMethods = []string{
 	"trace_call",
 	"trace_callMany",
 	"trace_filter",
+	"trace_replayBlockTransactions",
+	"trace_replayTransaction",
 	"trace_subscribe",
 	"trace_transaction",
 	"trace_unsubscribe",
@@ -166,6 +166,62 @@ func (ec *Client) CallContractWithBlockOverrides(ctx context.Context, msg ethere
 	return hex, err
 }
 
+// Simulate executes a series of blocks of message calls, which are directly executed
+// in the VM of the node, but never mined into the blockchain. The state changes of
+// every call carry forward into the next ones, also across blocks.
+//
+// blockNumber selects the block the simulation starts from. It can be nil, in which
+// case the latest known block is used.
+//
+// traceTransfers reports the ETH transfers of the calls as ERC-20 Transfer logs of the
+// 0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE address.
+func (ec *Client) Simulate(ctx context.Context, blocks []SimulateBlock, traceTransfers bool, blockNumber *big.Int) ([]SimulateBlockResult, error) {
+	type callResult struct {
+		ReturnData hexutil.Bytes      `json:"returnData"`
+		Logs       []*types.Log       `json:"logs"`
+		GasUsed    hexutil.Uint64     `json:"gasUsed"`
+		Status     hexutil.Uint64     `json:"status"`
+		Error      *SimulateCallError `json:"error"`
+	}
+	type blockResult struct {
+		Calls []callResult `json:"calls"`
+	}
+	opts := map[string]interface{}{
+		"blockStateCalls": blocks,
+		"traceTransfers":  traceTransfers,
+	}
+	var raw []json.RawMessage
+	if err := ec.c.CallContext(ctx, &raw, "eth_simulate", opts, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	// Turn hexutils back to normal datatypes
+	results := make([]SimulateBlockResult, 0, len(raw))
+	for _, blob := range raw {
+		var (
+			header types.Header
+			res    blockResult
+		)
+		if err := json.Unmarshal(blob, &header); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(blob, &res); err != nil {
+			return nil, err
+		}
+		calls := make([]SimulateCallResult, 0, len(res.Calls))
+		for _, call := range res.Calls {
+			calls = append(calls, SimulateCallResult{
+				ReturnData: call.ReturnData,
+				Logs:       call.Logs,
+				GasUsed:    uint64(call.GasUsed),
+				Status:     uint64(call.Status),
+				Error:      call.Error,
+			})
+		}
+		results = append(results, SimulateBlockResult{Header: &header, Calls: calls})
+	}
+	return results, nil
+}
+
 // GCStats retrieves the current garbage collection stats from a geth node.
 func (ec *Client) GCStats(ctx context.Context) (*debug.GCStats, error) {
 	var result debug.GCStats
@@ -333,3 +389,56 @@ func (o BlockOverrides) MarshalJSON() ([]byte, error) {
 	}
 	return json.Marshal(output)
 }
+
+// SimulateBlock is a block of message calls to simulate.
+type SimulateBlock struct {
+	// BlockOverrides overrides the fields of the block, if non-nil. Blocks missing
+	// between the overridden block number and the previous block are simulated
+	// empty.
+	BlockOverrides *BlockOverrides
+	// StateOverrides overrides the state before executing the calls, if non-nil.
+	StateOverrides *map[common.Address]OverrideAccount
+	// Calls are the message calls executed in the block.
+	Calls []ethereum.CallMsg
+}
+
+func (b SimulateBlock) MarshalJSON() ([]byte, error) {
+	type block struct {
+		BlockOverrides *BlockOverrides                     `json:"blockOverrides,omitempty"`
+		StateOverrides *map[common.Address]OverrideAccount `json:"stateOverrides,omitempty"`
+		Calls          []interface{}                       `json:"calls"`
+	}
+
+	output := block{
+		BlockOverrides: b.BlockOverrides,
+		StateOverrides: b.StateOverrides,
+		Calls:          make([]interface{}, 0, len(b.Calls)),
+	}
+	for _, msg := range b.Calls {
+		output.Calls = append(output.Calls, toCallArg(msg))
+	}
+	return json.Marshal(output)
+}
+
+// SimulateBlockResult is the result of a simulated block.
+type SimulateBlockResult struct {
+	Header *types.Header
+	Calls  []SimulateCallResult
+}
+
+// SimulateCallResult is the result of a simulated message call.
+type SimulateCallResult struct {
+	ReturnData []byte
+	Logs       []*types.Log
+	GasUsed    uint64
+	Status     uint64
+	// Error is set if the call failed, in which case the status is zero.
+	Error *SimulateCallError
+}
+
+// SimulateCallError is the error of a simulated message call which failed in the VM.
+type SimulateCallError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Data    string `json:"data,omitempty"`
+}
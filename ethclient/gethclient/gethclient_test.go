@@ -133,6 +133,9 @@ func TestGethClient(t *testing.T) {
 		}, {
 			"TestCallContractWithBlockOverrides",
 			func(t *testing.T) { testCallContractWithBlockOverrides(t, client) },
+		}, {
+			"TestSimulate",
+			func(t *testing.T) { testSimulate(t, client) },
 		},
 		// The testaccesslist is a bit time-sensitive: the newTestBackend imports
 		// one block. The `testAcessList` fails if the miner has not yet created a
@@ -522,3 +525,46 @@ func testCallContractWithBlockOverrides(t *testing.T, client *rpc.Client) {
 		t.Fatalf("unexpected result: %x", res)
 	}
 }
+
+func testSimulate(t *testing.T, client *rpc.Client) {
+	ec := New(client)
+	contract := common.HexToAddress("0xc0de")
+	overrides := map[common.Address]OverrideAccount{
+		// Returns the block number.
+		contract: {Code: common.FromHex("0x4360005260206000f3")},
+	}
+	call := ethereum.CallMsg{From: testAddr, To: &contract, Value: big.NewInt(1)}
+	blocks := []SimulateBlock{
+		{StateOverrides: &overrides, Calls: []ethereum.CallMsg{call}},
+		{BlockOverrides: &BlockOverrides{Number: big.NewInt(4)}, Calls: []ethereum.CallMsg{call, call}},
+	}
+	results, err := ec.Simulate(context.Background(), blocks, true, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("unexpected number of blocks: %d", len(results))
+	}
+	for i, result := range results {
+		if number := result.Header.Number.Uint64(); number != uint64(i+2) {
+			t.Fatalf("block %d: unexpected number %d", i, number)
+		}
+		if i > 0 && result.Header.ParentHash != results[i-1].Header.Hash() {
+			t.Fatalf("block %d: unexpected parent hash", i)
+		}
+	}
+	if len(results[1].Calls) != 0 || len(results[2].Calls) != 2 {
+		t.Fatalf("unexpected calls: %d, %d", len(results[1].Calls), len(results[2].Calls))
+	}
+	for _, call := range append(results[0].Calls, results[2].Calls...) {
+		if call.Status != types.ReceiptStatusSuccessful || call.Error != nil {
+			t.Fatalf("call failed: %v", call.Error)
+		}
+		if len(call.Logs) != 1 || call.Logs[0].Topics[2] != common.BytesToHash(contract.Bytes()) {
+			t.Fatalf("unexpected transfer logs: %v", call.Logs)
+		}
+	}
+	if number := new(big.Int).SetBytes(results[2].Calls[1].ReturnData); number.Uint64() != 4 {
+		t.Fatalf("unexpected result: %d", number)
+	}
+}
@@ -0,0 +1,503 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bbolt implements the key-value database layer based on bbolt, a
+// memory mapped B+tree storage engine.
+//
+// Reads are served straight from the memory map without any compaction
+// overhead, which makes it a good fit for read-heavy workloads such as archive
+// nodes serving RPC requests. Every write transaction is synced to disk however,
+// so it is considerably slower than the LSM engines while syncing the chain.
+package bbolt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/ethdb"
+	"github.com/yuriy0803/core-geth1/log"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	// FileName is the name of the database file created within the database
+	// directory.
+	FileName = "bbolt.db"
+
+	// initialMmapSize is the initial size of the memory map. The map must be
+	// grown while no read transaction is live, so it is reserved generously to
+	// avoid write transactions waiting for long lived snapshots.
+	initialMmapSize = 1 << 30
+
+	// iteratorChunk is the number of entries an iterator retrieves within a
+	// single read transaction.
+	iteratorChunk = 256
+)
+
+var (
+	// errClosed is returned if a database was already closed at the invocation
+	// of a data access operation.
+	errClosed = errors.New("database closed")
+
+	// errNotFound is returned if a key is requested that is not found in the
+	// database.
+	errNotFound = errors.New("not found")
+
+	// errSnapshotReleased is returned if callers want to retrieve data from a
+	// released snapshot.
+	errSnapshotReleased = errors.New("snapshot released")
+
+	// bucket is the name of the single bucket all the data is stored in.
+	bucket = []byte("ethdb")
+)
+
+// Database is a persistent key-value store based on the bbolt storage engine.
+// Apart from basic data storage functionality it also supports batch writes and
+// iterating over the keyspace in binary-alphabetical order.
+//
+// Note, bbolt does not support empty keys.
+type Database struct {
+	fn string    // filename for reporting
+	db *bbolt.DB // Underlying bbolt storage engine
+
+	lock      sync.Mutex             // Mutex protecting the snapshot set and the closed flag
+	snapshots map[*snapshot]struct{} // Live snapshots, released when closing the database
+	closed    bool                   // keep track of whether we're Closed
+}
+
+// New returns a wrapped bbolt object. The cache and handles allowances are
+// ignored, bbolt relies on the page cache of the operating system for caching
+// the memory mapped database file.
+func New(file string, cache int, handles int, namespace string, readonly bool) (*Database, error) {
+	log.Info("Allocating bbolt database", "database", file, "readonly", readonly)
+
+	if !readonly {
+		if err := os.MkdirAll(file, 0700); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bbolt.Open(filepath.Join(file, FileName), 0600, &bbolt.Options{
+		Timeout:         time.Second,
+		NoFreelistSync:  true,
+		FreelistType:    bbolt.FreelistMapType,
+		ReadOnly:        readonly,
+		InitialMmapSize: initialMmapSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !readonly {
+		if err := db.Update(func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			return err
+		}); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &Database{
+		fn:        file,
+		db:        db,
+		snapshots: make(map[*snapshot]struct{}),
+	}, nil
+}
+
+// Exists reports whether a bbolt database is present in the given directory.
+func Exists(file string) bool {
+	_, err := os.Stat(filepath.Join(file, FileName))
+	return err == nil
+}
+
+// Close releases any live snapshots and closes the database, ensuring any
+// consecutive data access op fails with an error.
+func (d *Database) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+
+	// Read transactions block closing the database, release them first
+	for snap := range d.snapshots {
+		snap.tx.Rollback()
+		snap.tx = nil
+	}
+	d.snapshots = nil
+	return d.db.Close()
+}
+
+// view runs the given function within a read transaction on the data bucket.
+func (d *Database) view(fn func(b *bbolt.Bucket) error) error {
+	return d.db.View(func(tx *bbolt.Tx) error {
+		// The bucket is missing only if a new database was opened readonly
+		if b := tx.Bucket(bucket); b != nil {
+			return fn(b)
+		}
+		return nil
+	})
+}
+
+// update runs the given function within a write transaction on the data bucket.
+func (d *Database) update(fn func(b *bbolt.Bucket) error) error {
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		return fn(tx.Bucket(bucket))
+	})
+}
+
+// get retrieves the given key from the bucket. A cursor is used, as the plain
+// lookup can't tell a missing key from an empty value.
+func get(b *bbolt.Bucket, key []byte) ([]byte, bool) {
+	k, v := b.Cursor().Seek(key)
+	if k == nil || !bytes.Equal(k, key) {
+		return nil, false
+	}
+	return common.CopyBytes(v), true
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (d *Database) Has(key []byte) (bool, error) {
+	var ok bool
+	err := d.view(func(b *bbolt.Bucket) error {
+		_, ok = get(b, key)
+		return nil
+	})
+	return ok, err
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (d *Database) Get(key []byte) ([]byte, error) {
+	var (
+		val []byte
+		ok  bool
+	)
+	if err := d.view(func(b *bbolt.Bucket) error {
+		val, ok = get(b, key)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNotFound
+	}
+	if val == nil {
+		val = []byte{}
+	}
+	return val, nil
+}
+
+// Put inserts the given value into the key-value store.
+func (d *Database) Put(key []byte, value []byte) error {
+	return d.update(func(b *bbolt.Bucket) error {
+		return b.Put(key, value)
+	})
+}
+
+// Delete removes the key from the key-value store.
+func (d *Database) Delete(key []byte) error {
+	return d.update(func(b *bbolt.Bucket) error {
+		return b.Delete(key)
+	})
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (d *Database) NewBatch() ethdb.Batch {
+	return &batch{db: d}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (d *Database) NewBatchWithSize(size int) ethdb.Batch {
+	return &batch{db: d, writes: make([]keyvalue, 0, size)}
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset
+// of database content with a particular key prefix, starting at a particular
+// initial key (or after, if it does not exist).
+//
+// The iterator reads the database in chunks, each from its own read transaction,
+// so that it never holds up write transactions. Writes made while iterating may
+// or may not be observed by it.
+func (d *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	return &iterator{
+		db:     d,
+		prefix: common.CopyBytes(prefix),
+		next:   append(common.CopyBytes(prefix), start...),
+		index:  -1,
+	}
+}
+
+// NewSnapshot creates a database snapshot based on the current state.
+// The created snapshot will not be affected by all following mutations
+// happened on the database.
+//
+// The snapshot holds a read transaction until released, during which the
+// memory map can't be grown. Snapshots should thus be short lived.
+func (d *Database) NewSnapshot() (ethdb.Snapshot, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.closed {
+		return nil, errClosed
+	}
+	tx, err := d.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{db: d, tx: tx}
+	d.snapshots[snap] = struct{}{}
+	return snap, nil
+}
+
+// Stat returns a particular internal stat of the database.
+func (d *Database) Stat(property string) (string, error) {
+	stats := d.db.Stats()
+	return fmt.Sprintf("Read transactions: %d (open %d)\nFree pages: %d (pending %d, allocated %d bytes, in use %d bytes)\n",
+		stats.TxN, stats.OpenTxN, stats.FreePageN, stats.PendingPageN, stats.FreeAlloc, stats.FreelistInuse), nil
+}
+
+// Compact is a noop, bbolt reuses the pages freed by deletions without needing
+// any compaction and can only be shrunk by copying it offline.
+func (d *Database) Compact(start []byte, limit []byte) error {
+	return nil
+}
+
+// keyvalue is a key-value tuple tagged with a deletion field to allow creating
+// memory-database write batches.
+type keyvalue struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// batch is a write-only bbolt batch that commits changes to its host database
+// when Write is called. A batch cannot be used concurrently.
+type batch struct {
+	db     *Database
+	writes []keyvalue
+	size   int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), common.CopyBytes(value), false})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete inserts the key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), nil, true})
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes any accumulated data to disk within a single transaction.
+func (b *batch) Write() error {
+	return b.db.update(func(bk *bbolt.Bucket) error {
+		for _, kv := range b.writes {
+			var err error
+			if kv.delete {
+				err = bk.Delete(kv.key)
+			} else {
+				err = bk.Put(kv.key, kv.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, kv := range b.writes {
+		if kv.delete {
+			if err := w.Delete(kv.key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(kv.key, kv.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterator can walk over the (potentially partial) keyspace of a bbolt
+// database, retrieving it chunk by chunk.
+type iterator struct {
+	db     *Database
+	prefix []byte
+	next   []byte // Key to continue the iteration from
+	done   bool   // Whether the last chunk was retrieved
+	keys   [][]byte
+	values [][]byte
+	index  int
+	err    error
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.index < len(it.keys) {
+		it.index++
+	}
+	if it.index < len(it.keys) {
+		return true
+	}
+	if it.done {
+		return false
+	}
+	start := it.next
+	it.keys, it.values, it.index, it.done = it.keys[:0], it.values[:0], 0, true
+
+	err := it.db.view(func(b *bbolt.Bucket) error {
+		var (
+			c    = b.Cursor()
+			k, v = c.First()
+		)
+		if len(start) > 0 {
+			k, v = c.Seek(start)
+		}
+		for ; k != nil && bytes.HasPrefix(k, it.prefix); k, v = c.Next() {
+			if len(it.keys) == iteratorChunk {
+				it.next, it.done = common.CopyBytes(k), false
+				break
+			}
+			it.keys = append(it.keys, common.CopyBytes(k))
+			it.values = append(it.values, common.CopyBytes(v))
+		}
+		return nil
+	})
+	if err != nil {
+		it.err, it.keys, it.values = err, nil, nil
+		return false
+	}
+	return len(it.keys) > 0
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done. The caller
+// should not modify the contents of the returned slice, and its contents may
+// change on the next call to Next.
+func (it *iterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.index]
+}
+
+// Value returns the value of the current key/value pair, or nil if done. The
+// caller should not modify the contents of the returned slice, and its contents
+// may change on the next call to Next.
+func (it *iterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.values[it.index]
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (it *iterator) Release() {
+	it.keys, it.values, it.index, it.done = nil, nil, 0, true
+}
+
+// snapshot wraps a bbolt read transaction, which sees the database as of the
+// time it was opened.
+type snapshot struct {
+	db *Database
+	tx *bbolt.Tx // Read transaction, nil if released
+}
+
+// get retrieves the given key from the snapshot.
+func (snap *snapshot) get(key []byte) ([]byte, bool, error) {
+	snap.db.lock.Lock()
+	defer snap.db.lock.Unlock()
+
+	if snap.tx == nil {
+		return nil, false, errSnapshotReleased
+	}
+	b := snap.tx.Bucket(bucket)
+	if b == nil {
+		return nil, false, nil
+	}
+	val, ok := get(b, key)
+	return val, ok, nil
+}
+
+// Has retrieves if a key is present in the snapshot backing by a key-value
+// data store.
+func (snap *snapshot) Has(key []byte) (bool, error) {
+	_, ok, err := snap.get(key)
+	return ok, err
+}
+
+// Get retrieves the given key if it's present in the snapshot backing by
+// key-value data store.
+func (snap *snapshot) Get(key []byte) ([]byte, error) {
+	val, ok, err := snap.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNotFound
+	}
+	if val == nil {
+		val = []byte{}
+	}
+	return val, nil
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (snap *snapshot) Release() {
+	snap.db.lock.Lock()
+	defer snap.db.lock.Unlock()
+
+	if snap.tx == nil {
+		return
+	}
+	snap.tx.Rollback()
+	snap.tx = nil
+	delete(snap.db.snapshots, snap)
+}
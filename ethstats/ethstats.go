@@ -82,6 +82,12 @@ type fullNodeBackend interface {
 	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
 }
 
+// artificialFinalityBackend is implemented by the backends able to report the
+// status of the ECBP1100 (MESS) artificial finality.
+type artificialFinalityBackend interface {
+	ArtificialFinalityEnabled() bool
+}
+
 // Service implements an Ethereum netstats reporting daemon that pushes local
 // chain statistics up to a monitoring server.
 type Service struct {
@@ -759,13 +765,14 @@ func (s *Service) reportPending(conn *connWrapper) error {
 
 // nodeStats is the information to report about the local node.
 type nodeStats struct {
-	Active   bool `json:"active"`
-	Syncing  bool `json:"syncing"`
-	Mining   bool `json:"mining"`
-	Hashrate int  `json:"hashrate"`
-	Peers    int  `json:"peers"`
-	GasPrice int  `json:"gasPrice"`
-	Uptime   int  `json:"uptime"`
+	Active             bool `json:"active"`
+	Syncing            bool `json:"syncing"`
+	Mining             bool `json:"mining"`
+	Hashrate           int  `json:"hashrate"`
+	Peers              int  `json:"peers"`
+	GasPrice           int  `json:"gasPrice"`
+	Uptime             int  `json:"uptime"`
+	ArtificialFinality bool `json:"artificialFinality"`
 }
 
 // reportStats retrieves various stats about the node at the networking and
@@ -777,6 +784,7 @@ func (s *Service) reportStats(conn *connWrapper) error {
 		hashrate int
 		syncing  bool
 		gasprice int
+		finality bool
 	)
 	// check if backend is a full node
 	fullBackend, ok := s.backend.(fullNodeBackend)
@@ -796,19 +804,23 @@ func (s *Service) reportStats(conn *connWrapper) error {
 		sync := s.backend.SyncProgress()
 		syncing = s.backend.CurrentHeader().Number.Uint64() >= sync.HighestBlock
 	}
+	if afBackend, ok := s.backend.(artificialFinalityBackend); ok {
+		finality = afBackend.ArtificialFinalityEnabled()
+	}
 	// Assemble the node stats and send it to the server
 	log.Trace("Sending node details to ethstats")
 
 	stats := map[string]interface{}{
 		"id": s.node,
 		"stats": &nodeStats{
-			Active:   true,
-			Mining:   mining,
-			Hashrate: hashrate,
-			Peers:    s.server.PeerCount(),
-			GasPrice: gasprice,
-			Syncing:  syncing,
-			Uptime:   100,
+			Active:             true,
+			Mining:             mining,
+			Hashrate:           hashrate,
+			Peers:              s.server.PeerCount(),
+			GasPrice:           gasprice,
+			Syncing:            syncing,
+			Uptime:             100,
+			ArtificialFinality: finality,
 		},
 	}
 	report := map[string][]interface{}{
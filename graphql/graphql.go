@@ -19,6 +19,7 @@ package graphql
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -26,6 +27,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/yuriy0803/core-geth1"
 	"github.com/yuriy0803/core-geth1/common"
@@ -35,15 +37,30 @@ import (
 	"github.com/yuriy0803/core-geth1/core/state"
 	"github.com/yuriy0803/core-geth1/core/types"
 	"github.com/yuriy0803/core-geth1/eth/filters"
+	"github.com/yuriy0803/core-geth1/eth/tracers"
 	"github.com/yuriy0803/core-geth1/internal/ethapi"
+	"github.com/yuriy0803/core-geth1/metrics"
+	"github.com/yuriy0803/core-geth1/params/confp"
+	"github.com/yuriy0803/core-geth1/params/mutations"
 	"github.com/yuriy0803/core-geth1/rlp"
 	"github.com/yuriy0803/core-geth1/rpc"
 )
 
 var (
 	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
+	errTraceDisabled  = errors.New("transaction tracing is disabled")
 )
 
+// maxTraceTimeout is the maximum runtime limit of a transaction trace.
+const maxTraceTimeout = 10 * time.Second
+
+// subscriptionBuffer is the number of events buffered per subscription. Events
+// are dropped while the buffer of a slow client is full, so that the shared
+// event system isn't held up.
+const subscriptionBuffer = 128
+
+var droppedEventsMeter = metrics.NewRegisteredMeter("graphql/subscriptions/dropped", nil)
+
 type Long int64
 
 // ImplementsGraphQLType returns true if Long implements the provided GraphQL type.
@@ -77,6 +94,38 @@ func (b *Long) UnmarshalGraphQL(input interface{}) error {
 	return err
 }
 
+// JSON is an arbitrary JSON value.
+type JSON json.RawMessage
+
+// ImplementsGraphQLType returns true if JSON implements the provided GraphQL type.
+func (j JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data. Strings are
+// decoded as JSON text, any other value is taken as is.
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	if input, ok := input.(string); ok {
+		if !json.Valid([]byte(input)) {
+			return errors.New("invalid JSON string")
+		}
+		*j = JSON(input)
+		return nil
+	}
+	blob, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	*j = blob
+	return nil
+}
+
+// MarshalJSON returns the JSON value as is.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
 // Account represents an Ethereum account at a particular block.
 type Account struct {
 	r             *Resolver
@@ -592,6 +641,134 @@ func (t *Transaction) RawReceipt(ctx context.Context) (hexutil.Bytes, error) {
 	return receipt.MarshalBinary()
 }
 
+func (t *Transaction) Trace(ctx context.Context, args struct {
+	Tracer *string
+	Config *JSON
+}) (*JSON, error) {
+	if !t.r.tracing {
+		return nil, errTraceDisabled
+	}
+	_, block := t.resolve(ctx)
+	if block == nil {
+		return nil, nil
+	}
+	backend, ok := t.r.backend.(tracers.Backend)
+	if !ok {
+		return nil, errors.New("tracing not supported by the backend")
+	}
+	config := new(tracers.TraceConfig)
+	if args.Config != nil {
+		if err := json.Unmarshal(*args.Config, config); err != nil {
+			return nil, fmt.Errorf("invalid trace config: %v", err)
+		}
+	}
+	if args.Tracer != nil {
+		config.Tracer = args.Tracer
+	}
+	// Only the native tracers are served, the struct logger and JavaScript
+	// tracers being too expensive to expose.
+	if config.Tracer == nil || tracers.DefaultDirectory.IsJS(*config.Tracer) {
+		return nil, errors.New("only native tracers are supported")
+	}
+	timeout := maxTraceTimeout
+	if config.Timeout != nil {
+		requested, err := time.ParseDuration(*config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trace timeout: %v", err)
+		}
+		if requested < timeout {
+			timeout = requested
+		}
+	}
+	limit := timeout.String()
+	config.Timeout = &limit
+
+	result, err := tracers.NewAPI(backend).TraceTransaction(ctx, t.hash, config)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	ret := JSON(blob)
+	return &ret, nil
+}
+
+// Receipt represents the receipt of a transaction included in a block.
+type Receipt struct {
+	r           *Resolver
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) *hexutil.Uint64 {
+	if len(r.receipt.PostState) != 0 {
+		return nil
+	}
+	ret := hexutil.Uint64(r.receipt.Status)
+	return &ret
+}
+
+func (r *Receipt) Root(ctx context.Context) *common.Hash {
+	if len(r.receipt.PostState) == 0 {
+		return nil
+	}
+	root := common.BytesToHash(r.receipt.PostState)
+	return &root
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.GasUsed)
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.CumulativeGasUsed)
+}
+
+func (r *Receipt) EffectiveGasPrice(ctx context.Context) *hexutil.Big {
+	return (*hexutil.Big)(r.receipt.EffectiveGasPrice)
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context, args BlockNumberArgs) *Account {
+	if r.receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		r:             r.r,
+		address:       r.receipt.ContractAddress,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (r *Receipt) Logs(ctx context.Context) []*Log {
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{
+			r:           r.r,
+			transaction: r.transaction,
+			log:         log,
+		})
+	}
+	return ret
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return r.receipt.Bloom.Bytes()
+}
+
+func (r *Receipt) Type(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.Type)
+}
+
+func (r *Receipt) Raw(ctx context.Context) (hexutil.Bytes, error) {
+	return r.receipt.MarshalBinary()
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -841,6 +1018,44 @@ func (b *Block) Ommers(ctx context.Context) (*[]*Block, error) {
 	return &ret, nil
 }
 
+func (b *Block) Rewards(ctx context.Context) (*[]*BlockReward, error) {
+	config := b.r.backend.ChainConfig()
+	if !config.GetConsensusEngineType().IsEthash() {
+		return nil, nil
+	}
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	ret := make([]*BlockReward, 0, len(block.Uncles())+1)
+	// Neither the genesis nor proof-of-stake blocks pay mining rewards
+	header := block.Header()
+	if header.Number.Sign() == 0 || header.Difficulty.Sign() == 0 {
+		return &ret, nil
+	}
+	minerReward, uncleRewards := mutations.GetRewards(config, header, block.Uncles())
+	ret = append(ret, &BlockReward{
+		r:           b.r,
+		beneficiary: header.Coinbase,
+		amount:      minerReward,
+	})
+	for i, uncle := range block.Uncles() {
+		blockNumberOrHash := rpc.BlockNumberOrHashWithHash(uncle.Hash(), false)
+		ret = append(ret, &BlockReward{
+			r:           b.r,
+			beneficiary: uncle.Coinbase,
+			amount:      uncleRewards[i],
+			uncle: &Block{
+				r:            b.r,
+				numberOrHash: &blockNumberOrHash,
+				header:       uncle,
+				hash:         uncle.Hash(),
+			},
+		})
+	}
+	return &ret, nil
+}
+
 func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
@@ -967,6 +1182,36 @@ func (b *Block) TransactionAt(ctx context.Context, args struct{ Index Long }) (*
 	}, nil
 }
 
+func (b *Block) Receipts(ctx context.Context) (*[]*Receipt, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	ret := make([]*Receipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		ret = append(ret, &Receipt{
+			r: b.r,
+			transaction: &Transaction{
+				r:     b.r,
+				hash:  txs[i].Hash(),
+				tx:    txs[i],
+				block: b,
+				index: uint64(i),
+			},
+			receipt: receipt,
+		})
+	}
+	return &ret, nil
+}
+
 func (b *Block) OmmerAt(ctx context.Context, args struct{ Index Long }) (*Block, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -1086,6 +1331,30 @@ func (b *Block) Account(ctx context.Context, args struct {
 	}, nil
 }
 
+// BlockReward represents a mining reward paid in a block.
+type BlockReward struct {
+	r           *Resolver
+	beneficiary common.Address
+	amount      *big.Int
+	uncle       *Block
+}
+
+func (br *BlockReward) Beneficiary(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		r:             br.r,
+		address:       br.beneficiary,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (br *BlockReward) Amount(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*br.amount)
+}
+
+func (br *BlockReward) Uncle(ctx context.Context) *Block {
+	return br.uncle
+}
+
 // CallData encapsulates arguments to `call` or `estimateGas`.
 // All arguments are optional.
 type CallData struct {
@@ -1140,7 +1409,7 @@ func (b *Block) Call(ctx context.Context, args struct {
 func (b *Block) EstimateGas(ctx context.Context, args struct {
 	Data ethapi.TransactionArgs
 }) (hexutil.Uint64, error) {
-	return ethapi.DoEstimateGas(ctx, b.r.backend, args.Data, *b.numberOrHash, nil, b.r.backend.RPCGasCap())
+	return ethapi.DoEstimateGas(ctx, b.r.backend, args.Data, *b.numberOrHash, nil, nil, b.r.backend.RPCGasCap())
 }
 
 type Pending struct {
@@ -1204,13 +1473,25 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 	Data ethapi.TransactionArgs
 }) (hexutil.Uint64, error) {
 	latestBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
-	return ethapi.DoEstimateGas(ctx, p.r.backend, args.Data, latestBlockNr, nil, p.r.backend.RPCGasCap())
+	return ethapi.DoEstimateGas(ctx, p.r.backend, args.Data, latestBlockNr, nil, nil, p.r.backend.RPCGasCap())
 }
 
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend      ethapi.Backend
 	filterSystem *filters.FilterSystem
+	tracing      bool // Whether transactions can be traced
+
+	eventOnce   sync.Once
+	eventSystem *filters.EventSystem // Feeds the subscriptions, created on first use
+}
+
+// events returns the event system feeding the subscriptions.
+func (r *Resolver) events() *filters.EventSystem {
+	r.eventOnce.Do(func() {
+		r.eventSystem = filters.NewEventSystem(r.filterSystem, false)
+	})
+	return r.eventSystem
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -1377,6 +1658,66 @@ func (r *Resolver) ChainID(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*r.backend.ChainConfig().GetChainID()), nil
 }
 
+func (r *Resolver) ChainConfig(ctx context.Context) *ChainConfig {
+	return &ChainConfig{r}
+}
+
+// ChainConfig represents the configuration of the chain.
+type ChainConfig struct {
+	r *Resolver
+}
+
+func (c *ChainConfig) ChainID(ctx context.Context) *hexutil.Big {
+	return (*hexutil.Big)(c.r.backend.ChainConfig().GetChainID())
+}
+
+func (c *ChainConfig) NetworkID(ctx context.Context) *hexutil.Uint64 {
+	return (*hexutil.Uint64)(c.r.backend.ChainConfig().GetNetworkID())
+}
+
+func (c *ChainConfig) ConsensusEngine(ctx context.Context) string {
+	return c.r.backend.ChainConfig().GetConsensusEngineType().String()
+}
+
+func (c *ChainConfig) Forks(ctx context.Context) []*Fork {
+	var (
+		config          = c.r.backend.ChainConfig()
+		byBlock, byTime = confp.ForkTransitions(config)
+		forks           []*Fork
+	)
+	for _, n := range confp.BlockForks(config) {
+		number := hexutil.Uint64(n)
+		forks = append(forks, &Fork{block: &number, transitions: byBlock[n]})
+	}
+	for _, n := range confp.TimeForks(config) {
+		time := hexutil.Uint64(n)
+		forks = append(forks, &Fork{timestamp: &time, transitions: byTime[n]})
+	}
+	return forks
+}
+
+// Fork represents a fork of the chain, activated at a block number or timestamp.
+type Fork struct {
+	block       *hexutil.Uint64
+	timestamp   *hexutil.Uint64
+	transitions []string
+}
+
+func (f *Fork) Block(ctx context.Context) *hexutil.Uint64 {
+	return f.block
+}
+
+func (f *Fork) Timestamp(ctx context.Context) *hexutil.Uint64 {
+	return f.timestamp
+}
+
+func (f *Fork) Transitions(ctx context.Context) []string {
+	if f.transitions == nil {
+		return []string{}
+	}
+	return f.transitions
+}
+
 // SyncState represents the synchronisation status returned from the `syncing` accessor.
 type SyncState struct {
 	progress ethereum.SyncProgress
@@ -1455,3 +1796,122 @@ func (r *Resolver) Syncing() (*SyncState, error) {
 	// Otherwise gather the block sync stats
 	return &SyncState{progress}, nil
 }
+
+// NewHeads streams the blocks imported as the new head of the chain, until the
+// subscription is cancelled.
+func (r *Resolver) NewHeads(ctx context.Context) (<-chan *Block, error) {
+	startSubscription(ctx)
+
+	var (
+		headers = make(chan *types.Header)
+		sub     = r.events().SubscribeNewHeads(headers)
+		blocks  = make(chan *Block, subscriptionBuffer)
+	)
+	go func() {
+		defer sub.Unsubscribe()
+		defer close(blocks)
+
+		for {
+			select {
+			case header := <-headers:
+				blockNrOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), false)
+				block := &Block{
+					r:            r,
+					numberOrHash: &blockNrOrHash,
+					hash:         header.Hash(),
+					header:       header,
+				}
+				select {
+				case blocks <- block:
+				default:
+					droppedEventsMeter.Mark(1)
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+// NewLogs streams the logs included in the chain matching the filter, until the
+// subscription is cancelled. It is served as newLogs, since Logs resolves the
+// logs query.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	startSubscription(ctx)
+
+	matches := make(chan []*types.Log)
+	sub, err := r.events().SubscribeLogs(crit, matches)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(chan *Log, subscriptionBuffer)
+	go func() {
+		defer sub.Unsubscribe()
+		defer close(logs)
+
+		for {
+			select {
+			case batch := <-matches:
+				for _, log := range batch {
+					if log.Removed {
+						continue
+					}
+					select {
+					case logs <- &Log{r: r, transaction: &Transaction{r: r, hash: log.TxHash}, log: log}:
+					default:
+						droppedEventsMeter.Mark(1)
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+// PendingTransactions streams the transactions entering the transaction pool,
+// until the subscription is cancelled.
+func (r *Resolver) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	startSubscription(ctx)
+
+	var (
+		pending = make(chan []*types.Transaction)
+		sub     = r.events().SubscribePendingTxs(pending)
+		txs     = make(chan *Transaction, subscriptionBuffer)
+	)
+	go func() {
+		defer sub.Unsubscribe()
+		defer close(txs)
+
+		for {
+			select {
+			case batch := <-pending:
+				for _, tx := range batch {
+					select {
+					case txs <- &Transaction{r: r, hash: tx.Hash(), tx: tx}:
+					default:
+						droppedEventsMeter.Mark(1)
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs, nil
+}
@@ -23,11 +23,13 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
 	"github.com/yuriy0803/core-geth1/consensus"
 	"github.com/yuriy0803/core-geth1/consensus/beacon"
 	"github.com/yuriy0803/core-geth1/consensus/ethash"
@@ -38,11 +40,14 @@ import (
 	"github.com/yuriy0803/core-geth1/eth"
 	"github.com/yuriy0803/core-geth1/eth/ethconfig"
 	"github.com/yuriy0803/core-geth1/eth/filters"
+	_ "github.com/yuriy0803/core-geth1/eth/tracers/native"
 	"github.com/yuriy0803/core-geth1/node"
 	"github.com/yuriy0803/core-geth1/params"
 	"github.com/yuriy0803/core-geth1/params/types/genesisT"
 	"github.com/yuriy0803/core-geth1/params/vars"
 
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -296,7 +301,7 @@ func TestGraphQLConcurrentResolvers(t *testing.T) {
 	defer stack.Close()
 
 	var tx *types.Transaction
-	handler, chain := newGQLService(t, stack, false, genesis, 1, func(i int, gen *core.BlockGen) {
+	handler, chain, _ := newGQLService(t, stack, false, genesis, 1, func(i int, gen *core.BlockGen) {
 		tx, _ = types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Gas: 100000, GasPrice: big.NewInt(vars.InitialBaseFee)})
 		gen.AddTx(tx)
 		tx, _ = types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Nonce: 1, Gas: 100000, GasPrice: big.NewInt(vars.InitialBaseFee)})
@@ -329,6 +334,11 @@ func TestGraphQLConcurrentResolvers(t *testing.T) {
 			body: "{block { transactions { status gasUsed } } }",
 			want: `{"block":{"transactions":[{"status":"0x1","gasUsed":"0x5508"},{"status":"0x1","gasUsed":"0x5508"},{"status":"0x1","gasUsed":"0x5508"}]}}`,
 		},
+		// Receipts of a block are fetched at once.
+		{
+			body: "{block { receipts { status gasUsed cumulativeGasUsed logs { index } transaction { nonce } } } }",
+			want: `{"block":{"receipts":[{"status":"0x1","gasUsed":"0x5508","cumulativeGasUsed":"0x5508","logs":[{"index":"0x0"},{"index":"0x1"}],"transaction":{"nonce":"0x0"}},{"status":"0x1","gasUsed":"0x5508","cumulativeGasUsed":"0xaa10","logs":[{"index":"0x2"},{"index":"0x3"}],"transaction":{"nonce":"0x1"}},{"status":"0x1","gasUsed":"0x5508","cumulativeGasUsed":"0xff18","logs":[{"index":"0x4"},{"index":"0x5"}],"transaction":{"nonce":"0x2"}}]}}`,
+		},
 		// Multiple fields of block race to resolve header and body.
 		{
 			body: "{ block { number hash gasLimit ommerCount transactionCount totalDifficulty } }",
@@ -364,6 +374,361 @@ func TestGraphQLConcurrentResolvers(t *testing.T) {
 	}
 }
 
+func TestTraceRewardsAndChainConfig(t *testing.T) {
+	var (
+		key, _    = crypto.GenerateKey()
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		dadStr    = "0x0000000000000000000000000000000000000dad"
+		dad       = common.HexToAddress(dadStr)
+		uncleAddr = common.HexToAddress("0x0000000000000000000000000000000000000bad")
+		config    = *params.AllEthashProtocolChanges
+		genesis   = &genesisT.Genesis{
+			Config:     &config,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: genesisT.GenesisAlloc{
+				addr: {Balance: big.NewInt(vars.Ether)},
+				dad: {
+					// LOG0(0, 0), RETURN(0, 0)
+					Code:    common.Hex2Bytes("60006000a060006000f3"),
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		stack  = createNode(t)
+	)
+	defer stack.Close()
+
+	// Schedule a fork after the generated blocks.
+	config.MergeNetsplitBlock = big.NewInt(1000)
+	stack.Config().GraphQLTracing = true
+
+	var tx *types.Transaction
+	handler, chain, _ := newGQLService(t, stack, false, genesis, 3, func(i int, gen *core.BlockGen) {
+		switch i {
+		case 0:
+			tx, _ = types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Gas: 100000, GasPrice: big.NewInt(vars.InitialBaseFee)})
+			gen.AddTx(tx)
+		case 2:
+			gen.AddUncle(&types.Header{ParentHash: gen.PrevBlock(0).Hash(), Number: big.NewInt(2), Coinbase: uncleAddr})
+		}
+	})
+	// start node
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	uncle := chain[2].Uncles()[0]
+
+	for i, tt := range []struct {
+		body string
+		want string
+	}{
+		// Transactions are traced with the given tracer and config.
+		{
+			body: fmt.Sprintf(`{ transaction(hash: "%s") { trace(tracer: "callTracer", config: {tracerConfig: {withLog: true}}) } }`, tx.Hash()),
+			want: fmt.Sprintf(`{"transaction":{"trace":{"from":"%s","gas":"0x186a0","gasUsed":"0x538b","to":"%s","input":"0x","logs":[{"address":"%s","topics":[],"data":"0x"}],"value":"0x0","type":"CALL"}}}`, strings.ToLower(addr.Hex()), dadStr, dadStr),
+		},
+		// The tracer can be given by a config passed as a string.
+		{
+			body: fmt.Sprintf(`{ transaction(hash: "%s") { trace(config: "{\"tracer\": \"callTracer\", \"timeout\": \"1h\"}") } }`, tx.Hash()),
+			want: fmt.Sprintf(`{"transaction":{"trace":{"from":"%s","gas":"0x186a0","gasUsed":"0x538b","to":"%s","input":"0x","value":"0x0","type":"CALL"}}}`, strings.ToLower(addr.Hex()), dadStr),
+		},
+		// The genesis block pays no rewards.
+		{
+			body: "{ block(number: 0) { rewards { amount } } }",
+			want: `{"block":{"rewards":[]}}`,
+		},
+		// The miner is rewarded for the block and the included uncle.
+		{
+			body: "{ block(number: 1) { rewards { beneficiary { address } amount uncle { number } } } }",
+			want: `{"block":{"rewards":[{"beneficiary":{"address":"0x0000000000000000000000000000000000000000"},"amount":"0x1bc16d674ec80000","uncle":null}]}}`,
+		},
+		{
+			body: "{ block(number: 3) { rewards { beneficiary { address } amount uncle { hash number } } } }",
+			want: fmt.Sprintf(`{"block":{"rewards":[{"beneficiary":{"address":"0x0000000000000000000000000000000000000000"},"amount":"0x1c9f78d2893e4000","uncle":null},{"beneficiary":{"address":"%s"},"amount":"0x18493fba64ef0000","uncle":{"hash":"%s","number":"0x2"}}]}}`, strings.ToLower(uncleAddr.Hex()), uncle.Hash()),
+		},
+		// The chain config lists the forks after the genesis.
+		{
+			body: "{ chainConfig { chainID networkID consensusEngine forks { block timestamp transitions } } }",
+			want: `{"chainConfig":{"chainID":"0x539","networkID":"0x539","consensusEngine":"ethash","forks":[{"block":"0x3e8","timestamp":null,"transitions":["MergeVirtualTransition"]}]}}`,
+		},
+	} {
+		res := handler.Schema.Exec(context.Background(), tt.body, "", map[string]interface{}{})
+		if res.Errors != nil {
+			t.Fatalf("failed to execute query for testcase #%d: %v", i, res.Errors)
+		}
+		have, err := json.Marshal(res.Data)
+		if err != nil {
+			t.Fatalf("failed to encode graphql response for testcase #%d: %s", i, err)
+		}
+		if string(have) != tt.want {
+			t.Errorf("response unmatch for testcase #%d.\nhave:\n%s\nwant:\n%s", i, have, tt.want)
+		}
+	}
+	// Only the native tracers are served.
+	for i, tt := range []struct {
+		body string
+		want string
+	}{
+		{body: fmt.Sprintf(`{ transaction(hash: "%s") { trace } }`, tx.Hash()), want: "only native tracers are supported"},
+		{body: fmt.Sprintf(`{ transaction(hash: "%s") { trace(tracer: "{result: function() { return 1 }, fault: function() {}}") } }`, tx.Hash()), want: "only native tracers are supported"},
+		{body: fmt.Sprintf(`{ transaction(hash: "%s") { trace(tracer: "callTracer", config: {timeout: "soon"}) } }`, tx.Hash()), want: "invalid trace timeout"},
+	} {
+		res := handler.Schema.Exec(context.Background(), tt.body, "", map[string]interface{}{})
+		if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, tt.want) {
+			t.Errorf("testcase #%d: wrong errors: have %v, want %q", i, res.Errors, tt.want)
+		}
+	}
+	// Tracing is disabled by default.
+	disabled := &Transaction{r: &Resolver{}, hash: tx.Hash()}
+	if _, err := disabled.Trace(context.Background(), struct {
+		Tracer *string
+		Config *JSON
+	}{}); err != errTraceDisabled {
+		t.Errorf("wrong error with tracing disabled: have %v, want %v", err, errTraceDisabled)
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		dadStr  = "0x0000000000000000000000000000000000000dad"
+		dad     = common.HexToAddress(dadStr)
+		genesis = &genesisT.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: genesisT.GenesisAlloc{
+				addr: {Balance: big.NewInt(vars.Ether)},
+				dad: {
+					// LOG0(0, 0), RETURN(0, 0)
+					Code:    common.Hex2Bytes("60006000a060006000f3"),
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		stack  = createNode(t)
+	)
+	defer stack.Close()
+
+	_, chain, backend := newGQLService(t, stack, false, genesis, 1, func(i int, gen *core.BlockGen) {})
+	// start node
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	url := "ws" + strings.TrimPrefix(stack.HTTPEndpoint(), "http") + "/graphql"
+
+	// Connections without the graphql-transport-ws protocol are closed.
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, wsCloseBadSubprotocol) {
+		t.Fatalf("wrong error for missing subprotocol: %v", err)
+	}
+	conn.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err = dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("could not send message: %v", err)
+		}
+	}
+	receive := func() string {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("could not receive message: %v", err)
+		}
+		return string(msg)
+	}
+	send(`{"type":"connection_init"}`)
+	if msg := receive(); msg != `{"type":"connection_ack"}` {
+		t.Fatalf("wrong connection ack: %s", msg)
+	}
+	// Invalid operations fail, all others are set up once the pong arrives.
+	send(`{"id":"0","type":"subscribe","payload":{"query":"subscription { oldHeads { number } }"}}`)
+	send(`{"id":"1","type":"subscribe","payload":{"query":"subscription { newHeads { number } }"}}`)
+	send(fmt.Sprintf(`{"id":"2","type":"subscribe","payload":{"query":"subscription { newLogs(filter: {addresses: [\"%s\"]}) { account { address } transaction { nonce } } }"}}`, dadStr))
+	send(`{"id":"3","type":"subscribe","payload":{"query":"subscription { pendingTransactions { nonce } }"}}`)
+	send(`{"type":"ping"}`)
+	for pong, failed := false, false; !pong || !failed; {
+		switch msg := receive(); {
+		case msg == `{"type":"pong"}`:
+			pong = true
+		case strings.HasPrefix(msg, `{"id":"0","type":"error","payload":[{"message":"Cannot query field \"oldHeads\"`):
+			failed = true
+		default:
+			t.Fatalf("unexpected message: %s", msg)
+		}
+	}
+
+	// Send a transaction over the connection and include it in a block.
+	tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Gas: 100000, GasPrice: big.NewInt(vars.InitialBaseFee)})
+	raw, _ := tx.MarshalBinary()
+	send(fmt.Sprintf(`{"id":"4","type":"subscribe","payload":{"query":"mutation { sendRawTransaction(data: \"%s\") }"}}`, hexutil.Encode(raw)))
+
+	want := map[string]bool{
+		fmt.Sprintf(`{"id":"4","type":"next","payload":{"data":{"sendRawTransaction":"%s"}}}`, tx.Hash()): true,
+		`{"id":"4","type":"complete"}`: true,
+		`{"id":"3","type":"next","payload":{"data":{"pendingTransactions":{"nonce":"0x0"}}}}`: true,
+	}
+	for len(want) > 0 {
+		if msg := receive(); !want[msg] {
+			t.Fatalf("unexpected message: %s", msg)
+		} else {
+			delete(want, msg)
+		}
+	}
+	blocks, _ := core.GenerateChain(params.AllEthashProtocolChanges, chain[0], ethash.NewFaker(), backend.ChainDb(), 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(tx)
+	})
+	if _, err := backend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	want = map[string]bool{
+		`{"id":"1","type":"next","payload":{"data":{"newHeads":{"number":"0x2"}}}}`:                                                               true,
+		fmt.Sprintf(`{"id":"2","type":"next","payload":{"data":{"newLogs":{"account":{"address":"%s"},"transaction":{"nonce":"0x0"}}}}}`, dadStr): true,
+	}
+	for len(want) > 0 {
+		if msg := receive(); !want[msg] {
+			t.Fatalf("unexpected message: %s", msg)
+		} else {
+			delete(want, msg)
+		}
+	}
+	// Completed subscriptions are stopped, reusing an active id fails.
+	send(`{"id":"1","type":"complete"}`)
+	send(`{"id":"1","type":"subscribe","payload":{"query":"subscription { newHeads { hash } }"}}`)
+	send(`{"id":"3","type":"subscribe","payload":{"query":"subscription { newHeads { hash } }"}}`)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, wsCloseSubscriberExists) {
+		t.Fatalf("wrong error for duplicate subscription: %v", err)
+	}
+}
+
+func TestWebsocketOrigins(t *testing.T) {
+	check := wsOriginValidator([]string{"http://good.com", "https://*.example.com"})
+	for _, tt := range []struct {
+		origin string
+		host   string
+		want   bool
+	}{
+		{"", "localhost:8545", true},
+		{"http://good.com", "localhost:8545", true},
+		{"http://GOOD.com", "localhost:8545", true},
+		{"https://app.example.com", "localhost:8545", true},
+		{"http://app.example.com", "localhost:8545", false},
+		{"http://bad.com", "localhost:8545", false},
+		{"http://localhost:8545", "localhost:8545", true},
+	} {
+		r := &http.Request{Header: make(http.Header), Host: tt.host}
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if have := check(r); have != tt.want {
+			t.Errorf("origin %q, host %q: have %v, want %v", tt.origin, tt.host, have, tt.want)
+		}
+	}
+}
+
+// wsLimitsResolver serves a query running until it is cancelled, and a
+// subscription yielding a single event after a second.
+type wsLimitsResolver struct{}
+
+func (*wsLimitsResolver) Sleep(ctx context.Context) (*bool, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (*wsLimitsResolver) Tick(ctx context.Context) (<-chan int32, error) {
+	startSubscription(ctx)
+
+	ticks := make(chan int32, 1)
+	go func() {
+		defer close(ticks)
+		select {
+		case <-time.After(time.Second):
+			ticks <- 1
+			<-ctx.Done()
+		case <-ctx.Done():
+		}
+	}()
+	return ticks, nil
+}
+
+func TestWebsocketLimits(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema { query: Query subscription: Subscription }
+		type Query { sleep: Boolean }
+		type Subscription { tick: Int! }
+	`, new(wsLimitsResolver))
+
+	ws := newWSServer(schema, nil)
+	ws.slots = make(chan struct{}, 1)
+	ws.maxOps = 1
+	defer ws.Stop()
+
+	// Queries have the runtime limit derived from the write timeout of the server.
+	srv := httptest.NewUnstartedServer(ws)
+	srv.Config.WriteTimeout = 600 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	var (
+		url    = "ws" + strings.TrimPrefix(srv.URL, "http")
+		dialer = websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	)
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Connections beyond the limit are refused.
+	if _, res, err := dialer.Dial(url, nil); err == nil || res == nil || res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected refused connection, got %v", err)
+	}
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("could not send message: %v", err)
+		}
+	}
+	expect := func(want string) {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("could not receive message: %v", err)
+		}
+		if !strings.HasPrefix(string(msg), want) {
+			t.Fatalf("unexpected message: have %s, want %s...", msg, want)
+		}
+	}
+	send(`{"type":"connection_init"}`)
+	expect(`{"type":"connection_ack"}`)
+
+	// Operations beyond the limit fail, subscriptions outlive the runtime limit.
+	send(`{"id":"1","type":"subscribe","payload":{"query":"subscription { tick }"}}`)
+	send(`{"id":"2","type":"subscribe","payload":{"query":"{ sleep }"}}`)
+	expect(`{"id":"2","type":"error","payload":[{"message":"too many active operations, the limit is 1"}]}`)
+	expect(`{"id":"1","type":"next","payload":{"data":{"tick":1}}}`)
+	send(`{"id":"1","type":"complete"}`)
+
+	// Queries are cancelled at the runtime limit.
+	send(`{"id":"2","type":"subscribe","payload":{"query":"{ sleep }"}}`)
+	expect(`{"id":"2","type":"error","payload":[{"message":"request timed out"}]}`)
+}
+
 func TestWithdrawals(t *testing.T) {
 	var (
 		key, _ = crypto.GenerateKey()
@@ -382,7 +747,7 @@ func TestWithdrawals(t *testing.T) {
 	)
 	defer stack.Close()
 
-	handler, _ := newGQLService(t, stack, true, genesis, 1, func(i int, gen *core.BlockGen) {
+	handler, _, _ := newGQLService(t, stack, true, genesis, 1, func(i int, gen *core.BlockGen) {
 		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{To: &common.Address{}, Gas: 100000, GasPrice: big.NewInt(vars.InitialBaseFee)})
 		gen.AddTx(tx)
 		gen.AddWithdrawal(&types.Withdrawal{
@@ -438,7 +803,7 @@ func createNode(t *testing.T) *node.Node {
 	return stack
 }
 
-func newGQLService(t *testing.T, stack *node.Node, shanghai bool, gspec *genesisT.Genesis, genBlocks int, genfunc func(i int, gen *core.BlockGen)) (*handler, []*types.Block) {
+func newGQLService(t *testing.T, stack *node.Node, shanghai bool, gspec *genesisT.Genesis, genBlocks int, genfunc func(i int, gen *core.BlockGen)) (*handler, []*types.Block, *eth.Ethereum) {
 	ethConf := &ethconfig.Config{
 		Genesis: gspec,
 		Ethash: ethash.Config{
@@ -482,5 +847,5 @@ func newGQLService(t *testing.T, stack *node.Node, shanghai bool, gspec *genesis
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
-	return handler, chain
+	return handler, chain, ethBackend
 }
@@ -32,10 +32,14 @@ const schema string = `
     # Strings may be either decimal or 0x-prefixed hexadecimal. Output values are all
     # 0x-prefixed hexadecimal.
     scalar Long
+    # JSON is an arbitrary JSON value. Input is accepted as either a JSON value or as a
+    # string holding its encoding.
+    scalar JSON
 
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -162,6 +166,47 @@ const schema string = `
         # RawReceipt is the canonical encoding of the receipt. For post EIP-2718 typed transactions
         # this is equivalent to TxType || ReceiptEncoding.
         rawReceipt: Bytes!
+        # Trace replays the transaction in the state of its block and returns the
+        # result of the tracer, as debug_traceTransaction does. The config is
+        # the trace config of debug_traceTransaction, the tracer argument takes
+        # precedence over the tracer of the config. Only the native tracers are
+        # supported, with a runtime limit of 10 seconds at most, and tracing must
+        # be enabled on the node. This will be null if the transaction has not yet
+        # been mined.
+        trace(tracer: String, config: JSON): JSON
+    }
+
+    # Receipt is the receipt of a transaction included in a block.
+    type Receipt {
+        # Transaction is the transaction this receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction. This will be 1 if the
+        # transaction succeeded, or 0 if it failed. Receipts of transactions
+        # before Byzantium hold a state root instead, and this field will be null.
+        status: Long
+        # Root is the intermediate state root after the transaction, present in
+        # the receipts of transactions before Byzantium.
+        root: Bytes32
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # the transaction.
+        cumulativeGasUsed: Long!
+        # EffectiveGasPrice is actual value per gas deducted from the sender's
+        # account.
+        effectiveGasPrice: BigInt
+        # CreatedContract is the account that was created by a contract creation
+        # transaction, null otherwise.
+        createdContract(block: Long): Account
+        # Logs is the list of log entries emitted by the transaction.
+        logs: [Log!]!
+        # LogsBloom is the bloom filter of the log entries.
+        logsBloom: Bytes!
+        # Type is the type of the transaction.
+        type: Long!
+        # Raw is the canonical encoding of the receipt. For post EIP-2718 typed
+        # transactions this is equivalent to TxType || ReceiptEncoding.
+        raw: Bytes!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -241,6 +286,12 @@ const schema string = `
         # OmmerHash is the keccak256 hash of all the ommers (AKA uncles)
         # associated with this block.
         ommerHash: Bytes32!
+        # Rewards lists the mining rewards paid in this block, the reward of
+        # the miner first, followed by the rewards of the ommers (AKA uncles).
+        # Rewards follow the ethash reward schedule of the chain, including the
+        # ECIP-1017 eras. If the chain does not use ethash, this field will be
+        # null.
+        rewards: [BlockReward!]
         # Transactions is a list of transactions associated with this block. If
         # transactions are unavailable for this block, this field will be null.
         transactions: [Transaction!]
@@ -248,6 +299,10 @@ const schema string = `
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
         transactionAt(index: Long!): Transaction
+        # Receipts is the list of receipts of the transactions in this block, in
+        # a single fetch. If receipts are unavailable for this block, this field
+        # will be null.
+        receipts: [Receipt!]
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
         # Account fetches an Ethereum account at the current block's state.
@@ -269,6 +324,17 @@ const schema string = `
         withdrawals: [Withdrawal!]
     }
 
+    # BlockReward is a mining reward paid in a block.
+    type BlockReward {
+        # Beneficiary is the account credited with the reward.
+        beneficiary(block: Long): Account!
+        # Amount is the reward, in wei.
+        amount: BigInt!
+        # Uncle is the ommer (AKA uncle) being rewarded, or null for the reward
+        # of the miner of the block.
+        uncle: Block
+    }
+
     # CallData represents the data associated with a local contract call.
     # All fields are optional.
     input CallData {
@@ -373,10 +439,56 @@ const schema string = `
         syncing: SyncState
         # ChainID returns the current chain ID for transaction replay protection.
         chainID: BigInt!
+        # ChainConfig returns the configuration of the chain.
+        chainConfig: ChainConfig!
+    }
+
+    # ChainConfig is the configuration of the chain.
+    type ChainConfig {
+        # ChainID is the chain ID for transaction replay protection, or null if
+        # the chain has none.
+        chainID: BigInt
+        # NetworkID is the network ID of the chain.
+        networkID: Long
+        # ConsensusEngine is the name of the consensus engine of the chain.
+        consensusEngine: String!
+        # Forks lists the scheduled forks of the chain in activation order,
+        # first by block number, then by timestamp.
+        forks: [Fork!]!
+    }
+
+    # Fork is a fork of the chain, activated at a block number or timestamp.
+    type Fork {
+        # Block is the number of the block activating the fork, or null if the
+        # fork is activated by timestamp.
+        block: Long
+        # Timestamp is the timestamp activating the fork, or null if the fork is
+        # activated by block number.
+        timestamp: Long
+        # Transitions lists the names of the transitions enabled by the fork.
+        transitions: [String!]!
     }
 
     type Mutation {
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription is served over websockets, using the graphql-transport-ws
+    # protocol.
+    type Subscription {
+        # NewHeads fires for every block imported as the new head of the chain.
+        # During chain reorganisations, it fires for each of the new canonical
+        # blocks.
+        newHeads: Block!
+        # NewLogs fires for every log entry included in the chain matching the
+        # provided filter. Logs of blocks removed from the chain during
+        # reorganisations are not delivered again. It is not named logs, as the
+        # query and subscription fields are resolved on the same root, which
+        # already resolves Query.logs.
+        newLogs(filter: BlockFilterCriteria!): Log!
+        # PendingTransactions fires for every transaction entering the
+        # transaction pool.
+        pendingTransactions: Transaction!
+    }
 `
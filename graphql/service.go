@@ -28,15 +28,21 @@ import (
 	"github.com/yuriy0803/core-geth1/internal/ethapi"
 	"github.com/yuriy0803/core-geth1/node"
 	"github.com/yuriy0803/core-geth1/rpc"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 	gqlErrors "github.com/graph-gophers/graphql-go/errors"
 )
 
 type handler struct {
 	Schema *graphql.Schema
+	ws     *wsServer
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.ws.ServeHTTP(w, r)
+		return
+	}
 	var params struct {
 		Query         string                 `json:"query"`
 		OperationName string                 `json:"operationName"`
@@ -109,16 +115,18 @@ func New(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterS
 	return err
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
+// newHandler returns a new `http.Handler` that will answer GraphQL queries,
+// and subscriptions over websockets. It additionally exports an interactive
+// query browser on the / endpoint.
 func newHandler(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string) (*handler, error) {
-	q := Resolver{backend, filterSystem}
+	q := Resolver{backend: backend, filterSystem: filterSystem, tracing: stack.Config().GraphQLTracing}
 
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
 		return nil, err
 	}
-	h := handler{Schema: s}
+	h := handler{Schema: s, ws: newWSServer(s, cors)}
+	stack.RegisterLifecycle(h.ws)
 	handler := node.NewHTTPHandlerStack(h, cors, vhosts, nil)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
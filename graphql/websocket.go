@@ -0,0 +1,467 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+const (
+	wsSubprotocol  = "graphql-transport-ws"
+	wsReadBuffer   = 1024
+	wsWriteBuffer  = 1024
+	wsReadLimit    = 1024 * 1024
+	wsInitTimeout  = 10 * time.Second
+	wsWriteTimeout = 10 * time.Second
+
+	// wsMaxConnections is the maximum number of websocket connections served
+	// at once.
+	wsMaxConnections = 256
+
+	// wsMaxOperations is the maximum number of active operations, subscriptions
+	// in particular, per connection.
+	wsMaxOperations = 32
+)
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	wsCloseInvalidMessage      = 4400
+	wsCloseUnauthorized        = 4401
+	wsCloseBadSubprotocol      = 4406
+	wsCloseInitTimeout         = 4408
+	wsCloseSubscriberExists    = 4409
+	wsCloseTooManyInitRequests = 4429
+)
+
+// Message types of the graphql-transport-ws protocol.
+const (
+	wsConnectionInit = "connection_init"
+	wsConnectionAck  = "connection_ack"
+	wsPing           = "ping"
+	wsPong           = "pong"
+	wsSubscribe      = "subscribe"
+	wsNext           = "next"
+	wsError          = "error"
+	wsComplete       = "complete"
+)
+
+// wsMessage is a message of the graphql-transport-ws protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsSubscribePayload is the payload of a subscribe message.
+type wsSubscribePayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsServer serves GraphQL operations, subscriptions in particular, over
+// websockets using the graphql-transport-ws protocol. It is registered as a
+// node lifecycle to close the connections when the node stops.
+type wsServer struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+	slots    chan struct{} // Semaphore of the connections served
+	maxOps   int           // Maximum number of active operations per connection
+
+	mu     sync.Mutex
+	conns  map[*wsConn]struct{}
+	closed bool
+}
+
+func newWSServer(schema *graphql.Schema, cors []string) *wsServer {
+	s := &wsServer{
+		schema: schema,
+		slots:  make(chan struct{}, wsMaxConnections),
+		maxOps: wsMaxOperations,
+		conns:  make(map[*wsConn]struct{}),
+	}
+	s.upgrader = websocket.Upgrader{
+		ReadBufferSize:  wsReadBuffer,
+		WriteBufferSize: wsWriteBuffer,
+		Subprotocols:    []string{wsSubprotocol},
+		CheckOrigin:     wsOriginValidator(cors),
+	}
+	return s
+}
+
+// wsOriginValidator returns a check of the origin of websocket handshakes.
+// Browsers may connect from the origins allowed by the CORS configuration of
+// the GraphQL endpoint, or from the same origin.
+func wsOriginValidator(cors []string) func(r *http.Request) bool {
+	allowed := make([]string, 0, len(cors))
+	for _, origin := range cors {
+		allowed = append(allowed, strings.ToLower(origin))
+	}
+	return func(r *http.Request) bool {
+		// Non-browser clients don't have to send an origin.
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		origin = strings.ToLower(origin)
+		for _, pattern := range allowed {
+			if pattern == "*" || pattern == origin {
+				return true
+			}
+			// Allow a single wildcard, as the CORS handler does.
+			if prefix, suffix, ok := strings.Cut(pattern, "*"); ok && len(origin) >= len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		if err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		log.Warn("Rejected GraphQL WebSocket connection", "origin", origin)
+		return false
+	}
+}
+
+// Start implements node.Lifecycle.
+func (s *wsServer) Start() error {
+	return nil
+}
+
+// Stop implements node.Lifecycle, closing all the connections.
+func (s *wsServer) Stop() error {
+	s.mu.Lock()
+	s.closed = true
+	conns := make([]*wsConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	return nil
+}
+
+// ServeHTTP upgrades the request to a websocket and serves the connection. The
+// queries sent over the connection have the runtime limit of the HTTP requests.
+func (s *wsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // The upgrader responded already
+	}
+	c := newWSConn(s.schema, conn, s.maxOps)
+	if timeout, ok := rpc.ContextRequestTimeout(r.Context()); ok {
+		c.timeout = timeout
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		c.close(websocket.CloseGoingAway, "server shutting down")
+		return
+	}
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+
+	c.run()
+
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+}
+
+// wsConn is a websocket connection of a GraphQL client.
+type wsConn struct {
+	schema  *graphql.Schema
+	conn    *websocket.Conn
+	maxOps  int           // Maximum number of active operations
+	timeout time.Duration // Runtime limit of queries, zero if unlimited
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	writeMu sync.Mutex // protects writes to the connection
+
+	mu   sync.Mutex
+	subs map[string]*wsOperation // active operations, by id
+}
+
+// wsOperation is an operation started by a subscribe message.
+type wsOperation struct {
+	cancel context.CancelFunc
+}
+
+// wsSubscriptionKey is the context key of the function lifting the runtime limit
+// of a websocket operation once it turns out to be a subscription.
+type wsSubscriptionKey struct{}
+
+// startSubscription lifts the runtime limit of the websocket operation of the
+// given context, if any, as subscriptions run until they are completed.
+func startSubscription(ctx context.Context) {
+	if stop, ok := ctx.Value(wsSubscriptionKey{}).(func() bool); ok {
+		stop()
+	}
+}
+
+func newWSConn(schema *graphql.Schema, conn *websocket.Conn, maxOps int) *wsConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &wsConn{
+		schema: schema,
+		conn:   conn,
+		maxOps: maxOps,
+		ctx:    ctx,
+		cancel: cancel,
+		subs:   make(map[string]*wsOperation),
+	}
+}
+
+// run reads the messages of the client until the connection is closed.
+func (c *wsConn) run() {
+	defer func() {
+		c.cancel()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+	if c.conn.Subprotocol() != wsSubprotocol {
+		c.close(wsCloseBadSubprotocol, "Subprotocol not acceptable")
+		return
+	}
+	c.conn.SetReadLimit(wsReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+
+	var acknowledged bool
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if !acknowledged && errors.As(err, &netErr) && netErr.Timeout() {
+				c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.close(wsCloseInvalidMessage, "Invalid message received")
+			return
+		}
+		switch msg.Type {
+		case wsConnectionInit:
+			if acknowledged {
+				c.close(wsCloseTooManyInitRequests, "Too many initialisation requests")
+				return
+			}
+			acknowledged = true
+			c.conn.SetReadDeadline(time.Time{})
+			if c.write(&wsMessage{Type: wsConnectionAck}) != nil {
+				return
+			}
+
+		case wsPing:
+			if c.write(&wsMessage{Type: wsPong}) != nil {
+				return
+			}
+
+		case wsPong:
+
+		case wsSubscribe:
+			if !acknowledged {
+				c.close(wsCloseUnauthorized, "Unauthorized")
+				return
+			}
+			var payload wsSubscribePayload
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil {
+				c.close(wsCloseInvalidMessage, "Invalid message received")
+				return
+			}
+			if !c.subscribe(msg.ID, &payload) {
+				c.close(wsCloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+				return
+			}
+
+		case wsComplete:
+			c.mu.Lock()
+			if op, ok := c.subs[msg.ID]; ok {
+				delete(c.subs, msg.ID)
+				op.cancel()
+			}
+			c.mu.Unlock()
+
+		default:
+			c.close(wsCloseInvalidMessage, "Invalid message received")
+			return
+		}
+	}
+}
+
+// subscribe starts the operation of a subscribe message, streaming its results
+// to the client. It returns false if an operation with the same id is active.
+// Operations beyond the limit of active ones fail, and queries are cancelled
+// after the runtime limit of the connection.
+//
+// Subscriptions are set up before returning, so that they observe all events
+// after any later message of the client.
+func (c *wsConn) subscribe(id string, payload *wsSubscribePayload) bool {
+	c.mu.Lock()
+	if _, ok := c.subs[id]; ok {
+		c.mu.Unlock()
+		return false
+	}
+	if len(c.subs) >= c.maxOps {
+		c.mu.Unlock()
+		c.writePayload(id, wsError, []map[string]string{{"message": fmt.Sprintf("too many active operations, the limit is %d", c.maxOps)}})
+		return true
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	op := &wsOperation{cancel: cancel}
+	c.subs[id] = op
+	c.mu.Unlock()
+
+	// The execution is cancelled at the runtime limit, unless the operation
+	// lifts it by starting a subscription.
+	var (
+		execCtx   = ctx
+		stopTimer = func() bool { return false }
+	)
+	if c.timeout > 0 {
+		var timeout context.CancelFunc
+		execCtx, timeout = context.WithCancel(ctx)
+		stopTimer = time.AfterFunc(c.timeout, timeout).Stop
+		execCtx = context.WithValue(execCtx, wsSubscriptionKey{}, stopTimer)
+	}
+	responses, err := c.schema.Subscribe(execCtx, payload.Query, payload.OperationName, payload.Variables)
+	if err != nil {
+		stopTimer()
+		cancel()
+		if c.finish(id, op) {
+			c.writePayload(id, wsError, []map[string]string{{"message": err.Error()}})
+		}
+		return true
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+		defer stopTimer()
+
+		var (
+			first  = true
+			failed bool
+		)
+		// The responses have to be drained until closed, even after the
+		// client went away.
+		for response := range responses {
+			res := response.(*graphql.Response)
+			if ctx.Err() != nil {
+				continue
+			}
+			// Operations failing before execution, e.g. invalid queries,
+			// yield a single response with errors only.
+			if first && res.Data == nil && len(res.Errors) > 0 {
+				failed = true
+				if c.finish(id, op) {
+					if execCtx.Err() != nil {
+						c.writePayload(id, wsError, []map[string]string{{"message": "request timed out"}})
+					} else {
+						c.writePayload(id, wsError, res.Errors)
+					}
+				}
+				continue
+			}
+			first = false
+			c.writePayload(id, wsNext, res)
+		}
+		if c.finish(id, op) && !failed {
+			c.write(&wsMessage{ID: id, Type: wsComplete})
+		}
+	}()
+	return true
+}
+
+// finish removes an operation from the active ones, returning false if the
+// client completed it already.
+func (c *wsConn) finish(id string, op *wsOperation) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The id may be reused after the client completed the operation.
+	if c.subs[id] != op {
+		return false
+	}
+	delete(c.subs, id)
+	return true
+}
+
+// writePayload sends a message with the given payload to the client.
+func (c *wsConn) writePayload(id string, typ string, payload interface{}) error {
+	blob, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return c.write(&wsMessage{ID: id, Type: typ, Payload: blob})
+}
+
+// write sends a message to the client. The connection is closed if the write
+// fails.
+func (c *wsConn) write(msg *wsMessage) error {
+	blob, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err = c.conn.WriteMessage(websocket.TextMessage, blob); err != nil {
+		c.cancel()
+		c.conn.Close()
+	}
+	return err
+}
+
+// close closes the connection with the given close code and reason.
+func (c *wsConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	deadline := time.Now().Add(wsWriteTimeout)
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	c.cancel()
+	c.conn.Close()
+}
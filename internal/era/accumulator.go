@@ -0,0 +1,99 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	"github.com/yuriy0803/core-geth1/common"
+)
+
+// ComputeAccumulator calculates the SSZ hash tree root of the era's header
+// records, a list of at most MaxEra1Size (block hash, total difficulty) pairs.
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, error) {
+	if len(hashes) != len(tds) {
+		return common.Hash{}, fmt.Errorf("must have equal number of hashes as td values: %d != %d", len(hashes), len(tds))
+	}
+	if len(hashes) > MaxEra1Size {
+		return common.Hash{}, fmt.Errorf("too many records: have %d, max %d", len(hashes), MaxEra1Size)
+	}
+	records := make([][32]byte, len(hashes))
+	for i := range hashes {
+		td, err := uint256LE(tds[i])
+		if err != nil {
+			return common.Hash{}, err
+		}
+		records[i] = sha256.Sum256(append(hashes[i].Bytes(), td...))
+	}
+	// Mix the length of the list into the root of the record tree.
+	var length [32]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(records)))
+
+	root := merkleize(records, MaxEra1Size)
+	return sha256.Sum256(append(root[:], length[:]...)), nil
+}
+
+// merkleize computes the root of the binary merkle tree of the chunks, padded
+// with zero chunks to the given limit.
+func merkleize(chunks [][32]byte, limit int) [32]byte {
+	depth := bits.Len(uint(limit - 1))
+
+	zero := make([][32]byte, depth+1)
+	for i := 1; i <= depth; i++ {
+		zero[i] = sha256.Sum256(append(zero[i-1][:], zero[i-1][:]...))
+	}
+	if len(chunks) == 0 {
+		return zero[depth]
+	}
+	layer := chunks
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zero[d])
+		}
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = sha256.Sum256(append(layer[2*i][:], layer[2*i+1][:]...))
+		}
+		layer = next
+	}
+	return layer[0]
+}
+
+// uint256LE encodes the value as a 32 bytes little-endian integer.
+func uint256LE(v *big.Int) ([]byte, error) {
+	if v.Sign() < 0 || v.BitLen() > 256 {
+		return nil, fmt.Errorf("invalid total difficulty %v", v)
+	}
+	b := v.FillBytes(make([]byte, 32))
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b, nil
+}
+
+// fromUint256LE decodes a 32 bytes little-endian integer.
+func fromUint256LE(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
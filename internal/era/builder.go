@@ -0,0 +1,176 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/golang/snappy"
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/internal/era/e2store"
+	"github.com/yuriy0803/core-geth1/rlp"
+)
+
+// Builder writes an era1 archive of consecutive blocks:
+//
+//	era1 := Version | block-tuple* | Accumulator | BlockIndex
+//	block-tuple := CompressedHeader | CompressedBody | CompressedReceipts | TotalDifficulty
+//
+// Headers, bodies and receipts are RLP encoded and snappy framed, the total
+// difficulties are 32 bytes little-endian integers. The accumulator is the SSZ
+// hash tree root of the (block hash, total difficulty) records, and the block
+// index is the number of the first block, followed by the offset of each block
+// tuple relative to the block index entry, followed by the block count.
+type Builder struct {
+	w        *e2store.Writer
+	start    *uint64
+	offsets  []uint64
+	hashes   []common.Hash
+	tds      []*big.Int
+	written  uint64
+	buf      *bytes.Buffer
+	snappy   *snappy.Writer
+	finished bool
+}
+
+// NewBuilder returns a new builder writing the era1 archive to w.
+func NewBuilder(w io.Writer) *Builder {
+	buf := bytes.NewBuffer(nil)
+	return &Builder{
+		w:      e2store.NewWriter(w),
+		buf:    buf,
+		snappy: snappy.NewBufferedWriter(buf),
+	}
+}
+
+// Add writes a block, its receipts and its total difficulty, including the
+// block's own difficulty, to the archive.
+func (b *Builder) Add(block *types.Block, receipts types.Receipts, td *big.Int) error {
+	header, err := rlp.EncodeToBytes(block.Header())
+	if err != nil {
+		return err
+	}
+	body, err := rlp.EncodeToBytes(block.Body())
+	if err != nil {
+		return err
+	}
+	rs, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		return err
+	}
+	return b.AddRLP(header, body, rs, block.NumberU64(), block.Hash(), td)
+}
+
+// AddRLP writes the RLP encoded header, body and receipts of a block, and its
+// total difficulty, to the archive.
+func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash common.Hash, td *big.Int) error {
+	if b.finished {
+		return errors.New("era1 archive already finalized")
+	}
+	if len(b.offsets) >= MaxEra1Size {
+		return fmt.Errorf("exceeds maximum batch size of %d", MaxEra1Size)
+	}
+	// Write the version entry before the first block.
+	if b.start == nil {
+		if err := b.write(TypeVersion, nil); err != nil {
+			return err
+		}
+		b.start = &number
+	} else if want := *b.start + uint64(len(b.offsets)); number != want {
+		return fmt.Errorf("non-contiguous block: have %d, want %d", number, want)
+	}
+	tdLE, err := uint256LE(td)
+	if err != nil {
+		return err
+	}
+	b.offsets = append(b.offsets, b.written)
+	b.hashes = append(b.hashes, hash)
+	b.tds = append(b.tds, new(big.Int).Set(td))
+
+	for _, item := range []struct {
+		typ  uint16
+		data []byte
+	}{
+		{TypeCompressedHeader, header},
+		{TypeCompressedBody, body},
+		{TypeCompressedReceipts, receipts},
+	} {
+		if err := b.snappyWrite(item.typ, item.data); err != nil {
+			return err
+		}
+	}
+	return b.write(TypeTotalDifficulty, tdLE)
+}
+
+// Finalize writes the accumulator and the block index, returning the
+// accumulator root.
+func (b *Builder) Finalize() (common.Hash, error) {
+	if b.start == nil {
+		return common.Hash{}, errors.New("finalize called on empty builder")
+	}
+	if b.finished {
+		return common.Hash{}, errors.New("era1 archive already finalized")
+	}
+	b.finished = true
+
+	root, err := ComputeAccumulator(b.hashes, b.tds)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error calculating accumulator root: %w", err)
+	}
+	if err := b.write(TypeAccumulator, root.Bytes()); err != nil {
+		return common.Hash{}, fmt.Errorf("error writing accumulator: %w", err)
+	}
+	// The offsets in the block index are relative to the block index entry.
+	base := int64(b.written)
+	index := make([]byte, 16+len(b.offsets)*8)
+	binary.LittleEndian.PutUint64(index, *b.start)
+	for i, offset := range b.offsets {
+		binary.LittleEndian.PutUint64(index[8+i*8:], uint64(int64(offset)-base))
+	}
+	binary.LittleEndian.PutUint64(index[8+len(b.offsets)*8:], uint64(len(b.offsets)))
+
+	if err := b.write(TypeBlockIndex, index); err != nil {
+		return common.Hash{}, fmt.Errorf("error writing block index: %w", err)
+	}
+	return root, nil
+}
+
+// write writes an entry, keeping track of the archive size.
+func (b *Builder) write(typ uint16, value []byte) error {
+	n, err := b.w.Write(typ, value)
+	b.written += uint64(n)
+	return err
+}
+
+// snappyWrite writes an entry with the snappy framed value.
+func (b *Builder) snappyWrite(typ uint16, value []byte) error {
+	b.buf.Reset()
+	b.snappy.Reset(b.buf)
+	if _, err := b.snappy.Write(value); err != nil {
+		return fmt.Errorf("error snappy encoding: %w", err)
+	}
+	if err := b.snappy.Flush(); err != nil {
+		return fmt.Errorf("error flushing snappy encoding: %w", err)
+	}
+	return b.write(typ, b.buf.Bytes())
+}
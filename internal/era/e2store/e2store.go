@@ -0,0 +1,145 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package e2store implements the e2store container format: a flat sequence of
+// type-length-value entries.
+package e2store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// headerSize is the size of an entry header: the type (2 bytes), the
+	// length of the value (4 bytes) and 2 reserved bytes, all little-endian.
+	headerSize = 8
+
+	// valueSizeLimit is the maximum length of an entry value.
+	valueSizeLimit = 1024 * 1024 * 50
+)
+
+// Entry is a type-length-value record of an e2store file.
+type Entry struct {
+	Type  uint16
+	Value []byte
+}
+
+// Writer writes e2store entries to an underlying writer.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a new e2store writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a single entry with the given type and value, returning the
+// number of bytes written, including the entry header.
+func (w *Writer) Write(typ uint16, value []byte) (int, error) {
+	if len(value) > valueSizeLimit {
+		return 0, fmt.Errorf("entry value too large: %d > %d", len(value), valueSizeLimit)
+	}
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint16(header[0:2], typ)
+	binary.LittleEndian.PutUint32(header[2:6], uint32(len(value)))
+
+	n, err := w.w.Write(header[:])
+	if err != nil {
+		return n, err
+	}
+	m, err := w.w.Write(value)
+	return n + m, err
+}
+
+// Reader reads e2store entries from an underlying reader.
+type Reader struct {
+	r      io.ReaderAt
+	offset int64
+}
+
+// NewReader returns a new e2store reader, reading from the beginning.
+func NewReader(r io.ReaderAt) *Reader {
+	return &Reader{r: r}
+}
+
+// Read reads the next entry, returning io.EOF once all entries have been read.
+func (r *Reader) Read() (*Entry, error) {
+	entry, n, err := r.ReadAt(r.offset)
+	if err != nil {
+		return nil, err
+	}
+	r.offset += int64(n)
+	return entry, nil
+}
+
+// ReadAt reads the entry at the given offset, also returning its size
+// including the entry header.
+func (r *Reader) ReadAt(off int64) (*Entry, int, error) {
+	typ, length, err := r.ReadMetadataAt(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	entry := &Entry{Type: typ, Value: make([]byte, length)}
+	if length == 0 {
+		return entry, headerSize, nil
+	}
+	if _, err := r.r.ReadAt(entry.Value, off+headerSize); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	return entry, headerSize + int(length), nil
+}
+
+// ReadMetadataAt reads the header of the entry at the given offset.
+func (r *Reader) ReadMetadataAt(off int64) (typ uint16, length uint32, err error) {
+	var header [headerSize]byte
+	if n, err := r.r.ReadAt(header[:], off); err != nil {
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	typ = binary.LittleEndian.Uint16(header[0:2])
+	length = binary.LittleEndian.Uint32(header[2:6])
+	if header[6] != 0 || header[7] != 0 {
+		return 0, 0, errors.New("reserved bytes are non-zero")
+	}
+	if length > valueSizeLimit {
+		return 0, 0, fmt.Errorf("entry value too large: %d > %d", length, valueSizeLimit)
+	}
+	return typ, length, nil
+}
+
+// Find returns the first entry of the given type, starting from the beginning.
+func (r *Reader) Find(want uint16) (*Entry, error) {
+	for off := int64(0); ; {
+		typ, length, err := r.ReadMetadataAt(off)
+		if err != nil {
+			return nil, err
+		}
+		if typ == want {
+			entry, _, err := r.ReadAt(off)
+			return entry, err
+		}
+		off += headerSize + int64(length)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package e2store
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+)
+
+func TestEncode(t *testing.T) {
+	for _, test := range []struct {
+		entries []Entry
+		want    string
+	}{
+		{
+			entries: []Entry{{Type: 0xffff, Value: nil}},
+			want:    "ffff000000000000",
+		},
+		{
+			entries: []Entry{{Type: 42, Value: common.Hex2Bytes("beef")}},
+			want:    "2a00020000000000beef",
+		},
+		{
+			entries: []Entry{
+				{Type: 42, Value: common.Hex2Bytes("beef")},
+				{Type: 9, Value: common.Hex2Bytes("abcdabcd")},
+			},
+			want: "2a00020000000000beef0900040000000000abcdabcd",
+		},
+	} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		for _, entry := range test.entries {
+			if _, err := w.Write(entry.Type, entry.Value); err != nil {
+				t.Fatalf("error writing entry %d: %v", entry.Type, err)
+			}
+		}
+		if have := common.Bytes2Hex(buf.Bytes()); have != test.want {
+			t.Fatalf("wrong encoding: have %s, want %s", have, test.want)
+		}
+		r := NewReader(bytes.NewReader(buf.Bytes()))
+		for _, want := range test.entries {
+			have, err := r.Read()
+			if err != nil {
+				t.Fatalf("error reading entry %d: %v", want.Type, err)
+			}
+			if have.Type != want.Type || !bytes.Equal(have.Value, want.Value) {
+				t.Fatalf("wrong entry: have %d %x, want %d %x", have.Type, have.Value, want.Type, want.Value)
+			}
+		}
+		if _, err := r.Read(); err != io.EOF {
+			t.Fatalf("wrong error after the last entry: have %v, want %v", err, io.EOF)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  error
+	}{
+		{"ffff00", io.ErrUnexpectedEOF},
+		{"2a00020000000000be", io.ErrUnexpectedEOF},
+	} {
+		r := NewReader(bytes.NewReader(common.Hex2Bytes(test.input)))
+		if _, err := r.Read(); err != test.want {
+			t.Errorf("input %s: wrong error: have %v, want %v", test.input, err, test.want)
+		}
+	}
+	r := NewReader(bytes.NewReader(common.Hex2Bytes("2a00000000000100")))
+	if _, err := r.Read(); err == nil {
+		t.Error("non-zero reserved bytes accepted")
+	}
+}
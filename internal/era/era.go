@@ -0,0 +1,262 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era implements era1 archives: self-describing, per-epoch files of
+// block headers, bodies, receipts and total difficulties, stored in the
+// e2store format together with an accumulator root of the archived blocks.
+package era
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/internal/era/e2store"
+	"github.com/yuriy0803/core-geth1/rlp"
+)
+
+// The e2store entry types of era1 archives.
+const (
+	TypeVersion            uint16 = 0x3265
+	TypeCompressedHeader   uint16 = 0x03
+	TypeCompressedBody     uint16 = 0x04
+	TypeCompressedReceipts uint16 = 0x05
+	TypeTotalDifficulty    uint16 = 0x06
+	TypeAccumulator        uint16 = 0x07
+	TypeBlockIndex         uint16 = 0x3266
+)
+
+// MaxEra1Size is the number of blocks of an epoch, the maximum number of
+// blocks of an era1 archive.
+const MaxEra1Size = 8192
+
+// Filename returns the file name of the era1 archive of the given network and
+// epoch, tagged with the accumulator root.
+func Filename(network string, epoch int, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%s.era1", network, epoch, root.Hex()[2:10])
+}
+
+// ReadDir returns the era1 archives of the given network in the directory,
+// ordered by epoch. Epochs must be consecutive.
+func ReadDir(dir, network string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	var (
+		next  = -1
+		files []string
+	)
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".era1" {
+			continue
+		}
+		parts := strings.Split(entry.Name(), "-")
+		if len(parts) != 3 || parts[0] != network {
+			// Other networks may share the directory.
+			continue
+		}
+		epoch, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed era1 filename: %s", entry.Name())
+		}
+		if next != -1 && int(epoch) != next {
+			return nil, fmt.Errorf("missing epoch %d", next)
+		}
+		next = int(epoch) + 1
+		files = append(files, entry.Name())
+	}
+	return files, nil
+}
+
+// ReadAtSeekCloser is the file interface needed by Era.
+type ReadAtSeekCloser interface {
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// Era reads an era1 archive.
+type Era struct {
+	f     ReadAtSeekCloser
+	s     *e2store.Reader
+	start uint64 // Number of the first block
+	count uint64 // Number of blocks
+	index int64  // Offset of the block index entry
+}
+
+// Open opens the era1 archive at the given path.
+func Open(filename string) (*Era, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	e, err := From(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// From reads the era1 archive from the given file.
+func From(f ReadAtSeekCloser) (*Era, error) {
+	length, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	e := &Era{f: f, s: e2store.NewReader(f)}
+
+	// The block count is the last value of the archive, ending the block index.
+	var buf [8]byte
+	if length < 8 {
+		return nil, errors.New("era1 archive too short")
+	}
+	if _, err := f.ReadAt(buf[:], length-8); err != nil {
+		return nil, err
+	}
+	e.count = binary.LittleEndian.Uint64(buf[:])
+	if e.count == 0 || e.count > MaxEra1Size {
+		return nil, fmt.Errorf("invalid block count %d", e.count)
+	}
+	e.index = length - 8 - int64(e.count)*8 - 8 - 8
+	if e.index < 0 {
+		return nil, errors.New("era1 archive too short")
+	}
+	typ, size, err := e.s.ReadMetadataAt(e.index)
+	if err != nil {
+		return nil, err
+	}
+	if typ != TypeBlockIndex || uint64(size) != 16+e.count*8 {
+		return nil, errors.New("invalid block index")
+	}
+	if _, err := f.ReadAt(buf[:], e.index+8); err != nil {
+		return nil, err
+	}
+	e.start = binary.LittleEndian.Uint64(buf[:])
+
+	// Ensure the archive is an era1 one.
+	if typ, _, err := e.s.ReadMetadataAt(0); err != nil {
+		return nil, err
+	} else if typ != TypeVersion {
+		return nil, errors.New("missing era1 version")
+	}
+	return e, nil
+}
+
+// Close closes the archive file.
+func (e *Era) Close() error {
+	return e.f.Close()
+}
+
+// Start returns the number of the first block of the archive.
+func (e *Era) Start() uint64 {
+	return e.start
+}
+
+// Count returns the number of blocks of the archive.
+func (e *Era) Count() uint64 {
+	return e.count
+}
+
+// Accumulator returns the accumulator root recorded in the archive.
+func (e *Era) Accumulator() (common.Hash, error) {
+	entry, err := e.s.Find(TypeAccumulator)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(entry.Value) != common.HashLength {
+		return common.Hash{}, errors.New("invalid accumulator")
+	}
+	return common.BytesToHash(entry.Value), nil
+}
+
+// GetRawBlockByNumber returns the RLP encoded header, body and receipts of the
+// given block, and its total difficulty.
+func (e *Era) GetRawBlockByNumber(number uint64) (header, body, receipts []byte, td *big.Int, err error) {
+	off, err := e.blockOffset(number)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	items := make([][]byte, 3)
+	for i, typ := range []uint16{TypeCompressedHeader, TypeCompressedBody, TypeCompressedReceipts} {
+		entry, n, err := e.s.ReadAt(off)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if entry.Type != typ {
+			return nil, nil, nil, nil, fmt.Errorf("block %d: unexpected entry type %#x, want %#x", number, entry.Type, typ)
+		}
+		if items[i], err = io.ReadAll(snappy.NewReader(bytes.NewReader(entry.Value))); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("block %d: %w", number, err)
+		}
+		off += int64(n)
+	}
+	entry, _, err := e.s.ReadAt(off)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if entry.Type != TypeTotalDifficulty || len(entry.Value) != 32 {
+		return nil, nil, nil, nil, fmt.Errorf("block %d: invalid total difficulty", number)
+	}
+	return items[0], items[1], items[2], fromUint256LE(entry.Value), nil
+}
+
+// GetBlockByNumber returns the given block, its receipts and its total difficulty.
+func (e *Era) GetBlockByNumber(number uint64) (*types.Block, types.Receipts, *big.Int, error) {
+	rawHeader, rawBody, rawReceipts, td, err := e.GetRawBlockByNumber(number)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var (
+		header   types.Header
+		body     types.Body
+		receipts types.Receipts
+	)
+	if err := rlp.DecodeBytes(rawHeader, &header); err != nil {
+		return nil, nil, nil, fmt.Errorf("block %d: invalid header: %w", number, err)
+	}
+	if err := rlp.DecodeBytes(rawBody, &body); err != nil {
+		return nil, nil, nil, fmt.Errorf("block %d: invalid body: %w", number, err)
+	}
+	if err := rlp.DecodeBytes(rawReceipts, &receipts); err != nil {
+		return nil, nil, nil, fmt.Errorf("block %d: invalid receipts: %w", number, err)
+	}
+	block := types.NewBlockWithHeader(&header).WithBody(body.Transactions, body.Uncles).WithWithdrawals(body.Withdrawals)
+	return block, receipts, td, nil
+}
+
+// blockOffset returns the offset of the given block's tuple.
+func (e *Era) blockOffset(number uint64) (int64, error) {
+	if number < e.start || number >= e.start+e.count {
+		return 0, fmt.Errorf("block %d out of range [%d, %d]", number, e.start, e.start+e.count-1)
+	}
+	var buf [8]byte
+	if _, err := e.f.ReadAt(buf[:], e.index+8+8+int64(number-e.start)*8); err != nil {
+		return 0, err
+	}
+	return e.index + int64(binary.LittleEndian.Uint64(buf[:])), nil
+}
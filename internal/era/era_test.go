@@ -0,0 +1,148 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/core/types"
+)
+
+func TestEra1Builder(t *testing.T) {
+	var (
+		f, _    = os.Create(filepath.Join(t.TempDir(), "test.era1"))
+		builder = NewBuilder(f)
+		start   = uint64(MaxEra1Size)
+		blocks  []*types.Block
+		tds     []*big.Int
+		td      = big.NewInt(1000)
+	)
+	defer f.Close()
+
+	for i := uint64(0); i < 128; i++ {
+		header := &types.Header{
+			Number:     new(big.Int).SetUint64(start + i),
+			Difficulty: big.NewInt(int64(i + 1)),
+			Extra:      []byte{byte(i)},
+		}
+		if len(blocks) > 0 {
+			header.ParentHash = blocks[len(blocks)-1].Hash()
+		}
+		block := types.NewBlockWithHeader(header)
+		receipts := types.Receipts{{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: i,
+			Logs:              []*types.Log{{Address: common.Address{byte(i)}, Data: []byte{byte(i)}}},
+		}}
+		receipts[0].Bloom = types.CreateBloom(receipts)
+		td = new(big.Int).Add(td, header.Difficulty)
+		if err := builder.Add(block, receipts, td); err != nil {
+			t.Fatalf("error adding block %d: %v", i, err)
+		}
+		blocks, tds = append(blocks, block), append(tds, td)
+	}
+	if err := builder.Add(blocks[0], nil, td); err == nil {
+		t.Fatal("non-contiguous block added")
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("error finalizing builder: %v", err)
+	}
+	hashes := make([]common.Hash, len(blocks))
+	for i, block := range blocks {
+		hashes[i] = block.Hash()
+	}
+	if want, _ := ComputeAccumulator(hashes, tds); root != want {
+		t.Fatalf("wrong accumulator root: have %x, want %x", root, want)
+	}
+
+	e, err := From(f)
+	if err != nil {
+		t.Fatalf("error opening era1 archive: %v", err)
+	}
+	if e.Start() != start || e.Count() != uint64(len(blocks)) {
+		t.Fatalf("wrong range: have [%d, +%d], want [%d, +%d]", e.Start(), e.Count(), start, len(blocks))
+	}
+	if have, err := e.Accumulator(); err != nil || have != root {
+		t.Fatalf("wrong recorded accumulator root: have %x (%v), want %x", have, err, root)
+	}
+	for i, want := range blocks {
+		block, receipts, td, err := e.GetBlockByNumber(start + uint64(i))
+		if err != nil {
+			t.Fatalf("error reading block %d: %v", i, err)
+		}
+		if block.Hash() != want.Hash() {
+			t.Fatalf("block %d: wrong hash %x, want %x", i, block.Hash(), want.Hash())
+		}
+		if td.Cmp(tds[i]) != 0 {
+			t.Fatalf("block %d: wrong total difficulty %v, want %v", i, td, tds[i])
+		}
+		if len(receipts) != 1 || receipts[0].CumulativeGasUsed != uint64(i) || !bytes.Equal(receipts[0].Logs[0].Data, []byte{byte(i)}) {
+			t.Fatalf("block %d: wrong receipts", i)
+		}
+	}
+	if _, _, _, err := e.GetBlockByNumber(start + uint64(len(blocks))); err == nil {
+		t.Fatal("block out of range read")
+	}
+}
+
+func TestAccumulator(t *testing.T) {
+	hashes := []common.Hash{{1}, {2}, {3}}
+	tds := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+
+	root, err := ComputeAccumulator(hashes, tds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every record contributes to the root.
+	tds[2] = big.NewInt(4)
+	if other, _ := ComputeAccumulator(hashes, tds); other == root {
+		t.Fatal("total difficulty change not reflected in the root")
+	}
+	if other, _ := ComputeAccumulator(hashes[:2], tds[:2]); other == root {
+		t.Fatal("length change not reflected in the root")
+	}
+	if _, err := ComputeAccumulator(hashes, tds[:2]); err == nil {
+		t.Fatal("mismatching records accepted")
+	}
+	if _, err := ComputeAccumulator(make([]common.Hash, MaxEra1Size+1), make([]*big.Int, MaxEra1Size+1)); err == nil {
+		t.Fatal("oversized records accepted")
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"classic-00000-01020304.era1", "classic-00001-05060708.era1", "mordor-00000-01020304.era1", "checksums.txt"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	files, err := ReadDir(dir, "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0] != "classic-00000-01020304.era1" || files[1] != "classic-00001-05060708.era1" {
+		t.Fatalf("wrong files: %v", files)
+	}
+	os.WriteFile(filepath.Join(dir, "classic-00003-01020304.era1"), nil, 0644)
+	if _, err := ReadDir(dir, "classic"); err == nil {
+		t.Fatal("missing epoch not detected")
+	}
+}
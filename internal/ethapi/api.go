@@ -38,6 +38,7 @@ import (
 	"github.com/yuriy0803/core-geth1/consensus/ethash"
 	"github.com/yuriy0803/core-geth1/consensus/misc/eip1559"
 	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/rawdb"
 	"github.com/yuriy0803/core-geth1/core/state"
 	"github.com/yuriy0803/core-geth1/core/types"
 	"github.com/yuriy0803/core-geth1/core/vm"
@@ -809,6 +810,12 @@ func (s *BlockChainAPI) GetBlockByNumber(ctx context.Context, number rpc.BlockNu
 		}
 		return response, err
 	}
+	if err == nil {
+		// Report blocks dropped by history expiry instead of an unknown block.
+		if header, _ := s.b.HeaderByNumber(ctx, number); header != nil {
+			return nil, checkPrunedHistory(s.b, header.Number.Uint64())
+		}
+	}
 	return nil, err
 }
 
@@ -819,9 +826,45 @@ func (s *BlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fu
 	if block != nil {
 		return s.rpcMarshalBlock(ctx, block, true, fullTx)
 	}
+	if err == nil {
+		if header, _ := s.b.HeaderByHash(ctx, hash); header != nil {
+			return nil, checkPrunedHistory(s.b, header.Number.Uint64())
+		}
+	}
 	return nil, err
 }
 
+// GetBlockReceipts returns the receipts of all the transactions of the given
+// block, along with the fields derived from the block.
+func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		if err == nil {
+			// Report blocks dropped by history expiry instead of an unknown block.
+			if header, _ := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash); header != nil {
+				return nil, checkPrunedHistory(s.b, header.Number.Uint64())
+			}
+		}
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	var (
+		signer = types.MakeSigner(s.b.ChainConfig(), block.Number(), block.Time())
+		result = make([]map[string]interface{}, len(receipts))
+	)
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i)
+	}
+	return result, nil
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index. When fullTx is true
 // all transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *BlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (*RPCMarshalBlockT, error) {
@@ -1041,16 +1084,23 @@ func doCall(ctx context.Context, b Backend, args TransactionArgs, state *state.S
 	// this makes sure resources are cleaned up.
 	defer cancel()
 
+	blockCtx := core.NewEVMBlockContext(header, NewChainContext(ctx, b), nil)
+	if blockOverrides != nil {
+		blockOverrides.Apply(&blockCtx)
+	}
+	gp := new(core.GasPool).AddGas(math.MaxUint64)
+	return applyMessage(ctx, b, args, state, header, &blockCtx, &vm.Config{NoBaseFee: true}, gp, timeout, globalGasCap)
+}
+
+// applyMessage executes the call on top of the given state within the block
+// context. The execution is aborted once the context is done.
+func applyMessage(ctx context.Context, b Backend, args TransactionArgs, state *state.StateDB, header *types.Header, blockCtx *vm.BlockContext, vmConfig *vm.Config, gp *core.GasPool, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
 	// Get a new instance of the EVM.
 	msg, err := args.ToMessage(globalGasCap, header.BaseFee)
 	if err != nil {
 		return nil, err
 	}
-	blockCtx := core.NewEVMBlockContext(header, NewChainContext(ctx, b), nil)
-	if blockOverrides != nil {
-		blockOverrides.Apply(&blockCtx)
-	}
-	evm, vmError := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true}, &blockCtx)
+	evm, vmError := b.GetEVM(ctx, msg, state, header, vmConfig, blockCtx)
 
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -1060,7 +1110,6 @@ func doCall(ctx context.Context, b Backend, args TransactionArgs, state *state.S
 	}()
 
 	// Execute the message.
-	gp := new(core.GasPool).AddGas(math.MaxUint64)
 	result, err := core.ApplyMessage(evm, msg, gp)
 	if err := vmError(); err != nil {
 		return nil, err
@@ -1117,6 +1166,47 @@ func (e *revertError) ErrorData() interface{} {
 	return e.reason
 }
 
+// prunedHistoryError is an API error returned for the blocks whose bodies and
+// receipts were dropped by history expiry.
+type prunedHistoryError struct {
+	tail uint64 // first block whose body and receipts are retained
+}
+
+func (e *prunedHistoryError) Error() string {
+	return fmt.Sprintf("pruned history unavailable: bodies and receipts before block %d are not retained", e.tail)
+}
+
+// ErrorCode returns the JSON error code for pruned history.
+func (e *prunedHistoryError) ErrorCode() int {
+	return 4444
+}
+
+// checkPrunedHistory returns a prunedHistoryError if the body and receipts of
+// the given block were dropped by history expiry.
+func checkPrunedHistory(b Backend, number uint64) error {
+	db := b.ChainDb()
+	if db == nil {
+		return nil
+	}
+	if tail, err := db.Tail(); err == nil && number < tail {
+		return &prunedHistoryError{tail: tail}
+	}
+	return nil
+}
+
+// checkPrunedTransaction returns a prunedHistoryError if the given transaction
+// is still indexed, but its block body was dropped by history expiry.
+func checkPrunedTransaction(b Backend, hash common.Hash) error {
+	db := b.ChainDb()
+	if db == nil {
+		return nil
+	}
+	if number := rawdb.ReadTxLookupEntry(db, hash); number != nil {
+		return checkPrunedHistory(b, *number)
+	}
+	return nil
+}
+
 // Call executes the given transaction on the state for the given block number.
 //
 // Additionally, the caller can specify a batch of contract for fields overriding.
@@ -1135,12 +1225,73 @@ func (s *BlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrO
 	return result.Return(), result.Err
 }
 
-func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, gasCap uint64) (hexutil.Uint64, error) {
+// estimateGasProbe is an execution of the call with a given gas limit during the
+// binary search of a gas estimation.
+type estimateGasProbe struct {
+	Gas          hexutil.Uint64 `json:"gas"`
+	Success      bool           `json:"success"`
+	Failure      string         `json:"failure,omitempty"` // One of outOfGas, revert, intrinsicGas or error
+	Error        string         `json:"error,omitempty"`
+	Revert       hexutil.Bytes  `json:"revert,omitempty"`
+	RevertReason string         `json:"revertReason,omitempty"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	Refund       hexutil.Uint64 `json:"refund"`
+}
+
+// estimateGasTrace explains a gas estimation, listing the probes of the binary
+// search along with the highest gas limit allowed and what bounds it.
+type estimateGasTrace struct {
+	Gas             hexutil.Uint64     `json:"gas"`             // Estimated gas, zero if the estimation failed
+	Error           string             `json:"error,omitempty"` // Reason the estimation failed
+	Allowance       hexutil.Uint64     `json:"allowance"`       // Highest gas limit probed
+	AllowanceSource string             `json:"allowanceSource"` // One of gas, blockGasLimit, balance or rpcGasCap
+	Probes          []estimateGasProbe `json:"probes"`
+}
+
+// addProbe records the outcome of executing the call with the given gas limit.
+// It is a no-op on a nil trace.
+func (t *estimateGasTrace) addProbe(gas uint64, result *core.ExecutionResult, err error) {
+	if t == nil {
+		return
+	}
+	probe := estimateGasProbe{Gas: hexutil.Uint64(gas)}
+	switch {
+	case errors.Is(err, core.ErrIntrinsicGas):
+		probe.Failure, probe.Error = "intrinsicGas", err.Error()
+	case err != nil:
+		probe.Failure, probe.Error = "error", err.Error()
+	case errors.Is(result.Err, vm.ErrOutOfGas) || errors.Is(result.Err, vm.ErrCodeStoreOutOfGas):
+		probe.Failure, probe.Error = "outOfGas", result.Err.Error()
+	case errors.Is(result.Err, vm.ErrExecutionReverted):
+		probe.Failure, probe.Error = "revert", result.Err.Error()
+		probe.Revert = result.Revert()
+		if reason, err := abi.UnpackRevert(result.Revert()); err == nil {
+			probe.RevertReason = reason
+		}
+	case result.Err != nil:
+		probe.Failure, probe.Error = "error", result.Err.Error()
+	default:
+		probe.Success = true
+	}
+	if result != nil {
+		probe.GasUsed, probe.Refund = hexutil.Uint64(result.UsedGas), hexutil.Uint64(result.RefundedGas)
+	}
+	t.Probes = append(t.Probes, probe)
+}
+
+func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, gasCap uint64) (hexutil.Uint64, error) {
+	return doEstimateGas(ctx, b, args, blockNrOrHash, overrides, blockOverrides, gasCap, nil)
+}
+
+// doEstimateGas estimates the gas of the call, recording the probes of the binary
+// search into the trace if it's non-nil.
+func doEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, gasCap uint64, trace *estimateGasTrace) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
-		lo  uint64 = vars.TxGas - 1
-		hi  uint64
-		cap uint64
+		lo     uint64 = vars.TxGas - 1
+		hi     uint64
+		cap    uint64
+		source string
 	)
 	// Use zero address if sender unspecified.
 	if args.From == nil {
@@ -1148,7 +1299,9 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 	}
 	// Determine the highest gas limit can be used during the estimation.
 	if args.Gas != nil && uint64(*args.Gas) >= vars.TxGas {
-		hi = uint64(*args.Gas)
+		hi, source = uint64(*args.Gas), "gas"
+	} else if blockOverrides != nil && blockOverrides.GasLimit != nil {
+		hi, source = uint64(*blockOverrides.GasLimit), "blockGasLimit"
 	} else {
 		// Retrieve the block to act as the gas ceiling
 		block, err := b.BlockByNumberOrHash(ctx, blockNrOrHash)
@@ -1158,7 +1311,7 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 		if block == nil {
 			return 0, errors.New("block not found")
 		}
-		hi = block.GasLimit()
+		hi, source = block.GasLimit(), "blockGasLimit"
 	}
 	// Normalize the max fee per gas the call is willing to spend.
 	var feeCap *big.Int
@@ -1199,21 +1352,25 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 			}
 			log.Warn("Gas estimation capped by limited funds", "original", hi, "balance", balance,
 				"sent", transfer.ToInt(), "maxFeePerGas", feeCap, "fundable", allowance)
-			hi = allowance.Uint64()
+			hi, source = allowance.Uint64(), "balance"
 		}
 	}
 	// Recap the highest gas allowance with specified gascap.
 	if gasCap != 0 && hi > gasCap {
 		log.Warn("Caller gas above allowance, capping", "requested", hi, "cap", gasCap)
-		hi = gasCap
+		hi, source = gasCap, "rpcGasCap"
 	}
 	cap = hi
+	if trace != nil {
+		trace.Allowance, trace.AllowanceSource = hexutil.Uint64(cap), source
+	}
 
 	// Create a helper to check if a gas allowance results in an executable transaction
 	executable := func(gas uint64, state *state.StateDB, header *types.Header) (bool, *core.ExecutionResult, error) {
 		args.Gas = (*hexutil.Uint64)(&gas)
 
-		result, err := doCall(ctx, b, args, state, header, nil, nil, 0, gasCap)
+		result, err := doCall(ctx, b, args, state, header, nil, blockOverrides, 0, gasCap)
+		trace.addProbe(gas, result, err)
 		if err != nil {
 			if errors.Is(err, core.ErrIntrinsicGas) {
 				return true, nil, nil // Special case, raise gas limit
@@ -1270,12 +1427,12 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
 // given transaction against the current pending block.
-func (s *BlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Uint64, error) {
+func (s *BlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Uint64, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
 	}
-	return DoEstimateGas(ctx, s.b, args, bNrOrHash, overrides, s.b.RPCGasCap())
+	return DoEstimateGas(ctx, s.b, args, bNrOrHash, overrides, blockOverrides, s.b.RPCGasCap())
 }
 
 // RPCMarshalHeader converts the given header to the RPC output .
@@ -1833,7 +1990,7 @@ func (s *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common.H
 	}
 
 	// Transaction unknown, return as such
-	return nil, nil
+	return nil, checkPrunedTransaction(s.b, hash)
 }
 
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
@@ -1859,7 +2016,7 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	if tx == nil || err != nil {
 		// When the transaction doesn't exist, the RPC method should return JSON null
 		// as per specification.
-		return nil, nil
+		return nil, checkPrunedTransaction(s.b, hash)
 	}
 	header, err := s.b.HeaderByHash(ctx, blockHash)
 	if err != nil {
@@ -1876,13 +2033,18 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 
 	// Derive the sender.
 	signer := types.MakeSigner(s.b.ChainConfig(), header.Number, header.Time)
+	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index)), nil
+}
+
+// marshalReceipt marshals a transaction receipt into a JSON object.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex int) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
-		"transactionIndex":  hexutil.Uint64(index),
+		"transactionHash":   tx.Hash(),
+		"transactionIndex":  hexutil.Uint64(txIndex),
 		"from":              from,
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
@@ -1908,7 +2070,7 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -2248,6 +2410,29 @@ func (s *DebugAPI) GetRawTransaction(ctx context.Context, hash common.Hash) (hex
 	return tx.MarshalBinary()
 }
 
+// EstimateGasVerbose estimates the gas needed to execute the given transaction
+// like eth_estimateGas, but explains the estimation: it returns every probe of
+// the binary search with its outcome, telling the executions running out of gas
+// from the reverting ones, along with the highest gas limit allowed and what
+// bounds it. A failed estimation is reported in the error field of the result.
+func (api *DebugAPI) EstimateGasVerbose(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (*estimateGasTrace, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	trace := &estimateGasTrace{Probes: []estimateGasProbe{}}
+	gas, err := doEstimateGas(ctx, api.b, args, bNrOrHash, overrides, blockOverrides, api.b.RPCGasCap(), trace)
+	if err != nil {
+		// Failures before the binary search have nothing to explain
+		if trace.AllowanceSource == "" {
+			return nil, err
+		}
+		trace.Error = err.Error()
+	}
+	trace.Gas = gas
+	return trace, nil
+}
+
 // PrintBlock retrieves a block and returns its pretty printed form.
 func (api *DebugAPI) PrintBlock(ctx context.Context, number uint64) (string, error) {
 	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
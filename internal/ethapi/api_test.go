@@ -561,11 +561,12 @@ func TestEstimateGas(t *testing.T) {
 		b.AddTx(tx)
 	}))
 	var testSuite = []struct {
-		blockNumber rpc.BlockNumber
-		call        TransactionArgs
-		overrides   StateOverride
-		expectErr   error
-		want        uint64
+		blockNumber    rpc.BlockNumber
+		call           TransactionArgs
+		overrides      StateOverride
+		blockOverrides BlockOverrides
+		expectErr      error
+		want           uint64
 	}{
 		// simple transfer on latest block
 		{
@@ -617,9 +618,34 @@ func TestEstimateGas(t *testing.T) {
 			},
 			expectErr: core.ErrInsufficientFunds,
 		},
+		// Block overrides should work, the code fails unless the block number is 11
+		{
+			blockNumber: rpc.LatestBlockNumber,
+			call: TransactionArgs{
+				From: &accounts[0].addr,
+				To:   &randomAccounts[1].addr,
+			},
+			overrides: StateOverride{
+				randomAccounts[1].addr: OverrideAccount{Code: hex2Bytes("43600b14600f576001600060003e005b00")},
+			},
+			blockOverrides: BlockOverrides{Number: (*hexutil.Big)(big.NewInt(11))},
+			expectErr:      nil,
+			want:           21022,
+		},
+		{
+			blockNumber: rpc.LatestBlockNumber,
+			call: TransactionArgs{
+				From: &accounts[0].addr,
+				To:   &randomAccounts[1].addr,
+			},
+			overrides: StateOverride{
+				randomAccounts[1].addr: OverrideAccount{Code: hex2Bytes("43600b14600f576001600060003e005b00")},
+			},
+			expectErr: vm.ErrReturnDataOutOfBounds,
+		},
 	}
 	for i, tc := range testSuite {
-		result, err := api.EstimateGas(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides)
+		result, err := api.EstimateGas(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides, &tc.blockOverrides)
 		if tc.expectErr != nil {
 			if err == nil {
 				t.Errorf("test %d: want error %v, have nothing", i, tc.expectErr)
@@ -640,6 +666,71 @@ func TestEstimateGas(t *testing.T) {
 	}
 }
 
+func TestEstimateGasVerbose(t *testing.T) {
+	t.Parallel()
+	// Initialize test accounts
+	var (
+		accounts = newAccounts(1)
+		genesis  = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: genesisT.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(vars.Ether)},
+			},
+		}
+		contract = common.HexToAddress("0xc0de")
+	)
+	api := NewDebugAPI(newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {}))
+
+	estimate := func(code string, gas *hexutil.Uint64) *estimateGasTrace {
+		t.Helper()
+		args := TransactionArgs{From: &accounts[0].addr, To: &contract, Gas: gas}
+		overrides := StateOverride{contract: OverrideAccount{Code: hex2Bytes(code)}}
+		trace, err := api.EstimateGasVerbose(context.Background(), args, nil, &overrides, nil)
+		if err != nil {
+			t.Fatalf("failed to estimate gas: %v", err)
+		}
+		return trace
+	}
+	failures := func(trace *estimateGasTrace) map[string]int {
+		failures := make(map[string]int)
+		for _, probe := range trace.Probes {
+			if probe.Success != (probe.Failure == "") {
+				t.Errorf("probe with %d gas: success %v with failure %q", probe.Gas, probe.Success, probe.Failure)
+			}
+			failures[probe.Failure]++
+		}
+		return failures
+	}
+	// The code stores a slot, probes with too little gas run out of it.
+	trace := estimate("6001600055", nil)
+	if trace.Error != "" || trace.Gas <= 41000 || trace.AllowanceSource != "blockGasLimit" {
+		t.Errorf("wrong estimation: gas %d, error %q, allowance source %q", trace.Gas, trace.Error, trace.AllowanceSource)
+	}
+	if failures := failures(trace); failures["outOfGas"] == 0 || failures["revert"] != 0 || failures[""] == 0 {
+		t.Errorf("wrong probe failures: %v", failures)
+	}
+	// The code reverts with less than 30000 gas left.
+	trace = estimate("5a61753010600d5760006000fd5b00", nil)
+	if trace.Error != "" || trace.Gas <= 51000 {
+		t.Errorf("wrong estimation: gas %d, error %q", trace.Gas, trace.Error)
+	}
+	if failures := failures(trace); failures["revert"] == 0 || failures[""] == 0 {
+		t.Errorf("wrong probe failures: %v", failures)
+	}
+	// The code always reverts, the estimation fails with the revert.
+	gas := hexutil.Uint64(30000)
+	trace = estimate("60006000fd", &gas)
+	if trace.Error != "execution reverted" || trace.Gas != 0 || trace.Allowance != gas || trace.AllowanceSource != "gas" {
+		t.Errorf("wrong estimation: gas %d, error %q, allowance %d from %q", trace.Gas, trace.Error, trace.Allowance, trace.AllowanceSource)
+	}
+	if failures := failures(trace); failures["revert"] == 0 || failures[""] != 0 {
+		t.Errorf("wrong probe failures: %v", failures)
+	}
+	if last := trace.Probes[len(trace.Probes)-1]; last.Gas != gas || last.Failure != "revert" {
+		t.Errorf("wrong last probe: %+v", last)
+	}
+}
+
 func TestCall(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -809,6 +900,112 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestSimulate(t *testing.T) {
+	t.Parallel()
+	// Initialize test accounts
+	var (
+		accounts = newAccounts(2)
+		genesis  = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: genesisT.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(vars.Ether)},
+			},
+		}
+		genBlocks = 10
+		counter   = common.HexToAddress("0xc0de")
+		reverter  = common.HexToAddress("0xdead")
+	)
+	backend := newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {})
+	api := NewBlockChainAPI(backend)
+	head := backend.chain.CurrentHeader()
+
+	// The counter returns and increments its slot 0, logging the block number.
+	overrides := StateOverride{
+		counter: OverrideAccount{Code: hex2Bytes("600054806001016000554360006000a160005260206000f3")},
+	}
+	call := TransactionArgs{From: &accounts[0].addr, To: &counter}
+	results, err := api.Simulate(context.Background(), SimulateOpts{
+		BlockStateCalls: []SimulateBlock{
+			{
+				StateOverrides: &overrides,
+				Calls: []TransactionArgs{
+					{From: &accounts[0].addr, To: &counter, Value: (*hexutil.Big)(big.NewInt(1000))},
+					call,
+				},
+			},
+			{
+				BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(big.NewInt(int64(genBlocks) + 3))},
+				StateOverrides: &StateOverride{
+					reverter: OverrideAccount{Code: hex2Bytes("60006000fd")},
+				},
+				Calls: []TransactionArgs{call, {From: &accounts[0].addr, To: &reverter}},
+			},
+		},
+		TraceTransfers: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	// The gap before the last block is filled with an empty block.
+	if len(results) != 3 {
+		t.Fatalf("wrong number of blocks: have %d, want 3", len(results))
+	}
+	parent := head.Hash()
+	for i, result := range results {
+		if number := result["number"].(*hexutil.Big).ToInt().Uint64(); number != uint64(genBlocks+1+i) {
+			t.Errorf("block %d: wrong number %d", i, number)
+		}
+		if result["parentHash"].(common.Hash) != parent {
+			t.Errorf("block %d: wrong parent hash", i)
+		}
+		parent = result["hash"].(common.Hash)
+	}
+	if calls := results[1]["calls"].([]simCallResult); len(calls) != 0 {
+		t.Errorf("empty block has %d calls", len(calls))
+	}
+	// The state carries forward across calls and blocks.
+	calls := append(results[0]["calls"].([]simCallResult), results[2]["calls"].([]simCallResult)...)
+	for i, call := range calls[:3] {
+		if have := new(big.Int).SetBytes(call.ReturnValue); have.Int64() != int64(i) {
+			t.Errorf("call %d: wrong counter %d", i, have)
+		}
+		if call.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) || call.Error != nil {
+			t.Errorf("call %d: failed: %v", i, call.Error)
+		}
+	}
+	// The transfer of the first call is logged before the log of the counter.
+	logs := calls[0].Logs
+	if len(logs) != 2 {
+		t.Fatalf("wrong number of logs: have %d, want 2", len(logs))
+	}
+	if logs[0].Address != transferAddress || logs[0].Topics[1] != common.BytesToHash(accounts[0].addr.Bytes()) ||
+		logs[0].Topics[2] != common.BytesToHash(counter.Bytes()) || new(big.Int).SetBytes(logs[0].Data).Int64() != 1000 {
+		t.Errorf("wrong transfer log: %+v", logs[0])
+	}
+	if logs[1].Address != counter || logs[1].Topics[0] != common.BigToHash(big.NewInt(int64(genBlocks)+1)) || logs[1].Index != 1 {
+		t.Errorf("wrong counter log: %+v", logs[1])
+	}
+	if logs[1].BlockHash != results[0]["hash"].(common.Hash) {
+		t.Errorf("wrong log block hash: %x", logs[1].BlockHash)
+	}
+	if topic := calls[2].Logs[0].Topics[0]; topic != common.BigToHash(big.NewInt(int64(genBlocks)+3)) {
+		t.Errorf("wrong block number logged: %x", topic)
+	}
+	// Reverting calls report the error without aborting the simulation.
+	if call := calls[3]; call.Status != hexutil.Uint64(types.ReceiptStatusFailed) || call.Error == nil || call.Error.Code != 3 {
+		t.Errorf("reverting call: status %d, error %+v", call.Status, call.Error)
+	}
+	// The block numbers must increase.
+	_, err = api.Simulate(context.Background(), SimulateOpts{
+		BlockStateCalls: []SimulateBlock{
+			{BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(big.NewInt(int64(genBlocks)))}},
+		},
+	}, nil)
+	if err == nil {
+		t.Error("simulated block below the base block")
+	}
+}
+
 type Account struct {
 	key  *ecdsa.PrivateKey
 	addr common.Address
@@ -2006,3 +2203,189 @@ func TestRPCGetTransactionReceipt(t *testing.T) {
 		require.JSONEqf(t, want, have, "test %d: json not match, want: %s, have: %s", i, want, have)
 	}
 }
+
+func TestRPCGetBlockReceipts(t *testing.T) {
+	t.Parallel()
+
+	var (
+		acc1Key, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		acc1Addr   = crypto.PubkeyToAddress(acc1Key.PublicKey)
+		acc2Addr   = common.Address{0x02}
+		genesis    = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  genesisT.GenesisAlloc{acc1Addr: {Balance: big.NewInt(vars.Ether)}},
+		}
+		genBlocks = 3
+		signer    = types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+		txHashes  []common.Hash
+	)
+	backend := newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {
+		// Block n holds n-1 transactions.
+		for n := 0; n < i; n++ {
+			tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{Nonce: b.TxNonce(acc1Addr), To: &acc2Addr, Value: big.NewInt(1000), Gas: vars.TxGas, GasFeeCap: b.BaseFee(), Data: nil}), signer, acc1Key)
+			if err != nil {
+				t.Errorf("failed to sign tx: %v", err)
+			}
+			b.AddTx(tx)
+			txHashes = append(txHashes, tx.Hash())
+		}
+	})
+	var (
+		api   = NewBlockChainAPI(backend)
+		txAPI = NewTransactionAPI(backend, new(AddrLocker))
+		ctx   = context.Background()
+	)
+	// Receipts of a block match the ones of its transactions.
+	var have []map[string]interface{}
+	for number := 1; number <= genBlocks; number++ {
+		receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number)))
+		if err != nil {
+			t.Fatalf("block %d: failed to get receipts: %v", number, err)
+		}
+		if len(receipts) != number-1 {
+			t.Fatalf("block %d: wrong receipt count: have %d, want %d", number, len(receipts), number-1)
+		}
+		have = append(have, receipts...)
+	}
+	for i, hash := range txHashes {
+		want, err := txAPI.GetTransactionReceipt(ctx, hash)
+		if err != nil {
+			t.Fatalf("tx %d: failed to get receipt: %v", i, err)
+		}
+		wantJSON, _ := json.Marshal(want)
+		haveJSON, _ := json.Marshal(have[i])
+		require.JSONEqf(t, string(wantJSON), string(haveJSON), "tx %d: receipt mismatch", i)
+	}
+	// Blocks can be selected by hash too.
+	header, _ := backend.HeaderByNumber(ctx, rpc.BlockNumber(genBlocks))
+	receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+	if err != nil || len(receipts) != 2 || receipts[1]["transactionHash"] != txHashes[2] {
+		t.Fatalf("wrong receipts by hash: %v, %v", receipts, err)
+	}
+	// Unknown blocks have no receipts.
+	if receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(genBlocks+1))); receipts != nil || err != nil {
+		t.Fatalf("unknown block: have %v, %v", receipts, err)
+	}
+}
+
+func TestRPCPrunedHistory(t *testing.T) {
+	t.Parallel()
+
+	var (
+		acc1Key, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		acc1Addr   = crypto.PubkeyToAddress(acc1Key.PublicKey)
+		acc2Addr   = common.Address{0x02}
+		genesis    = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  genesisT.GenesisAlloc{acc1Addr: {Balance: big.NewInt(vars.Ether)}},
+		}
+		genBlocks = 8
+		tail      = uint64(4)
+		signer    = types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+	)
+	_, blocks, receipts := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), genBlocks, func(i int, b *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{Nonce: b.TxNonce(acc1Addr), To: &acc2Addr, Value: big.NewInt(1000), Gas: vars.TxGas, GasFeeCap: b.BaseFee(), Data: nil}), signer, acc1Key)
+		if err != nil {
+			t.Errorf("failed to sign tx: %v", err)
+		}
+		b.AddTx(tx)
+	})
+	// Freeze the chain and drop the bodies and receipts below the tail.
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	genesisBlock := core.MustCommitGenesis(rawdb.NewMemoryDatabase(), genesis)
+	rawdb.WriteAncientBlocks(db, append([]*types.Block{genesisBlock}, blocks...), append([]types.Receipts{{}}, receipts...), big.NewInt(0))
+	rawdb.IndexTransactions(db, 0, uint64(genBlocks+1), nil)
+
+	chain, err := core.NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := db.TruncateTail(tail); err != nil {
+		t.Fatalf("failed to truncate history: %v", err)
+	}
+	var (
+		backend = &testBackend{db: db, chain: chain}
+		api     = NewBlockChainAPI(backend)
+		txAPI   = NewTransactionAPI(backend, new(AddrLocker))
+		ctx     = context.Background()
+	)
+	expectPruned := func(name string, err error) {
+		t.Helper()
+		var pruned *prunedHistoryError
+		if !errors.As(err, &pruned) || pruned.ErrorCode() != 4444 || pruned.tail != tail {
+			t.Fatalf("%s: expected pruned history error, got %v", name, err)
+		}
+	}
+	for _, block := range blocks {
+		var (
+			number = block.NumberU64()
+			hash   = block.Hash()
+			txHash = block.Transactions()[0].Hash()
+		)
+		if number < tail {
+			res, err := api.GetBlockByNumber(ctx, rpc.BlockNumber(number), false)
+			if res != nil {
+				t.Fatalf("block %d: unexpected block", number)
+			}
+			expectPruned(fmt.Sprintf("block %d", number), err)
+
+			receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number)))
+			if receipts != nil {
+				t.Fatalf("block %d: unexpected receipts", number)
+			}
+			expectPruned(fmt.Sprintf("block %d receipts", number), err)
+
+			_, err = api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(hash, false))
+			expectPruned(fmt.Sprintf("block %d receipts by hash", number), err)
+
+			receipt, err := txAPI.GetTransactionReceipt(ctx, txHash)
+			if receipt != nil {
+				t.Fatalf("block %d: unexpected transaction receipt", number)
+			}
+			expectPruned(fmt.Sprintf("block %d transaction receipt", number), err)
+			continue
+		}
+		// The retained history is served as usual.
+		if res, err := api.GetBlockByNumber(ctx, rpc.BlockNumber(number), false); res == nil || err != nil {
+			t.Fatalf("block %d: failed to get block: %v", number, err)
+		}
+		if receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number))); len(receipts) != 1 || err != nil {
+			t.Fatalf("block %d: failed to get receipts: %v", number, err)
+		}
+		if receipt, err := txAPI.GetTransactionReceipt(ctx, txHash); receipt == nil || err != nil {
+			t.Fatalf("block %d: failed to get transaction receipt: %v", number, err)
+		}
+	}
+	// Unknown blocks and transactions are still reported as missing.
+	if res, err := api.GetBlockByNumber(ctx, rpc.BlockNumber(genBlocks+1), false); res != nil || err != nil {
+		t.Fatalf("unknown block: have %v, %v", res, err)
+	}
+	if receipt, err := txAPI.GetTransactionReceipt(ctx, common.Hash{0x01}); receipt != nil || err != nil {
+		t.Fatalf("unknown transaction: have %v, %v", receipt, err)
+	}
+}
+
+func TestDbCheck(t *testing.T) {
+	t.Parallel()
+
+	genesis := &genesisT.Genesis{Config: params.TestChainConfig}
+	api := NewDebugAPI(newTestBackend(t, 4, genesis, func(i int, b *core.BlockGen) {}))
+
+	result, err := api.DbCheck(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(result.Issues) != 0 {
+		t.Errorf("unexpected issues: %+v", result.Issues)
+	}
+	// The live database is only checked, never repaired.
+	if _, err := api.DbCheck(context.Background(), &DbCheckArgs{Repair: true}); !errors.Is(err, errDbCheckRepair) {
+		t.Fatalf("repair not rejected: %v", err)
+	}
+}
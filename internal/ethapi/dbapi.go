@@ -17,10 +17,28 @@
 package ethapi
 
 import (
+	"context"
+	"errors"
+
 	"github.com/yuriy0803/core-geth1/common"
 	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/core"
 )
 
+const (
+	// dbCheckDefaultCount is the number of blocks checked by a debug_dbCheck
+	// call, unless specified.
+	dbCheckDefaultCount = 10000
+
+	// dbCheckMaxIssues is the number of issues after which a debug_dbCheck
+	// call stops.
+	dbCheckMaxIssues = 1000
+)
+
+// errDbCheckRepair is returned for debug_dbCheck calls requesting repairs, which
+// would race with the chain import. Repairs are done by the offline geth db check.
+var errDbCheckRepair = errors.New("repair is only supported by the offline geth db check command")
+
 // DbGet returns the raw value of a key stored in the database.
 func (api *DebugAPI) DbGet(key string) (hexutil.Bytes, error) {
 	blob, err := common.ParseHexOrString(key)
@@ -41,3 +59,31 @@ func (api *DebugAPI) DbAncient(kind string, number uint64) (hexutil.Bytes, error
 func (api *DebugAPI) DbAncients() (uint64, error) {
 	return api.b.ChainDb().Ancients()
 }
+
+// DbCheckArgs represents the arguments of a database consistency check.
+type DbCheckArgs struct {
+	Start  *hexutil.Uint64 `json:"start"`  // First block to check, 0 if unspecified
+	Count  *hexutil.Uint64 `json:"count"`  // Number of blocks to check, 0 for all up to the head
+	Repair bool            `json:"repair"` // Rejected, repairs are only done offline
+}
+
+// DbCheck checks the consistency of the canonical hashes, headers, bodies,
+// receipts and transaction lookups of a range of blocks, the continuity of the
+// ancient and key-value stores and, once the head is reached, the agreement of
+// the snapshot with the persisted state. The check can be resumed from the
+// returned next block. The live database is never repaired.
+func (api *DebugAPI) DbCheck(ctx context.Context, args *DbCheckArgs) (*core.CheckResult, error) {
+	config := core.CheckConfig{Count: dbCheckDefaultCount, MaxIssues: dbCheckMaxIssues}
+	if args != nil {
+		if args.Start != nil {
+			config.Start = uint64(*args.Start)
+		}
+		if args.Count != nil {
+			config.Count = uint64(*args.Count)
+		}
+		if args.Repair {
+			return nil, errDbCheckRepair
+		}
+	}
+	return core.CheckDatabase(api.b.ChainDb(), config, ctx.Done())
+}
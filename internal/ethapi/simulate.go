@@ -0,0 +1,448 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/consensus/misc/eip1559"
+	"github.com/yuriy0803/core-geth1/core"
+	"github.com/yuriy0803/core-geth1/core/state"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/core/vm"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+	"github.com/yuriy0803/core-geth1/rpc"
+	"github.com/yuriy0803/core-geth1/trie"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks simulated at once,
+	// including the empty blocks filling the gaps between block numbers.
+	maxSimulateBlocks = 256
+
+	// timestampIncrement is the default time between simulated blocks.
+	timestampIncrement = 12
+
+	// errcodeVMError is the JSON error code of a call failing in the EVM
+	// for any other reason than a revert.
+	errcodeVMError = -32015
+)
+
+var (
+	// transferAddress is the pseudo-address emitting the logs of ETH transfers
+	// when tracing transfers, following the ERC-7528 convention.
+	transferAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+	// transferTopic is the topic of the ERC-20 Transfer event, which the logs of
+	// ETH transfers mimic.
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// SimulateBlock is a block of calls to simulate. The block fields and the state
+// can be overridden before executing the calls.
+type SimulateBlock struct {
+	BlockOverrides *BlockOverrides   `json:"blockOverrides"`
+	StateOverrides *StateOverride    `json:"stateOverrides"`
+	Calls          []TransactionArgs `json:"calls"`
+}
+
+// SimulateOpts is the series of blocks to simulate.
+type SimulateOpts struct {
+	BlockStateCalls []SimulateBlock `json:"blockStateCalls"`
+	TraceTransfers  bool            `json:"traceTransfers"`
+}
+
+// simCallResult is the outcome of a simulated call.
+type simCallResult struct {
+	ReturnValue hexutil.Bytes  `json:"returnData"`
+	Logs        []*types.Log   `json:"logs"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Status      hexutil.Uint64 `json:"status"`
+	Error       *callError     `json:"error,omitempty"`
+}
+
+// callError is the error of a call which failed in the EVM.
+type callError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Data    string `json:"data,omitempty"`
+}
+
+// Simulate executes a series of blocks of calls on top of the state of the given
+// block. The state changes of every call carry forward into the next ones, also
+// across blocks. The result holds the header of every simulated block together
+// with the return data, logs and gas used of its calls.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to dry-run a sequence of transactions.
+func (s *BlockChainAPI) Simulate(ctx context.Context, opts SimulateOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errors.New("no blocks to simulate")
+	}
+	if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks to simulate, the maximum is %d", maxSimulateBlocks)
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, bNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	sim := &simulator{
+		b:              s.b,
+		state:          state,
+		base:           header,
+		config:         s.b.ChainConfig(),
+		headers:        make(map[common.Hash]*types.Header),
+		traceTransfers: opts.TraceTransfers,
+		timeout:        s.b.RPCEVMTimeout(),
+		gasCap:         s.b.RPCGasCap(),
+	}
+	return sim.execute(ctx, opts.BlockStateCalls)
+}
+
+// simulator executes the blocks of a simulation on top of a single state.
+type simulator struct {
+	b      Backend
+	state  *state.StateDB
+	base   *types.Header // Header of the block the simulation starts from
+	config ctypes.ChainConfigurator
+
+	headers        map[common.Hash]*types.Header // Simulated headers, served to the BLOCKHASH opcode
+	traceTransfers bool
+
+	timeout time.Duration // Timeout of the whole simulation
+	gasCap  uint64        // Gas available to all the calls together, zero if unlimited
+	gasUsed uint64        // Gas used by the calls so far
+}
+
+// execute simulates the blocks in order, filling any gap between the requested
+// block numbers with empty blocks.
+func (sim *simulator) execute(ctx context.Context, blocks []SimulateBlock) ([]map[string]interface{}, error) {
+	// Setup context so the whole simulation is aborted once the timeout passes.
+	var cancel context.CancelFunc
+	if sim.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, sim.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		results []map[string]interface{}
+		parent  = sim.base
+		limit   = new(big.Int).Add(sim.base.Number, big.NewInt(maxSimulateBlocks))
+	)
+	for i := range blocks {
+		number := new(big.Int).Add(parent.Number, common.Big1)
+		if overrides := blocks[i].BlockOverrides; overrides != nil && overrides.Number != nil {
+			number = overrides.Number.ToInt()
+		}
+		if number.Cmp(parent.Number) <= 0 {
+			return nil, fmt.Errorf("block number %d not above the previous block number %d", number, parent.Number)
+		}
+		if number.Cmp(limit) > 0 {
+			return nil, fmt.Errorf("too many blocks to simulate, the maximum is %d", maxSimulateBlocks)
+		}
+		for new(big.Int).Add(parent.Number, common.Big1).Cmp(number) < 0 {
+			result, header, err := sim.processBlock(ctx, &SimulateBlock{}, parent)
+			if err != nil {
+				return nil, err
+			}
+			results, parent = append(results, result), header
+		}
+		result, header, err := sim.processBlock(ctx, &blocks[i], parent)
+		if err != nil {
+			return nil, err
+		}
+		results, parent = append(results, result), header
+	}
+	return results, nil
+}
+
+// processBlock executes the calls of a block on top of the state left by the
+// previous one, returning the result and the header of the simulated block.
+func (sim *simulator) processBlock(ctx context.Context, block *SimulateBlock, parent *types.Header) (map[string]interface{}, *types.Header, error) {
+	header, err := sim.makeHeader(block.BlockOverrides, parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := block.StateOverrides.Apply(sim.state); err != nil {
+		return nil, nil, err
+	}
+	var (
+		chain    = &simChainContext{ChainContext: NewChainContext(ctx, sim.b), headers: sim.headers}
+		blockCtx = core.NewEVMBlockContext(header, chain, nil)
+		tracer   = &simTracer{traceTransfers: sim.traceTransfers}
+		vmConfig = &vm.Config{Tracer: tracer, NoBaseFee: true}
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		eip161d  = sim.config.IsEnabled(sim.config.GetEIP161dTransition, header.Number)
+
+		calls    = make([]simCallResult, len(block.Calls))
+		txs      = make(types.Transactions, len(block.Calls))
+		receipts = make(types.Receipts, len(block.Calls))
+		logs     []*types.Log
+	)
+	// Apply the overrides without a header counterpart, like the randomness.
+	block.BlockOverrides.Apply(&blockCtx)
+
+	for i, args := range block.Calls {
+		if err := sim.sanitizeCall(&args, gp); err != nil {
+			return nil, nil, fmt.Errorf("call %d of block %d: %w", i, header.Number, err)
+		}
+		tx := args.ToTransaction()
+		sim.state.SetTxContext(tx.Hash(), i)
+
+		result, err := applyMessage(ctx, sim.b, args, sim.state, header, &blockCtx, vmConfig, gp, sim.timeout, sim.gasRemaining())
+		if err != nil {
+			return nil, nil, fmt.Errorf("call %d of block %d: %w", i, header.Number, err)
+		}
+		sim.gasUsed += result.UsedGas
+		sim.state.Finalise(eip161d)
+		header.GasUsed += result.UsedGas
+
+		for _, log := range tracer.logs {
+			log.TxHash, log.TxIndex, log.BlockNumber, log.Index = tx.Hash(), uint(i), header.Number.Uint64(), uint(len(logs))
+			logs = append(logs, log)
+		}
+		call := simCallResult{
+			ReturnValue: result.Return(),
+			Logs:        tracer.logs,
+			GasUsed:     hexutil.Uint64(result.UsedGas),
+			Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if call.Logs == nil {
+			call.Logs = []*types.Log{}
+		}
+		if result.Failed() {
+			call.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if errors.Is(result.Err, vm.ErrExecutionReverted) {
+				revertErr := newRevertError(result)
+				call.Error = &callError{Message: revertErr.Error(), Code: revertErr.ErrorCode(), Data: revertErr.reason}
+			} else {
+				call.Error = &callError{Message: result.Err.Error(), Code: errcodeVMError}
+			}
+		}
+		calls[i], txs[i] = call, tx
+		receipts[i] = &types.Receipt{
+			Type:              tx.Type(),
+			Status:            uint64(call.Status),
+			CumulativeGasUsed: header.GasUsed,
+			Logs:              tracer.logs,
+			TxHash:            tx.Hash(),
+			GasUsed:           result.UsedGas,
+		}
+		receipts[i].Bloom = types.CreateBloom(types.Receipts{receipts[i]})
+	}
+	// Seal the block, so that the later blocks can access its hash.
+	header.Root = sim.state.IntermediateRoot(eip161d)
+	header = types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)).Header()
+
+	hash := header.Hash()
+	for _, log := range logs {
+		log.BlockHash = hash
+	}
+	sim.headers[hash] = header
+
+	result := RPCMarshalHeader(header)
+	result["calls"] = calls
+	return result, header, nil
+}
+
+// makeHeader assembles the header of the block following the parent, applying
+// the overrides. The state root and the gas used are filled in after executing
+// the calls of the block.
+func (sim *simulator) makeHeader(overrides *BlockOverrides, parent *types.Header) (*types.Header, error) {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + timestampIncrement,
+	}
+	if overrides != nil {
+		if overrides.Time != nil {
+			if uint64(*overrides.Time) <= parent.Time {
+				return nil, fmt.Errorf("block timestamp %d not above the previous block timestamp %d", *overrides.Time, parent.Time)
+			}
+			header.Time = uint64(*overrides.Time)
+		}
+		if overrides.Difficulty != nil {
+			header.Difficulty = new(big.Int).Set(overrides.Difficulty.ToInt())
+		}
+		if overrides.GasLimit != nil {
+			header.GasLimit = uint64(*overrides.GasLimit)
+		}
+		if overrides.Coinbase != nil {
+			header.Coinbase = *overrides.Coinbase
+		}
+		if overrides.Random != nil {
+			header.MixDigest = *overrides.Random
+		}
+	}
+	if sim.config.IsEnabled(sim.config.GetEIP1559Transition, header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(sim.config, parent)
+	}
+	if overrides != nil && overrides.BaseFee != nil {
+		header.BaseFee = new(big.Int).Set(overrides.BaseFee.ToInt())
+	}
+	return header, nil
+}
+
+// gasRemaining returns the part of the gas cap not yet used by the calls, or
+// zero if the gas is not capped.
+func (sim *simulator) gasRemaining() uint64 {
+	if sim.gasCap == 0 {
+		return 0
+	}
+	return sim.gasCap - sim.gasUsed
+}
+
+// sanitizeCall fills in the nonce and the gas of a call if unspecified. The gas
+// defaults to the gas left in the block, within the remainder of the gas cap.
+func (sim *simulator) sanitizeCall(args *TransactionArgs, gp *core.GasPool) error {
+	if sim.gasCap != 0 && sim.gasUsed >= sim.gasCap {
+		return fmt.Errorf("gas cap of %d exhausted", sim.gasCap)
+	}
+	if args.Nonce == nil {
+		nonce := hexutil.Uint64(sim.state.GetNonce(args.from()))
+		args.Nonce = &nonce
+	}
+	if args.Gas == nil {
+		gas := gp.Gas()
+		if remaining := sim.gasRemaining(); remaining != 0 && remaining < gas {
+			gas = remaining
+		}
+		args.Gas = (*hexutil.Uint64)(&gas)
+	}
+	return nil
+}
+
+// simChainContext serves the headers of the simulated blocks on top of the ones
+// of the chain.
+type simChainContext struct {
+	*ChainContext
+	headers map[common.Hash]*types.Header
+}
+
+// GetHeader retrieves a simulated or canonical header by hash and number.
+func (c *simChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := c.headers[hash]; ok {
+		return header
+	}
+	return c.ChainContext.GetHeader(hash, number)
+}
+
+// simTracer collects the logs of a simulated call in execution order, along with
+// the pseudo-logs of the ETH transfers if requested. The logs of the call frames
+// which revert are discarded, as they are by the state.
+type simTracer struct {
+	traceTransfers bool
+	frames         [][]*types.Log // Logs of the active call frames
+	logs           []*types.Log   // Logs of the last executed call
+}
+
+var _ vm.EVMLogger = (*simTracer)(nil)
+
+func (t *simTracer) CaptureTxStart(gasLimit uint64) {
+	t.frames, t.logs = nil, nil
+}
+
+func (t *simTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *simTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frames = [][]*types.Log{nil}
+	t.captureTransfer(from, to, value)
+}
+
+func (t *simTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if err == nil && len(t.frames) > 0 {
+		t.logs = t.frames[0]
+	}
+	t.frames = nil
+}
+
+func (t *simTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.frames = append(t.frames, nil)
+
+	// Only calls, creations and self-destructs move value between accounts.
+	if typ != vm.CALLCODE && typ != vm.DELEGATECALL && typ != vm.STATICCALL {
+		t.captureTransfer(from, to, value)
+	}
+}
+
+func (t *simTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) < 2 {
+		return
+	}
+	n := len(t.frames) - 1
+	logs := t.frames[n]
+	t.frames = t.frames[:n]
+	if err == nil {
+		t.frames[n-1] = append(t.frames[n-1], logs...)
+	}
+}
+
+func (t *simTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || op < vm.LOG0 || op > vm.LOG4 {
+		return
+	}
+	var (
+		stack  = scope.Stack.Data()
+		offset = stack[len(stack)-1]
+		size   = stack[len(stack)-2]
+		topics = make([]common.Hash, op-vm.LOG0)
+	)
+	for i := range topics {
+		topics[i] = stack[len(stack)-3-i].Bytes32()
+	}
+	// The tracer is invoked before the memory is expanded, pad the data.
+	data := make([]byte, size.Uint64())
+	if len(data) > 0 && offset.Uint64() < uint64(scope.Memory.Len()) {
+		copy(data, scope.Memory.Data()[offset.Uint64():])
+	}
+	t.addLog(&types.Log{Address: scope.Contract.Address(), Topics: topics, Data: data})
+}
+
+func (t *simTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// captureTransfer records the pseudo-log of an ETH transfer, if enabled.
+func (t *simTracer) captureTransfer(from, to common.Address, value *big.Int) {
+	if !t.traceTransfers || value == nil || value.Sign() == 0 {
+		return
+	}
+	topics := []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())}
+	t.addLog(&types.Log{Address: transferAddress, Topics: topics, Data: common.BigToHash(value).Bytes()})
+}
+
+// addLog records a log in the current call frame.
+func (t *simTracer) addLog(log *types.Log) {
+	if len(t.frames) > 0 {
+		t.frames[len(t.frames)-1] = append(t.frames[len(t.frames)-1], log)
+	}
+}
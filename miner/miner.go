@@ -34,6 +34,7 @@ import (
 	"github.com/yuriy0803/core-geth1/eth/fetcher"
 	"github.com/yuriy0803/core-geth1/event"
 	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/miner/stratum"
 	"github.com/yuriy0803/core-geth1/params/types/ctypes"
 	"github.com/yuriy0803/core-geth1/params/vars"
 )
@@ -57,6 +58,11 @@ type Config struct {
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
 
+	Stratum              string `toml:",omitempty"` // Listening address of the Stratum server for remote miners (only useful in ethash and lyra2).
+	StratumDifficulty    uint64 // Share difficulty of the Stratum workers not setting their own
+	StratumMaxSessions   int    // Maximum number of miners connected to the Stratum server
+	StratumMaxSessionsIP int    // Maximum number of miners connected to the Stratum server from an IP address, unlimited if zero
+
 	NewPayloadTimeout time.Duration // The maximum time allowance for creating a new payload
 }
 
@@ -71,6 +77,9 @@ var DefaultConfig = Config{
 	// run 3 rounds.
 	Recommit:          2 * time.Second,
 	NewPayloadTimeout: 2 * time.Second,
+
+	StratumDifficulty:  stratum.DefaultDifficulty,
+	StratumMaxSessions: stratum.DefaultMaxSessions,
 }
 
 // Miner creates blocks and searches for proof-of-work values.
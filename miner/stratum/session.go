@@ -0,0 +1,602 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package stratum
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/crypto"
+	"github.com/yuriy0803/core-geth1/log"
+)
+
+// protocol is the flavour of the Stratum protocol spoken by a session.
+type protocol int
+
+const (
+	protocolUnknown protocol = iota
+	protocolStratum          // EthereumStratum/1.0.0
+	protocolProxy            // stratum-proxy
+)
+
+// stratumVersion is the EthereumStratum version served.
+const stratumVersion = "EthereumStratum/1.0.0"
+
+// stratumUnit is the number of hashes a share of EthereumStratum difficulty 1
+// takes, 2^32.
+var stratumUnit = new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 32))
+
+// request is a request of a miner. The stratum-proxy login request may carry
+// the worker name next to the params.
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Worker string          `json:"worker"`
+}
+
+// share is a valid share submitted by a worker.
+type share struct {
+	time       time.Time
+	difficulty *big.Int
+}
+
+// session is a connected miner.
+type session struct {
+	server     *Server
+	conn       net.Conn
+	id         uint64
+	extranonce uint16
+
+	writeLock sync.Mutex       // Serializes the writes to the connection
+	queue     chan interface{} // Notifications pending to be written to the miner
+	closed    chan struct{}    // Closed when the session ends
+
+	lock           sync.Mutex
+	protocol       protocol
+	subscribed     bool
+	authorized     bool
+	worker         string
+	rateID         common.Hash // Identifier of the hash rate reported to the sealer
+	difficulty     *big.Int    // Share difficulty requested by the worker
+	sentDifficulty *big.Int    // Share difficulty last announced to the miner
+	started        time.Time
+	shares         []share
+	reported       uint64    // Hash rate reported by the miner itself
+	reportedTime   time.Time // Time the hash rate was last reported
+}
+
+func newSession(server *Server, conn net.Conn, id uint64, extranonce uint16) *session {
+	return &session{
+		server:     server,
+		conn:       conn,
+		id:         id,
+		extranonce: extranonce,
+		queue:      make(chan interface{}, notifyQueueSize),
+		closed:     make(chan struct{}),
+		difficulty: new(big.Int).SetUint64(server.config.Difficulty),
+	}
+}
+
+// serve handles the requests of the miner until the connection is closed.
+func (sess *session) serve() {
+	written := make(chan struct{})
+	go func() {
+		sess.writeLoop()
+		close(written)
+	}()
+	defer func() {
+		sess.conn.Close()
+		close(sess.closed)
+		<-written
+	}()
+
+	log.Debug("Stratum miner connected", "remote", sess.conn.RemoteAddr())
+	scanner := bufio.NewScanner(sess.conn)
+	scanner.Buffer(make([]byte, maxRequestSize), maxRequestSize)
+	for {
+		sess.conn.SetReadDeadline(time.Now().Add(readTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			log.Debug("Stratum miner sent invalid request", "remote", sess.conn.RemoteAddr(), "err", err)
+			break
+		}
+		result, err := sess.handle(&req)
+		if err := sess.reply(req.ID, result, err); err != nil {
+			break
+		}
+		// Hand out the current job to the newly authorized EthereumStratum miners.
+		if err == nil && req.Method == "mining.authorize" {
+			if job := sess.server.currentJob(); job != nil {
+				sess.notify(job, true)
+			}
+		}
+	}
+	log.Debug("Stratum miner disconnected", "remote", sess.conn.RemoteAddr(), "worker", sess.worker)
+}
+
+// handle executes the request, returning its result.
+func (sess *session) handle(req *request) (interface{}, error) {
+	var params []string
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, errInvalidParams
+		}
+	}
+	switch req.Method {
+	case "mining.subscribe":
+		if len(params) > 1 && !strings.HasPrefix(params[1], "EthereumStratum/") {
+			return nil, errInvalidProtocol
+		}
+		if err := sess.setProtocol(protocolStratum); err != nil {
+			return nil, err
+		}
+		sess.lock.Lock()
+		sess.subscribed = true
+		sess.lock.Unlock()
+
+		notify := []interface{}{"mining.notify", fmt.Sprintf("%x", sess.id), stratumVersion}
+		return []interface{}{notify, sess.extranonceHex()}, nil
+
+	case "mining.extranonce.subscribe":
+		if err := sess.setProtocol(protocolStratum); err != nil {
+			return nil, err
+		}
+		return true, nil
+
+	case "mining.authorize":
+		sess.lock.Lock()
+		subscribed := sess.subscribed
+		sess.lock.Unlock()
+		if !subscribed {
+			return nil, errNotSubscribed
+		}
+		if len(params) < 1 {
+			return nil, errInvalidParams
+		}
+		if err := sess.login(params[0], param(params, 1), ""); err != nil {
+			return nil, err
+		}
+		return true, nil
+
+	case "mining.submit":
+		if len(params) < 3 {
+			return nil, errInvalidParams
+		}
+		if err := sess.checkAuthorized(); err != nil {
+			return nil, err
+		}
+		job := sess.server.findJob(params[1], common.Hash{})
+		if job == nil {
+			return nil, errJobNotFound
+		}
+		nonce, err := sess.stratumNonce(params[2])
+		if err != nil {
+			return nil, err
+		}
+		if err := sess.submit(job, nonce, nil); err != nil {
+			return nil, err
+		}
+		return true, nil
+
+	case "eth_submitLogin":
+		if err := sess.setProtocol(protocolProxy); err != nil {
+			return nil, err
+		}
+		if len(params) < 1 {
+			return nil, errInvalidParams
+		}
+		if err := sess.login(params[0], param(params, 1), req.Worker); err != nil {
+			return nil, err
+		}
+		return true, nil
+
+	case "eth_getWork":
+		if err := sess.checkAuthorized(); err != nil {
+			return nil, err
+		}
+		job := sess.server.currentJob()
+		if job == nil {
+			return nil, errors.New("no mining work available yet")
+		}
+		return sess.proxyWork(job), nil
+
+	case "eth_submitWork":
+		if len(params) < 3 {
+			return nil, errInvalidParams
+		}
+		if err := sess.checkAuthorized(); err != nil {
+			return nil, err
+		}
+		var (
+			nonce types.BlockNonce
+			hash  common.Hash
+			mix   common.Hash
+		)
+		if nonce.UnmarshalText([]byte(params[0])) != nil || hash.UnmarshalText([]byte(params[1])) != nil || mix.UnmarshalText([]byte(params[2])) != nil {
+			return nil, errInvalidParams
+		}
+		// Like eth_submitWork, invalid solutions are not errors.
+		job := sess.server.findJob("", hash)
+		if job == nil {
+			log.Debug("Stratum share for unknown job", "worker", sess.worker, "hash", hash)
+			return false, nil
+		}
+		if err := sess.submit(job, nonce, &mix); err != nil {
+			return false, nil
+		}
+		return true, nil
+
+	case "eth_submitHashrate":
+		if len(params) < 1 {
+			return nil, errInvalidParams
+		}
+		if err := sess.checkAuthorized(); err != nil {
+			return nil, err
+		}
+		rate, err := hexutil.DecodeUint64(params[0])
+		if err != nil {
+			return nil, errInvalidParams
+		}
+		sess.lock.Lock()
+		sess.reported, sess.reportedTime = rate, time.Now()
+		sess.lock.Unlock()
+		return true, nil
+	}
+	return nil, errUnknownMethod
+}
+
+// param returns the optional string param at the given index.
+func param(params []string, i int) string {
+	if i < len(params) {
+		return params[i]
+	}
+	return ""
+}
+
+// setProtocol sets the protocol flavour of the session, which cannot change.
+func (sess *session) setProtocol(proto protocol) error {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	if sess.protocol != protocolUnknown && sess.protocol != proto {
+		return errInvalidProtocol
+	}
+	sess.protocol = proto
+	return nil
+}
+
+// login authorizes the worker of the login, which is either the worker name
+// or a "<account>.<worker>" pair. The password may set the share difficulty of
+// the worker as "d=<difficulty>", among other comma separated options.
+func (sess *session) login(login, password, worker string) error {
+	if worker == "" {
+		worker = login
+		if i := strings.LastIndexByte(login, '.'); i >= 0 {
+			worker = login[i+1:]
+		}
+	}
+	if worker == "" {
+		worker = "default"
+	}
+	difficulty := new(big.Int).SetUint64(sess.server.config.Difficulty)
+	for _, option := range strings.Split(password, ",") {
+		if option = strings.TrimSpace(option); strings.HasPrefix(option, "d=") {
+			d, err := strconv.ParseUint(option[2:], 10, 64)
+			if err != nil || d == 0 {
+				return errInvalidParams
+			}
+			difficulty.SetUint64(d)
+		}
+	}
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	sess.authorized = true
+	sess.worker = worker
+	sess.difficulty = difficulty
+	sess.rateID = crypto.Keccak256Hash([]byte(fmt.Sprintf("stratum/%s/%d", worker, sess.id)))
+	sess.started = time.Now()
+
+	log.Info("Stratum worker authorized", "worker", worker, "remote", sess.conn.RemoteAddr(), "difficulty", difficulty)
+	return nil
+}
+
+func (sess *session) checkAuthorized() error {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	if !sess.authorized {
+		return errUnauthorized
+	}
+	return nil
+}
+
+// target returns the share target of the worker for the job. The block target
+// is used instead if it is easier, so that every block solution is a share.
+func (sess *session) target(job *job) *big.Int {
+	sess.lock.Lock()
+	target := new(big.Int).Div(two256, sess.difficulty)
+	sess.lock.Unlock()
+
+	if target.Cmp(maxTarget) > 0 {
+		target.Set(maxTarget)
+	}
+	if job.target.Cmp(target) > 0 {
+		return new(big.Int).Set(job.target)
+	}
+	return target
+}
+
+// extranonceHex returns the hex encoded extranonce of the session.
+func (sess *session) extranonceHex() string {
+	var extranonce [extranonceSize]byte
+	binary.BigEndian.PutUint16(extranonce[:], sess.extranonce)
+	return hex.EncodeToString(extranonce[:])
+}
+
+// stratumNonce returns the nonce of an EthereumStratum share. The miners either
+// submit the nonce following the extranonce of the session, or the whole nonce.
+func (sess *session) stratumNonce(value string) (types.BlockNonce, error) {
+	value = strings.TrimPrefix(value, "0x")
+	switch len(value) {
+	case 2 * (8 - extranonceSize):
+		value = sess.extranonceHex() + value
+	case 2 * 8:
+		if !strings.HasPrefix(value, sess.extranonceHex()) {
+			return types.BlockNonce{}, errInvalidNonce
+		}
+	default:
+		return types.BlockNonce{}, errInvalidNonce
+	}
+	var nonce types.BlockNonce
+	if _, err := hex.Decode(nonce[:], []byte(value)); err != nil {
+		return types.BlockNonce{}, errInvalidNonce
+	}
+	return nonce, nil
+}
+
+// submit validates the share of the worker, and accounts for it in the hash
+// rate of the worker.
+func (sess *session) submit(job *job, nonce types.BlockNonce, mix *common.Hash) error {
+	difficulty, err := sess.server.submit(sess, job, nonce, mix)
+	if err != nil {
+		log.Debug("Stratum share rejected", "worker", sess.worker, "job", job.id, "nonce", nonce.Uint64(), "err", err)
+		return err
+	}
+	sess.lock.Lock()
+	sess.shares = append(sess.shares, share{time: time.Now(), difficulty: difficulty})
+	sess.lock.Unlock()
+
+	log.Trace("Stratum share accepted", "worker", sess.worker, "job", job.id, "nonce", nonce.Uint64(), "difficulty", difficulty)
+	return nil
+}
+
+// hashrate returns the hash rate identifier and hash rate of the worker, either
+// reported by the miner or estimated from the shares within the hash rate window.
+func (sess *session) hashrate(now time.Time) (common.Hash, uint64, bool) {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	if !sess.authorized {
+		return common.Hash{}, 0, false
+	}
+	if !sess.reportedTime.IsZero() && now.Sub(sess.reportedTime) < hashrateWindow {
+		return sess.rateID, sess.reported, true
+	}
+	start := now.Add(-hashrateWindow)
+	for len(sess.shares) > 0 && sess.shares[0].time.Before(start) {
+		sess.shares = sess.shares[1:]
+	}
+	if sess.started.After(start) {
+		start = sess.started
+	}
+	elapsed := now.Sub(start)
+	if elapsed < time.Second {
+		elapsed = time.Second
+	}
+	total := new(big.Int)
+	for _, share := range sess.shares {
+		total.Add(total, share.difficulty)
+	}
+	rate := total.Div(total, big.NewInt(int64(elapsed/time.Second)))
+	return sess.rateID, rate.Uint64(), true
+}
+
+// proxyWork returns the stratum-proxy work package of the job, with the share
+// target of the worker.
+func (sess *session) proxyWork(job *job) [4]string {
+	work := job.work
+	work[2] = common.BytesToHash(sess.target(job).Bytes()).Hex()
+	return work
+}
+
+// notify announces the job to the authorized miner.
+func (sess *session) notify(job *job, clean bool) {
+	sess.lock.Lock()
+	proto, authorized := sess.protocol, sess.authorized
+	sess.lock.Unlock()
+
+	if !authorized {
+		return
+	}
+	switch proto {
+	case protocolStratum:
+		difficulty := new(big.Int).Div(two256, sess.target(job))
+
+		sess.lock.Lock()
+		changed := sess.sentDifficulty == nil || sess.sentDifficulty.Cmp(difficulty) != 0
+		sess.sentDifficulty = difficulty
+		sess.lock.Unlock()
+
+		if changed {
+			value, _ := new(big.Float).Quo(new(big.Float).SetInt(difficulty), stratumUnit).Float64()
+			if !sess.send(nil, "mining.set_difficulty", []interface{}{value}) {
+				return
+			}
+		}
+		params := []interface{}{
+			job.id,
+			strings.TrimPrefix(job.work[1], "0x"),
+			strings.TrimPrefix(job.work[0], "0x"),
+			clean,
+		}
+		sess.send(nil, "mining.notify", params)
+
+	case protocolProxy:
+		sess.push(map[string]interface{}{
+			"id":      0,
+			"jsonrpc": "2.0",
+			"result":  sess.proxyWork(job),
+		})
+	}
+}
+
+// send queues an EthereumStratum notification to the miner.
+func (sess *session) send(id interface{}, method string, params interface{}) bool {
+	return sess.push(map[string]interface{}{
+		"id":     id,
+		"method": method,
+		"params": params,
+	})
+}
+
+// push queues a notification to the miner, returning whether it was queued. The
+// miner is disconnected if it doesn't keep up with its notifications.
+func (sess *session) push(msg interface{}) bool {
+	select {
+	case sess.queue <- msg:
+		return true
+	default:
+		log.Warn("Disconnecting slow stratum miner", "remote", sess.conn.RemoteAddr(), "worker", sess.worker)
+		sess.conn.Close()
+		return false
+	}
+}
+
+// writeLoop writes the queued notifications to the miner until the session ends.
+func (sess *session) writeLoop() {
+	for {
+		select {
+		case msg := <-sess.queue:
+			if sess.write(msg) != nil {
+				return
+			}
+		case <-sess.closed:
+			return
+		}
+	}
+}
+
+// reply sends the response to the request, in the format of the protocol of the
+// session.
+func (sess *session) reply(id json.RawMessage, result interface{}, err error) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	sess.lock.Lock()
+	proto := sess.protocol
+	sess.lock.Unlock()
+
+	if proto == protocolStratum {
+		var stratumErr interface{}
+		if err != nil {
+			stratumErr = []interface{}{stratumErrorCode(err), err.Error(), nil}
+		}
+		return sess.write(map[string]interface{}{
+			"id":     id,
+			"result": result,
+			"error":  stratumErr,
+		})
+	}
+	msg := map[string]interface{}{
+		"id":      id,
+		"jsonrpc": "2.0",
+	}
+	if err != nil {
+		msg["error"] = map[string]interface{}{"code": jsonrpcErrorCode(err), "message": err.Error()}
+	} else {
+		msg["result"] = result
+	}
+	return sess.write(msg)
+}
+
+// write sends the message to the miner, disconnecting it on failure.
+func (sess *session) write(msg interface{}) error {
+	blob, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	sess.writeLock.Lock()
+	defer sess.writeLock.Unlock()
+
+	sess.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := sess.conn.Write(append(blob, '\n')); err != nil {
+		log.Debug("Stratum miner write failed", "remote", sess.conn.RemoteAddr(), "err", err)
+		sess.conn.Close()
+		return err
+	}
+	return nil
+}
+
+// stratumErrorCode returns the EthereumStratum error code of the error.
+func stratumErrorCode(err error) int {
+	switch err {
+	case errJobNotFound:
+		return 21
+	case errDuplicateShare:
+		return 22
+	case errLowDifficulty:
+		return 23
+	case errUnauthorized:
+		return 24
+	case errNotSubscribed:
+		return 25
+	}
+	return 20
+}
+
+// jsonrpcErrorCode returns the JSON-RPC error code of the error.
+func jsonrpcErrorCode(err error) int {
+	switch err {
+	case errUnknownMethod:
+		return -32601
+	case errInvalidParams:
+		return -32602
+	}
+	return -32000
+}
@@ -0,0 +1,499 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stratum implements a Stratum server handing out the work of the
+// remote sealer of a proof-of-work engine to TCP connected miners.
+//
+// Two flavours of the protocol are served on the same port, told apart by the
+// first request of a connection:
+//
+//   - EthereumStratum/1.0.0, where every session is assigned an extranonce
+//     prefixing the nonces of its shares, and jobs are announced with
+//     mining.notify and mining.set_difficulty.
+//   - stratum-proxy, which is the eth_getWork, eth_submitWork and
+//     eth_submitHashrate JSON-RPC methods over TCP, with the work pushed to
+//     the miners as eth_getWork results.
+//
+// Every worker mines shares against its own difficulty, set with a "d=<difficulty>"
+// password, and the shares also solving the block are submitted to the sealer.
+// The hash rate of the workers is estimated from their shares and reported to
+// the sealer, unless the workers report it themselves.
+//
+// The jobs are the work packages of the sealer. For ethash, the seed hash
+// identifies the DAG of the block, accounting for the ECIP-1099 epoch length.
+// For lyra2, it is replaced by the header bytes the nonce is appended to.
+package stratum
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/event"
+	"github.com/yuriy0803/core-geth1/log"
+)
+
+const (
+	// extranonceSize is the number of leading nonce bytes assigned to each
+	// EthereumStratum/1.0.0 session, so that sessions never mine the same nonces.
+	extranonceSize = 2
+
+	// maxJobs is the number of recent jobs shares are accepted for.
+	maxJobs = 8
+
+	// hashrateWindow is the time span of the shares the hash rate of a worker
+	// is estimated from.
+	hashrateWindow = 10 * time.Minute
+
+	// notifyQueueSize is the number of notifications queued for a miner. Miners
+	// falling further behind are disconnected, so they can't hold up the others.
+	notifyQueueSize = 16
+
+	readTimeout    = 10 * time.Minute
+	writeTimeout   = 10 * time.Second
+	maxRequestSize = 4096
+)
+
+// DefaultDifficulty is the default share difficulty of the workers, about a
+// share every 40 seconds at 100 MH/s.
+const DefaultDifficulty = 4_000_000_000
+
+// DefaultMaxSessions is the default maximum number of connected miners.
+const DefaultMaxSessions = 1024
+
+// hashrateInterval is the interval of reporting the hash rate of the workers to
+// the sealer, which forgets hash rates not reported for 10 seconds.
+var hashrateInterval = 5 * time.Second
+
+var (
+	two256    = new(big.Int).Lsh(big.NewInt(1), 256)
+	maxTarget = new(big.Int).Sub(two256, big.NewInt(1))
+
+	errJobNotFound       = errors.New("job not found")
+	errDuplicateShare    = errors.New("duplicate share")
+	errLowDifficulty     = errors.New("low difficulty share")
+	errInvalidMix        = errors.New("invalid mix digest")
+	errNoExtranonce      = errors.New("no extranonce available")
+	errTooManySessions   = errors.New("too many sessions")
+	errTooManyIPSessions = errors.New("too many sessions from the address")
+	errInvalidNonce      = errors.New("invalid nonce")
+	errUnauthorized      = errors.New("unauthorized worker")
+	errNotSubscribed     = errors.New("not subscribed")
+	errUnknownMethod     = errors.New("method not found")
+	errInvalidParams     = errors.New("invalid params")
+	errInvalidProtocol   = errors.New("unsupported protocol")
+	errServerStopped     = errors.New("server stopped")
+)
+
+// Sealer is a proof-of-work engine handing out work to remote miners, like the
+// ethash and lyra2 engines. The work packages are the ones of eth_getWork.
+type Sealer interface {
+	// GetWork returns the current work package.
+	GetWork() ([4]string, error)
+
+	// SubscribeWork subscribes to the new work packages.
+	SubscribeWork(ch chan<- [4]string) event.Subscription
+
+	// ComputeWork computes the mix digest and PoW result of the nonce for the
+	// work package.
+	ComputeWork(work [4]string, nonce types.BlockNonce) (common.Hash, common.Hash, error)
+
+	// SubmitWork submits a PoW solution, returning whether it was accepted.
+	SubmitWork(nonce types.BlockNonce, hash, digest common.Hash) bool
+
+	// SubmitHashrate submits the hash rate of a remote miner.
+	SubmitHashrate(rate hexutil.Uint64, id common.Hash) bool
+}
+
+// Config is the configuration of the Stratum server.
+type Config struct {
+	Addr          string // Listening address of the server
+	Difficulty    uint64 // Share difficulty of the workers not setting their own
+	MaxSessions   int    // Maximum number of connected miners
+	MaxSessionsIP int    // Maximum number of miners connected from an IP address, unlimited if zero
+}
+
+// job is a work package handed out to the miners.
+type job struct {
+	id     string
+	work   [4]string
+	hash   common.Hash
+	number uint64
+	target *big.Int                      // Block target, 2^256/difficulty
+	shares map[types.BlockNonce]struct{} // Nonces of the shares submitted so far
+}
+
+// Server is a Stratum server for the remote sealer of a proof-of-work engine.
+type Server struct {
+	config Config
+	sealer Sealer
+
+	listener net.Listener
+	workCh   chan [4]string
+	workSub  event.Subscription
+
+	lock        sync.RWMutex
+	jobs        []*job // Recent jobs, the current one last
+	nextJob     uint64
+	sessions    map[*session]struct{}
+	ipSessions  map[string]int // Number of sessions by remote IP address
+	extranonces map[uint16]struct{}
+	nextNonce   uint16
+	nextSession uint64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a Stratum server for the given sealer, to be started with Start.
+func New(sealer Sealer, config Config) *Server {
+	if config.Difficulty == 0 {
+		log.Warn("Sanitizing invalid stratum share difficulty", "provided", config.Difficulty, "updated", DefaultDifficulty)
+		config.Difficulty = DefaultDifficulty
+	}
+	if config.MaxSessions <= 0 {
+		log.Warn("Sanitizing invalid stratum session limit", "provided", config.MaxSessions, "updated", DefaultMaxSessions)
+		config.MaxSessions = DefaultMaxSessions
+	}
+	return &Server{
+		config:      config,
+		sealer:      sealer,
+		sessions:    make(map[*session]struct{}),
+		ipSessions:  make(map[string]int),
+		extranonces: make(map[uint16]struct{}),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start implements node.Lifecycle, starting to listen for miners.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	s.workCh = make(chan [4]string, 1)
+	s.workSub = s.sealer.SubscribeWork(s.workCh)
+	if work, err := s.sealer.GetWork(); err == nil {
+		s.newJob(work)
+	}
+	s.wg.Add(3)
+	go s.workLoop()
+	go s.hashrateLoop()
+	go s.acceptLoop()
+
+	log.Info("Stratum server started", "addr", listener.Addr(), "difficulty", s.config.Difficulty, "maxsessions", s.config.MaxSessions)
+	return nil
+}
+
+// Stop implements node.Lifecycle, disconnecting the miners.
+func (s *Server) Stop() error {
+	if s.listener == nil {
+		return nil
+	}
+	close(s.quit)
+	s.listener.Close()
+	s.workSub.Unsubscribe()
+
+	s.lock.Lock()
+	for sess := range s.sessions {
+		sess.conn.Close()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+	log.Info("Stratum server stopped")
+	return nil
+}
+
+// Addr returns the listening address of the started server.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// acceptLoop serves the incoming connections.
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+			default:
+				log.Warn("Stratum server failed to accept connection", "err", err)
+			}
+			return
+		}
+		sess, err := s.newSession(conn)
+		if err != nil {
+			log.Warn("Stratum server rejected connection", "remote", conn.RemoteAddr(), "err", err)
+			conn.Close()
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			sess.serve()
+			s.removeSession(sess)
+		}()
+	}
+}
+
+// workLoop turns the new work packages of the sealer into jobs, and announces
+// them to the miners. The announcements are queued to the sessions, so that the
+// loop keeps up with the sealer, which blocks on slow work subscribers.
+func (s *Server) workLoop() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case work := <-s.workCh:
+			// Only the latest work is mined, drop the stale packages received meanwhile.
+			for drained := false; !drained; {
+				select {
+				case work = <-s.workCh:
+				default:
+					drained = true
+				}
+			}
+			job, clean := s.newJob(work)
+			if job == nil {
+				continue
+			}
+			log.Debug("New stratum job", "id", job.id, "number", job.number, "hash", job.hash, "clean", clean)
+
+			s.lock.RLock()
+			sessions := make([]*session, 0, len(s.sessions))
+			for sess := range s.sessions {
+				sessions = append(sessions, sess)
+			}
+			s.lock.RUnlock()
+
+			for _, sess := range sessions {
+				sess.notify(job, clean)
+			}
+		case <-s.workSub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// hashrateLoop periodically reports the hash rate of the workers to the sealer.
+func (s *Server) hashrateLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(hashrateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.lock.RLock()
+			sessions := make([]*session, 0, len(s.sessions))
+			for sess := range s.sessions {
+				sessions = append(sessions, sess)
+			}
+			s.lock.RUnlock()
+
+			for _, sess := range sessions {
+				if id, rate, ok := sess.hashrate(time.Now()); ok {
+					s.sealer.SubmitHashrate(hexutil.Uint64(rate), id)
+				}
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// newJob adds a job for the work package, returning it and whether it is for a
+// new block, invalidating the previous jobs. A work package already handed out
+// does not make a new job.
+func (s *Server) newJob(work [4]string) (*job, bool) {
+	hash := common.HexToHash(work[0])
+	number, err := hexutil.DecodeUint64(work[3])
+	if err != nil {
+		log.Warn("Stratum server received invalid work", "number", work[3], "err", err)
+		return nil, false
+	}
+	target, ok := new(big.Int).SetString(strings.TrimPrefix(work[2], "0x"), 16)
+	if !ok || target.Sign() <= 0 {
+		log.Warn("Stratum server received invalid work", "target", work[2])
+		return nil, false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	clean := true
+	if len(s.jobs) > 0 {
+		current := s.jobs[len(s.jobs)-1]
+		if current.work == work {
+			return nil, false
+		}
+		clean = current.number != number
+	}
+	job := &job{
+		id:     fmt.Sprintf("%x", s.nextJob),
+		work:   work,
+		hash:   hash,
+		number: number,
+		target: target,
+		shares: make(map[types.BlockNonce]struct{}),
+	}
+	s.nextJob++
+	s.jobs = append(s.jobs, job)
+	if len(s.jobs) > maxJobs {
+		s.jobs = s.jobs[len(s.jobs)-maxJobs:]
+	}
+	return job, clean
+}
+
+// currentJob returns the job being mined, or nil if there is no work yet.
+func (s *Server) currentJob() *job {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.jobs) == 0 {
+		return nil
+	}
+	return s.jobs[len(s.jobs)-1]
+}
+
+// findJob returns the recent job with the given id, or the given work hash if
+// the id is empty.
+func (s *Server) findJob(id string, hash common.Hash) *job {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		if (id != "" && s.jobs[i].id == id) || (id == "" && s.jobs[i].hash == hash) {
+			return s.jobs[i]
+		}
+	}
+	return nil
+}
+
+// submit validates the share of a session for the job, submitting it to the
+// sealer if it solves the block. For the miners submitting it, the mix digest
+// must match the computed one. The share difficulty is returned.
+//
+// The nonce is reserved before computing the share, so that duplicates are
+// rejected without hashing. The reservation is released if the share is invalid.
+func (s *Server) submit(sess *session, job *job, nonce types.BlockNonce, mix *common.Hash) (*big.Int, error) {
+	s.lock.Lock()
+	if _, ok := job.shares[nonce]; ok {
+		s.lock.Unlock()
+		return nil, errDuplicateShare
+	}
+	job.shares[nonce] = struct{}{}
+	s.lock.Unlock()
+
+	digest, result, err := s.sealer.ComputeWork(job.work, nonce)
+	if err == nil && mix != nil && *mix != digest {
+		err = errInvalidMix
+	}
+	target := sess.target(job)
+	value := result.Big()
+	if err == nil && value.Cmp(target) > 0 {
+		err = errLowDifficulty
+	}
+	if err != nil {
+		s.lock.Lock()
+		delete(job.shares, nonce)
+		s.lock.Unlock()
+		return nil, err
+	}
+	if value.Cmp(job.target) <= 0 {
+		if s.sealer.SubmitWork(nonce, job.hash, digest) {
+			log.Info("Stratum share solved block", "worker", sess.worker, "number", job.number, "hash", job.hash, "nonce", nonce.Uint64())
+		} else {
+			log.Warn("Stratum block solution rejected", "worker", sess.worker, "number", job.number, "hash", job.hash, "nonce", nonce.Uint64())
+		}
+	}
+	return new(big.Int).Div(two256, target), nil
+}
+
+// newSession creates a session for the connection, assigning it an extranonce.
+// Connections beyond the session limits are rejected.
+func (s *Server) newSession(conn net.Conn) (*session, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case <-s.quit:
+		return nil, errServerStopped
+	default:
+	}
+	if len(s.sessions) >= s.config.MaxSessions {
+		return nil, errTooManySessions
+	}
+	ip := remoteIP(conn)
+	if s.config.MaxSessionsIP > 0 && s.ipSessions[ip] >= s.config.MaxSessionsIP {
+		return nil, errTooManyIPSessions
+	}
+	if len(s.extranonces) >= 1<<(8*extranonceSize) {
+		return nil, errNoExtranonce
+	}
+	for {
+		if _, ok := s.extranonces[s.nextNonce]; !ok {
+			break
+		}
+		s.nextNonce++
+	}
+	extranonce := s.nextNonce
+	s.extranonces[extranonce] = struct{}{}
+	s.nextNonce++
+
+	sess := newSession(s, conn, s.nextSession, extranonce)
+	s.sessions[sess] = struct{}{}
+	s.ipSessions[ip]++
+	s.nextSession++
+	return sess, nil
+}
+
+// removeSession releases the session and its extranonce.
+func (s *Server) removeSession(sess *session) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.sessions, sess)
+	delete(s.extranonces, sess.extranonce)
+
+	ip := remoteIP(sess.conn)
+	if s.ipSessions[ip]--; s.ipSessions[ip] <= 0 {
+		delete(s.ipSessions, ip)
+	}
+}
+
+// remoteIP returns the IP address of the remote end of the connection.
+func remoteIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
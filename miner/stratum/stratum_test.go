@@ -0,0 +1,453 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package stratum
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/consensus/ethash"
+	"github.com/yuriy0803/core-geth1/consensus/lyra2"
+	"github.com/yuriy0803/core-geth1/core/types"
+	"github.com/yuriy0803/core-geth1/event"
+)
+
+var (
+	_ Sealer = (*ethash.Ethash)(nil)
+	_ Sealer = (*lyra2.Lyra2)(nil)
+)
+
+// testMiner is a Stratum client, collecting the notifications it receives.
+type testMiner struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+
+	notifications []map[string]json.RawMessage
+}
+
+func newTestMiner(t *testing.T, srv *Server) *testMiner {
+	conn, err := net.Dial("tcp", srv.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	return &testMiner{t: t, conn: conn, reader: bufio.NewReader(conn), nextID: 1}
+}
+
+// read returns the next message received.
+func (m *testMiner) read() map[string]json.RawMessage {
+	m.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := m.reader.ReadBytes('\n')
+	if err != nil {
+		m.t.Fatalf("failed to read message: %v", err)
+	}
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		m.t.Fatalf("failed to decode message %s: %v", line, err)
+	}
+	return msg
+}
+
+// call sends the request, returning its result and error.
+func (m *testMiner) call(method string, params ...interface{}) (json.RawMessage, json.RawMessage) {
+	id := m.nextID
+	m.nextID++
+	blob, _ := json.Marshal(map[string]interface{}{"id": id, "method": method, "params": params})
+	if _, err := m.conn.Write(append(blob, '\n')); err != nil {
+		m.t.Fatalf("failed to send request: %v", err)
+	}
+	for {
+		msg := m.read()
+		if string(msg["id"]) == fmt.Sprint(id) {
+			if string(msg["error"]) == "null" {
+				delete(msg, "error")
+			}
+			return msg["result"], msg["error"]
+		}
+		m.notifications = append(m.notifications, msg)
+	}
+}
+
+// notification returns the next notification of the given method, or the next
+// pushed work if the method is empty.
+func (m *testMiner) notification(method string) json.RawMessage {
+	for {
+		var msg map[string]json.RawMessage
+		if len(m.notifications) > 0 {
+			msg, m.notifications = m.notifications[0], m.notifications[1:]
+		} else {
+			msg = m.read()
+		}
+		if method == "" && string(msg["id"]) == "0" {
+			return msg["result"]
+		}
+		if method != "" && string(msg["method"]) == fmt.Sprintf("%q", method) {
+			return msg["params"]
+		}
+	}
+}
+
+// newTestSealer creates an ethash test engine handing out the work of a block
+// with the given difficulty to remote miners only.
+func newTestSealer(t *testing.T, difficulty int64) (*ethash.Ethash, chan *types.Block) {
+	engine := ethash.NewTester(nil, false)
+	engine.SetThreads(-1)
+
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(difficulty)}
+	results := make(chan *types.Block, 1)
+	if err := engine.Seal(nil, types.NewBlockWithHeader(header), results, nil); err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	return engine, results
+}
+
+func startTestServer(t *testing.T, sealer Sealer) *Server {
+	srv := New(sealer, Config{Addr: "127.0.0.1:0", Difficulty: 1})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	return srv
+}
+
+func waitHashrate(t *testing.T, engine *ethash.Ethash, min float64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for engine.Hashrate() < min {
+		if time.Now().After(deadline) {
+			t.Fatalf("hash rate not reported: have %v, want at least %v", engine.Hashrate(), min)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// checkSolution checks the seal of the block solving the work.
+func checkSolution(t *testing.T, engine *ethash.Ethash, work [4]string, block *types.Block) {
+	digest, result, err := engine.ComputeWork(work, types.EncodeNonce(block.Nonce()))
+	if err != nil {
+		t.Fatalf("failed to compute work: %v", err)
+	}
+	if digest != block.MixDigest() {
+		t.Fatalf("block mix digest mismatch: have %x, want %x", block.MixDigest(), digest)
+	}
+	if result.Big().Cmp(new(big.Int).Div(two256, block.Difficulty())) > 0 {
+		t.Fatalf("block solution above target: %x", result)
+	}
+}
+
+func TestEthereumStratum(t *testing.T) {
+	defer func(interval time.Duration) { hashrateInterval = interval }(hashrateInterval)
+	hashrateInterval = 50 * time.Millisecond
+
+	engine, results := newTestSealer(t, 10)
+	defer engine.Close()
+	srv := startTestServer(t, engine)
+	defer srv.Stop()
+
+	miner := newTestMiner(t, srv)
+	defer miner.conn.Close()
+
+	// Shares can't be submitted before subscribing and authorizing.
+	if _, err := miner.call("mining.authorize", "0x0000000000000000000000000000000000000001.rig", "x"); err == nil {
+		t.Fatal("authorized without subscription")
+	}
+	result, err := miner.call("mining.subscribe", "testminer/1.0", "EthereumStratum/1.0.0")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	var subscription []json.RawMessage
+	if err := json.Unmarshal(result, &subscription); err != nil || len(subscription) != 2 {
+		t.Fatalf("invalid subscription result: %s", result)
+	}
+	var extranonce string
+	json.Unmarshal(subscription[1], &extranonce)
+	if len(extranonce) != 2*extranonceSize {
+		t.Fatalf("invalid extranonce %q", extranonce)
+	}
+	// The worker sets a share difficulty of 2, easier than the block difficulty.
+	if result, err := miner.call("mining.authorize", "0x0000000000000000000000000000000000000001.rig", "x,d=2"); err != nil || string(result) != "true" {
+		t.Fatalf("failed to authorize: %s %s", result, err)
+	}
+	var difficulty []float64
+	if err := json.Unmarshal(miner.notification("mining.set_difficulty"), &difficulty); err != nil {
+		t.Fatalf("invalid difficulty: %v", err)
+	}
+	if want := 2 / float64(1<<32); len(difficulty) != 1 || difficulty[0] != want {
+		t.Fatalf("difficulty mismatch: have %v, want %v", difficulty, want)
+	}
+	var job []interface{}
+	if err := json.Unmarshal(miner.notification("mining.notify"), &job); err != nil || len(job) != 4 {
+		t.Fatalf("invalid job: %v", job)
+	}
+	work, _ := engine.GetWork()
+	if job[1] != work[1][2:] || job[2] != work[0][2:] || job[3] != true {
+		t.Fatalf("job mismatch: have %v, want work %v", job, work)
+	}
+	// Submit shares until the block is solved.
+	var (
+		target   = new(big.Int).Div(two256, big.NewInt(2))
+		accepted int
+		solved   *types.Block
+	)
+	for i := 0; solved == nil && i < 1000; i++ {
+		suffix := fmt.Sprintf("%012x", i)
+
+		var nonce types.BlockNonce
+		nonce.UnmarshalText([]byte("0x" + extranonce + suffix))
+		_, res, _ := engine.ComputeWork(work, nonce)
+
+		result, err := miner.call("mining.submit", "rig", job[0], suffix)
+		if res.Big().Cmp(target) > 0 {
+			if err == nil {
+				t.Fatalf("low difficulty share accepted: %s", result)
+			}
+			continue
+		}
+		if err != nil || string(result) != "true" {
+			t.Fatalf("valid share rejected: %s %s", result, err)
+		}
+		accepted++
+		select {
+		case solved = <-results:
+		default:
+		}
+		if accepted == 1 {
+			if _, err := miner.call("mining.submit", "rig", job[0], suffix); err == nil {
+				t.Fatal("duplicate share accepted")
+			}
+		}
+	}
+	if solved == nil {
+		select {
+		case solved = <-results:
+		case <-time.After(time.Second):
+			t.Fatal("block not solved")
+		}
+	}
+	checkSolution(t, engine, work, solved)
+	if _, err := miner.call("mining.submit", "rig", "unknown", "000000000000"); err == nil {
+		t.Fatal("share for unknown job accepted")
+	}
+	// The estimated hash rate of the worker is reported to the sealer.
+	waitHashrate(t, engine, 1)
+}
+
+func TestStratumProxy(t *testing.T) {
+	defer func(interval time.Duration) { hashrateInterval = interval }(hashrateInterval)
+	hashrateInterval = 50 * time.Millisecond
+
+	engine, results := newTestSealer(t, 2)
+	defer engine.Close()
+	srv := startTestServer(t, engine)
+	defer srv.Stop()
+
+	miner := newTestMiner(t, srv)
+	defer miner.conn.Close()
+
+	if _, err := miner.call("eth_getWork"); err == nil {
+		t.Fatal("work handed out before login")
+	}
+	if result, err := miner.call("eth_submitLogin", "0x0000000000000000000000000000000000000001", "d=1"); err != nil || string(result) != "true" {
+		t.Fatalf("failed to login: %s %s", result, err)
+	}
+	// EthereumStratum requests are refused once logged in with stratum-proxy.
+	if _, err := miner.call("mining.subscribe", "testminer/1.0", "EthereumStratum/1.0.0"); err == nil {
+		t.Fatal("protocol switched")
+	}
+	result, err := miner.call("eth_getWork")
+	if err != nil {
+		t.Fatalf("failed to get work: %s", err)
+	}
+	var work [4]string
+	if err := json.Unmarshal(result, &work); err != nil {
+		t.Fatalf("invalid work: %v", err)
+	}
+	want, _ := engine.GetWork()
+	if work[0] != want[0] || work[1] != want[1] || work[3] != want[3] {
+		t.Fatalf("work mismatch: have %v, want %v", work, want)
+	}
+	// The share target of the worker is the one of difficulty 1, not the block target.
+	if target := common.BytesToHash(maxTarget.Bytes()).Hex(); work[2] != target {
+		t.Fatalf("target mismatch: have %v, want %v", work[2], target)
+	}
+	// Find a block solution.
+	var (
+		nonce types.BlockNonce
+		mix   common.Hash
+	)
+	for i := uint64(0); ; i++ {
+		nonce = types.EncodeNonce(i)
+		digest, res, _ := engine.ComputeWork(want, nonce)
+		if res.Big().Cmp(new(big.Int).Div(two256, big.NewInt(2))) <= 0 {
+			mix = digest
+			break
+		}
+	}
+	if result, _ := miner.call("eth_submitWork", nonce, want[0], common.Hash{}); string(result) != "false" {
+		t.Fatalf("invalid mix digest accepted: %s", result)
+	}
+	if result, _ := miner.call("eth_submitWork", nonce, want[0], mix); string(result) != "true" {
+		t.Fatalf("valid share rejected: %s", result)
+	}
+	select {
+	case block := <-results:
+		checkSolution(t, engine, want, block)
+	case <-time.After(time.Second):
+		t.Fatal("block not solved")
+	}
+	// The hash rate reported by the miner is forwarded to the sealer.
+	if result, _ := miner.call("eth_submitHashrate", hexutil.Uint64(1000000), common.Hash{1}); string(result) != "true" {
+		t.Fatalf("hash rate rejected: %s", result)
+	}
+	waitHashrate(t, engine, 1000000)
+
+	// New work is pushed to the miner.
+	header := &types.Header{Number: big.NewInt(2), Difficulty: big.NewInt(2)}
+	if err := engine.Seal(nil, types.NewBlockWithHeader(header), results, nil); err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if err := json.Unmarshal(miner.notification(""), &work); err != nil {
+		t.Fatalf("invalid pushed work: %v", err)
+	}
+	if work[3] != "0x2" {
+		t.Fatalf("pushed work number mismatch: have %v, want 0x2", work[3])
+	}
+}
+
+func TestSlowMiner(t *testing.T) {
+	srv := New(nil, Config{Difficulty: 1})
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	// The session isn't served, so its notifications are never written.
+	sess := newSession(srv, conn, 0, 0)
+	sess.protocol, sess.authorized = protocolProxy, true
+	job := &job{id: "0", target: big.NewInt(1)}
+	for i := 0; i < notifyQueueSize; i++ {
+		sess.notify(job, true)
+	}
+	// The miner falling further behind is disconnected.
+	sess.notify(job, true)
+	peer.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := peer.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("slow miner not disconnected: %v", err)
+	}
+}
+
+// countingSealer is a sealer computing the same result for every nonce, counting
+// the computations.
+type countingSealer struct {
+	result   common.Hash
+	computed int
+}
+
+func (s *countingSealer) GetWork() ([4]string, error) { return [4]string{}, nil }
+func (s *countingSealer) SubscribeWork(ch chan<- [4]string) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error { <-quit; return nil })
+}
+func (s *countingSealer) ComputeWork(work [4]string, nonce types.BlockNonce) (common.Hash, common.Hash, error) {
+	s.computed++
+	return common.Hash{}, s.result, nil
+}
+func (s *countingSealer) SubmitWork(nonce types.BlockNonce, hash, digest common.Hash) bool {
+	return false
+}
+func (s *countingSealer) SubmitHashrate(rate hexutil.Uint64, id common.Hash) bool { return true }
+
+func TestDuplicateShare(t *testing.T) {
+	sealer := &countingSealer{result: common.BytesToHash(maxTarget.Bytes())}
+	srv := New(sealer, Config{Difficulty: 2})
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	sess := newSession(srv, conn, 0, 0)
+	job := &job{id: "0", target: big.NewInt(1), shares: make(map[types.BlockNonce]struct{})}
+
+	// Invalid shares don't reserve their nonce.
+	nonce := types.EncodeNonce(1)
+	if _, err := srv.submit(sess, job, nonce, nil); err != errLowDifficulty {
+		t.Fatalf("low difficulty share not rejected: %v", err)
+	}
+	if _, err := srv.submit(sess, job, nonce, &common.Hash{0x01}); err != errInvalidMix {
+		t.Fatalf("invalid mix digest not rejected: %v", err)
+	}
+	if sealer.computed != 2 {
+		t.Fatalf("computed shares mismatch: have %d, want 2", sealer.computed)
+	}
+	// Duplicates of valid shares are rejected without computing them.
+	sealer.result = common.Hash{0x01}
+	if _, err := srv.submit(sess, job, nonce, nil); err != nil {
+		t.Fatalf("valid share rejected: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := srv.submit(sess, job, nonce, nil); err != errDuplicateShare {
+			t.Fatalf("duplicate share not rejected: %v", err)
+		}
+	}
+	if sealer.computed != 3 {
+		t.Fatalf("computed shares mismatch: have %d, want 3", sealer.computed)
+	}
+}
+
+// testConn is a connection with a custom remote address.
+type testConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *testConn) RemoteAddr() net.Addr { return c.remote }
+
+func TestSessionLimits(t *testing.T) {
+	srv := New(nil, Config{Difficulty: 1, MaxSessions: 3, MaxSessionsIP: 2})
+
+	connect := func(remote string) (*session, error) {
+		conn, peer := net.Pipe()
+		t.Cleanup(func() { conn.Close(); peer.Close() })
+		addr, _ := net.ResolveTCPAddr("tcp", remote)
+		return srv.newSession(&testConn{Conn: conn, remote: addr})
+	}
+	first, err := connect("10.0.0.1:1")
+	if err != nil {
+		t.Fatalf("session rejected: %v", err)
+	}
+	if _, err := connect("10.0.0.1:2"); err != nil {
+		t.Fatalf("session rejected: %v", err)
+	}
+	// The sessions of an address are limited.
+	if _, err := connect("10.0.0.1:3"); err != errTooManyIPSessions {
+		t.Fatalf("session beyond the address limit not rejected: %v", err)
+	}
+	if _, err := connect("10.0.0.2:1"); err != nil {
+		t.Fatalf("session rejected: %v", err)
+	}
+	// The sessions of the server are limited.
+	if _, err := connect("10.0.0.3:1"); err != errTooManySessions {
+		t.Fatalf("session beyond the server limit not rejected: %v", err)
+	}
+	// Disconnected sessions free their slots.
+	srv.removeSession(first)
+	if _, err := connect("10.0.0.1:4"); err != nil {
+		t.Fatalf("session rejected after disconnect: %v", err)
+	}
+}
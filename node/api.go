@@ -179,6 +179,9 @@ func (api *adminAPI) StartHTTP(host *string, port *int, cors *string, apis *stri
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			policy:                 api.node.config.HTTPPolicy,
+			rpcCosts:               api.node.rpcCosts,
+			accessLog:              api.node.rpcAccessLog,
 		},
 	}
 	if cors != nil {
@@ -257,6 +260,9 @@ func (api *adminAPI) StartWS(host *string, port *int, allowedOrigins *string, ap
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			policy:                 api.node.config.WSPolicy,
+			rpcCosts:               api.node.rpcCosts,
+			accessLog:              api.node.rpcAccessLog,
 		},
 	}
 	if apis != nil {
@@ -139,6 +139,10 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// HTTPPolicy restricts the methods served by the HTTP RPC server and rate limits
+	// their calls.
+	HTTPPolicy RPCPolicy
+
 	// AuthAddr is the listening address on which authenticated APIs are provided.
 	AuthAddr string `toml:",omitempty"`
 
@@ -149,6 +153,10 @@ type Config struct {
 	// for the authenticated api. This is by default {'localhost'}.
 	AuthVirtualHosts []string `toml:",omitempty"`
 
+	// AuthPolicy restricts the methods served by the authenticated RPC servers and
+	// rate limits their calls.
+	AuthPolicy RPCPolicy
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
@@ -161,6 +169,10 @@ type Config struct {
 	// WSPathPrefix specifies a path prefix on which ws-rpc is to be served.
 	WSPathPrefix string `toml:",omitempty"`
 
+	// WSPolicy restricts the methods served by the websocket RPC server and rate
+	// limits their calls.
+	WSPolicy RPCPolicy
+
 	// WSOrigins is the list of domain to accept websocket requests from. Please be
 	// aware that the server can only act upon the HTTP request the client sends and
 	// cannot verify the validity of the request header.
@@ -192,6 +204,10 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLTracing enables tracing transactions with the native tracers over
+	// GraphQL. It is disabled by default, as tracing is expensive.
+	GraphQLTracing bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -206,6 +222,10 @@ type Config struct {
 	// BatchResponseMaxSize is the maximum number of bytes returned from a batched rpc call.
 	BatchResponseMaxSize int `toml:",omitempty"`
 
+	// RPCAccessLog configures the access log of the calls served by the HTTP and
+	// websocket RPC servers.
+	RPCAccessLog RPCAccessLogConfig
+
 	// JWTSecret is the path to the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
 
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/yuriy0803/core-geth1/rpc"
 )
 
 const jwtExpiryTimeout = 60 * time.Second
@@ -75,6 +76,9 @@ func (handler *jwtHandler) ServeHTTP(out http.ResponseWriter, r *http.Request) {
 	case time.Until(claims.IssuedAt.Time) > jwtExpiryTimeout:
 		http.Error(out, "future token", http.StatusUnauthorized)
 	default:
+		if claims.Subject != "" {
+			r = r.WithContext(rpc.NewContextWithPeerSubject(r.Context(), claims.Subject))
+		}
 		handler.next.ServeHTTP(out, r)
 	}
 }
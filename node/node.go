@@ -57,14 +57,16 @@ type Node struct {
 	state         int           // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	httpAuth      *httpServer //
-	wsAuth        *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle            // All registered backends, services, and auxiliary services that have a lifecycle
+	rpcAPIs       []rpc.API              // List of APIs currently provided by the node
+	rpcCosts      map[string]RPCCostFunc // Cost models of RPC methods, by method
+	rpcAccessLog  *rpcAccessLog          // Access log of the HTTP and WS RPC servers, if enabled
+	http          *httpServer            //
+	ws            *httpServer            //
+	httpAuth      *httpServer            //
+	wsAuth        *httpServer            //
+	ipc           *ipcServer             // Stores information about the ipc http server
+	inprocHandler *rpc.Server            // In-process RPC request handler to process the API requests
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 
@@ -119,6 +121,7 @@ func New(conf *Config) (*Node, error) {
 		log:           conf.Logger,
 		stop:          make(chan struct{}),
 		server:        &p2p.Server{Config: conf.P2P},
+		rpcCosts:      make(map[string]RPCCostFunc),
 		databases:     make(map[*closeTrackingDB]struct{}),
 	}
 
@@ -486,21 +489,32 @@ func (n *Node) startRPC() error {
 		openAPIs, allAPIs = n.getAPIs()
 	)
 
+	if n.config.RPCAccessLog.Enabled {
+		accessLog, err := newRPCAccessLog(n.config.RPCAccessLog, n.log)
+		if err != nil {
+			return err
+		}
+		n.rpcAccessLog = accessLog
+	}
 	rpcConfig := rpcEndpointConfig{
 		batchItemLimit:         n.config.BatchRequestLimit,
 		batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		rpcCosts:               n.rpcCosts,
+		accessLog:              n.rpcAccessLog,
 	}
 
 	initHttp := func(server *httpServer, port int) error {
 		if err := server.setListenAddr(n.config.HTTPHost, port); err != nil {
 			return err
 		}
+		httpRPCConfig := rpcConfig
+		httpRPCConfig.policy = n.config.HTTPPolicy
 		if err := server.enableRPC(openAPIs, httpConfig{
 			CorsAllowedOrigins: n.config.HTTPCors,
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
-			rpcEndpointConfig:  rpcConfig,
+			rpcEndpointConfig:  httpRPCConfig,
 		}); err != nil {
 			return err
 		}
@@ -513,11 +527,13 @@ func (n *Node) startRPC() error {
 		if err := server.setListenAddr(n.config.WSHost, port); err != nil {
 			return err
 		}
+		wsRPCConfig := rpcConfig
+		wsRPCConfig.policy = n.config.WSPolicy
 		if err := server.enableWS(openAPIs, wsConfig{
 			Modules:           n.config.WSModules,
 			Origins:           n.config.WSOrigins,
 			prefix:            n.config.WSPathPrefix,
-			rpcEndpointConfig: rpcConfig,
+			rpcEndpointConfig: wsRPCConfig,
 		}); err != nil {
 			return err
 		}
@@ -533,6 +549,7 @@ func (n *Node) startRPC() error {
 		}
 		sharedConfig := rpcConfig
 		sharedConfig.jwtSecret = secret
+		sharedConfig.policy = n.config.AuthPolicy
 		if err := server.enableRPC(allAPIs, httpConfig{
 			CorsAllowedOrigins: DefaultAuthCors,
 			Vhosts:             n.config.AuthVirtualHosts,
@@ -612,6 +629,9 @@ func (n *Node) stopRPC() {
 	n.wsAuth.stop()
 	n.ipc.stop()
 	n.stopInProc()
+	if n.rpcAccessLog != nil {
+		n.rpcAccessLog.close()
+	}
 }
 
 // startInProc registers all RPC APIs on the inproc server.
@@ -670,6 +690,18 @@ func (n *Node) RegisterAPIs(apis []rpc.API) {
 	n.rpcAPIs = append(n.rpcAPIs, apis...)
 }
 
+// RegisterRPCCost registers the cost model of an RPC method, which scales the cost
+// of its calls with their parameters when rate limiting them.
+func (n *Node) RegisterRPCCost(method string, cost RPCCostFunc) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.state != initializingState {
+		panic("can't register RPC costs on running/stopped node")
+	}
+	n.rpcCosts[method] = cost
+}
+
 // getAPIs return two sets of APIs, both the ones that do not require
 // authentication, and the complete set
 func (n *Node) getAPIs() (unauthenticated, all []rpc.API) {
@@ -851,6 +883,39 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	return db, err
 }
 
+// OpenDatabaseWithFreezerRemote opens an existing database with the given name
+// (or creates one if no previous can be found) from within the node's data
+// directory, also attaching a chain freezer to it that moves ancient chain data
+// from the database to the remote ancient store at the given endpoint. If the
+// node is an ephemeral one, a memory database is returned.
+func (n *Node) OpenDatabaseWithFreezerRemote(name string, cache, handles int, endpoint string, namespace string, readonly bool) (ethdb.Database, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.state == closedState {
+		return nil, ErrNodeStopped
+	}
+	var db ethdb.Database
+	var err error
+	if n.config.DataDir == "" {
+		db = rawdb.NewMemoryDatabase()
+	} else {
+		db, err = rawdb.Open(rawdb.OpenOptions{
+			Type:           n.config.DBEngine,
+			Directory:      n.ResolvePath(name),
+			AncientsRemote: endpoint,
+			Namespace:      namespace,
+			Cache:          cache,
+			Handles:        handles,
+			ReadOnly:       readonly,
+		})
+	}
+
+	if err == nil {
+		db = n.wrapDatabase(db)
+	}
+	return db, err
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
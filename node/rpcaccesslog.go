@@ -0,0 +1,159 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/metrics"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+// rpcAccessLogDroppedMeter counts the entries which couldn't be written to the
+// file of the access log.
+var rpcAccessLogDroppedMeter = metrics.NewRegisteredMeter("rpc/accesslog/dropped", nil)
+
+// RPCAccessLogConfig configures the access log of the calls served by the HTTP and
+// websocket RPC servers.
+type RPCAccessLogConfig struct {
+	// Enabled turns on the access log.
+	Enabled bool `toml:",omitempty"`
+
+	// File is the file the calls are appended to as JSON lines. The calls are
+	// logged through the node logger if empty.
+	File string `toml:",omitempty"`
+
+	// SampleRate is the fraction of the calls logged, between 0 and 1.
+	SampleRate float64 `toml:",omitempty"`
+
+	// SlowThreshold is the duration from which calls are logged regardless of the
+	// sampling. It is disabled if zero.
+	SlowThreshold time.Duration `toml:",omitempty"`
+}
+
+// rpcAccessLogEntry is an entry of the access log.
+type rpcAccessLogEntry struct {
+	Time         time.Time `json:"time"`
+	Transport    string    `json:"transport"`
+	RemoteAddr   string    `json:"remoteAddr"`
+	Subject      string    `json:"subject,omitempty"`
+	Method       string    `json:"method"`
+	ParamsSize   int       `json:"paramsSize"`
+	BatchSize    int       `json:"batchSize,omitempty"`
+	DurationMs   float64   `json:"durationMs"`
+	ResponseSize int       `json:"responseSize"`
+	ErrorCode    int       `json:"errorCode,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// rpcAccessLog logs the calls served by RPC servers.
+type rpcAccessLog struct {
+	config RPCAccessLogConfig
+	logger log.Logger
+
+	mu      sync.Mutex
+	out     io.WriteCloser // file of the log, nil if logged through logger or closed
+	dropped bool           // whether an entry was dropped already, to warn once
+}
+
+// newRPCAccessLog creates an access log, opening its file if configured.
+func newRPCAccessLog(config RPCAccessLogConfig, logger log.Logger) (*rpcAccessLog, error) {
+	l := &rpcAccessLog{config: config, logger: logger}
+	if config.File != "" {
+		f, err := os.OpenFile(config.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		l.out = f
+	}
+	logger.Info("RPC access log enabled", "file", config.File, "sample", config.SampleRate, "slow", config.SlowThreshold)
+	return l, nil
+}
+
+// observe implements rpc.CallObserver.
+func (l *rpcAccessLog) observe(rec *rpc.CallRecord) {
+	slow := l.config.SlowThreshold > 0 && rec.Duration >= l.config.SlowThreshold
+	if !slow && (l.config.SampleRate <= 0 || (l.config.SampleRate < 1 && rand.Float64() >= l.config.SampleRate)) {
+		return
+	}
+	if l.config.File == "" {
+		ctx := []interface{}{"transport", rec.Peer.Transport, "remote", rec.Peer.RemoteAddr}
+		if rec.Peer.Subject != "" {
+			ctx = append(ctx, "subject", rec.Peer.Subject)
+		}
+		ctx = append(ctx, "method", rec.Method, "params", rec.ParamsSize)
+		if rec.BatchSize > 0 {
+			ctx = append(ctx, "batch", rec.BatchSize)
+		}
+		ctx = append(ctx, "duration", common.PrettyDuration(rec.Duration), "response", rec.ResponseSize)
+		if rec.ErrorCode != 0 {
+			ctx = append(ctx, "code", rec.ErrorCode, "err", rec.Error)
+		}
+		l.logger.Info("RPC access", ctx...)
+		return
+	}
+	blob, err := json.Marshal(&rpcAccessLogEntry{
+		Time:         rec.Time,
+		Transport:    rec.Peer.Transport,
+		RemoteAddr:   rec.Peer.RemoteAddr,
+		Subject:      rec.Peer.Subject,
+		Method:       rec.Method,
+		ParamsSize:   rec.ParamsSize,
+		BatchSize:    rec.BatchSize,
+		DurationMs:   float64(rec.Duration) / float64(time.Millisecond),
+		ResponseSize: rec.ResponseSize,
+		ErrorCode:    rec.ErrorCode,
+		Error:        rec.Error,
+	})
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.out == nil {
+		err = errors.New("access log closed")
+	} else {
+		_, err = l.out.Write(append(blob, '\n'))
+	}
+	if err != nil {
+		rpcAccessLogDroppedMeter.Mark(1)
+		if !l.dropped {
+			l.dropped = true
+			l.logger.Warn("Dropping RPC access log entries", "file", l.config.File, "err", err)
+		}
+	}
+}
+
+// close closes the file of the log.
+func (l *rpcAccessLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.out != nil {
+		l.out.Close()
+		l.out = nil
+	}
+}
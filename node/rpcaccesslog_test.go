@@ -0,0 +1,150 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/yuriy0803/core-geth1/internal/testlog"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+func TestRPCAccessLog(t *testing.T) {
+	var (
+		secret = [32]byte{0x01}
+		file   = filepath.Join(t.TempDir(), "access.log")
+	)
+	accessLog, err := newRPCAccessLog(RPCAccessLogConfig{Enabled: true, File: file, SampleRate: 1}, log.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := rpcEndpointConfig{jwtSecret: secret[:], accessLog: accessLog}
+	srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	assert.NoError(t, srv.enableRPC(apis(), httpConfig{rpcEndpointConfig: cfg}))
+	assert.NoError(t, srv.enableWS(apis(), wsConfig{Origins: []string{"*"}, rpcEndpointConfig: cfg}))
+	assert.NoError(t, srv.setListenAddr("localhost", 0))
+	assert.NoError(t, srv.start())
+	defer srv.stop()
+
+	auth := rpc.WithHTTPAuth(func(h http.Header) error {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+			Subject:  "alice",
+		}).SignedString(secret[:])
+		h.Set("Authorization", "Bearer "+token)
+		return err
+	})
+	for _, url := range []string{"http://" + srv.listenAddr(), "ws://" + srv.listenAddr()} {
+		client, err := rpc.DialOptions(context.Background(), url, auth)
+		if err != nil {
+			t.Fatalf("can't dial %s: %v", url, err)
+		}
+		var result string
+		if err := client.Call(&result, "test_greet"); err != nil {
+			t.Fatalf("%s: call failed: %v", url, err)
+		}
+		batch := []rpc.BatchElem{{Method: "test_greet", Result: &result}, {Method: "test_missing", Args: []interface{}{1}}}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("%s: batch failed: %v", url, err)
+		}
+		client.Close()
+	}
+	accessLog.close()
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []rpcAccessLogEntry
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var entry rpcAccessLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid entry %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	want := []rpcAccessLogEntry{
+		{Transport: "http", Subject: "alice", Method: "test_greet", ParamsSize: 0, ResponseSize: 7},
+		{Transport: "http", Subject: "alice", Method: "test_greet", ParamsSize: 0, BatchSize: 2, ResponseSize: 7},
+		{Transport: "http", Subject: "alice", Method: "test_missing", ParamsSize: 3, BatchSize: 2, ResponseSize: 83, ErrorCode: -32601, Error: "the method test_missing does not exist/is not available"},
+		{Transport: "ws", Subject: "alice", Method: "test_greet", ParamsSize: 0, ResponseSize: 7},
+		{Transport: "ws", Subject: "alice", Method: "test_greet", ParamsSize: 0, BatchSize: 2, ResponseSize: 7},
+		{Transport: "ws", Subject: "alice", Method: "test_missing", ParamsSize: 3, BatchSize: 2, ResponseSize: 83, ErrorCode: -32601, Error: "the method test_missing does not exist/is not available"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("wrong number of entries: have %d, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Time.IsZero() || entry.RemoteAddr == "" || entry.DurationMs < 0 {
+			t.Errorf("entry %d: missing details: %+v", i, entry)
+		}
+		entry.Time, entry.RemoteAddr, entry.DurationMs = time.Time{}, "", 0
+		if entry != want[i] {
+			t.Errorf("entry %d: wrong entry\nhave %+v\nwant %+v", i, entry, want[i])
+		}
+	}
+}
+
+func TestRPCAccessLogSampling(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "access.log")
+	accessLog, err := newRPCAccessLog(RPCAccessLogConfig{Enabled: true, File: file, SlowThreshold: time.Second}, log.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []time.Duration{time.Millisecond, time.Second, 2 * time.Second, 999 * time.Millisecond} {
+		accessLog.observe(&rpc.CallRecord{Method: "test_" + d.String(), Duration: d})
+	}
+	accessLog.config.SampleRate = 1
+	accessLog.observe(&rpc.CallRecord{Method: "test_sampled"})
+	accessLog.close()
+
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var methods []string
+	for scanner := bufio.NewScanner(bytes.NewReader(blob)); scanner.Scan(); {
+		var entry rpcAccessLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		methods = append(methods, entry.Method)
+	}
+	assert.Equal(t, []string{"test_1s", "test_2s", "test_sampled"}, methods)
+
+	// Entries after closing are dropped.
+	accessLog.observe(&rpc.CallRecord{Method: "test_closed"})
+	if !accessLog.dropped {
+		t.Fatal("entry after closing not reported as dropped")
+	}
+	if after, err := os.ReadFile(file); err != nil || !bytes.Equal(after, blob) {
+		t.Fatalf("log changed after closing: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuriy0803/core-geth1/common/mclock"
+	"github.com/yuriy0803/core-geth1/metrics"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+// rpcBucketPruneInterval is how often the token buckets of idle clients are dropped.
+const rpcBucketPruneInterval = time.Minute
+
+// RPCPolicy configures which methods an RPC endpoint serves, and how much clients
+// may call them. Method patterns are method names, or prefixes ending in "*" like
+// "debug_*" or "debug_trace*".
+type RPCPolicy struct {
+	// AllowMethods lists the methods served. All methods of the enabled modules
+	// are served if empty.
+	AllowMethods []string `toml:",omitempty"`
+
+	// DenyMethods lists the methods which are not served, even if allowed.
+	DenyMethods []string `toml:",omitempty"`
+
+	// RateLimit is the number of cost units per second a client may spend. Clients
+	// are identified by the subject of their JWT token, or else their IP address.
+	// Calls are not limited if zero.
+	RateLimit float64 `toml:",omitempty"`
+
+	// RateBurst is the number of cost units a client may spend at once, which is also
+	// the maximum cost of a single call. It defaults to one second of RateLimit.
+	RateBurst float64 `toml:",omitempty"`
+
+	// MethodCosts are the costs of calls by method pattern, the longest matching
+	// pattern applying. Calls of other methods cost a single unit. Some methods have
+	// a cost model scaling their cost with the parameters, e.g. by the block span of
+	// eth_getLogs.
+	MethodCosts map[string]float64 `toml:",omitempty"`
+}
+
+// enabled returns whether the policy restricts any calls.
+func (p *RPCPolicy) enabled() bool {
+	return len(p.AllowMethods) > 0 || len(p.DenyMethods) > 0 || p.RateLimit > 0
+}
+
+// RPCCostFunc returns the cost of a call to an RPC method from its parameters, as a
+// multiple of the configured cost of the method.
+type RPCCostFunc func(params json.RawMessage) float64
+
+// rpcMethodNotAllowedError is returned for calls of methods denied by the policy.
+type rpcMethodNotAllowedError struct{ method string }
+
+func (e *rpcMethodNotAllowedError) ErrorCode() int { return -32601 }
+
+func (e *rpcMethodNotAllowedError) Error() string {
+	return fmt.Sprintf("the method %s is not available on this endpoint", e.method)
+}
+
+// rpcLimitExceededError is returned for calls exceeding the rate limit.
+type rpcLimitExceededError struct{ message string }
+
+func (e *rpcLimitExceededError) ErrorCode() int { return -32005 }
+
+func (e *rpcLimitExceededError) Error() string { return e.message }
+
+// rpcLimiter enforces an RPCPolicy on the calls served by an RPC server.
+type rpcLimiter struct {
+	policy RPCPolicy
+	burst  float64
+	costs  map[string]RPCCostFunc
+	clock  mclock.Clock
+
+	deniedMeter  metrics.Meter
+	limitedMeter metrics.Meter
+
+	mu      sync.Mutex
+	buckets map[string]*rpcBucket // token buckets of the clients
+	pruned  mclock.AbsTime        // last time idle buckets were dropped
+}
+
+// rpcBucket is the token bucket of a client.
+type rpcBucket struct {
+	tokens float64
+	last   mclock.AbsTime
+}
+
+// newRPCLimiter creates a limiter of the calls served by an endpoint, with the cost
+// models of the methods.
+func newRPCLimiter(endpoint string, policy RPCPolicy, costs map[string]RPCCostFunc, clock mclock.Clock) *rpcLimiter {
+	burst := policy.RateBurst
+	if burst <= 0 {
+		burst = policy.RateLimit
+	}
+	return &rpcLimiter{
+		policy:       policy,
+		burst:        burst,
+		costs:        costs,
+		clock:        clock,
+		deniedMeter:  metrics.GetOrRegisterMeter("rpc/denied/"+endpoint, nil),
+		limitedMeter: metrics.GetOrRegisterMeter("rpc/limited/"+endpoint, nil),
+		buckets:      make(map[string]*rpcBucket),
+		pruned:       clock.Now(),
+	}
+}
+
+// filter implements rpc.CallFilter.
+func (l *rpcLimiter) filter(ctx context.Context, method string, params json.RawMessage) error {
+	return l.check(rpc.PeerInfoFromContext(ctx), method, params)
+}
+
+// check returns an error if a call of the client is denied or limited.
+func (l *rpcLimiter) check(peer rpc.PeerInfo, method string, params json.RawMessage) error {
+	if (len(l.policy.AllowMethods) > 0 && !matchMethod(l.policy.AllowMethods, method)) || matchMethod(l.policy.DenyMethods, method) {
+		l.deniedMeter.Mark(1)
+		return &rpcMethodNotAllowedError{method}
+	}
+	if l.policy.RateLimit <= 0 {
+		return nil
+	}
+	if err := l.take(rpcClient(peer), l.cost(method, params)); err != nil {
+		l.limitedMeter.Mark(1)
+		return err
+	}
+	return nil
+}
+
+// cost returns the cost of a call.
+func (l *rpcLimiter) cost(method string, params json.RawMessage) float64 {
+	cost, ok := l.policy.MethodCosts[method]
+	if !ok {
+		cost = 1
+		var longest int
+		for pattern, c := range l.policy.MethodCosts {
+			if len(pattern) > longest && matchMethod([]string{pattern}, method) {
+				cost, longest = c, len(pattern)
+			}
+		}
+	}
+	if fn := l.costs[method]; fn != nil {
+		cost *= fn(params)
+	}
+	return cost
+}
+
+// take spends the cost of a call from the bucket of the client.
+func (l *rpcLimiter) take(client string, cost float64) error {
+	if cost > l.burst {
+		return &rpcLimitExceededError{fmt.Sprintf("request cost %g exceeds the limit of %g", cost, l.burst)}
+	}
+	now := l.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Duration(now-l.pruned) >= rpcBucketPruneInterval {
+		l.prune(now)
+	}
+	b := l.buckets[client]
+	if b == nil {
+		b = &rpcBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	l.refill(b, now)
+	if b.tokens < cost {
+		return &rpcLimitExceededError{"rate limit exceeded"}
+	}
+	b.tokens -= cost
+	return nil
+}
+
+// refill adds the tokens accrued since the last call to a bucket.
+func (l *rpcLimiter) refill(b *rpcBucket, now mclock.AbsTime) {
+	b.tokens += time.Duration(now-b.last).Seconds() * l.policy.RateLimit
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+}
+
+// prune drops the buckets of the clients which are full again, the caller must hold
+// l.mu.
+func (l *rpcLimiter) prune(now mclock.AbsTime) {
+	for client, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+	l.pruned = now
+}
+
+// rpcClient returns the identity of a client for rate limiting.
+func rpcClient(info rpc.PeerInfo) string {
+	if info.Subject != "" {
+		return "sub:" + info.Subject
+	}
+	host, _, err := net.SplitHostPort(info.RemoteAddr)
+	if err != nil {
+		return "ip:" + info.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// matchMethod reports whether a method matches one of the patterns.
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if pattern == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+// Copyright 2023 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/common/mclock"
+	"github.com/yuriy0803/core-geth1/internal/testlog"
+	"github.com/yuriy0803/core-geth1/log"
+	"github.com/yuriy0803/core-geth1/rpc"
+)
+
+func TestRPCLimiter(t *testing.T) {
+	var (
+		clock  = new(mclock.Simulated)
+		policy = RPCPolicy{
+			AllowMethods: []string{"eth_*", "debug_*"},
+			DenyMethods:  []string{"debug_trace*"},
+			RateLimit:    10,
+			RateBurst:    20,
+			MethodCosts:  map[string]float64{"debug_*": 5, "debug_getRawBlock": 2, "eth_getLogs": 2},
+		}
+		costs = map[string]RPCCostFunc{
+			"eth_getLogs": func(params json.RawMessage) float64 { return float64(len(params)) },
+		}
+		l = newRPCLimiter("test", policy, costs, clock)
+	)
+	// Access lists.
+	for method, allowed := range map[string]bool{
+		"eth_blockNumber":          true,
+		"debug_getRawBlock":        true,
+		"debug_traceBlockByNumber": false,
+		"admin_peers":              false,
+		"ethx":                     false,
+	} {
+		err := l.check(testPeer("10.0.0.1:1", ""), method, nil)
+		if allowed && err != nil {
+			t.Errorf("%s: unexpected error: %v", method, err)
+		}
+		var denied *rpcMethodNotAllowedError
+		if !allowed && (!errors.As(err, &denied) || denied.ErrorCode() != -32601) {
+			t.Errorf("%s: expected denial, got %v", method, err)
+		}
+	}
+	// Costs by the longest matching pattern, scaled by the cost models.
+	for method, want := range map[string]float64{
+		"eth_blockNumber":   1,
+		"debug_getRawBlock": 2,
+		"debug_getRawTx":    5,
+		"eth_getLogs":       6,
+	} {
+		if have := l.cost(method, json.RawMessage("[1]")); have != want {
+			t.Errorf("%s: wrong cost: have %v, want %v", method, have, want)
+		}
+	}
+	// Token buckets are kept by IP, or by the subject of authenticated clients.
+	l = newRPCLimiter("test", policy, nil, clock)
+	for _, peer := range []rpc.PeerInfo{
+		testPeer("10.0.0.1:1", ""),
+		testPeer("10.0.0.1:2", ""),
+		testPeer("10.0.0.1:3", "alice"),
+		testPeer("10.0.0.2:1", "alice"),
+		testPeer("10.0.0.2:2", ""),
+	} {
+		for i := 0; i < 10; i++ {
+			if err := l.check(peer, "eth_blockNumber", nil); err != nil {
+				t.Fatalf("call %d failed: %v", i, err)
+			}
+		}
+	}
+	expectLimited := func(peer rpc.PeerInfo, method string) {
+		t.Helper()
+		var limited *rpcLimitExceededError
+		if err := l.check(peer, method, nil); !errors.As(err, &limited) || limited.ErrorCode() != -32005 {
+			t.Fatalf("expected limit error, got %v", err)
+		}
+	}
+	expectLimited(testPeer("10.0.0.1:4", ""), "eth_blockNumber")
+	expectLimited(testPeer("10.0.0.3:1", "alice"), "eth_blockNumber")
+	if err := l.check(testPeer("10.0.0.2:3", ""), "eth_blockNumber", nil); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	// Buckets refill at the rate limit.
+	clock.Run(500 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		if err := l.check(testPeer("10.0.0.1:5", ""), "eth_blockNumber", nil); err != nil {
+			t.Fatalf("call %d failed after refill: %v", i, err)
+		}
+	}
+	expectLimited(testPeer("10.0.0.1:5", ""), "eth_blockNumber")
+
+	// Calls costing more than the burst always fail.
+	l.policy.MethodCosts = map[string]float64{"eth_getLogs": 21}
+	clock.Run(time.Hour)
+	expectLimited(testPeer("10.0.0.4:1", ""), "eth_getLogs")
+
+	// The buckets of idle clients are dropped.
+	if err := l.check(testPeer("10.0.0.4:1", ""), "eth_blockNumber", nil); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if len(l.buckets) != 1 {
+		t.Fatalf("wrong number of buckets after pruning: have %d, want 1", len(l.buckets))
+	}
+}
+
+func testPeer(remote string, subject string) rpc.PeerInfo {
+	return rpc.PeerInfo{Transport: "http", RemoteAddr: remote, Subject: subject}
+}
+
+func TestRPCPolicy(t *testing.T) {
+	secret := [32]byte{0x01}
+	cfg := rpcEndpointConfig{
+		jwtSecret: secret[:],
+		policy: RPCPolicy{
+			DenyMethods: []string{"test_sleep"},
+			RateLimit:   1,
+			RateBurst:   3,
+		},
+	}
+	srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	assert.NoError(t, srv.enableRPC(apis(), httpConfig{rpcEndpointConfig: cfg}))
+	assert.NoError(t, srv.enableWS(apis(), wsConfig{Origins: []string{"*"}, rpcEndpointConfig: cfg}))
+	assert.NoError(t, srv.setListenAddr("localhost", 0))
+	assert.NoError(t, srv.start())
+	defer srv.stop()
+
+	auth := func(subject string) rpc.HTTPAuth {
+		return func(h http.Header) error {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+				IssuedAt: jwt.NewNumericDate(time.Now()),
+				Subject:  subject,
+			}).SignedString(secret[:])
+			h.Set("Authorization", "Bearer "+token)
+			return err
+		}
+	}
+	dial := func(url string, subject string) *rpc.Client {
+		client, err := rpc.DialOptions(context.Background(), url, rpc.WithHTTPAuth(auth(subject)))
+		if err != nil {
+			t.Fatalf("can't dial %s: %v", url, err)
+		}
+		return client
+	}
+	errorCode := func(err error) int {
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			return 0
+		}
+		return rpcErr.ErrorCode()
+	}
+	for _, url := range []string{"http://" + srv.listenAddr(), "ws://" + srv.listenAddr()} {
+		var (
+			alice = dial(url, "alice-"+url)
+			bob   = dial(url, "bob-"+url)
+		)
+		var result string
+		if err := alice.Call(&result, "test_sleep"); errorCode(err) != -32601 {
+			t.Fatalf("%s: expected denied call, got %v", url, err)
+		}
+		// Denied calls don't cost anything.
+		for i := 0; i < 3; i++ {
+			if err := alice.Call(&result, "test_greet"); err != nil {
+				t.Fatalf("%s: call %d failed: %v", url, i, err)
+			}
+		}
+		if err := alice.Call(&result, "test_greet"); errorCode(err) != -32005 {
+			t.Fatalf("%s: expected limited call, got %v", url, err)
+		}
+		// Other clients have their own limits, also within batches.
+		batch := make([]rpc.BatchElem, 4)
+		for i := range batch {
+			batch[i] = rpc.BatchElem{Method: "test_greet", Result: new(string)}
+		}
+		if err := bob.BatchCall(batch); err != nil {
+			t.Fatalf("%s: batch failed: %v", url, err)
+		}
+		for i, elem := range batch {
+			if code := errorCode(elem.Error); (i < 3 && elem.Error != nil) || (i == 3 && code != -32005) {
+				t.Fatalf("%s: wrong result of batch element %d: %v", url, i, fmt.Sprint(elem.Error))
+			}
+		}
+		alice.Close()
+		bob.Close()
+	}
+}
+
+func TestAuthRPCPolicy(t *testing.T) {
+	secret := [32]byte{0x01}
+	jwtPath := filepath.Join(t.TempDir(), "jwt_secret")
+	if err := os.WriteFile(jwtPath, []byte(hexutil.Encode(secret[:])), 0600); err != nil {
+		t.Fatalf("failed to prepare jwt secret file: %v", err)
+	}
+	node, err := New(&Config{
+		AuthAddr:  "127.0.0.1",
+		AuthPort:  0,
+		JWTSecret: jwtPath,
+		AuthPolicy: RPCPolicy{
+			DenyMethods: []string{"eth_*"},
+			RateLimit:   1,
+			RateBurst:   2,
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not create a new node: %v", err)
+	}
+	node.RegisterAPIs([]rpc.API{
+		{Namespace: "engine", Service: helloRPC("hello engine"), Authenticated: true},
+		{Namespace: "eth", Service: helloRPC("hello eth"), Authenticated: true},
+	})
+	if err := node.Start(); err != nil {
+		t.Fatalf("failed to start test node: %v", err)
+	}
+	defer node.Close()
+
+	dial := func(url string, subject string) *rpc.Client {
+		client, err := rpc.DialOptions(context.Background(), url, rpc.WithHTTPAuth(func(h http.Header) error {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+				IssuedAt: jwt.NewNumericDate(time.Now()),
+				Subject:  subject,
+			}).SignedString(secret[:])
+			h.Set("Authorization", "Bearer "+token)
+			return err
+		}))
+		if err != nil {
+			t.Fatalf("can't dial %s: %v", url, err)
+		}
+		return client
+	}
+	errorCode := func(err error) int {
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			return 0
+		}
+		return rpcErr.ErrorCode()
+	}
+	for _, url := range []string{node.HTTPAuthEndpoint(), node.WSAuthEndpoint()} {
+		var (
+			alice = dial(url, "alice")
+			bob   = dial(url, "bob")
+		)
+		var result string
+		if err := alice.Call(&result, "eth_helloWorld"); errorCode(err) != -32601 {
+			t.Fatalf("%s: expected denied call, got %v", url, err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := alice.Call(&result, "engine_helloWorld"); err != nil {
+				t.Fatalf("%s: call %d failed: %v", url, i, err)
+			}
+		}
+		if err := alice.Call(&result, "engine_helloWorld"); errorCode(err) != -32005 {
+			t.Fatalf("%s: expected limited call, got %v", url, err)
+		}
+		// The limit is kept by the subject of the token, not the address.
+		if err := bob.Call(&result, "engine_helloWorld"); err != nil {
+			t.Fatalf("%s: call of other subject failed: %v", url, err)
+		}
+		alice.Close()
+		bob.Close()
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/yuriy0803/core-geth1/common/mclock"
 	"github.com/yuriy0803/core-geth1/log"
 	"github.com/yuriy0803/core-geth1/rpc"
 	"github.com/rs/cors"
@@ -56,6 +57,9 @@ type rpcEndpointConfig struct {
 	jwtSecret              []byte // optional JWT secret
 	batchItemLimit         int
 	batchResponseSizeLimit int
+	policy                 RPCPolicy              // access policy of the methods
+	rpcCosts               map[string]RPCCostFunc // cost models of the methods
+	accessLog              *rpcAccessLog          // optional access log of the calls
 }
 
 type rpcHandler struct {
@@ -200,6 +204,14 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if ws != nil && isWebsocket(r) {
 		if checkPath(r, h.wsConfig.prefix) {
 			ws.ServeHTTP(w, r)
+			return
+		}
+		// Websocket requests to other paths may be served by the handlers
+		// registered in the mux, e.g. GraphQL subscriptions.
+		if handler, ok := h.httpHandler.Load().(*rpcHandler); ok && handler != nil {
+			if muxHandler, pattern := h.mux.Handler(r); pattern != "" {
+				muxHandler.ServeHTTP(w, r)
+			}
 		}
 		return
 	}
@@ -304,6 +316,12 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
 	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	if config.policy.enabled() {
+		srv.SetCallFilter(newRPCLimiter("http", config.policy, config.rpcCosts, mclock.System{}).filter)
+	}
+	if config.accessLog != nil {
+		srv.SetCallObserver(config.accessLog.observe)
+	}
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
@@ -336,6 +354,12 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
 	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	if config.policy.enabled() {
+		srv.SetCallFilter(newRPCLimiter("ws", config.policy, config.rpcCosts, mclock.System{}).filter)
+	}
+	if config.accessLog != nil {
+		srv.SetCallObserver(config.accessLog.observe)
+	}
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
@@ -564,7 +588,8 @@ func (w *gzipResponseWriter) close() {
 
 func newGzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		// Websocket connections are hijacked, they can't be compressed.
+		if isWebsocket(r) || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/yuriy0803/core-geth1/params/types/ctypes"
 )
@@ -527,6 +528,35 @@ func TimeForks(conf ctypes.ChainConfigurator) []uint64 {
 	return forks
 }
 
+// ForkTransitions returns the names of the transitions enabled at each of the forks
+// returned by BlockForks and TimeForks, keyed by fork block number and timestamp respectively.
+func ForkTransitions(conf ctypes.ChainConfigurator) (byBlock, byTime map[uint64][]string) {
+	byBlock, byTime = make(map[uint64][]string), make(map[uint64][]string)
+	blockForks, timeForks := BlockForks(conf), TimeForks(conf)
+
+	transitions, names := Transitions(conf)
+	for i, tr := range transitions {
+		if nameSignalsCompatibility(names[i]) {
+			continue
+		}
+		response := tr()
+		if response == nil {
+			continue
+		}
+		forks, m := blockForks, byBlock
+		if nameSignalsTimeBasedFork(names[i]) {
+			forks, m = timeForks, byTime
+		}
+		for _, f := range forks {
+			if f == *response {
+				m[f] = append(m[f], strings.TrimPrefix(names[i], "Get"))
+				break
+			}
+		}
+	}
+	return byBlock, byTime
+}
+
 func isBlockForkIncompatible(a, b, head *big.Int) bool {
 	// If the head is nil, then either fork config is ok. Return incompatible = false.
 	if head == nil {
@@ -0,0 +1,121 @@
+// Copyright 2019 The multi-geth Authors
+// This file is part of the multi-geth library.
+//
+// The multi-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The multi-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the multi-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package besu
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/yuriy0803/core-geth1/params/confp"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+)
+
+// ChainConfig is the "config" object of a Besu genesis file.
+// https://besu.hyperledger.org/public-networks/reference/genesis-items
+//
+// Besu configures Ethereum and Ethereum Classic networks with different sets of
+// named forks. A configuration using any of the Ethereum Classic forks is treated
+// as a Classic configuration; see isClassic.
+type ChainConfig struct {
+	NetworkID                 uint64   `json:"-"`
+	ChainID                   *big.Int `json:"chainId"`
+	SupportedProtocolVersions []uint   `json:"-"`
+
+	// Ethereum forks
+	HomesteadBlock      *big.Int `json:"homesteadBlock,omitempty"`
+	DAOForkBlock        *big.Int `json:"daoForkBlock,omitempty"`
+	EIP150Block         *big.Int `json:"eip150Block,omitempty"`
+	EIP155Block         *big.Int `json:"eip155Block,omitempty"`
+	EIP158Block         *big.Int `json:"eip158Block,omitempty"`
+	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`
+	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"`
+	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty"`
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`
+	MuirGlacierBlock    *big.Int `json:"muirGlacierBlock,omitempty"`
+	BerlinBlock         *big.Int `json:"berlinBlock,omitempty"`
+	LondonBlock         *big.Int `json:"londonBlock,omitempty"`
+	ArrowGlacierBlock   *big.Int `json:"arrowGlacierBlock,omitempty"`
+	GrayGlacierBlock    *big.Int `json:"grayGlacierBlock,omitempty"`
+	MergeNetSplitBlock  *big.Int `json:"mergeNetSplitBlock,omitempty"`
+	ShanghaiTime        *uint64  `json:"shanghaiTime,omitempty"`
+	CancunTime          *uint64  `json:"cancunTime,omitempty"`
+
+	TerminalTotalDifficulty *big.Int `json:"terminalTotalDifficulty,omitempty"`
+
+	// Ethereum Classic forks
+	ClassicForkBlock  *big.Int `json:"classicForkBlock,omitempty"`
+	ECIP1015Block     *big.Int `json:"ecip1015Block,omitempty"`
+	DieHardBlock      *big.Int `json:"diehardBlock,omitempty"`
+	GothamBlock       *big.Int `json:"gothamBlock,omitempty"`
+	ECIP1041Block     *big.Int `json:"ecip1041Block,omitempty"`
+	AtlantisBlock     *big.Int `json:"atlantisBlock,omitempty"`
+	AghartaBlock      *big.Int `json:"aghartaBlock,omitempty"`
+	PhoenixBlock      *big.Int `json:"phoenixBlock,omitempty"`
+	ThanosBlock       *big.Int `json:"thanosBlock,omitempty"`
+	MagnetoBlock      *big.Int `json:"magnetoBlock,omitempty"`
+	MystiqueBlock     *big.Int `json:"mystiqueBlock,omitempty"`
+	SpiralBlock       *big.Int `json:"spiralBlock,omitempty"`
+	ECIP1017EraRounds *big.Int `json:"ecip1017EraRounds,omitempty"`
+
+	// Various consensus engines
+	Ethash *EthashConfig       `json:"ethash,omitempty"`
+	Clique *CliqueConfig       `json:"clique,omitempty"`
+	Lyra2  *ctypes.Lyra2Config `json:"lyra2,omitempty"` // core-geth extension
+
+	Lyra2NonceTransitionBlock *big.Int `json:"lyra2NonceTransitionBlock,omitempty"` // core-geth extension
+
+	// NOTE: These features are not configurable for Besu.
+	// They are cached for use with conversion, but will not show up in the config JSON.
+	EIP1706Transition  *big.Int `json:"-"`
+	ECIP1080Transition *big.Int `json:"-"`
+
+	ecbp1100Transition           *big.Int
+	ecbp1100DeactivateTransition *big.Int
+}
+
+// EthashConfig is the Besu Ethash engine configuration.
+type EthashConfig struct {
+	FixedDifficulty *uint64 `json:"fixeddifficulty,omitempty"`
+}
+
+// CliqueConfig is the Besu Clique engine configuration.
+type CliqueConfig struct {
+	BlockPeriodSeconds uint64 `json:"blockperiodseconds"`
+	EpochLength        uint64 `json:"epochlength"`
+}
+
+// String implements the fmt.Stringer interface.
+func (c *ChainConfig) String() string {
+	var banner string
+
+	banner += fmt.Sprintf("Chain ID:  %v\n", c.ChainID)
+	switch c.GetConsensusEngineType() {
+	case ctypes.ConsensusEngineT_Ethash:
+		banner += "Consensus: Ethash (proof-of-work)\n"
+	case ctypes.ConsensusEngineT_Clique:
+		banner += "Consensus: Clique (proof-of-authority)\n"
+	case ctypes.ConsensusEngineT_Lyra2:
+		banner += "Consensus: Lyra2 (proof-of-work)\n"
+	default:
+		banner += "Consensus: unknown\n"
+	}
+	banner += "\n"
+	banner += fmt.Sprintf(`_ Block-based Forks: %v`, confp.BlockForks(c))
+	banner += fmt.Sprintf(`_ Time-based Forks: %v`, confp.TimeForks(c))
+	banner += fmt.Sprintf(`_ TTD: %v`, c.GetEthashTerminalTotalDifficulty())
+	return banner
+}
@@ -0,0 +1,1142 @@
+// Copyright 2019 The multi-geth Authors
+// This file is part of the multi-geth library.
+//
+// The multi-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The multi-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the multi-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package besu
+
+import (
+	"math/big"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+	"github.com/yuriy0803/core-geth1/params/types/internal"
+	"github.com/yuriy0803/core-geth1/params/vars"
+)
+
+// File contains the Besu implementation of the Configurator interface.
+//
+// Like go-ethereum, Besu configures features by named forks, so many features share a single field.
+// Setting a feature to nil does not unset the fork it shares with other features.
+//
+// Besu names the Ethereum Classic forks differently, and they group features differently.
+// A configuration is treated as an Ethereum Classic one if any of the Classic forks are set (see isClassic),
+// and the first feature only Ethereum Classic configures converts the Ethereum forks set so far
+// to their Classic counterparts (see classicize).
+
+func newU64(u uint64) *uint64 {
+	return &u
+}
+
+func bigNewU64(i *big.Int) *uint64 {
+	if i == nil {
+		return nil
+	}
+	return newU64(i.Uint64())
+}
+
+// isClassic returns true if any of the Ethereum Classic forks are configured.
+func (c *ChainConfig) isClassic() bool {
+	for _, f := range []*big.Int{
+		c.ClassicForkBlock,
+		c.ECIP1015Block,
+		c.DieHardBlock,
+		c.GothamBlock,
+		c.ECIP1041Block,
+		c.AtlantisBlock,
+		c.AghartaBlock,
+		c.PhoenixBlock,
+		c.ThanosBlock,
+		c.MagnetoBlock,
+		c.MystiqueBlock,
+		c.SpiralBlock,
+		c.ECIP1017EraRounds,
+	} {
+		if f != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// classicize moves the Ethereum forks to the Ethereum Classic forks enabling the same features.
+// Difficulty bomb delays have no Classic counterpart, and are dropped.
+func (c *ChainConfig) classicize() {
+	if c.isClassic() {
+		return
+	}
+	c.ECIP1015Block, c.EIP150Block = c.EIP150Block, nil
+	c.DieHardBlock, c.EIP155Block = c.EIP155Block, nil
+
+	c.AtlantisBlock = c.ByzantiumBlock
+	if c.AtlantisBlock == nil {
+		c.AtlantisBlock = c.EIP158Block
+	}
+	c.ByzantiumBlock, c.EIP158Block = nil, nil
+
+	c.AghartaBlock = c.PetersburgBlock
+	if c.AghartaBlock == nil {
+		c.AghartaBlock = c.ConstantinopleBlock
+	}
+	c.ConstantinopleBlock, c.PetersburgBlock = nil, nil
+
+	c.PhoenixBlock, c.IstanbulBlock = c.IstanbulBlock, nil
+	c.MagnetoBlock, c.BerlinBlock = c.BerlinBlock, nil
+	c.MystiqueBlock, c.LondonBlock = c.LondonBlock, nil
+
+	c.MuirGlacierBlock, c.ArrowGlacierBlock, c.GrayGlacierBlock = nil, nil, nil
+}
+
+// fork returns the field of the fork enabling a feature, given the Ethereum and Ethereum Classic fork fields.
+// A nil field means the feature is not available for the configuration.
+func (c *ChainConfig) fork(eth, etc **big.Int) **big.Int {
+	if c.isClassic() {
+		return etc
+	}
+	return eth
+}
+
+func (c *ChainConfig) getFork(eth, etc **big.Int) *uint64 {
+	f := c.fork(eth, etc)
+	if f == nil {
+		return nil
+	}
+	return bigNewU64(*f)
+}
+
+func (c *ChainConfig) setFork(eth, etc **big.Int, n *uint64) error {
+	f := c.fork(eth, etc)
+	if f == nil {
+		if n == nil {
+			return nil
+		}
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if n == nil {
+		return nil
+	}
+	*f = new(big.Int).SetUint64(*n)
+	return nil
+}
+
+// setClassicFork sets a fork only Ethereum Classic configures, converting the configuration if need be.
+func (c *ChainConfig) setClassicFork(etc **big.Int, n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	c.classicize()
+	*etc = new(big.Int).SetUint64(*n)
+	return nil
+}
+
+func (c *ChainConfig) GetAccountStartNonce() *uint64 {
+	return internal.GlobalConfigurator().GetAccountStartNonce()
+}
+
+func (c *ChainConfig) SetAccountStartNonce(n *uint64) error {
+	return internal.GlobalConfigurator().SetAccountStartNonce(n)
+}
+
+func (c *ChainConfig) GetMaximumExtraDataSize() *uint64 {
+	return internal.GlobalConfigurator().GetMaximumExtraDataSize()
+}
+
+func (c *ChainConfig) SetMaximumExtraDataSize(n *uint64) error {
+	return internal.GlobalConfigurator().SetMaximumExtraDataSize(n)
+}
+
+func (c *ChainConfig) GetMinGasLimit() *uint64 {
+	return internal.GlobalConfigurator().GetMinGasLimit()
+}
+
+func (c *ChainConfig) SetMinGasLimit(n *uint64) error {
+	return internal.GlobalConfigurator().SetMinGasLimit(n)
+}
+
+func (c *ChainConfig) GetGasLimitBoundDivisor() *uint64 {
+	return internal.GlobalConfigurator().GetGasLimitBoundDivisor()
+}
+
+func (c *ChainConfig) SetGasLimitBoundDivisor(n *uint64) error {
+	return internal.GlobalConfigurator().SetGasLimitBoundDivisor(n)
+}
+
+func (c *ChainConfig) GetElasticityMultiplier() uint64 {
+	return internal.GlobalConfigurator().GetElasticityMultiplier()
+}
+
+func (c *ChainConfig) SetElasticityMultiplier(n uint64) error {
+	return internal.GlobalConfigurator().SetElasticityMultiplier(n)
+}
+
+func (c *ChainConfig) GetBaseFeeChangeDenominator() uint64 {
+	return internal.GlobalConfigurator().GetBaseFeeChangeDenominator()
+}
+
+func (c *ChainConfig) SetBaseFeeChangeDenominator(n uint64) error {
+	return internal.GlobalConfigurator().SetBaseFeeChangeDenominator(n)
+}
+
+// GetNetworkID returns the network id, which Besu takes from the command line,
+// defaulting to the chain id.
+func (c *ChainConfig) GetNetworkID() *uint64 {
+	if c.NetworkID != 0 {
+		return &c.NetworkID
+	}
+	if c.ChainID != nil {
+		return newU64(c.ChainID.Uint64())
+	}
+	return newU64(vars.DefaultNetworkID)
+}
+
+func (c *ChainConfig) SetNetworkID(n *uint64) error {
+	if n == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if c.ChainID == nil {
+		c.ChainID = new(big.Int).SetUint64(*n)
+	}
+	c.NetworkID = *n
+	return nil
+}
+
+func (c *ChainConfig) GetChainID() *big.Int {
+	return c.ChainID
+}
+
+func (c *ChainConfig) SetChainID(n *big.Int) error {
+	c.ChainID = n
+	return nil
+}
+
+func (c *ChainConfig) GetSupportedProtocolVersions() []uint {
+	if len(c.SupportedProtocolVersions) == 0 {
+		return vars.DefaultProtocolVersions
+	}
+	return c.SupportedProtocolVersions
+}
+
+func (c *ChainConfig) SetSupportedProtocolVersions(p []uint) error {
+	c.SupportedProtocolVersions = p
+	return nil
+}
+
+func (c *ChainConfig) GetMaxCodeSize() *uint64 {
+	return internal.GlobalConfigurator().GetMaxCodeSize()
+}
+
+func (c *ChainConfig) SetMaxCodeSize(n *uint64) error {
+	return internal.GlobalConfigurator().SetMaxCodeSize(n)
+}
+
+func (c *ChainConfig) GetEIP7Transition() *uint64 {
+	return bigNewU64(c.HomesteadBlock)
+}
+
+func (c *ChainConfig) SetEIP7Transition(n *uint64) error {
+	return c.setFork(&c.HomesteadBlock, &c.HomesteadBlock, n)
+}
+
+func (c *ChainConfig) GetEIP150Transition() *uint64 {
+	return c.getFork(&c.EIP150Block, &c.ECIP1015Block)
+}
+
+func (c *ChainConfig) SetEIP150Transition(n *uint64) error {
+	return c.setFork(&c.EIP150Block, &c.ECIP1015Block, n)
+}
+
+func (c *ChainConfig) GetEIP152Transition() *uint64 {
+	return c.getFork(&c.IstanbulBlock, &c.PhoenixBlock)
+}
+
+func (c *ChainConfig) SetEIP152Transition(n *uint64) error {
+	return c.setFork(&c.IstanbulBlock, &c.PhoenixBlock, n)
+}
+
+func (c *ChainConfig) GetEIP160Transition() *uint64 {
+	return c.getFork(&c.EIP158Block, &c.DieHardBlock)
+}
+
+func (c *ChainConfig) SetEIP160Transition(n *uint64) error {
+	return c.setFork(&c.EIP158Block, &c.DieHardBlock, n)
+}
+
+func (c *ChainConfig) GetEIP161dTransition() *uint64 {
+	return c.getFork(&c.EIP158Block, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP161dTransition(n *uint64) error {
+	return c.setFork(&c.EIP158Block, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP161abcTransition() *uint64 {
+	return c.getFork(&c.EIP158Block, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP161abcTransition(n *uint64) error {
+	return c.setFork(&c.EIP158Block, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP170Transition() *uint64 {
+	return c.getFork(&c.EIP158Block, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP170Transition(n *uint64) error {
+	return c.setFork(&c.EIP158Block, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP155Transition() *uint64 {
+	return c.getFork(&c.EIP155Block, &c.DieHardBlock)
+}
+
+func (c *ChainConfig) SetEIP155Transition(n *uint64) error {
+	return c.setFork(&c.EIP155Block, &c.DieHardBlock, n)
+}
+
+func (c *ChainConfig) GetEIP140Transition() *uint64 {
+	return c.getFork(&c.ByzantiumBlock, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP140Transition(n *uint64) error {
+	return c.setFork(&c.ByzantiumBlock, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP198Transition() *uint64 {
+	return c.getFork(&c.ByzantiumBlock, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP198Transition(n *uint64) error {
+	return c.setFork(&c.ByzantiumBlock, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP211Transition() *uint64 {
+	return c.getFork(&c.ByzantiumBlock, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP211Transition(n *uint64) error {
+	return c.setFork(&c.ByzantiumBlock, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP212Transition() *uint64 {
+	return c.getFork(&c.ByzantiumBlock, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP212Transition(n *uint64) error {
+	return c.setFork(&c.ByzantiumBlock, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP213Transition() *uint64 {
+	return c.getFork(&c.ByzantiumBlock, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP213Transition(n *uint64) error {
+	return c.setFork(&c.ByzantiumBlock, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP214Transition() *uint64 {
+	return c.getFork(&c.ByzantiumBlock, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP214Transition(n *uint64) error {
+	return c.setFork(&c.ByzantiumBlock, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP658Transition() *uint64 {
+	return c.getFork(&c.ByzantiumBlock, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEIP658Transition(n *uint64) error {
+	return c.setFork(&c.ByzantiumBlock, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEIP145Transition() *uint64 {
+	return c.getFork(&c.ConstantinopleBlock, &c.AghartaBlock)
+}
+
+func (c *ChainConfig) SetEIP145Transition(n *uint64) error {
+	return c.setFork(&c.ConstantinopleBlock, &c.AghartaBlock, n)
+}
+
+func (c *ChainConfig) GetEIP1014Transition() *uint64 {
+	return c.getFork(&c.ConstantinopleBlock, &c.AghartaBlock)
+}
+
+func (c *ChainConfig) SetEIP1014Transition(n *uint64) error {
+	return c.setFork(&c.ConstantinopleBlock, &c.AghartaBlock, n)
+}
+
+func (c *ChainConfig) GetEIP1052Transition() *uint64 {
+	return c.getFork(&c.ConstantinopleBlock, &c.AghartaBlock)
+}
+
+func (c *ChainConfig) SetEIP1052Transition(n *uint64) error {
+	return c.setFork(&c.ConstantinopleBlock, &c.AghartaBlock, n)
+}
+
+// GetEIP1283Transition returns the Constantinople block.
+// Ethereum Classic never activated EIP1283.
+func (c *ChainConfig) GetEIP1283Transition() *uint64 {
+	return c.getFork(&c.ConstantinopleBlock, nil)
+}
+
+func (c *ChainConfig) SetEIP1283Transition(n *uint64) error {
+	return c.setFork(&c.ConstantinopleBlock, nil, n)
+}
+
+func (c *ChainConfig) GetEIP1283DisableTransition() *uint64 {
+	return c.getFork(&c.PetersburgBlock, nil)
+}
+
+func (c *ChainConfig) SetEIP1283DisableTransition(n *uint64) error {
+	return c.setFork(&c.PetersburgBlock, nil, n)
+}
+
+func (c *ChainConfig) GetEIP1108Transition() *uint64 {
+	return c.getFork(&c.IstanbulBlock, &c.PhoenixBlock)
+}
+
+func (c *ChainConfig) SetEIP1108Transition(n *uint64) error {
+	return c.setFork(&c.IstanbulBlock, &c.PhoenixBlock, n)
+}
+
+func (c *ChainConfig) GetEIP2200Transition() *uint64 {
+	return c.getFork(&c.IstanbulBlock, &c.PhoenixBlock)
+}
+
+func (c *ChainConfig) SetEIP2200Transition(n *uint64) error {
+	return c.setFork(&c.IstanbulBlock, &c.PhoenixBlock, n)
+}
+
+func (c *ChainConfig) GetEIP2200DisableTransition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP2200DisableTransition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP1344Transition() *uint64 {
+	return c.getFork(&c.IstanbulBlock, &c.PhoenixBlock)
+}
+
+func (c *ChainConfig) SetEIP1344Transition(n *uint64) error {
+	return c.setFork(&c.IstanbulBlock, &c.PhoenixBlock, n)
+}
+
+func (c *ChainConfig) GetEIP1884Transition() *uint64 {
+	return c.getFork(&c.IstanbulBlock, &c.PhoenixBlock)
+}
+
+func (c *ChainConfig) SetEIP1884Transition(n *uint64) error {
+	return c.setFork(&c.IstanbulBlock, &c.PhoenixBlock, n)
+}
+
+func (c *ChainConfig) GetEIP2028Transition() *uint64 {
+	return c.getFork(&c.IstanbulBlock, &c.PhoenixBlock)
+}
+
+func (c *ChainConfig) SetEIP2028Transition(n *uint64) error {
+	return c.setFork(&c.IstanbulBlock, &c.PhoenixBlock, n)
+}
+
+func (c *ChainConfig) GetECIP1080Transition() *uint64 {
+	return bigNewU64(c.ECIP1080Transition)
+}
+
+func (c *ChainConfig) SetECIP1080Transition(n *uint64) error {
+	if n == nil {
+		c.ECIP1080Transition = nil
+		return nil
+	}
+	c.ECIP1080Transition = new(big.Int).SetUint64(*n)
+	return nil
+}
+
+func (c *ChainConfig) GetEIP1706Transition() *uint64 {
+	return bigNewU64(c.EIP1706Transition)
+}
+
+func (c *ChainConfig) SetEIP1706Transition(n *uint64) error {
+	if n == nil {
+		c.EIP1706Transition = nil
+		return nil
+	}
+	c.EIP1706Transition = new(big.Int).SetUint64(*n)
+	return nil
+}
+
+func (c *ChainConfig) GetEIP2537Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP2537Transition(n *uint64) error {
+	if n != nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return nil
+}
+
+func (c *ChainConfig) GetECBP1100Transition() *uint64 {
+	return bigNewU64(c.ecbp1100Transition)
+}
+
+func (c *ChainConfig) SetECBP1100Transition(n *uint64) error {
+	if n == nil {
+		c.ecbp1100Transition = nil
+		return nil
+	}
+	c.ecbp1100Transition = new(big.Int).SetUint64(*n)
+	return nil
+}
+
+func (c *ChainConfig) GetECBP1100DeactivateTransition() *uint64 {
+	return bigNewU64(c.ecbp1100DeactivateTransition)
+}
+
+func (c *ChainConfig) SetECBP1100DeactivateTransition(n *uint64) error {
+	if n == nil {
+		c.ecbp1100DeactivateTransition = nil
+		return nil
+	}
+	c.ecbp1100DeactivateTransition = new(big.Int).SetUint64(*n)
+	return nil
+}
+
+func (c *ChainConfig) GetEIP2315Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP2315Transition(n *uint64) error {
+	if n != nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return nil
+}
+
+func (c *ChainConfig) GetEIP2929Transition() *uint64 {
+	return c.getFork(&c.BerlinBlock, &c.MagnetoBlock)
+}
+
+func (c *ChainConfig) SetEIP2929Transition(n *uint64) error {
+	return c.setFork(&c.BerlinBlock, &c.MagnetoBlock, n)
+}
+
+func (c *ChainConfig) GetEIP2930Transition() *uint64 {
+	return c.getFork(&c.BerlinBlock, &c.MagnetoBlock)
+}
+
+func (c *ChainConfig) SetEIP2930Transition(n *uint64) error {
+	return c.setFork(&c.BerlinBlock, &c.MagnetoBlock, n)
+}
+
+// GetEIP1559Transition returns the London block.
+// Ethereum Classic has not activated EIP1559.
+func (c *ChainConfig) GetEIP1559Transition() *uint64 {
+	return c.getFork(&c.LondonBlock, nil)
+}
+
+func (c *ChainConfig) SetEIP1559Transition(n *uint64) error {
+	return c.setFork(&c.LondonBlock, nil, n)
+}
+
+func (c *ChainConfig) GetEIP3541Transition() *uint64 {
+	return c.getFork(&c.LondonBlock, &c.MystiqueBlock)
+}
+
+func (c *ChainConfig) SetEIP3541Transition(n *uint64) error {
+	return c.setFork(&c.LondonBlock, &c.MystiqueBlock, n)
+}
+
+func (c *ChainConfig) GetEIP3529Transition() *uint64 {
+	return c.getFork(&c.LondonBlock, &c.MystiqueBlock)
+}
+
+func (c *ChainConfig) SetEIP3529Transition(n *uint64) error {
+	return c.setFork(&c.LondonBlock, &c.MystiqueBlock, n)
+}
+
+func (c *ChainConfig) GetEIP3198Transition() *uint64 {
+	return c.getFork(&c.LondonBlock, nil)
+}
+
+func (c *ChainConfig) SetEIP3198Transition(n *uint64) error {
+	return c.setFork(&c.LondonBlock, nil, n)
+}
+
+func (c *ChainConfig) GetEIP2565Transition() *uint64 {
+	return c.getFork(&c.BerlinBlock, &c.MagnetoBlock)
+}
+
+func (c *ChainConfig) SetEIP2565Transition(n *uint64) error {
+	return c.setFork(&c.BerlinBlock, &c.MagnetoBlock, n)
+}
+
+func (c *ChainConfig) GetEIP2718Transition() *uint64 {
+	return c.getFork(&c.BerlinBlock, &c.MagnetoBlock)
+}
+
+func (c *ChainConfig) SetEIP2718Transition(n *uint64) error {
+	return c.setFork(&c.BerlinBlock, &c.MagnetoBlock, n)
+}
+
+func (c *ChainConfig) GetEIP4399Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP4399Transition(n *uint64) error {
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+// GetEIP3651TransitionTime EIP3651: Warm COINBASE
+func (c *ChainConfig) GetEIP3651TransitionTime() *uint64 {
+	return c.ShanghaiTime
+}
+
+func (c *ChainConfig) SetEIP3651TransitionTime(n *uint64) error {
+	c.ShanghaiTime = n
+	return nil
+}
+
+// GetEIP3855TransitionTime EIP3855: PUSH0 instruction
+func (c *ChainConfig) GetEIP3855TransitionTime() *uint64 {
+	return c.ShanghaiTime
+}
+
+func (c *ChainConfig) SetEIP3855TransitionTime(n *uint64) error {
+	c.ShanghaiTime = n
+	return nil
+}
+
+// GetEIP3860TransitionTime EIP3860: Limit and meter initcode
+func (c *ChainConfig) GetEIP3860TransitionTime() *uint64 {
+	return c.ShanghaiTime
+}
+
+func (c *ChainConfig) SetEIP3860TransitionTime(n *uint64) error {
+	c.ShanghaiTime = n
+	return nil
+}
+
+// GetEIP4895TransitionTime EIP4895: Beacon chain push withdrawals as operations
+func (c *ChainConfig) GetEIP4895TransitionTime() *uint64 {
+	return c.ShanghaiTime
+}
+
+func (c *ChainConfig) SetEIP4895TransitionTime(n *uint64) error {
+	c.ShanghaiTime = n
+	return nil
+}
+
+// GetEIP6049TransitionTime EIP6049: Deprecate SELFDESTRUCT
+func (c *ChainConfig) GetEIP6049TransitionTime() *uint64 {
+	return c.ShanghaiTime
+}
+
+func (c *ChainConfig) SetEIP6049TransitionTime(n *uint64) error {
+	c.ShanghaiTime = n
+	return nil
+}
+
+// GetEIP3651Transition EIP3651: Warm COINBASE
+// The block-based Shanghai features are only configured by Ethereum Classic, with Spiral.
+func (c *ChainConfig) GetEIP3651Transition() *uint64 {
+	return bigNewU64(c.SpiralBlock)
+}
+
+func (c *ChainConfig) SetEIP3651Transition(n *uint64) error {
+	return c.setClassicFork(&c.SpiralBlock, n)
+}
+
+// GetEIP3855Transition EIP3855: PUSH0 instruction
+func (c *ChainConfig) GetEIP3855Transition() *uint64 {
+	return bigNewU64(c.SpiralBlock)
+}
+
+func (c *ChainConfig) SetEIP3855Transition(n *uint64) error {
+	return c.setClassicFork(&c.SpiralBlock, n)
+}
+
+// GetEIP3860Transition EIP3860: Limit and meter initcode
+func (c *ChainConfig) GetEIP3860Transition() *uint64 {
+	return bigNewU64(c.SpiralBlock)
+}
+
+func (c *ChainConfig) SetEIP3860Transition(n *uint64) error {
+	return c.setClassicFork(&c.SpiralBlock, n)
+}
+
+// GetEIP4895Transition EIP4895: Beacon chain push withdrawals as operations
+func (c *ChainConfig) GetEIP4895Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP4895Transition(n *uint64) error {
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+// GetEIP6049Transition EIP6049: Deprecate SELFDESTRUCT
+func (c *ChainConfig) GetEIP6049Transition() *uint64 {
+	return bigNewU64(c.SpiralBlock)
+}
+
+func (c *ChainConfig) SetEIP6049Transition(n *uint64) error {
+	return c.setClassicFork(&c.SpiralBlock, n)
+}
+
+// GetEIP4844TransitionTime EIP4844: Shard Blob Transactions
+func (c *ChainConfig) GetEIP4844TransitionTime() *uint64 {
+	return c.CancunTime
+}
+
+func (c *ChainConfig) SetEIP4844TransitionTime(n *uint64) error {
+	c.CancunTime = n
+	return nil
+}
+
+// GetEIP1153TransitionTime EIP1153: Transient Storage opcodes
+func (c *ChainConfig) GetEIP1153TransitionTime() *uint64 {
+	return c.CancunTime
+}
+
+func (c *ChainConfig) SetEIP1153TransitionTime(n *uint64) error {
+	c.CancunTime = n
+	return nil
+}
+
+// GetEIP5656TransitionTime EIP5656: MCOPY - Memory copying instruction
+func (c *ChainConfig) GetEIP5656TransitionTime() *uint64 {
+	return c.CancunTime
+}
+
+func (c *ChainConfig) SetEIP5656TransitionTime(n *uint64) error {
+	c.CancunTime = n
+	return nil
+}
+
+// GetEIP6780TransitionTime EIP6780: SELFDESTRUCT only in same transaction
+func (c *ChainConfig) GetEIP6780TransitionTime() *uint64 {
+	return c.CancunTime
+}
+
+func (c *ChainConfig) SetEIP6780TransitionTime(n *uint64) error {
+	c.CancunTime = n
+	return nil
+}
+
+func (c *ChainConfig) GetMergeVirtualTransition() *uint64 {
+	return bigNewU64(c.MergeNetSplitBlock)
+}
+
+func (c *ChainConfig) SetMergeVirtualTransition(n *uint64) error {
+	return c.setFork(&c.MergeNetSplitBlock, &c.MergeNetSplitBlock, n)
+}
+
+func (c *ChainConfig) IsEnabled(fn func() *uint64, n *big.Int) bool {
+	f := fn()
+	if f == nil || n == nil {
+		return false
+	}
+	fnName := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	if strings.Contains(fnName, "ECBP1100Transition") {
+		deactivateTransition := c.GetECBP1100DeactivateTransition()
+		if deactivateTransition != nil {
+			return big.NewInt(int64(*deactivateTransition)).Cmp(n) > 0 && big.NewInt(int64(*f)).Cmp(n) <= 0
+		}
+	}
+	return big.NewInt(int64(*f)).Cmp(n) <= 0
+}
+
+func (c *ChainConfig) IsEnabledByTime(fn func() *uint64, n *uint64) bool {
+	f := fn()
+	if f == nil || n == nil {
+		return false
+	}
+	return *f <= *n
+}
+
+func (c *ChainConfig) GetForkCanonHash(n uint64) common.Hash {
+	return common.Hash{}
+}
+
+func (c *ChainConfig) SetForkCanonHash(n uint64, h common.Hash) error {
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (c *ChainConfig) GetForkCanonHashes() map[uint64]common.Hash {
+	return nil
+}
+
+func (c *ChainConfig) GetConsensusEngineType() ctypes.ConsensusEngineT {
+	if c.Clique != nil {
+		return ctypes.ConsensusEngineT_Clique
+	}
+	if c.Lyra2 != nil {
+		return ctypes.ConsensusEngineT_Lyra2
+	}
+	return ctypes.ConsensusEngineT_Ethash
+}
+
+func (c *ChainConfig) MustSetConsensusEngineType(t ctypes.ConsensusEngineT) error {
+	switch t {
+	case ctypes.ConsensusEngineT_Ethash:
+		c.Ethash = new(EthashConfig)
+		c.Clique = nil
+		c.Lyra2 = nil
+		return nil
+	case ctypes.ConsensusEngineT_Clique:
+		c.Clique = new(CliqueConfig)
+		c.Ethash = nil
+		c.Lyra2 = nil
+		return nil
+	case ctypes.ConsensusEngineT_Lyra2:
+		c.Lyra2 = new(ctypes.Lyra2Config)
+		c.Ethash = nil
+		c.Clique = nil
+		return nil
+	default:
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+}
+
+func (c *ChainConfig) GetIsDevMode() bool {
+	return false
+}
+
+func (c *ChainConfig) SetDevMode(devMode bool) error {
+	if !devMode {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (c *ChainConfig) GetEthashTerminalTotalDifficulty() *big.Int {
+	return c.TerminalTotalDifficulty
+}
+
+func (c *ChainConfig) SetEthashTerminalTotalDifficulty(n *big.Int) error {
+	if n == nil {
+		c.TerminalTotalDifficulty = nil
+		return nil
+	}
+	c.TerminalTotalDifficulty = new(big.Int).Set(n)
+	return nil
+}
+
+func (c *ChainConfig) GetEthashTerminalTotalDifficultyPassed() bool {
+	return false
+}
+
+func (c *ChainConfig) SetEthashTerminalTotalDifficultyPassed(t bool) error {
+	if !t {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+// IsTerminalPoWBlock returns whether the given block is the last block of PoW stage.
+func (c *ChainConfig) IsTerminalPoWBlock(parentTotalDiff *big.Int, totalDiff *big.Int) bool {
+	terminalTotalDifficulty := c.GetEthashTerminalTotalDifficulty()
+	if terminalTotalDifficulty == nil {
+		return false
+	}
+	return parentTotalDiff.Cmp(terminalTotalDifficulty) < 0 && totalDiff.Cmp(terminalTotalDifficulty) >= 0
+}
+
+func (c *ChainConfig) GetEthashMinimumDifficulty() *big.Int {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return internal.GlobalConfigurator().GetEthashMinimumDifficulty()
+}
+
+func (c *ChainConfig) SetEthashMinimumDifficulty(i *big.Int) error {
+	return internal.GlobalConfigurator().SetEthashMinimumDifficulty(i)
+}
+
+func (c *ChainConfig) GetEthashDifficultyBoundDivisor() *big.Int {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return internal.GlobalConfigurator().GetEthashDifficultyBoundDivisor()
+}
+
+func (c *ChainConfig) SetEthashDifficultyBoundDivisor(i *big.Int) error {
+	return internal.GlobalConfigurator().SetEthashDifficultyBoundDivisor(i)
+}
+
+func (c *ChainConfig) GetEthashDurationLimit() *big.Int {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return internal.GlobalConfigurator().GetEthashDurationLimit()
+}
+
+func (c *ChainConfig) SetEthashDurationLimit(i *big.Int) error {
+	return internal.GlobalConfigurator().SetEthashDurationLimit(i)
+}
+
+func (c *ChainConfig) GetEthashHomesteadTransition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return bigNewU64(c.HomesteadBlock)
+}
+
+func (c *ChainConfig) SetEthashHomesteadTransition(n *uint64) error {
+	return c.setFork(&c.HomesteadBlock, &c.HomesteadBlock, n)
+}
+
+func (c *ChainConfig) GetEIP2Transition() *uint64 {
+	return bigNewU64(c.HomesteadBlock)
+}
+
+func (c *ChainConfig) SetEIP2Transition(n *uint64) error {
+	return c.setFork(&c.HomesteadBlock, &c.HomesteadBlock, n)
+}
+
+func (c *ChainConfig) GetEthashEIP779Transition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return bigNewU64(c.DAOForkBlock)
+}
+
+func (c *ChainConfig) SetEthashEIP779Transition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if n == nil {
+		c.DAOForkBlock = nil
+		return nil
+	}
+	c.DAOForkBlock = new(big.Int).SetUint64(*n)
+	return nil
+}
+
+// getBombFork returns the Ethereum fork delaying the difficulty bomb.
+// Ethereum Classic defused the difficulty bomb instead; see ECIP1010 and ECIP1041.
+func (c *ChainConfig) getBombFork(eth **big.Int) *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return c.getFork(eth, nil)
+}
+
+func (c *ChainConfig) setBombFork(eth **big.Int, n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setFork(eth, nil, n)
+}
+
+func (c *ChainConfig) GetEthashEIP649Transition() *uint64 {
+	return c.getBombFork(&c.ByzantiumBlock)
+}
+
+func (c *ChainConfig) SetEthashEIP649Transition(n *uint64) error {
+	return c.setBombFork(&c.ByzantiumBlock, n)
+}
+
+func (c *ChainConfig) GetEthashEIP1234Transition() *uint64 {
+	return c.getBombFork(&c.ConstantinopleBlock)
+}
+
+func (c *ChainConfig) SetEthashEIP1234Transition(n *uint64) error {
+	return c.setBombFork(&c.ConstantinopleBlock, n)
+}
+
+func (c *ChainConfig) GetEthashEIP2384Transition() *uint64 {
+	return c.getBombFork(&c.MuirGlacierBlock)
+}
+
+func (c *ChainConfig) SetEthashEIP2384Transition(n *uint64) error {
+	return c.setBombFork(&c.MuirGlacierBlock, n)
+}
+
+func (c *ChainConfig) GetEthashEIP3554Transition() *uint64 {
+	return c.getBombFork(&c.LondonBlock)
+}
+
+func (c *ChainConfig) SetEthashEIP3554Transition(n *uint64) error {
+	return c.setBombFork(&c.LondonBlock, n)
+}
+
+func (c *ChainConfig) GetEthashEIP4345Transition() *uint64 {
+	return c.getBombFork(&c.ArrowGlacierBlock)
+}
+
+func (c *ChainConfig) SetEthashEIP4345Transition(n *uint64) error {
+	return c.setBombFork(&c.ArrowGlacierBlock, n)
+}
+
+func (c *ChainConfig) GetEthashEIP5133Transition() *uint64 {
+	return c.getBombFork(&c.GrayGlacierBlock)
+}
+
+func (c *ChainConfig) SetEthashEIP5133Transition(n *uint64) error {
+	return c.setBombFork(&c.GrayGlacierBlock, n)
+}
+
+// GetEthashECIP1010PauseTransition returns the Die Hard block, which pauses the difficulty bomb.
+func (c *ChainConfig) GetEthashECIP1010PauseTransition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return c.getFork(nil, &c.DieHardBlock)
+}
+
+func (c *ChainConfig) SetEthashECIP1010PauseTransition(n *uint64) error {
+	return c.setClassicFork(&c.DieHardBlock, n)
+}
+
+// GetEthashECIP1010ContinueTransition returns the Gotham block, which continues the difficulty bomb.
+func (c *ChainConfig) GetEthashECIP1010ContinueTransition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return c.getFork(nil, &c.GothamBlock)
+}
+
+func (c *ChainConfig) SetEthashECIP1010ContinueTransition(n *uint64) error {
+	return c.setClassicFork(&c.GothamBlock, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1017Transition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return c.getFork(nil, &c.GothamBlock)
+}
+
+func (c *ChainConfig) SetEthashECIP1017Transition(n *uint64) error {
+	return c.setClassicFork(&c.GothamBlock, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1017EraRounds() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return bigNewU64(c.ECIP1017EraRounds)
+}
+
+func (c *ChainConfig) SetEthashECIP1017EraRounds(n *uint64) error {
+	return c.setClassicFork(&c.ECIP1017EraRounds, n)
+}
+
+func (c *ChainConfig) GetEthashEIP100BTransition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return c.getFork(&c.ByzantiumBlock, &c.AtlantisBlock)
+}
+
+func (c *ChainConfig) SetEthashEIP100BTransition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setFork(&c.ByzantiumBlock, &c.AtlantisBlock, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1041Transition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return c.getFork(nil, &c.ECIP1041Block)
+}
+
+func (c *ChainConfig) SetEthashECIP1041Transition(n *uint64) error {
+	return c.setClassicFork(&c.ECIP1041Block, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1099Transition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return c.getFork(nil, &c.ThanosBlock)
+}
+
+func (c *ChainConfig) SetEthashECIP1099Transition(n *uint64) error {
+	return c.setClassicFork(&c.ThanosBlock, n)
+}
+
+func (c *ChainConfig) GetEthashDifficultyBombDelaySchedule() ctypes.Uint64BigMapEncodesHex {
+	return nil
+}
+
+func (c *ChainConfig) SetEthashDifficultyBombDelaySchedule(m ctypes.Uint64BigMapEncodesHex) error {
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (c *ChainConfig) GetEthashBlockRewardSchedule() ctypes.Uint64BigMapEncodesHex {
+	return nil
+}
+
+func (c *ChainConfig) SetEthashBlockRewardSchedule(m ctypes.Uint64BigMapEncodesHex) error {
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (c *ChainConfig) GetCliquePeriod() uint64 {
+	if c.Clique == nil {
+		return 0
+	}
+	return c.Clique.BlockPeriodSeconds
+}
+
+func (c *ChainConfig) SetCliquePeriod(n uint64) error {
+	if c.Clique == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	c.Clique.BlockPeriodSeconds = n
+	return nil
+}
+
+func (c *ChainConfig) GetCliqueEpoch() uint64 {
+	if c.Clique == nil {
+		return 0
+	}
+	return c.Clique.EpochLength
+}
+
+func (c *ChainConfig) SetCliqueEpoch(n uint64) error {
+	if c.Clique == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	c.Clique.EpochLength = n
+	return nil
+}
+
+func (c *ChainConfig) GetLyra2NonceTransition() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Lyra2 {
+		return nil
+	}
+	return bigNewU64(c.Lyra2NonceTransitionBlock)
+}
+
+func (c *ChainConfig) SetLyra2NonceTransition(n *uint64) error {
+	if c.Lyra2 == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if n == nil {
+		c.Lyra2NonceTransitionBlock = nil
+		return nil
+	}
+	c.Lyra2NonceTransitionBlock = new(big.Int).SetUint64(*n)
+	return nil
+}
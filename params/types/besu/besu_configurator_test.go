@@ -0,0 +1,56 @@
+package besu
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestChainConfig_classicize(t *testing.T) {
+	c := &ChainConfig{}
+	atlantis, diehard := uint64(8772000), uint64(3000000)
+
+	// Features are configured by the Ethereum forks until any Classic-only feature is set.
+	if err := c.SetEIP155Transition(&diehard); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetEIP140Transition(&atlantis); err != nil {
+		t.Fatal(err)
+	}
+	if c.isClassic() {
+		t.Fatal("classic before any classic-only feature")
+	}
+	if c.EIP155Block == nil || c.ByzantiumBlock == nil {
+		t.Fatal("ethereum forks not set")
+	}
+
+	gotham := uint64(5000000)
+	if err := c.SetEthashECIP1017Transition(&gotham); err != nil {
+		t.Fatal(err)
+	}
+	if !c.isClassic() {
+		t.Fatal("not classic after setting a classic-only feature")
+	}
+	for name, f := range map[string]*big.Int{
+		"eip155Block":    c.EIP155Block,
+		"byzantiumBlock": c.ByzantiumBlock,
+	} {
+		if f != nil {
+			t.Errorf("%s not moved to its classic fork", name)
+		}
+	}
+	if v := c.GetEIP155Transition(); v == nil || *v != diehard {
+		t.Errorf("eip155: want %d, got %v", diehard, v)
+	}
+	if v := c.GetEIP140Transition(); v == nil || *v != atlantis {
+		t.Errorf("eip140: want %d, got %v", atlantis, v)
+	}
+	if v := c.GetEthashECIP1017Transition(); v == nil || *v != gotham {
+		t.Errorf("ecip1017: want %d, got %v", gotham, v)
+	}
+
+	// Features Ethereum Classic has not activated cannot be configured.
+	london := uint64(12965000)
+	if err := c.SetEIP1559Transition(&london); err == nil {
+		t.Error("eip1559: want error for classic configuration")
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2019 The multi-geth Authors
+// This file is part of the multi-geth library.
+//
+// The multi-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The multi-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the multi-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package parity
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/common/math"
+	"github.com/yuriy0803/core-geth1/params/confp"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+)
+
+// ParityChainSpec is the chain specification format used by Parity and OpenEthereum.
+// https://openethereum.github.io/Chain-specification
+//
+// Fields which have no counterpart in OpenEthereum's schema are core-geth extensions.
+// They are all optional, and are only written when their value is set, so that
+// specs describing features OpenEthereum supports remain readable by OpenEthereum.
+type ParityChainSpec struct {
+	Name    string `json:"name"`
+	DataDir string `json:"dataDir,omitempty"`
+
+	Engine struct {
+		Ethash *ParityChainSpecEthashEngine `json:"Ethash,omitempty"`
+		Clique *ParityChainSpecCliqueEngine `json:"clique,omitempty"`
+		Lyra2  *ParityChainSpecLyra2Engine  `json:"lyra2,omitempty"` // core-geth extension
+	} `json:"engine"`
+
+	Params ParityChainSpecParams `json:"params"`
+
+	Genesis struct {
+		Seal struct {
+			Ethereum struct {
+				Nonce   hexutil.Bytes `json:"nonce"`
+				MixHash common.Hash   `json:"mixHash"`
+			} `json:"ethereum"`
+		} `json:"seal"`
+
+		Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+		Author     common.Address        `json:"author"`
+		Timestamp  math.HexOrDecimal64   `json:"timestamp"`
+		ParentHash common.Hash           `json:"parentHash"`
+		ExtraData  hexutil.Bytes         `json:"extraData"`
+		GasLimit   math.HexOrDecimal64   `json:"gasLimit"`
+	} `json:"genesis"`
+
+	Nodes    []string                                   `json:"nodes,omitempty"`
+	Accounts map[common.Address]*ParityChainSpecAccount `json:"accounts"`
+}
+
+// ParityChainSpecParams holds the engine-agnostic protocol parameters of a ParityChainSpec.
+type ParityChainSpecParams struct {
+	AccountStartNonce    *math.HexOrDecimal64  `json:"accountStartNonce,omitempty"`
+	MaximumExtraDataSize *math.HexOrDecimal64  `json:"maximumExtraDataSize,omitempty"`
+	MinGasLimit          *math.HexOrDecimal64  `json:"minGasLimit,omitempty"`
+	GasLimitBoundDivisor *math.HexOrDecimal64  `json:"gasLimitBoundDivisor,omitempty"`
+	NetworkID            *math.HexOrDecimal64  `json:"networkID,omitempty"`
+	ChainID              *math.HexOrDecimal256 `json:"chainID,omitempty"`
+	SubprotocolName      string                `json:"subprotocolName,omitempty"`
+
+	MaxCodeSize           *math.HexOrDecimal64 `json:"maxCodeSize,omitempty"`
+	MaxCodeSizeTransition *math.HexOrDecimal64 `json:"maxCodeSizeTransition,omitempty"`
+
+	EIP150Transition          *math.HexOrDecimal64 `json:"eip150Transition,omitempty"`
+	EIP160Transition          *math.HexOrDecimal64 `json:"eip160Transition,omitempty"`
+	EIP161abcTransition       *math.HexOrDecimal64 `json:"eip161abcTransition,omitempty"`
+	EIP161dTransition         *math.HexOrDecimal64 `json:"eip161dTransition,omitempty"`
+	EIP155Transition          *math.HexOrDecimal64 `json:"eip155Transition,omitempty"`
+	EIP140Transition          *math.HexOrDecimal64 `json:"eip140Transition,omitempty"`
+	EIP211Transition          *math.HexOrDecimal64 `json:"eip211Transition,omitempty"`
+	EIP214Transition          *math.HexOrDecimal64 `json:"eip214Transition,omitempty"`
+	EIP658Transition          *math.HexOrDecimal64 `json:"eip658Transition,omitempty"`
+	EIP145Transition          *math.HexOrDecimal64 `json:"eip145Transition,omitempty"`
+	EIP1014Transition         *math.HexOrDecimal64 `json:"eip1014Transition,omitempty"`
+	EIP1052Transition         *math.HexOrDecimal64 `json:"eip1052Transition,omitempty"`
+	EIP1283Transition         *math.HexOrDecimal64 `json:"eip1283Transition,omitempty"`
+	EIP1283DisableTransition  *math.HexOrDecimal64 `json:"eip1283DisableTransition,omitempty"`
+	EIP1283ReenableTransition *math.HexOrDecimal64 `json:"eip1283ReenableTransition,omitempty"` // EIP2200
+	EIP1706Transition         *math.HexOrDecimal64 `json:"eip1706Transition,omitempty"`
+	EIP1344Transition         *math.HexOrDecimal64 `json:"eip1344Transition,omitempty"`
+	EIP1884Transition         *math.HexOrDecimal64 `json:"eip1884Transition,omitempty"`
+	EIP2028Transition         *math.HexOrDecimal64 `json:"eip2028Transition,omitempty"`
+	EIP2315Transition         *math.HexOrDecimal64 `json:"eip2315Transition,omitempty"`
+	EIP2929Transition         *math.HexOrDecimal64 `json:"eip2929Transition,omitempty"`
+	EIP2930Transition         *math.HexOrDecimal64 `json:"eip2930Transition,omitempty"`
+	EIP1559Transition         *math.HexOrDecimal64 `json:"eip1559Transition,omitempty"`
+	EIP3198Transition         *math.HexOrDecimal64 `json:"eip3198Transition,omitempty"`
+	EIP3529Transition         *math.HexOrDecimal64 `json:"eip3529Transition,omitempty"`
+	EIP3541Transition         *math.HexOrDecimal64 `json:"eip3541Transition,omitempty"`
+
+	EIP1559BaseFeeMaxChangeDenominator *math.HexOrDecimal64 `json:"eip1559BaseFeeMaxChangeDenominator,omitempty"`
+	EIP1559ElasticityMultiplier        *math.HexOrDecimal64 `json:"eip1559ElasticityMultiplier,omitempty"`
+
+	ForkBlock     *math.HexOrDecimal64 `json:"forkBlock,omitempty"`
+	ForkCanonHash *common.Hash         `json:"forkCanonHash,omitempty"`
+
+	// core-geth extensions.
+	SupportedProtocolVersions    []uint               `json:"supportedProtocolVersions,omitempty"`
+	EIP2200DisableTransition     *math.HexOrDecimal64 `json:"eip2200DisableTransition,omitempty"`
+	EIP2537Transition            *math.HexOrDecimal64 `json:"eip2537Transition,omitempty"`
+	EIP2718Transition            *math.HexOrDecimal64 `json:"eip2718Transition,omitempty"`
+	EIP4399Transition            *math.HexOrDecimal64 `json:"eip4399Transition,omitempty"`
+	ECIP1080Transition           *math.HexOrDecimal64 `json:"ecip1080Transition,omitempty"`
+	ECBP1100Transition           *math.HexOrDecimal64 `json:"ecbp1100Transition,omitempty"`
+	ECBP1100DeactivateTransition *math.HexOrDecimal64 `json:"ecbp1100DeactivateTransition,omitempty"`
+	MergeNetsplitTransition      *math.HexOrDecimal64 `json:"mergeNetsplitTransition,omitempty"`
+
+	// Shanghai, expressed as block numbers (core-geth extensions).
+	EIP3651Transition *math.HexOrDecimal64 `json:"eip3651Transition,omitempty"`
+	EIP3855Transition *math.HexOrDecimal64 `json:"eip3855Transition,omitempty"`
+	EIP3860Transition *math.HexOrDecimal64 `json:"eip3860Transition,omitempty"`
+	EIP4895Transition *math.HexOrDecimal64 `json:"eip4895Transition,omitempty"`
+	EIP6049Transition *math.HexOrDecimal64 `json:"eip6049Transition,omitempty"`
+
+	// Shanghai and Cancun, expressed as block timestamps (core-geth extensions).
+	EIP3651TransitionTimestamp *math.HexOrDecimal64 `json:"eip3651TransitionTimestamp,omitempty"`
+	EIP3855TransitionTimestamp *math.HexOrDecimal64 `json:"eip3855TransitionTimestamp,omitempty"`
+	EIP3860TransitionTimestamp *math.HexOrDecimal64 `json:"eip3860TransitionTimestamp,omitempty"`
+	EIP4895TransitionTimestamp *math.HexOrDecimal64 `json:"eip4895TransitionTimestamp,omitempty"`
+	EIP6049TransitionTimestamp *math.HexOrDecimal64 `json:"eip6049TransitionTimestamp,omitempty"`
+	EIP4844TransitionTimestamp *math.HexOrDecimal64 `json:"eip4844TransitionTimestamp,omitempty"`
+	EIP1153TransitionTimestamp *math.HexOrDecimal64 `json:"eip1153TransitionTimestamp,omitempty"`
+	EIP5656TransitionTimestamp *math.HexOrDecimal64 `json:"eip5656TransitionTimestamp,omitempty"`
+	EIP6780TransitionTimestamp *math.HexOrDecimal64 `json:"eip6780TransitionTimestamp,omitempty"`
+}
+
+type ParityChainSpecEthashEngine struct {
+	Params ParityChainSpecEthashParams `json:"params"`
+}
+
+type ParityChainSpecEthashParams struct {
+	MinimumDifficulty      *math.HexOrDecimal256 `json:"minimumDifficulty,omitempty"`
+	DifficultyBoundDivisor *math.HexOrDecimal256 `json:"difficultyBoundDivisor,omitempty"`
+	DurationLimit          *math.HexOrDecimal256 `json:"durationLimit,omitempty"`
+
+	HomesteadTransition *math.HexOrDecimal64 `json:"homesteadTransition,omitempty"`
+	EIP100bTransition   *math.HexOrDecimal64 `json:"eip100bTransition,omitempty"`
+
+	DaoHardforkTransition  *math.HexOrDecimal64 `json:"daoHardforkTransition,omitempty"`
+	DaoHardforkBeneficiary *common.Address      `json:"daoHardforkBeneficiary,omitempty"`
+	DaoHardforkAccounts    []common.Address     `json:"daoHardforkAccounts,omitempty"`
+
+	BombDefuseTransition       *math.HexOrDecimal64 `json:"bombDefuseTransition,omitempty"`
+	ECIP1010PauseTransition    *math.HexOrDecimal64 `json:"ecip1010PauseTransition,omitempty"`
+	ECIP1010ContinueTransition *math.HexOrDecimal64 `json:"ecip1010ContinueTransition,omitempty"`
+	ECIP1017EraRounds          *math.HexOrDecimal64 `json:"ecip1017EraRounds,omitempty"`
+	ECIP1099Transition         *math.HexOrDecimal64 `json:"ecip1099Transition,omitempty"`
+
+	BlockReward          ctypes.Uint64BigValOrMapHex   `json:"blockReward,omitempty"`
+	DifficultyBombDelays ctypes.Uint64BigMapEncodesHex `json:"difficultyBombDelays,omitempty"`
+
+	// core-geth extensions.
+	ECIP1017Transition            *math.HexOrDecimal64  `json:"ecip1017Transition,omitempty"`
+	TerminalTotalDifficulty       *math.HexOrDecimal256 `json:"terminalTotalDifficulty,omitempty"`
+	TerminalTotalDifficultyPassed bool                  `json:"terminalTotalDifficultyPassed,omitempty"`
+}
+
+type ParityChainSpecCliqueEngine struct {
+	Params struct {
+		Period uint64 `json:"period"`
+		Epoch  uint64 `json:"epoch"`
+	} `json:"params"`
+}
+
+// ParityChainSpecLyra2Engine configures the Lyra2 proof-of-work engine used by MintMe.
+// It is a core-geth extension, and is not understood by Parity or OpenEthereum.
+type ParityChainSpecLyra2Engine struct {
+	Params struct {
+		NonceTransition *math.HexOrDecimal64 `json:"lyra2NonceTransition,omitempty"`
+	} `json:"params"`
+}
+
+type ParityChainSpecAccount struct {
+	Balance *math.HexOrDecimal256       `json:"balance,omitempty"`
+	Nonce   math.HexOrDecimal64         `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+	Builtin *ParityChainSpecBuiltin     `json:"builtin,omitempty"`
+}
+
+// isBuiltinOnly tells if the account exists only to configure a builtin (precompile) contract,
+// in which case it does not exist in the genesis state.
+func (a *ParityChainSpecAccount) isBuiltinOnly() bool {
+	return a.Balance == nil && a.Nonce == 0 && len(a.Code) == 0 && len(a.Storage) == 0
+}
+
+// ParityChainSpecBuiltin configures a builtin (precompiled) contract.
+//
+// Pricing is given either with an activation block ('activate_at') and a single price,
+// or as a map of activation blocks to prices, in which case the lowest
+// block defines the activation of the builtin.
+// The latter form is normalized on unmarshaling, and is the only one written.
+type ParityChainSpecBuiltin struct {
+	Name    string
+	Pricing map[uint64]ParityChainSpecPricing
+}
+
+type ParityChainSpecPricing struct {
+	Linear       *ParityChainSpecLinearPricing       `json:"linear,omitempty"`
+	ModExp       *ParityChainSpecModExpPricing       `json:"modexp,omitempty"`
+	ModExp2565   *struct{}                           `json:"modexp2565,omitempty"`
+	AltBnConstOp *ParityChainSpecAltBnConstOpPricing `json:"alt_bn128_const_operations,omitempty"`
+	AltBnPairing *ParityChainSpecAltBnPairingPricing `json:"alt_bn128_pairing,omitempty"`
+	Blake2F      *ParityChainSpecBlake2FPricing      `json:"blake2_f,omitempty"`
+}
+
+type ParityChainSpecLinearPricing struct {
+	Base uint64 `json:"base"`
+	Word uint64 `json:"word"`
+}
+
+type ParityChainSpecModExpPricing struct {
+	Divisor uint64 `json:"divisor"`
+}
+
+type ParityChainSpecAltBnConstOpPricing struct {
+	Price uint64 `json:"price"`
+}
+
+type ParityChainSpecAltBnPairingPricing struct {
+	Base uint64 `json:"base"`
+	Pair uint64 `json:"pair"`
+}
+
+type ParityChainSpecBlake2FPricing struct {
+	GasPerRound uint64 `json:"gas_per_round"`
+}
+
+type parityChainSpecBuiltinJSON struct {
+	Name       string                     `json:"name"`
+	ActivateAt *math.HexOrDecimal64       `json:"activate_at,omitempty"`
+	Pricing    map[string]json.RawMessage `json:"pricing"`
+}
+
+type parityChainSpecPricingAt struct {
+	Price ParityChainSpecPricing `json:"price"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (b *ParityChainSpecBuiltin) UnmarshalJSON(input []byte) error {
+	var dec parityChainSpecBuiltinJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	b.Name = dec.Name
+	b.Pricing = make(map[uint64]ParityChainSpecPricing)
+
+	// The legacy form keys the pricing by its type, eg. {"linear": {"base": 3000, "word": 0}}.
+	// The modern form keys prices by activation block, eg. {"0": {"price": {"linear": ...}}}.
+	legacy := false
+	for k := range dec.Pricing {
+		if _, ok := math.ParseUint64(k); !ok {
+			legacy = true
+			break
+		}
+	}
+	if legacy {
+		raw, err := json.Marshal(dec.Pricing)
+		if err != nil {
+			return err
+		}
+		var p ParityChainSpecPricing
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return err
+		}
+		var at uint64
+		if dec.ActivateAt != nil {
+			at = uint64(*dec.ActivateAt)
+		}
+		b.Pricing[at] = p
+		return nil
+	}
+	for k, v := range dec.Pricing {
+		at, _ := math.ParseUint64(k)
+		var p parityChainSpecPricingAt
+		if err := json.Unmarshal(v, &p); err != nil {
+			return fmt.Errorf("builtin %s pricing at %s: %v", dec.Name, k, err)
+		}
+		b.Pricing[at] = p.Price
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (b ParityChainSpecBuiltin) MarshalJSON() ([]byte, error) {
+	pricing := make(map[string]parityChainSpecPricingAt, len(b.Pricing))
+	for k, v := range b.Pricing {
+		pricing[fmt.Sprintf("%#x", k)] = parityChainSpecPricingAt{Price: v}
+	}
+	return json.Marshal(struct {
+		Name    string                              `json:"name"`
+		Pricing map[string]parityChainSpecPricingAt `json:"pricing"`
+	}{b.Name, pricing})
+}
+
+// activations returns the sorted activation blocks of the builtin prices.
+func (b *ParityChainSpecBuiltin) activations() []uint64 {
+	var at []uint64
+	for k := range b.Pricing {
+		at = append(at, k)
+	}
+	sort.Slice(at, func(i, j int) bool {
+		return at[i] < at[j]
+	})
+	return at
+}
+
+// String implements the fmt.Stringer interface.
+func (spec *ParityChainSpec) String() string {
+	engine := "unknown"
+	switch {
+	case spec.Engine.Ethash != nil:
+		engine = "ethash"
+	case spec.Engine.Clique != nil:
+		engine = "clique"
+	case spec.Engine.Lyra2 != nil:
+		engine = "lyra2"
+	}
+	trxs, names := confp.Transitions(spec)
+	str := fmt.Sprintf("Name: %s, NetworkID: %v, ChainID: %v Engine: %v ",
+		spec.Name,
+		*spec.GetNetworkID(),
+		spec.GetChainID(),
+		engine)
+
+	for i, trx := range trxs {
+		if trx() != nil {
+			str += fmt.Sprintf("%s: %d ", strings.TrimSuffix(strings.TrimPrefix(names[i], "Get"), "Transition"), *trx())
+		}
+	}
+	return str
+}
+
+func newU64(u uint64) *uint64 {
+	return &u
+}
+
+func hexU64(n *math.HexOrDecimal64) *uint64 {
+	if n == nil {
+		return nil
+	}
+	return newU64(uint64(*n))
+}
+
+func newHexU64(n *uint64) *math.HexOrDecimal64 {
+	if n == nil {
+		return nil
+	}
+	h := math.HexOrDecimal64(*n)
+	return &h
+}
+
+func hexBig(i *math.HexOrDecimal256) *big.Int {
+	if i == nil {
+		return nil
+	}
+	return i.ToInt()
+}
+
+func newHexBig(i *big.Int) *math.HexOrDecimal256 {
+	if i == nil {
+		return nil
+	}
+	return (*math.HexOrDecimal256)(new(big.Int).Set(i))
+}
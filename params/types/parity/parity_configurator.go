@@ -0,0 +1,1557 @@
+// Copyright 2019 The multi-geth Authors
+// This file is part of the multi-geth library.
+//
+// The multi-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The multi-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the multi-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+/*
+This file contains logic implementing the Configurator interface for Parity's chain specification.
+
+Notes:
+Parity configures precompiled contracts as 'builtin' accounts, so the EIPs
+introducing (or repricing) precompiles are read from and written to the accounts
+of the spec. Builtin-only accounts are not part of the genesis state, and are
+not visited by ForEachAccount.
+
+As with the core-geth data type, EIPs 649, 1234, 2384, 3554, 4345 and 5133 are inferred
+from the block reward and difficulty bomb delay schedules.
+
+Homestead is an Ethash engine parameter. Parity applies Homestead rules from genesis
+for all other engines.
+*/
+
+package parity
+
+import (
+	"encoding/binary"
+	"math/big"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/common/hexutil"
+	"github.com/yuriy0803/core-geth1/common/math"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+	"github.com/yuriy0803/core-geth1/params/types/internal"
+	"github.com/yuriy0803/core-geth1/params/vars"
+)
+
+var (
+	builtinModExpAddress       = common.BytesToAddress([]byte{5})
+	builtinAltBnAddAddress     = common.BytesToAddress([]byte{6})
+	builtinAltBnMulAddress     = common.BytesToAddress([]byte{7})
+	builtinAltBnPairingAddress = common.BytesToAddress([]byte{8})
+	builtinBlake2FAddress      = common.BytesToAddress([]byte{9})
+)
+
+func (spec *ParityChainSpec) GetAccountStartNonce() *uint64 {
+	if spec.Params.AccountStartNonce == nil {
+		return internal.GlobalConfigurator().GetAccountStartNonce()
+	}
+	return hexU64(spec.Params.AccountStartNonce)
+}
+
+func (spec *ParityChainSpec) SetAccountStartNonce(n *uint64) error {
+	spec.Params.AccountStartNonce = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetMaximumExtraDataSize() *uint64 {
+	if spec.Params.MaximumExtraDataSize == nil {
+		return internal.GlobalConfigurator().GetMaximumExtraDataSize()
+	}
+	return hexU64(spec.Params.MaximumExtraDataSize)
+}
+
+func (spec *ParityChainSpec) SetMaximumExtraDataSize(n *uint64) error {
+	spec.Params.MaximumExtraDataSize = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetMinGasLimit() *uint64 {
+	if spec.Params.MinGasLimit == nil {
+		return internal.GlobalConfigurator().GetMinGasLimit()
+	}
+	return hexU64(spec.Params.MinGasLimit)
+}
+
+func (spec *ParityChainSpec) SetMinGasLimit(n *uint64) error {
+	spec.Params.MinGasLimit = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGasLimitBoundDivisor() *uint64 {
+	if spec.Params.GasLimitBoundDivisor == nil {
+		return internal.GlobalConfigurator().GetGasLimitBoundDivisor()
+	}
+	return hexU64(spec.Params.GasLimitBoundDivisor)
+}
+
+func (spec *ParityChainSpec) SetGasLimitBoundDivisor(n *uint64) error {
+	spec.Params.GasLimitBoundDivisor = newHexU64(n)
+	return nil
+}
+
+// GetNetworkID returns the network id, falling back to the chain id
+// (and the other way around in GetChainID), as Parity does.
+func (spec *ParityChainSpec) GetNetworkID() *uint64 {
+	if spec.Params.NetworkID != nil {
+		return hexU64(spec.Params.NetworkID)
+	}
+	if spec.Params.ChainID != nil {
+		return newU64(spec.Params.ChainID.ToInt().Uint64())
+	}
+	return newU64(vars.DefaultNetworkID)
+}
+
+func (spec *ParityChainSpec) SetNetworkID(n *uint64) error {
+	if n == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Params.NetworkID = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetChainID() *big.Int {
+	if spec.Params.ChainID != nil {
+		return hexBig(spec.Params.ChainID)
+	}
+	if spec.Params.NetworkID != nil {
+		return new(big.Int).SetUint64(uint64(*spec.Params.NetworkID))
+	}
+	return nil
+}
+
+func (spec *ParityChainSpec) SetChainID(i *big.Int) error {
+	spec.Params.ChainID = newHexBig(i)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetSupportedProtocolVersions() []uint {
+	if len(spec.Params.SupportedProtocolVersions) == 0 {
+		return vars.DefaultProtocolVersions
+	}
+	return spec.Params.SupportedProtocolVersions
+}
+
+func (spec *ParityChainSpec) SetSupportedProtocolVersions(p []uint) error {
+	spec.Params.SupportedProtocolVersions = p
+	return nil
+}
+
+func (spec *ParityChainSpec) GetMaxCodeSize() *uint64 {
+	if spec.Params.MaxCodeSize == nil {
+		return internal.GlobalConfigurator().GetMaxCodeSize()
+	}
+	return hexU64(spec.Params.MaxCodeSize)
+}
+
+func (spec *ParityChainSpec) SetMaxCodeSize(n *uint64) error {
+	spec.Params.MaxCodeSize = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetElasticityMultiplier() uint64 {
+	if spec.Params.EIP1559ElasticityMultiplier == nil {
+		return internal.GlobalConfigurator().GetElasticityMultiplier()
+	}
+	return uint64(*spec.Params.EIP1559ElasticityMultiplier)
+}
+
+func (spec *ParityChainSpec) SetElasticityMultiplier(n uint64) error {
+	spec.Params.EIP1559ElasticityMultiplier = newHexU64(&n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetBaseFeeChangeDenominator() uint64 {
+	if spec.Params.EIP1559BaseFeeMaxChangeDenominator == nil {
+		return internal.GlobalConfigurator().GetBaseFeeChangeDenominator()
+	}
+	return uint64(*spec.Params.EIP1559BaseFeeMaxChangeDenominator)
+}
+
+func (spec *ParityChainSpec) SetBaseFeeChangeDenominator(n uint64) error {
+	spec.Params.EIP1559BaseFeeMaxChangeDenominator = newHexU64(&n)
+	return nil
+}
+
+// getHomestead returns the Homestead transition for the configured engine.
+func (spec *ParityChainSpec) getHomestead() *uint64 {
+	switch {
+	case spec.Engine.Ethash != nil:
+		return hexU64(spec.Engine.Ethash.Params.HomesteadTransition)
+	case spec.Engine.Clique != nil, spec.Engine.Lyra2 != nil:
+		return newU64(0)
+	}
+	return nil
+}
+
+// setHomestead sets the Homestead transition for the configured engine.
+// If no engine is configured yet, Ethash is assumed.
+func (spec *ParityChainSpec) setHomestead(n *uint64) error {
+	if spec.Engine.Ethash == nil && (spec.Engine.Clique != nil || spec.Engine.Lyra2 != nil) {
+		if n == nil || *n == 0 {
+			return nil
+		}
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if spec.Engine.Ethash == nil {
+		spec.Engine.Ethash = new(ParityChainSpecEthashEngine)
+	}
+	spec.Engine.Ethash.Params.HomesteadTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2Transition() *uint64 {
+	return spec.getHomestead()
+}
+
+func (spec *ParityChainSpec) SetEIP2Transition(n *uint64) error {
+	return spec.setHomestead(n)
+}
+
+func (spec *ParityChainSpec) GetEIP7Transition() *uint64 {
+	return spec.getHomestead()
+}
+
+func (spec *ParityChainSpec) SetEIP7Transition(n *uint64) error {
+	return spec.setHomestead(n)
+}
+
+func (spec *ParityChainSpec) GetEIP150Transition() *uint64 {
+	return hexU64(spec.Params.EIP150Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP150Transition(n *uint64) error {
+	spec.Params.EIP150Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP152Transition() *uint64 {
+	return spec.getBuiltinActivation(builtinBlake2FAddress)
+}
+
+func (spec *ParityChainSpec) SetEIP152Transition(n *uint64) error {
+	spec.setBuiltinActivation(builtinBlake2FAddress, "blake2_f", n, ParityChainSpecPricing{
+		Blake2F: &ParityChainSpecBlake2FPricing{GasPerRound: 1},
+	})
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP160Transition() *uint64 {
+	return hexU64(spec.Params.EIP160Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP160Transition(n *uint64) error {
+	spec.Params.EIP160Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP161abcTransition() *uint64 {
+	return hexU64(spec.Params.EIP161abcTransition)
+}
+
+func (spec *ParityChainSpec) SetEIP161abcTransition(n *uint64) error {
+	spec.Params.EIP161abcTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP161dTransition() *uint64 {
+	return hexU64(spec.Params.EIP161dTransition)
+}
+
+func (spec *ParityChainSpec) SetEIP161dTransition(n *uint64) error {
+	spec.Params.EIP161dTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP170Transition() *uint64 {
+	return hexU64(spec.Params.MaxCodeSizeTransition)
+}
+
+func (spec *ParityChainSpec) SetEIP170Transition(n *uint64) error {
+	spec.Params.MaxCodeSizeTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP155Transition() *uint64 {
+	return hexU64(spec.Params.EIP155Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP155Transition(n *uint64) error {
+	spec.Params.EIP155Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP140Transition() *uint64 {
+	return hexU64(spec.Params.EIP140Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP140Transition(n *uint64) error {
+	spec.Params.EIP140Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP198Transition() *uint64 {
+	return spec.getBuiltinActivation(builtinModExpAddress)
+}
+
+func (spec *ParityChainSpec) SetEIP198Transition(n *uint64) error {
+	spec.setBuiltinActivation(builtinModExpAddress, "modexp", n, ParityChainSpecPricing{
+		ModExp: &ParityChainSpecModExpPricing{Divisor: 20},
+	})
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP211Transition() *uint64 {
+	return hexU64(spec.Params.EIP211Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP211Transition(n *uint64) error {
+	spec.Params.EIP211Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP212Transition() *uint64 {
+	return spec.getBuiltinActivation(builtinAltBnPairingAddress)
+}
+
+func (spec *ParityChainSpec) SetEIP212Transition(n *uint64) error {
+	spec.setBuiltinActivation(builtinAltBnPairingAddress, "alt_bn128_pairing", n, ParityChainSpecPricing{
+		AltBnPairing: &ParityChainSpecAltBnPairingPricing{
+			Base: vars.Bn256PairingBaseGasByzantium,
+			Pair: vars.Bn256PairingPerPointGasByzantium,
+		},
+	})
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP213Transition() *uint64 {
+	return spec.getBuiltinActivation(builtinAltBnAddAddress)
+}
+
+func (spec *ParityChainSpec) SetEIP213Transition(n *uint64) error {
+	spec.setBuiltinActivation(builtinAltBnAddAddress, "alt_bn128_add", n, ParityChainSpecPricing{
+		AltBnConstOp: &ParityChainSpecAltBnConstOpPricing{Price: vars.Bn256AddGasByzantium},
+	})
+	spec.setBuiltinActivation(builtinAltBnMulAddress, "alt_bn128_mul", n, ParityChainSpecPricing{
+		AltBnConstOp: &ParityChainSpecAltBnConstOpPricing{Price: vars.Bn256ScalarMulGasByzantium},
+	})
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP214Transition() *uint64 {
+	return hexU64(spec.Params.EIP214Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP214Transition(n *uint64) error {
+	spec.Params.EIP214Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP658Transition() *uint64 {
+	return hexU64(spec.Params.EIP658Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP658Transition(n *uint64) error {
+	spec.Params.EIP658Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP145Transition() *uint64 {
+	return hexU64(spec.Params.EIP145Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP145Transition(n *uint64) error {
+	spec.Params.EIP145Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1014Transition() *uint64 {
+	return hexU64(spec.Params.EIP1014Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP1014Transition(n *uint64) error {
+	spec.Params.EIP1014Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1052Transition() *uint64 {
+	return hexU64(spec.Params.EIP1052Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP1052Transition(n *uint64) error {
+	spec.Params.EIP1052Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1283Transition() *uint64 {
+	return hexU64(spec.Params.EIP1283Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP1283Transition(n *uint64) error {
+	spec.Params.EIP1283Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1283DisableTransition() *uint64 {
+	return hexU64(spec.Params.EIP1283DisableTransition)
+}
+
+func (spec *ParityChainSpec) SetEIP1283DisableTransition(n *uint64) error {
+	spec.Params.EIP1283DisableTransition = newHexU64(n)
+	return nil
+}
+
+// GetEIP1108Transition returns the block at which the alt_bn128 builtins are repriced.
+func (spec *ParityChainSpec) GetEIP1108Transition() *uint64 {
+	return spec.getBuiltinPriceActivation(builtinAltBnAddAddress, func(p ParityChainSpecPricing) bool {
+		return p.AltBnConstOp != nil && p.AltBnConstOp.Price == vars.Bn256AddGasIstanbul
+	})
+}
+
+func (spec *ParityChainSpec) SetEIP1108Transition(n *uint64) error {
+	spec.setBuiltinPrice(builtinAltBnAddAddress, "alt_bn128_add", n, ParityChainSpecPricing{
+		AltBnConstOp: &ParityChainSpecAltBnConstOpPricing{Price: vars.Bn256AddGasIstanbul},
+	})
+	spec.setBuiltinPrice(builtinAltBnMulAddress, "alt_bn128_mul", n, ParityChainSpecPricing{
+		AltBnConstOp: &ParityChainSpecAltBnConstOpPricing{Price: vars.Bn256ScalarMulGasIstanbul},
+	})
+	spec.setBuiltinPrice(builtinAltBnPairingAddress, "alt_bn128_pairing", n, ParityChainSpecPricing{
+		AltBnPairing: &ParityChainSpecAltBnPairingPricing{
+			Base: vars.Bn256PairingBaseGasIstanbul,
+			Pair: vars.Bn256PairingPerPointGasIstanbul,
+		},
+	})
+	return nil
+}
+
+// GetEIP2200Transition maps to Parity's eip1283ReenableTransition.
+func (spec *ParityChainSpec) GetEIP2200Transition() *uint64 {
+	return hexU64(spec.Params.EIP1283ReenableTransition)
+}
+
+func (spec *ParityChainSpec) SetEIP2200Transition(n *uint64) error {
+	spec.Params.EIP1283ReenableTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2200DisableTransition() *uint64 {
+	return hexU64(spec.Params.EIP2200DisableTransition)
+}
+
+func (spec *ParityChainSpec) SetEIP2200DisableTransition(n *uint64) error {
+	spec.Params.EIP2200DisableTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1344Transition() *uint64 {
+	return hexU64(spec.Params.EIP1344Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP1344Transition(n *uint64) error {
+	spec.Params.EIP1344Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1884Transition() *uint64 {
+	return hexU64(spec.Params.EIP1884Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP1884Transition(n *uint64) error {
+	spec.Params.EIP1884Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2028Transition() *uint64 {
+	return hexU64(spec.Params.EIP2028Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP2028Transition(n *uint64) error {
+	spec.Params.EIP2028Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetECIP1080Transition() *uint64 {
+	return hexU64(spec.Params.ECIP1080Transition)
+}
+
+func (spec *ParityChainSpec) SetECIP1080Transition(n *uint64) error {
+	spec.Params.ECIP1080Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1706Transition() *uint64 {
+	return hexU64(spec.Params.EIP1706Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP1706Transition(n *uint64) error {
+	spec.Params.EIP1706Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2537Transition() *uint64 {
+	return hexU64(spec.Params.EIP2537Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP2537Transition(n *uint64) error {
+	spec.Params.EIP2537Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetECBP1100Transition() *uint64 {
+	return hexU64(spec.Params.ECBP1100Transition)
+}
+
+func (spec *ParityChainSpec) SetECBP1100Transition(n *uint64) error {
+	spec.Params.ECBP1100Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetECBP1100DeactivateTransition() *uint64 {
+	return hexU64(spec.Params.ECBP1100DeactivateTransition)
+}
+
+func (spec *ParityChainSpec) SetECBP1100DeactivateTransition(n *uint64) error {
+	spec.Params.ECBP1100DeactivateTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2315Transition() *uint64 {
+	return hexU64(spec.Params.EIP2315Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP2315Transition(n *uint64) error {
+	spec.Params.EIP2315Transition = newHexU64(n)
+	return nil
+}
+
+// GetEIP2565Transition returns the block at which the modexp builtin is repriced.
+func (spec *ParityChainSpec) GetEIP2565Transition() *uint64 {
+	return spec.getBuiltinPriceActivation(builtinModExpAddress, func(p ParityChainSpecPricing) bool {
+		return p.ModExp2565 != nil
+	})
+}
+
+func (spec *ParityChainSpec) SetEIP2565Transition(n *uint64) error {
+	spec.setBuiltinPrice(builtinModExpAddress, "modexp", n, ParityChainSpecPricing{
+		ModExp2565: &struct{}{},
+	})
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2929Transition() *uint64 {
+	return hexU64(spec.Params.EIP2929Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP2929Transition(n *uint64) error {
+	spec.Params.EIP2929Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2930Transition() *uint64 {
+	return hexU64(spec.Params.EIP2930Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP2930Transition(n *uint64) error {
+	spec.Params.EIP2930Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2718Transition() *uint64 {
+	return hexU64(spec.Params.EIP2718Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP2718Transition(n *uint64) error {
+	spec.Params.EIP2718Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1559Transition() *uint64 {
+	return hexU64(spec.Params.EIP1559Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP1559Transition(n *uint64) error {
+	spec.Params.EIP1559Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP3541Transition() *uint64 {
+	return hexU64(spec.Params.EIP3541Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP3541Transition(n *uint64) error {
+	spec.Params.EIP3541Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP3529Transition() *uint64 {
+	return hexU64(spec.Params.EIP3529Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP3529Transition(n *uint64) error {
+	spec.Params.EIP3529Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP3198Transition() *uint64 {
+	return hexU64(spec.Params.EIP3198Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP3198Transition(n *uint64) error {
+	spec.Params.EIP3198Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP4399Transition() *uint64 {
+	return hexU64(spec.Params.EIP4399Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP4399Transition(n *uint64) error {
+	spec.Params.EIP4399Transition = newHexU64(n)
+	return nil
+}
+
+// GetEIP3651TransitionTime EIP3651: Warm COINBASE
+func (spec *ParityChainSpec) GetEIP3651TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP3651TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP3651TransitionTime(n *uint64) error {
+	spec.Params.EIP3651TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+// GetEIP3855TransitionTime EIP3855: PUSH0 instruction
+func (spec *ParityChainSpec) GetEIP3855TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP3855TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP3855TransitionTime(n *uint64) error {
+	spec.Params.EIP3855TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+// GetEIP3860TransitionTime EIP3860: Limit and meter initcode
+func (spec *ParityChainSpec) GetEIP3860TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP3860TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP3860TransitionTime(n *uint64) error {
+	spec.Params.EIP3860TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+// GetEIP4895TransitionTime EIP4895: Beacon chain push withdrawals as operations
+func (spec *ParityChainSpec) GetEIP4895TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP4895TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP4895TransitionTime(n *uint64) error {
+	spec.Params.EIP4895TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+// GetEIP6049TransitionTime EIP6049: Deprecate SELFDESTRUCT
+func (spec *ParityChainSpec) GetEIP6049TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP6049TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP6049TransitionTime(n *uint64) error {
+	spec.Params.EIP6049TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+// GetEIP3651Transition EIP3651: Warm COINBASE
+func (spec *ParityChainSpec) GetEIP3651Transition() *uint64 {
+	return hexU64(spec.Params.EIP3651Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP3651Transition(n *uint64) error {
+	spec.Params.EIP3651Transition = newHexU64(n)
+	return nil
+}
+
+// GetEIP3855Transition EIP3855: PUSH0 instruction
+func (spec *ParityChainSpec) GetEIP3855Transition() *uint64 {
+	return hexU64(spec.Params.EIP3855Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP3855Transition(n *uint64) error {
+	spec.Params.EIP3855Transition = newHexU64(n)
+	return nil
+}
+
+// GetEIP3860Transition EIP3860: Limit and meter initcode
+func (spec *ParityChainSpec) GetEIP3860Transition() *uint64 {
+	return hexU64(spec.Params.EIP3860Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP3860Transition(n *uint64) error {
+	spec.Params.EIP3860Transition = newHexU64(n)
+	return nil
+}
+
+// GetEIP4895Transition EIP4895: Beacon chain push withdrawals as operations
+func (spec *ParityChainSpec) GetEIP4895Transition() *uint64 {
+	return hexU64(spec.Params.EIP4895Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP4895Transition(n *uint64) error {
+	spec.Params.EIP4895Transition = newHexU64(n)
+	return nil
+}
+
+// GetEIP6049Transition EIP6049: Deprecate SELFDESTRUCT
+func (spec *ParityChainSpec) GetEIP6049Transition() *uint64 {
+	return hexU64(spec.Params.EIP6049Transition)
+}
+
+func (spec *ParityChainSpec) SetEIP6049Transition(n *uint64) error {
+	spec.Params.EIP6049Transition = newHexU64(n)
+	return nil
+}
+
+// GetEIP4844TransitionTime EIP4844: Shard Blob Transactions
+func (spec *ParityChainSpec) GetEIP4844TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP4844TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP4844TransitionTime(n *uint64) error {
+	spec.Params.EIP4844TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+// GetEIP1153TransitionTime EIP1153: Transient Storage opcodes
+func (spec *ParityChainSpec) GetEIP1153TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP1153TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP1153TransitionTime(n *uint64) error {
+	spec.Params.EIP1153TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+// GetEIP5656TransitionTime EIP5656: MCOPY - Memory copying instruction
+func (spec *ParityChainSpec) GetEIP5656TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP5656TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP5656TransitionTime(n *uint64) error {
+	spec.Params.EIP5656TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+// GetEIP6780TransitionTime EIP6780: SELFDESTRUCT only in same transaction
+func (spec *ParityChainSpec) GetEIP6780TransitionTime() *uint64 {
+	return hexU64(spec.Params.EIP6780TransitionTimestamp)
+}
+
+func (spec *ParityChainSpec) SetEIP6780TransitionTime(n *uint64) error {
+	spec.Params.EIP6780TransitionTimestamp = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetMergeVirtualTransition() *uint64 {
+	return hexU64(spec.Params.MergeNetsplitTransition)
+}
+
+func (spec *ParityChainSpec) SetMergeVirtualTransition(n *uint64) error {
+	spec.Params.MergeNetsplitTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) IsEnabled(fn func() *uint64, n *big.Int) bool {
+	f := fn()
+	if f == nil || n == nil {
+		return false
+	}
+	fnName := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	if strings.Contains(fnName, "ECBP1100Transition") {
+		deactivateTransition := spec.GetECBP1100DeactivateTransition()
+		if deactivateTransition != nil {
+			return big.NewInt(int64(*deactivateTransition)).Cmp(n) > 0 && big.NewInt(int64(*f)).Cmp(n) <= 0
+		}
+	}
+	return big.NewInt(int64(*f)).Cmp(n) <= 0
+}
+
+func (spec *ParityChainSpec) IsEnabledByTime(fn func() *uint64, n *uint64) bool {
+	f := fn()
+	if f == nil || n == nil {
+		return false
+	}
+	return *f <= *n
+}
+
+// GetForkCanonHash returns the hash Parity requires of peers at the spec's fork block.
+func (spec *ParityChainSpec) GetForkCanonHash(n uint64) common.Hash {
+	if spec.Params.ForkBlock == nil || spec.Params.ForkCanonHash == nil {
+		return common.Hash{}
+	}
+	if uint64(*spec.Params.ForkBlock) != n {
+		return common.Hash{}
+	}
+	return *spec.Params.ForkCanonHash
+}
+
+// SetForkCanonHash sets the fork block and hash.
+// Parity supports only one pair, so the lowest block number given is kept.
+func (spec *ParityChainSpec) SetForkCanonHash(n uint64, h common.Hash) error {
+	if spec.Params.ForkBlock != nil && uint64(*spec.Params.ForkBlock) < n {
+		return ctypes.ErrUnsupportedConfigNoop
+	}
+	spec.Params.ForkBlock = newHexU64(&n)
+	spec.Params.ForkCanonHash = &h
+	return nil
+}
+
+func (spec *ParityChainSpec) GetForkCanonHashes() map[uint64]common.Hash {
+	if spec.Params.ForkBlock == nil || spec.Params.ForkCanonHash == nil {
+		return nil
+	}
+	return map[uint64]common.Hash{
+		uint64(*spec.Params.ForkBlock): *spec.Params.ForkCanonHash,
+	}
+}
+
+func (spec *ParityChainSpec) GetConsensusEngineType() ctypes.ConsensusEngineT {
+	if spec.Engine.Ethash != nil {
+		return ctypes.ConsensusEngineT_Ethash
+	}
+	if spec.Engine.Clique != nil {
+		return ctypes.ConsensusEngineT_Clique
+	}
+	if spec.Engine.Lyra2 != nil {
+		return ctypes.ConsensusEngineT_Lyra2
+	}
+	return ctypes.ConsensusEngineT_Unknown
+}
+
+// MustSetConsensusEngineType sets the engine, keeping any existing parameters
+// for that engine (eg. the Ethash Homestead transition).
+func (spec *ParityChainSpec) MustSetConsensusEngineType(t ctypes.ConsensusEngineT) error {
+	switch t {
+	case ctypes.ConsensusEngineT_Ethash:
+		if spec.Engine.Ethash == nil {
+			spec.Engine.Ethash = new(ParityChainSpecEthashEngine)
+		}
+		spec.Engine.Clique = nil
+		spec.Engine.Lyra2 = nil
+		return nil
+	case ctypes.ConsensusEngineT_Clique:
+		if spec.Engine.Clique == nil {
+			spec.Engine.Clique = new(ParityChainSpecCliqueEngine)
+		}
+		spec.Engine.Ethash = nil
+		spec.Engine.Lyra2 = nil
+		return nil
+	case ctypes.ConsensusEngineT_Lyra2:
+		if spec.Engine.Lyra2 == nil {
+			spec.Engine.Lyra2 = new(ParityChainSpecLyra2Engine)
+		}
+		spec.Engine.Ethash = nil
+		spec.Engine.Clique = nil
+		return nil
+	default:
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+}
+
+func (spec *ParityChainSpec) GetIsDevMode() bool {
+	return false
+}
+
+func (spec *ParityChainSpec) SetDevMode(devMode bool) error {
+	if !devMode {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (spec *ParityChainSpec) GetEthashTerminalTotalDifficulty() *big.Int {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexBig(spec.Engine.Ethash.Params.TerminalTotalDifficulty)
+}
+
+func (spec *ParityChainSpec) SetEthashTerminalTotalDifficulty(n *big.Int) error {
+	if spec.Engine.Ethash == nil {
+		if n == nil {
+			return nil
+		}
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.TerminalTotalDifficulty = newHexBig(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashTerminalTotalDifficultyPassed() bool {
+	if spec.Engine.Ethash == nil {
+		return false
+	}
+	return spec.Engine.Ethash.Params.TerminalTotalDifficultyPassed
+}
+
+func (spec *ParityChainSpec) SetEthashTerminalTotalDifficultyPassed(t bool) error {
+	if spec.Engine.Ethash == nil {
+		if !t {
+			return nil
+		}
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.TerminalTotalDifficultyPassed = t
+	return nil
+}
+
+// IsTerminalPoWBlock returns whether the given block is the last block of PoW stage.
+func (spec *ParityChainSpec) IsTerminalPoWBlock(parentTotalDiff *big.Int, totalDiff *big.Int) bool {
+	terminalTotalDifficulty := spec.GetEthashTerminalTotalDifficulty()
+	if terminalTotalDifficulty == nil {
+		return false
+	}
+	return parentTotalDiff.Cmp(terminalTotalDifficulty) < 0 && totalDiff.Cmp(terminalTotalDifficulty) >= 0
+}
+
+func (spec *ParityChainSpec) GetEthashMinimumDifficulty() *big.Int {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	if spec.Engine.Ethash.Params.MinimumDifficulty == nil {
+		return internal.GlobalConfigurator().GetEthashMinimumDifficulty()
+	}
+	return hexBig(spec.Engine.Ethash.Params.MinimumDifficulty)
+}
+
+func (spec *ParityChainSpec) SetEthashMinimumDifficulty(i *big.Int) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.MinimumDifficulty = newHexBig(i)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashDifficultyBoundDivisor() *big.Int {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	if spec.Engine.Ethash.Params.DifficultyBoundDivisor == nil {
+		return internal.GlobalConfigurator().GetEthashDifficultyBoundDivisor()
+	}
+	return hexBig(spec.Engine.Ethash.Params.DifficultyBoundDivisor)
+}
+
+func (spec *ParityChainSpec) SetEthashDifficultyBoundDivisor(i *big.Int) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.DifficultyBoundDivisor = newHexBig(i)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashDurationLimit() *big.Int {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	if spec.Engine.Ethash.Params.DurationLimit == nil {
+		return internal.GlobalConfigurator().GetEthashDurationLimit()
+	}
+	return hexBig(spec.Engine.Ethash.Params.DurationLimit)
+}
+
+func (spec *ParityChainSpec) SetEthashDurationLimit(i *big.Int) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.DurationLimit = newHexBig(i)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashHomesteadTransition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.HomesteadTransition)
+}
+
+func (spec *ParityChainSpec) SetEthashHomesteadTransition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.HomesteadTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashEIP779Transition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.DaoHardforkTransition)
+}
+
+// SetEthashEIP779Transition sets the DAO hard fork block,
+// along with the beneficiary and drained accounts Parity requires.
+func (spec *ParityChainSpec) SetEthashEIP779Transition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.DaoHardforkTransition = newHexU64(n)
+	if n == nil {
+		spec.Engine.Ethash.Params.DaoHardforkBeneficiary = nil
+		spec.Engine.Ethash.Params.DaoHardforkAccounts = nil
+		return nil
+	}
+	beneficiary := vars.DAORefundContract
+	spec.Engine.Ethash.Params.DaoHardforkBeneficiary = &beneficiary
+	spec.Engine.Ethash.Params.DaoHardforkAccounts = vars.DAODrainList()
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashEIP649Transition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	// Get block number (key) from maps where EIP649 criteria is met.
+	diffN := ctypes.MapMeetsSpecification(
+		spec.Engine.Ethash.Params.DifficultyBombDelays,
+		ctypes.Uint64BigMapEncodesHex(spec.Engine.Ethash.Params.BlockReward),
+		vars.EIP649DifficultyBombDelay,
+		vars.EIP649FBlockReward,
+	)
+	if diffN == nil {
+		diffN = spec.GetEthashEIP1234Transition()
+	}
+	return diffN
+}
+
+func (spec *ParityChainSpec) SetEthashEIP649Transition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if n == nil {
+		return nil
+	}
+	if eip1234 := spec.GetEthashEIP1234Transition(); eip1234 != nil {
+		if *eip1234 <= *n {
+			return nil
+		}
+	}
+
+	spec.ensureExistingRewardSchedule()
+	spec.Engine.Ethash.Params.BlockReward[*n] = vars.EIP649FBlockReward
+
+	spec.ensureExistingDifficultySchedule()
+	spec.Engine.Ethash.Params.DifficultyBombDelays.SetValueTotalForHeight(n, vars.EIP649DifficultyBombDelay)
+
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashEIP1234Transition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	// Get block number (key) from maps where EIP1234 criteria is met.
+	return ctypes.MapMeetsSpecification(
+		spec.Engine.Ethash.Params.DifficultyBombDelays,
+		ctypes.Uint64BigMapEncodesHex(spec.Engine.Ethash.Params.BlockReward),
+		vars.EIP1234DifficultyBombDelay,
+		vars.EIP1234FBlockReward,
+	)
+}
+
+func (spec *ParityChainSpec) SetEthashEIP1234Transition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if n == nil {
+		return nil
+	}
+
+	// Block reward is a simple lookup; doesn't matter if overwrite or not.
+	spec.ensureExistingRewardSchedule()
+	spec.Engine.Ethash.Params.BlockReward[*n] = vars.EIP1234FBlockReward
+
+	spec.ensureExistingDifficultySchedule()
+	spec.Engine.Ethash.Params.DifficultyBombDelays.SetValueTotalForHeight(n, vars.EIP1234DifficultyBombDelay)
+
+	return nil
+}
+
+// getBombDelayTransition returns the block at which the difficulty bomb delay schedule
+// sums to the given total delay.
+func (spec *ParityChainSpec) getBombDelayTransition(delay *big.Int) *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return ctypes.MapMeetsSpecification(spec.Engine.Ethash.Params.DifficultyBombDelays, nil, delay, nil)
+}
+
+// setBombDelayTransition sets the difficulty bomb delay schedule to sum to the given
+// total delay at the given block.
+func (spec *ParityChainSpec) setBombDelayTransition(n *uint64, delay *big.Int) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if n == nil {
+		return nil
+	}
+	spec.ensureExistingDifficultySchedule()
+	spec.Engine.Ethash.Params.DifficultyBombDelays.SetValueTotalForHeight(n, delay)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashEIP2384Transition() *uint64 {
+	return spec.getBombDelayTransition(vars.EIP2384DifficultyBombDelay)
+}
+
+func (spec *ParityChainSpec) SetEthashEIP2384Transition(n *uint64) error {
+	return spec.setBombDelayTransition(n, vars.EIP2384DifficultyBombDelay)
+}
+
+func (spec *ParityChainSpec) GetEthashEIP3554Transition() *uint64 {
+	return spec.getBombDelayTransition(vars.EIP3554DifficultyBombDelay)
+}
+
+func (spec *ParityChainSpec) SetEthashEIP3554Transition(n *uint64) error {
+	return spec.setBombDelayTransition(n, vars.EIP3554DifficultyBombDelay)
+}
+
+func (spec *ParityChainSpec) GetEthashEIP4345Transition() *uint64 {
+	return spec.getBombDelayTransition(vars.EIP4345DifficultyBombDelay)
+}
+
+func (spec *ParityChainSpec) SetEthashEIP4345Transition(n *uint64) error {
+	return spec.setBombDelayTransition(n, vars.EIP4345DifficultyBombDelay)
+}
+
+func (spec *ParityChainSpec) GetEthashEIP5133Transition() *uint64 {
+	return spec.getBombDelayTransition(vars.EIP5133DifficultyBombDelay)
+}
+
+func (spec *ParityChainSpec) SetEthashEIP5133Transition(n *uint64) error {
+	return spec.setBombDelayTransition(n, vars.EIP5133DifficultyBombDelay)
+}
+
+func (spec *ParityChainSpec) GetEthashECIP1010PauseTransition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.ECIP1010PauseTransition)
+}
+
+func (spec *ParityChainSpec) SetEthashECIP1010PauseTransition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.ECIP1010PauseTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashECIP1010ContinueTransition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.ECIP1010ContinueTransition)
+}
+
+func (spec *ParityChainSpec) SetEthashECIP1010ContinueTransition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.ECIP1010ContinueTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashECIP1017Transition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.ECIP1017Transition)
+}
+
+func (spec *ParityChainSpec) SetEthashECIP1017Transition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.ECIP1017Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashECIP1017EraRounds() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.ECIP1017EraRounds)
+}
+
+func (spec *ParityChainSpec) SetEthashECIP1017EraRounds(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.ECIP1017EraRounds = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashEIP100BTransition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.EIP100bTransition)
+}
+
+func (spec *ParityChainSpec) SetEthashEIP100BTransition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.EIP100bTransition = newHexU64(n)
+	return nil
+}
+
+// GetEthashECIP1041Transition maps to Parity's bombDefuseTransition.
+func (spec *ParityChainSpec) GetEthashECIP1041Transition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.BombDefuseTransition)
+}
+
+func (spec *ParityChainSpec) SetEthashECIP1041Transition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.BombDefuseTransition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashECIP1099Transition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Ethash.Params.ECIP1099Transition)
+}
+
+func (spec *ParityChainSpec) SetEthashECIP1099Transition(n *uint64) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.ECIP1099Transition = newHexU64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashDifficultyBombDelaySchedule() ctypes.Uint64BigMapEncodesHex {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.DifficultyBombDelays
+}
+
+func (spec *ParityChainSpec) SetEthashDifficultyBombDelaySchedule(m ctypes.Uint64BigMapEncodesHex) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.DifficultyBombDelays = m
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEthashBlockRewardSchedule() ctypes.Uint64BigMapEncodesHex {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return ctypes.Uint64BigMapEncodesHex(spec.Engine.Ethash.Params.BlockReward)
+}
+
+func (spec *ParityChainSpec) SetEthashBlockRewardSchedule(m ctypes.Uint64BigMapEncodesHex) error {
+	if spec.Engine.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Ethash.Params.BlockReward = ctypes.Uint64BigValOrMapHex(m)
+	return nil
+}
+
+func (spec *ParityChainSpec) ensureExistingRewardSchedule() {
+	if spec.Engine.Ethash.Params.BlockReward == nil {
+		spec.Engine.Ethash.Params.BlockReward = ctypes.Uint64BigValOrMapHex{}
+	}
+}
+
+func (spec *ParityChainSpec) ensureExistingDifficultySchedule() {
+	if spec.Engine.Ethash.Params.DifficultyBombDelays == nil {
+		spec.Engine.Ethash.Params.DifficultyBombDelays = ctypes.Uint64BigMapEncodesHex{}
+	}
+}
+
+func (spec *ParityChainSpec) GetCliquePeriod() uint64 {
+	if spec.Engine.Clique == nil {
+		return 0
+	}
+	return spec.Engine.Clique.Params.Period
+}
+
+func (spec *ParityChainSpec) SetCliquePeriod(n uint64) error {
+	if spec.Engine.Clique == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Clique.Params.Period = n
+	return nil
+}
+
+func (spec *ParityChainSpec) GetCliqueEpoch() uint64 {
+	if spec.Engine.Clique == nil {
+		return 0
+	}
+	return spec.Engine.Clique.Params.Epoch
+}
+
+func (spec *ParityChainSpec) SetCliqueEpoch(n uint64) error {
+	if spec.Engine.Clique == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Clique.Params.Epoch = n
+	return nil
+}
+
+func (spec *ParityChainSpec) GetLyra2NonceTransition() *uint64 {
+	if spec.Engine.Lyra2 == nil {
+		return nil
+	}
+	return hexU64(spec.Engine.Lyra2.Params.NonceTransition)
+}
+
+func (spec *ParityChainSpec) SetLyra2NonceTransition(n *uint64) error {
+	if spec.Engine.Lyra2 == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Lyra2.Params.NonceTransition = newHexU64(n)
+	return nil
+}
+
+// Following methods implement the ctypes.GenesisBlocker interface.
+
+func (spec *ParityChainSpec) GetSealingType() ctypes.BlockSealingT {
+	return ctypes.BlockSealing_Ethereum
+}
+
+func (spec *ParityChainSpec) SetSealingType(t ctypes.BlockSealingT) error {
+	if t != ctypes.BlockSealing_Ethereum {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGenesisSealerEthereumNonce() uint64 {
+	return new(big.Int).SetBytes(spec.Genesis.Seal.Ethereum.Nonce).Uint64()
+}
+
+func (spec *ParityChainSpec) SetGenesisSealerEthereumNonce(n uint64) error {
+	spec.Genesis.Seal.Ethereum.Nonce = make(hexutil.Bytes, 8)
+	binary.BigEndian.PutUint64(spec.Genesis.Seal.Ethereum.Nonce, n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGenesisSealerEthereumMixHash() common.Hash {
+	return spec.Genesis.Seal.Ethereum.MixHash
+}
+
+func (spec *ParityChainSpec) SetGenesisSealerEthereumMixHash(h common.Hash) error {
+	spec.Genesis.Seal.Ethereum.MixHash = h
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGenesisDifficulty() *big.Int {
+	return hexBig(spec.Genesis.Difficulty)
+}
+
+func (spec *ParityChainSpec) SetGenesisDifficulty(i *big.Int) error {
+	spec.Genesis.Difficulty = newHexBig(i)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGenesisAuthor() common.Address {
+	return spec.Genesis.Author
+}
+
+func (spec *ParityChainSpec) SetGenesisAuthor(a common.Address) error {
+	spec.Genesis.Author = a
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGenesisTimestamp() uint64 {
+	return uint64(spec.Genesis.Timestamp)
+}
+
+func (spec *ParityChainSpec) SetGenesisTimestamp(u uint64) error {
+	spec.Genesis.Timestamp = math.HexOrDecimal64(u)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGenesisParentHash() common.Hash {
+	return spec.Genesis.ParentHash
+}
+
+func (spec *ParityChainSpec) SetGenesisParentHash(h common.Hash) error {
+	spec.Genesis.ParentHash = h
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGenesisExtraData() []byte {
+	return spec.Genesis.ExtraData
+}
+
+func (spec *ParityChainSpec) SetGenesisExtraData(b []byte) error {
+	spec.Genesis.ExtraData = b
+	return nil
+}
+
+func (spec *ParityChainSpec) GetGenesisGasLimit() uint64 {
+	return uint64(spec.Genesis.GasLimit)
+}
+
+func (spec *ParityChainSpec) SetGenesisGasLimit(u uint64) error {
+	spec.Genesis.GasLimit = math.HexOrDecimal64(u)
+	return nil
+}
+
+// ForEachAccount calls fn for each account of the genesis state.
+// Accounts only configuring a builtin contract are skipped.
+func (spec *ParityChainSpec) ForEachAccount(fn func(address common.Address, bal *big.Int, nonce uint64, code []byte, storage map[common.Hash]common.Hash) error) error {
+	for k, v := range spec.Accounts {
+		if v.isBuiltinOnly() {
+			continue
+		}
+		bal := hexBig(v.Balance)
+		if bal == nil {
+			bal = new(big.Int)
+		}
+		if err := fn(k, bal, uint64(v.Nonce), v.Code, v.Storage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateAccount sets the genesis state of the account, keeping any builtin it configures.
+func (spec *ParityChainSpec) UpdateAccount(address common.Address, bal *big.Int, nonce uint64, code []byte, storage map[common.Hash]common.Hash) error {
+	if spec.Accounts == nil {
+		spec.Accounts = make(map[common.Address]*ParityChainSpecAccount)
+	}
+	acc, ok := spec.Accounts[address]
+	if !ok {
+		acc = new(ParityChainSpecAccount)
+		spec.Accounts[address] = acc
+	}
+	if bal == nil {
+		bal = new(big.Int)
+	}
+	acc.Balance = newHexBig(bal)
+	acc.Nonce = math.HexOrDecimal64(nonce)
+	acc.Code = code
+	acc.Storage = storage
+	return nil
+}
+
+// getBuiltin returns the builtin configured at the address, or nil.
+func (spec *ParityChainSpec) getBuiltin(address common.Address) *ParityChainSpecBuiltin {
+	acc, ok := spec.Accounts[address]
+	if !ok || acc.Builtin == nil || len(acc.Builtin.Pricing) == 0 {
+		return nil
+	}
+	return acc.Builtin
+}
+
+// getBuiltinActivation returns the block at which the builtin is activated.
+func (spec *ParityChainSpec) getBuiltinActivation(address common.Address) *uint64 {
+	b := spec.getBuiltin(address)
+	if b == nil {
+		return nil
+	}
+	return newU64(b.activations()[0])
+}
+
+// getBuiltinPriceActivation returns the first block from which the builtin is priced as matched.
+func (spec *ParityChainSpec) getBuiltinPriceActivation(address common.Address, match func(p ParityChainSpecPricing) bool) *uint64 {
+	b := spec.getBuiltin(address)
+	if b == nil {
+		return nil
+	}
+	for _, at := range b.activations() {
+		if match(b.Pricing[at]) {
+			return newU64(at)
+		}
+	}
+	return nil
+}
+
+// setBuiltinActivation moves the builtin's initial price to the given block.
+// A price already set at that block (eg. a repricing also activated at the block) takes precedence.
+// A nil block removes the initial price, removing the builtin if no prices are left.
+func (spec *ParityChainSpec) setBuiltinActivation(address common.Address, name string, n *uint64, price ParityChainSpecPricing) {
+	b := spec.ensureBuiltin(address, name)
+	for at, p := range b.Pricing {
+		if reflect.DeepEqual(p, price) {
+			delete(b.Pricing, at)
+		}
+	}
+	if n != nil {
+		if _, ok := b.Pricing[*n]; !ok {
+			b.Pricing[*n] = price
+		}
+	}
+	spec.pruneBuiltin(address)
+}
+
+// setBuiltinPrice moves the given price of the builtin to the given block,
+// or removes it if the block is nil.
+func (spec *ParityChainSpec) setBuiltinPrice(address common.Address, name string, n *uint64, price ParityChainSpecPricing) {
+	b := spec.ensureBuiltin(address, name)
+	for at, p := range b.Pricing {
+		if reflect.DeepEqual(p, price) {
+			delete(b.Pricing, at)
+		}
+	}
+	if n != nil {
+		b.Pricing[*n] = price
+	}
+	spec.pruneBuiltin(address)
+}
+
+func (spec *ParityChainSpec) ensureBuiltin(address common.Address, name string) *ParityChainSpecBuiltin {
+	if spec.Accounts == nil {
+		spec.Accounts = make(map[common.Address]*ParityChainSpecAccount)
+	}
+	acc, ok := spec.Accounts[address]
+	if !ok {
+		acc = new(ParityChainSpecAccount)
+		spec.Accounts[address] = acc
+	}
+	if acc.Builtin == nil {
+		acc.Builtin = &ParityChainSpecBuiltin{Name: name}
+	}
+	if acc.Builtin.Pricing == nil {
+		acc.Builtin.Pricing = make(map[uint64]ParityChainSpecPricing)
+	}
+	return acc.Builtin
+}
+
+// pruneBuiltin removes a builtin without prices, and its account if it only configured the builtin.
+func (spec *ParityChainSpec) pruneBuiltin(address common.Address) {
+	acc, ok := spec.Accounts[address]
+	if !ok || acc.Builtin == nil || len(acc.Builtin.Pricing) > 0 {
+		return
+	}
+	acc.Builtin = nil
+	if acc.isBuiltinOnly() {
+		delete(spec.Accounts, address)
+	}
+}
@@ -0,0 +1,96 @@
+package parity
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/yuriy0803/core-geth1/common"
+	"github.com/yuriy0803/core-geth1/params/types/ctypes"
+)
+
+func TestParityChainSpec_builtins(t *testing.T) {
+	input := `{
+	"accounts": {
+		"0x0000000000000000000000000000000000000005": {
+			"builtin": {"name": "modexp", "activate_at": "0x85d9a0", "pricing": {"modexp": {"divisor": 20}}}
+		},
+		"0x0000000000000000000000000000000000000006": {
+			"balance": "0x1",
+			"builtin": {"name": "alt_bn128_add", "pricing": {
+				"8772000": {"price": {"alt_bn128_const_operations": {"price": 500}}},
+				"0xa03ae7": {"price": {"alt_bn128_const_operations": {"price": 150}}}
+			}}
+		}
+	}
+}`
+	spec := &ParityChainSpec{}
+	if err := json.Unmarshal([]byte(input), spec); err != nil {
+		t.Fatal(err)
+	}
+	if v := spec.GetEIP198Transition(); v == nil || *v != 8772000 {
+		t.Errorf("eip198: want 8772000, got %v", v)
+	}
+	if v := spec.GetEIP213Transition(); v == nil || *v != 8772000 {
+		t.Errorf("eip213: want 8772000, got %v", v)
+	}
+	if v := spec.GetEIP1108Transition(); v == nil || *v != 10500839 {
+		t.Errorf("eip1108: want 10500839, got %v", v)
+	}
+
+	// Only the funded account is part of the genesis state.
+	var accounts []common.Address
+	err := spec.ForEachAccount(func(address common.Address, bal *big.Int, nonce uint64, code []byte, storage map[common.Hash]common.Hash) error {
+		accounts = append(accounts, address)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0] != builtinAltBnAddAddress {
+		t.Errorf("accounts: want [%x], got %x", builtinAltBnAddAddress, accounts)
+	}
+
+	// Unsetting the feature removes the builtin, and the account if only configuring the builtin.
+	if err := spec.SetEIP198Transition(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Accounts[builtinModExpAddress]; ok {
+		t.Error("modexp builtin account not removed")
+	}
+	if err := spec.SetEIP1108Transition(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.SetEIP213Transition(nil); err != nil {
+		t.Fatal(err)
+	}
+	if acc, ok := spec.Accounts[builtinAltBnAddAddress]; !ok || acc.Builtin != nil {
+		t.Error("alt_bn128_add account not kept without builtin")
+	}
+}
+
+func TestParityChainSpec_engine(t *testing.T) {
+	spec := &ParityChainSpec{}
+	n := uint64(1150000)
+
+	// Homestead is an Ethash parameter, and is kept when the engine is set.
+	if err := spec.SetEIP2Transition(&n); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.MustSetConsensusEngineType(ctypes.ConsensusEngineT_Ethash); err != nil {
+		t.Fatal(err)
+	}
+	if v := spec.GetEthashHomesteadTransition(); v == nil || *v != n {
+		t.Errorf("homestead: want %d, got %v", n, v)
+	}
+
+	if err := spec.MustSetConsensusEngineType(ctypes.ConsensusEngineT_Clique); err != nil {
+		t.Fatal(err)
+	}
+	if v := spec.GetEIP2Transition(); v == nil || *v != 0 {
+		t.Errorf("clique homestead: want 0, got %v", v)
+	}
+	if err := spec.SetEIP2Transition(&n); err != ctypes.ErrUnsupportedConfigFatal {
+		t.Errorf("clique homestead: want error %v, got %v", ctypes.ErrUnsupportedConfigFatal, err)
+	}
+}
@@ -36,6 +36,14 @@ const (
 	// considered probably final and its rotated bits are calculated.
 	BloomConfirms = 256
 
+	// TraceIndexBlocks is the number of blocks a single trace index section
+	// contains.
+	TraceIndexBlocks uint64 = 4096
+
+	// TraceIndexConfirms is the number of confirmation blocks before a trace index
+	// section is considered probably final and its blocks are traced.
+	TraceIndexConfirms = 256
+
 	// CHTFrequency is the block frequency for creating CHTs
 	CHTFrequency = 32768
 
@@ -88,6 +88,8 @@ type Client struct {
 	// config fields
 	batchItemLimit       int
 	batchResponseMaxSize int
+	callFilter           CallFilter
+	callObserver         CallObserver
 
 	// writeConn is used for writing to the connection on the caller's goroutine. It should
 	// only be accessed outside of dispatch, with the write lock held. The write lock is
@@ -120,6 +122,8 @@ func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx = context.WithValue(ctx, clientContextKey{}, c)
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, conn.peerInfo())
 	handler := newHandler(ctx, conn, c.idgen, c.services, c.batchItemLimit, c.batchResponseMaxSize)
+	handler.callFilter = c.callFilter
+	handler.callObserver = c.callObserver
 	return &clientConn{conn, handler}
 }
 
@@ -247,6 +251,8 @@ func initClient(conn ServerCodec, services *serviceRegistry, cfg *clientConfig)
 		idgen:                cfg.idgen,
 		batchItemLimit:       cfg.batchItemLimit,
 		batchResponseMaxSize: cfg.batchResponseLimit,
+		callFilter:           cfg.callFilter,
+		callObserver:         cfg.callObserver,
 		writeConn:            conn,
 		close:                make(chan struct{}),
 		closing:              make(chan struct{}),
@@ -40,6 +40,8 @@ type clientConfig struct {
 	idgen              func() ID
 	batchItemLimit     int
 	batchResponseLimit int
+	callFilter         CallFilter
+	callObserver       CallObserver
 }
 
 func (cfg *clientConfig) initHeaders() {
@@ -62,6 +62,8 @@ type handler struct {
 	allowSubscribe       bool
 	batchRequestLimit    int
 	batchResponseMaxSize int
+	callFilter           CallFilter
+	callObserver         CallObserver
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -70,6 +72,7 @@ type handler struct {
 type callProc struct {
 	ctx       context.Context
 	notifiers []*Notifier
+	batchSize int // number of calls in the batch being processed
 }
 
 func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, batchRequestLimit, batchResponseMaxSize int) *handler {
@@ -201,6 +204,7 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		)
 
 		cp.ctx, cancel = context.WithCancel(cp.ctx)
+		cp.batchSize = len(calls)
 		defer cancel()
 
 		// Cancel the request context after timeout and send an error response. Since the
@@ -468,6 +472,7 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 	case msg.isCall():
 		resp := h.handleCall(ctx, msg)
+		h.observeCall(ctx, msg, resp, start)
 		var ctx []interface{}
 		ctx = append(ctx, "reqid", idForLog{msg.ID}, "duration", time.Since(start))
 		if resp.Error != nil {
@@ -489,8 +494,36 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	}
 }
 
+// observeCall reports a served call to the call observer.
+func (h *handler) observeCall(cp *callProc, msg, resp *jsonrpcMessage, start time.Time) {
+	if h.callObserver == nil {
+		return
+	}
+	rec := &CallRecord{
+		Time:         start,
+		Peer:         PeerInfoFromContext(cp.ctx),
+		Method:       msg.Method,
+		ParamsSize:   len(msg.Params),
+		BatchSize:    cp.batchSize,
+		Duration:     time.Since(start),
+		ResponseSize: len(resp.Result),
+	}
+	if resp.Error != nil {
+		rec.ErrorCode, rec.Error = resp.Error.Code, resp.Error.Message
+		if blob, err := json.Marshal(resp.Error); err == nil {
+			rec.ResponseSize = len(blob)
+		}
+	}
+	h.callObserver(rec)
+}
+
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if h.callFilter != nil && !msg.isUnsubscribe() {
+		if err := h.callFilter(cp.ctx, msg.Method, msg.Params); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
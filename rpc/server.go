@@ -18,9 +18,11 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/yuriy0803/core-geth1/log"
 )
@@ -51,8 +53,31 @@ type Server struct {
 	run                atomic.Bool
 	batchItemLimit     int
 	batchResponseLimit int
+	callFilter         CallFilter
+	callObserver       CallObserver
 }
 
+// CallFilter is consulted before serving a method call. The context carries the
+// PeerInfo of the client. A non-nil error rejects the call and is sent to the client
+// in place of the result, with its error code if it implements Error.
+type CallFilter func(ctx context.Context, method string, params json.RawMessage) error
+
+// CallRecord describes a served method call.
+type CallRecord struct {
+	Time         time.Time     // when the call started
+	Peer         PeerInfo      // client of the call
+	Method       string        // name of the method
+	ParamsSize   int           // size of the parameters in bytes
+	BatchSize    int           // number of calls in the batch, zero if not batched
+	Duration     time.Duration // time taken to serve the call
+	ResponseSize int           // size of the result or error in bytes
+	ErrorCode    int           // code of the error, zero on success
+	Error        string        // message of the error
+}
+
+// CallObserver is called after serving each method call, e.g. to log it.
+type CallObserver func(rec *CallRecord)
+
 // NewServer creates a new server instance with no registered handlers.
 func NewServer() *Server {
 	server := &Server{
@@ -78,6 +103,23 @@ func (s *Server) SetBatchLimits(itemLimit, maxResponseSize int) {
 	s.batchResponseLimit = maxResponseSize
 }
 
+// SetCallFilter sets a filter of the method calls served. Unsubscribing is always
+// allowed.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetCallFilter(filter CallFilter) {
+	s.callFilter = filter
+}
+
+// SetCallObserver sets an observer of the method calls served.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetCallObserver(observer CallObserver) {
+	s.callObserver = observer
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -103,6 +145,8 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 		idgen:              s.idgen,
 		batchItemLimit:     s.batchItemLimit,
 		batchResponseLimit: s.batchResponseLimit,
+		callFilter:         s.callFilter,
+		callObserver:       s.callObserver,
 	}
 	c := initClient(codec, &s.services, cfg)
 	<-codec.closed()
@@ -138,6 +182,8 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 
 	h := newHandler(ctx, codec, s.idgen, &s.services, s.batchItemLimit, s.batchResponseLimit)
 	h.allowSubscribe = false
+	h.callFilter = s.callFilter
+	h.callObserver = s.callObserver
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -229,10 +275,29 @@ type PeerInfo struct {
 		Origin    string
 		Host      string
 	}
+
+	// Subject is the identity of the authenticated client, e.g. the subject of its
+	// JWT token. It is empty if the client did not authenticate.
+	Subject string
 }
 
 type peerInfoContextKey struct{}
 
+type peerSubjectContextKey struct{}
+
+// NewContextWithPeerSubject wraps the context of an HTTP request, adding the identity
+// of the authenticated client. Servers serving the request report it as the Subject
+// of the PeerInfo.
+func NewContextWithPeerSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, peerSubjectContextKey{}, subject)
+}
+
+// peerSubjectFromContext returns the identity of the authenticated client.
+func peerSubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(peerSubjectContextKey{}).(string)
+	return subject
+}
+
 // PeerInfoFromContext returns information about the client's network connection.
 // Use this with the context passed to RPC method handler functions.
 //
@@ -19,6 +19,9 @@ package rpc
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net"
 	"os"
@@ -191,3 +194,74 @@ func TestServerBatchResponseSizeLimit(t *testing.T) {
 		}
 	}
 }
+
+func TestServerCallFilter(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	var methods []string
+	server.SetCallFilter(func(ctx context.Context, method string, params json.RawMessage) error {
+		if PeerInfoFromContext(ctx).Transport != "ipc" {
+			t.Errorf("call filter of %s without peer info", method)
+		}
+		methods = append(methods, method)
+		if method == "test_echo" {
+			return testError{}
+		}
+		return nil
+	})
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result echoResult
+	err := client.Call(&result, "test_echo", "x", 1)
+	if re, ok := err.(Error); !ok || re.ErrorCode() != (testError{}).ErrorCode() {
+		t.Fatalf("expected filtered call, got %v", err)
+	}
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sub, err := client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 1)
+	if err != nil {
+		t.Fatalf("can't subscribe: %v", err)
+	}
+	sub.Unsubscribe()
+
+	want := []string{"test_echo", "test_noArgsRets", "nftest_subscribe"}
+	if fmt.Sprint(methods) != fmt.Sprint(want) {
+		t.Fatalf("wrong filtered methods: have %v, want %v", methods, want)
+	}
+}
+
+func TestServerCallObserver(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	records := make(chan *CallRecord, 10)
+	server.SetCallObserver(func(rec *CallRecord) { records <- rec })
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatal(err)
+	}
+	batch := []BatchElem{{Method: "test_null"}, {Method: "test_returnError"}}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []CallRecord{
+		{Method: "test_echo", ParamsSize: 7, ResponseSize: 34},
+		{Method: "test_null", ParamsSize: 0, BatchSize: 2, ResponseSize: 4},
+		{Method: "test_returnError", ParamsSize: 0, BatchSize: 2, ResponseSize: 58, ErrorCode: 444, Error: "testError"},
+	} {
+		have := <-records
+		if have.Peer.Transport != "ipc" || have.Time.IsZero() || have.Duration < 0 {
+			t.Errorf("record %d: missing details: %+v", i, have)
+		}
+		have.Peer, have.Time, have.Duration = PeerInfo{}, time.Time{}, 0
+		if *have != want {
+			t.Errorf("record %d: wrong record\nhave %+v\nwant %+v", i, *have, want)
+		}
+	}
+}
@@ -33,6 +33,10 @@ type Config struct {
 	Preimages bool           // Flag whether the preimage of trie key is recorded
 	PathDB    *pathdb.Config // Configs for experimental path-based scheme, not used yet.
 
+	// OnUpdate is invoked with the original values of the states mutated by
+	// every state transition committed, allowing to record it in reverse.
+	OnUpdate func(root common.Hash, parent common.Hash, block uint64, states *triestate.Set)
+
 	// Testing hooks
 	OnCommit func(states *triestate.Set) // Hook invoked when commit is performed
 }
@@ -133,6 +137,9 @@ func (db *Database) Update(root common.Hash, parent common.Hash, block uint64, n
 	if db.config != nil && db.config.OnCommit != nil {
 		db.config.OnCommit(states)
 	}
+	if db.config != nil && db.config.OnUpdate != nil && states != nil {
+		db.config.OnUpdate(root, parent, block, states)
+	}
 	if db.preimages != nil {
 		db.preimages.commit(false)
 	}